@@ -2,11 +2,20 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -14,6 +23,7 @@ import (
 
 	"github.com/0x524A/metricsd/internal/collector"
 	"github.com/0x524A/metricsd/internal/config"
+	"github.com/0x524A/metricsd/internal/logging"
 	"github.com/0x524A/metricsd/internal/orchestrator"
 	"github.com/0x524A/metricsd/internal/plugin"
 	"github.com/0x524A/metricsd/internal/server"
@@ -24,16 +34,57 @@ const (
 	defaultConfigPath = "config.json"
 )
 
+// Build information, injected with -ldflags "-X main.Version=... -X main.Commit=... -X main.BuildDate=..."
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
+// Exit codes for --once mode
+const (
+	exitCollectionFailed = 2
+	exitShipFailed       = 3
+)
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(runValidate(os.Args[2:], os.Stdout))
+	}
+
 	// Parse command-line flags
-	configPath := flag.String("config", defaultConfigPath, "Path to configuration file")
+	configPath := flag.String("config", defaultConfigPath, "Path to configuration file, or - to read it from stdin (MC_CONFIG_JSON takes precedence)")
 	logLevel := flag.String("log-level", "info", "Log level (debug, info, warn, error)")
+	dryRun := flag.Bool("dry-run", false, "Collect once, print metrics to stdout and exit without shipping")
+	once := flag.Bool("once", false, "Run a single collect-and-ship cycle and exit (exit code 2: collection failed, 3: shipping failed)")
+	demo := flag.Bool("demo", false, "Replace the configured collectors with synthetic demo metrics, to test shipping end to end")
+	logFormat := flag.String("log-format", "console", "Log format (console, json)")
+	logFilePath := flag.String("log-file", "", "Write logs to this file instead of stdout")
+	logMaxSize := flag.Int("log-max-size", 100, "Rotate the log file once it reaches this many megabytes")
+	logMaxFiles := flag.Int("log-max-files", 5, "Number of rotated log files to keep")
+	printCfg := flag.Bool("print-config", false, "Print the resolved configuration (after env overrides and defaults) as JSON and exit")
+	showVersion := flag.Bool("version", false, "Print version, commit and build date and exit")
 	flag.Parse()
 
-	// Setup logging
-	setupLogging(*logLevel)
+	if *showVersion {
+		fmt.Printf("metricsd %s (commit %s, built %s)\n", Version, Commit, BuildDate)
+		return
+	}
+
+	// Setup logging; dry-run and print-config keep stdout for their output
+	logOut := io.Writer(os.Stdout)
+	if *dryRun || *printCfg {
+		logOut = os.Stderr
+	}
+	logFile, err := setupLogging(*logLevel, *logFormat, *logFilePath, *logMaxSize, *logMaxFiles, logOut)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to set up logging")
+	}
+	if logFile != nil {
+		defer func() { _ = logFile.Close() }()
+	}
 
-	log.Info().Msg("Starting Metrics Collector Service")
+	log.Info().Str("version", Version).Str("commit", Commit).Msg("Starting Metrics Collector Service")
 
 	// Load configuration
 	cfg, err := config.Load(*configPath)
@@ -41,25 +92,84 @@ func main() {
 		log.Fatal().Err(err).Msg("Failed to load configuration")
 	}
 
+	configSource := *configPath
+	if os.Getenv(config.ConfigJSONEnv) != "" {
+		configSource = config.ConfigJSONEnv
+	}
 	log.Info().
-		Str("config_file", *configPath).
+		Str("config_file", configSource).
 		Msg("Configuration loaded successfully")
 
+	if *printCfg {
+		if err := printConfig(cfg, os.Stdout); err != nil {
+			log.Fatal().Err(err).Msg("Failed to print configuration")
+		}
+		return
+	}
+
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	// Initialize components
-	collectorRegistry, pluginMgr := setupCollectors(cfg)
+	var collectorRegistry *collector.Registry
+	var pluginMgr *plugin.Manager
+	if *demo {
+		collectorRegistry = collector.NewRegistry()
+		collectorRegistry.Register(collector.NewDemoCollector())
+		log.Warn().Msg("Demo mode: shipping synthetic metrics instead of the configured collectors")
+	} else {
+		collectorRegistry, pluginMgr = setupCollectors(cfg)
+	}
+	if logFile != nil {
+		collectorRegistry.Register(logFile)
+	}
+	collectorRegistry.SetLogger(log.Logger)
+
+	if *dryRun {
+		runDryRun(ctx, newOrchestrator(cfg, collectorRegistry, nil), os.Stdout)
+		return
+	}
+
 	metricShipper := setupShipper(cfg)
 	defer func() { _ = metricShipper.Close() }()
 
 	// Create orchestrator
-	orch := orchestrator.NewOrchestrator(
-		collectorRegistry,
-		metricShipper,
-		cfg.GetCollectionInterval(),
-	)
+	orch := newOrchestrator(cfg, collectorRegistry, metricShipper)
+	if err := orch.SetStateFile(cfg.StateFile); err != nil {
+		log.Warn().Err(err).Msg("Failed to restore counter state, counting from zero")
+	}
+	if idle := cfg.Collector.IdleIntervalSeconds; idle > 0 {
+		var conditions []orchestrator.IdleCondition
+		if cfg.Collector.IdleOnBattery {
+			conditions = append(conditions, orchestrator.OnBattery())
+		}
+		if n := cfg.Collector.IdleAfterShipFailures; n > 0 {
+			conditions = append(conditions, orchestrator.ShipFailures(orch.Status(), n))
+		}
+		orch.SetIdleInterval(time.Duration(idle)*time.Second, conditions...)
+	}
+	orch.SetReadinessFailureThreshold(cfg.Server.ReadinessFailureThreshold)
+
+	if *once {
+		os.Exit(runOnce(ctx, orch, metricShipper))
+	}
+
+	// Tell systemd when we're ready and ping its watchdog after each good cycle
+	notifier, err := newSystemdNotifier()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to connect to systemd notify socket")
+	}
+	if notifier != nil {
+		defer notifier.close()
+		orch.SetCycleSuccessHook(notifier.cycleSucceeded)
+		if wd := systemdWatchdogInterval(); wd > 0 && wd < cfg.GetCollectionInterval() {
+			log.Warn().
+				Dur("watchdog", wd).
+				Dur("interval", cfg.GetCollectionInterval()).
+				Msg("systemd WatchdogSec is shorter than the collection interval; the service will be restarted between cycles")
+		}
+	}
 
 	// Create HTTP server for health checks
 	var healthProvider server.HealthProvider
@@ -67,6 +177,24 @@ func main() {
 		healthProvider = &pluginHealthAdapter{mgr: pluginMgr}
 	}
 	httpServer := server.NewServer(cfg.Server.Host, cfg.Server.Port, healthProvider)
+	httpServer.SetLogger(log.Logger)
+	httpServer.SetReadinessChecker(orch.Status())
+	httpServer.SetStatusProvider(&orchestratorStatusAdapter{status: orch.Status()})
+	if pluginMgr != nil {
+		httpServer.SetPluginStatusProvider(&pluginStatusAdapter{mgr: pluginMgr})
+	}
+	httpServer.SetPprofEnabled(cfg.Server.EnablePprof)
+	if cfg.Server.EnableAdmin {
+		httpServer.SetCollectorToggler(&collectorTogglerAdapter{registry: collectorRegistry})
+		httpServer.SetCollectTrigger(&collectTriggerAdapter{orch: orch})
+	}
+	if cfg.Server.TLS.Enabled {
+		httpServer.SetTLS(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile, cfg.Server.TLS.CAFile)
+	}
+	httpServer.SetAuth(cfg.Server.Auth.Username, cfg.Server.Auth.Password, cfg.Server.Auth.BearerToken)
+	if cfg.Server.EnablePprof {
+		log.Warn().Msg("pprof endpoints enabled under /debug/pprof/")
+	}
 
 	// Setup signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -99,6 +227,7 @@ func main() {
 
 	// Graceful shutdown
 	log.Info().Msg("Initiating graceful shutdown")
+	notifier.notify("STOPPING=1")
 	cancel()
 
 	// Give services time to shutdown gracefully
@@ -109,17 +238,19 @@ func main() {
 		log.Error().Err(err).Msg("Error during server shutdown")
 	}
 
-	orch.Stop()
-
-	// Cleanup GPU collector if enabled
-	if cfg.Collector.EnableGPU {
-		cleanupGPUCollector(collectorRegistry)
+	// Ships one last cycle, flushes shipper buffers and releases collector
+	// resources such as NVML, all within the shutdown timeout
+	if err := orch.Shutdown(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Final flush before shutdown failed")
 	}
 
 	log.Info().Msg("Metrics Collector Service stopped")
 }
 
-func setupLogging(level string) {
+// setupLogging configures the global logger. Logs go to out unless filePath is
+// set, in which case they go to a size-rotated file that is returned so the
+// caller can close it.
+func setupLogging(level, format, filePath string, maxSizeMB, maxFiles int, out io.Writer) (*logging.RotatingFile, error) {
 	// Configure zerolog
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 
@@ -137,43 +268,120 @@ func setupLogging(level string) {
 		zerolog.SetGlobalLevel(zerolog.InfoLevel)
 	}
 
-	// Pretty console output
-	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stdout})
+	var logFile *logging.RotatingFile
+	if filePath != "" {
+		f, err := logging.NewRotatingFile(filePath, maxSizeMB, maxFiles)
+		if err != nil {
+			return nil, err
+		}
+		logFile = f
+		out = f
+	}
+
+	switch format {
+	case "json":
+		log.Logger = zerolog.New(out).With().Timestamp().Logger()
+	case "console", "":
+		// Pretty console output; no colour codes when writing to a file
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: out, NoColor: logFile != nil})
+	default:
+		if logFile != nil {
+			_ = logFile.Close()
+		}
+		return nil, fmt.Errorf("invalid log format %q (must be console or json)", format)
+	}
+
+	return logFile, nil
 }
 
 func setupCollectors(cfg *config.Config) (*collector.Registry, *plugin.Manager) {
 	registry := collector.NewRegistry()
+	registry.SetFailureThreshold(cfg.Collector.FailureThreshold)
 	var pluginMgr *plugin.Manager
 
 	// Register system collector if any OS metrics are enabled
-	if cfg.Collector.EnableCPU || cfg.Collector.EnableMemory || cfg.Collector.EnableDisk || cfg.Collector.EnableNetwork {
+	if cfg.Collector.EnableCPU || cfg.Collector.EnableMemory || cfg.Collector.EnableDisk || cfg.Collector.EnableNetwork || cfg.Collector.EnableTCPStats || cfg.Collector.EnableThermal || cfg.Collector.EnablePower {
 		systemCollector := collector.NewSystemCollector(
 			cfg.Collector.EnableCPU,
 			cfg.Collector.EnableMemory,
 			cfg.Collector.EnableDisk,
 			cfg.Collector.EnableNetwork,
 		)
+		systemCollector.SetTCPStats(cfg.Collector.EnableTCPStats)
+		systemCollector.SetThermal(cfg.Collector.EnableThermal)
+		systemCollector.SetPower(cfg.Collector.EnablePower)
+		systemCollector.SetDiskIO(cfg.Collector.DiskIO)
 		registry.Register(systemCollector)
 		log.Info().Msg("System collector registered")
 	}
 
 	// Register GPU collector if enabled
 	if cfg.Collector.EnableGPU {
-		gpuCollector := collector.NewGPUCollector()
+		gpuCollector := collector.NewGPUCollector(cfg.Collector.GPUPerProcess)
 		registry.Register(gpuCollector)
 		log.Info().Msg("GPU collector registered")
 	}
 
+	// Register cgroup collector if enabled and metricsd runs in a cgroup
+	if cfg.Collector.EnableCgroup {
+		cgroupCollector, err := collector.NewCgroupCollector()
+		if err != nil {
+			log.Warn().Err(err).Msg("Cgroup collector not registered")
+		} else {
+			registry.Register(cgroupCollector)
+			log.Info().Msg("Cgroup collector registered")
+		}
+	}
+
+	// Register process collector if any process patterns are configured
+	if len(cfg.Collector.Processes) > 0 {
+		processCollector, err := collector.NewProcessCollector(cfg.Collector.Processes)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to create process collector")
+		}
+		registry.Register(processCollector)
+		log.Info().Strs("patterns", cfg.Collector.Processes).Msg("Process collector registered")
+	}
+
 	// Register HTTP collectors for application endpoints
 	if len(cfg.Endpoints) > 0 {
+		facts := collector.CurrentHostFacts()
 		endpoints := make([]collector.EndpointConfig, 0, len(cfg.Endpoints))
 		for _, ep := range cfg.Endpoints {
+			url, urls, err := expandEndpointURLs(ep, facts)
+			if err != nil {
+				log.Fatal().Err(err).Str("endpoint", ep.Name).Msg("Invalid endpoint URL template")
+			}
 			endpoints = append(endpoints, collector.EndpointConfig{
-				Name: ep.Name,
-				URL:  ep.URL,
+				Name:            ep.Name,
+				URL:             url,
+				URLs:            urls,
+				Method:          ep.Method,
+				Body:            ep.Body,
+				Headers:         ep.Headers,
+				Labels:          ep.Labels,
+				MetricAllowlist: ep.MetricAllowlist,
+				MetricDenylist:  ep.MetricDenylist,
+				NameRewrite:     nameRewriteRules(ep.NameRewrite),
+				FollowRedirects: ep.FollowRedirects,
+				MaxRedirects:    ep.MaxRedirects,
+				AcceptStatus:    ep.AcceptStatus,
+				Exemplars:       ep.Exemplars,
+				Retries:         ep.Retries,
+				RetryBackoff:    time.Duration(ep.RetryBackoffSeconds * float64(time.Second)),
+				ParseRegex:      ep.ParseRegex,
+				Discovery:       ep.Discovery,
+				DNSName:         ep.DNSName,
+				DNSType:         ep.DNSType,
+				RefreshInterval: time.Duration(ep.RefreshIntervalSeconds) * time.Second,
+
+				CounterContinuity: ep.CounterContinuity,
 			})
 		}
 		httpCollector := collector.NewHTTPCollector(endpoints, cfg.Shipper.Timeout)
+		httpCollector.SetMaxConcurrency(cfg.Collector.HTTPMaxConcurrency)
+		httpCollector.SetUserAgent(userAgent(cfg))
+		httpCollector.SetProxy(proxyConfig(cfg))
 		registry.Register(httpCollector)
 		log.Info().Int("endpoint_count", len(endpoints)).Msg("HTTP collector registered")
 	}
@@ -181,41 +389,57 @@ func setupCollectors(cfg *config.Config) (*collector.Registry, *plugin.Manager)
 	// Register plugin manager
 	if cfg.Collector.Plugins.Enabled {
 		pluginMgr = plugin.NewManager()
+		pluginMgr.SetMaxConcurrency(cfg.Collector.Plugins.MaxConcurrency)
 
-		// Discover shell plugins
+		// Discover shell plugins; invalid ones are skipped unless strict_plugins is set
 		defaultTimeout := time.Duration(cfg.Collector.Plugins.DefaultTimeoutSeconds) * time.Second
 		if defaultTimeout == 0 {
 			defaultTimeout = plugin.DefaultTimeout
 		}
-		execPlugins, err := plugin.DiscoverPlugins(
-			cfg.Collector.Plugins.PluginsDir,
-			defaultTimeout,
-			cfg.Collector.Plugins.ValidateOnStartup,
-		)
+		execPlugins, loadErrs, err := plugin.LoadPlugins(cfg.Collector.Plugins.PluginsDir, commandAllowlist(cfg.Collector.Plugins), log.Logger)
 		if err != nil {
 			log.Warn().Err(err).Msg("Failed to discover plugins")
 		}
+		minInterval := time.Duration(cfg.Collector.Plugins.MinIntervalSeconds) * time.Second
 		for _, ep := range execPlugins {
+			ep.SetDefaultTimeout(defaultTimeout)
+			ep.SetDefaultMaxOutputBytes(cfg.Collector.Plugins.MaxOutputBytes)
+			if configured := ep.Interval(); ep.SetMinInterval(minInterval) {
+				log.Warn().
+					Str("plugin", ep.Name()).
+					Dur("interval", configured).
+					Dur("min_interval", ep.Interval()).
+					Msg("Plugin interval is below min_interval_seconds, raised to the minimum")
+			}
+			ep.SetUserAgent(userAgent(cfg))
+			ep.SetProxy(proxyConfig(cfg))
 			pluginMgr.AddExecPlugin(ep)
 		}
 
 		// Instantiate registered Go plugins
 		for _, gpCfg := range cfg.Collector.Plugins.GoPlugins {
-			factories := plugin.GetRegisteredGoPlugins()
-			factory, ok := factories[gpCfg.Name]
-			if !ok {
-				log.Warn().Str("name", gpCfg.Name).Msg("No registered Go plugin factory found, skipping")
-				continue
-			}
-			c, err := factory(gpCfg.Config)
+			c, err := newGoPlugin(gpCfg)
 			if err != nil {
-				log.Warn().Str("name", gpCfg.Name).Err(err).Msg("Go plugin factory failed, skipping")
+				loadErrs = append(loadErrs, err)
 				continue
 			}
 			pluginMgr.AddGoPlugin(gpCfg.Name, c)
 		}
 
-		if pluginMgr.PluginCount() > 0 {
+		for _, loadErr := range loadErrs {
+			if cfg.Collector.Plugins.StrictPlugins {
+				log.Error().Err(loadErr).Msg("Invalid plugin")
+			} else {
+				log.Warn().Err(loadErr).Msg("Skipping invalid plugin")
+			}
+		}
+		if len(loadErrs) > 0 && cfg.Collector.Plugins.StrictPlugins {
+			log.Fatal().Int("invalid_plugins", len(loadErrs)).Msg("Plugins failed to load and strict_plugins is set")
+		}
+		pluginMgr.SetLoadErrors(len(loadErrs))
+
+		// Also register with only failed plugins so metricsd_plugin_load_errors is shipped
+		if pluginMgr.PluginCount() > 0 || len(loadErrs) > 0 {
 			registry.Register(pluginMgr)
 			log.Info().Int("plugin_count", pluginMgr.PluginCount()).Msg("Plugin manager registered")
 		}
@@ -224,18 +448,29 @@ func setupCollectors(cfg *config.Config) (*collector.Registry, *plugin.Manager)
 	return registry, pluginMgr
 }
 
+// newGoPlugin instantiates a compile-time registered Go plugin
+func newGoPlugin(gpCfg config.GoPluginEntry) (collector.Collector, error) {
+	factory, ok := plugin.GetRegisteredGoPlugins()[gpCfg.Name]
+	if !ok {
+		return nil, fmt.Errorf("no registered Go plugin factory named %q", gpCfg.Name)
+	}
+	c, err := factory(gpCfg.Config)
+	if err != nil {
+		return nil, fmt.Errorf("go plugin %s: factory failed: %w", gpCfg.Name, err)
+	}
+	return c, nil
+}
+
 func setupShipper(cfg *config.Config) shipper.Shipper {
 	var shpr shipper.Shipper
 	var err error
 
 	timeout := cfg.Shipper.Timeout
-	if timeout == 0 {
-		timeout = 30 * time.Second
-	}
 
 	switch cfg.Shipper.Type {
 	case "prometheus_remote_write":
-		shpr, err = shipper.NewPrometheusRemoteWriteShipper(
+		var rw *shipper.PrometheusRemoteWriteShipper
+		rw, err = shipper.NewPrometheusRemoteWriteShipper(
 			cfg.Shipper.Endpoint,
 			cfg.Shipper.TLS.Enabled,
 			cfg.Shipper.TLS.CertFile,
@@ -247,13 +482,19 @@ func setupShipper(cfg *config.Config) shipper.Shipper {
 		if err != nil {
 			log.Fatal().Err(err).Msg("Failed to create Prometheus remote write shipper")
 		}
+		if err := rw.SetCompression(cfg.Shipper.Compression); err != nil {
+			log.Fatal().Err(err).Msg("Failed to configure Prometheus remote write shipper")
+		}
+		rw.SetMaxSamplesPerSend(cfg.Shipper.MaxSamplesPerSend)
+		shpr = rw
 		log.Info().
 			Str("type", "prometheus_remote_write").
 			Str("endpoint", cfg.Shipper.Endpoint).
 			Msg("Shipper initialized")
 
 	case "http_json":
-		shpr, err = shipper.NewHTTPJSONShipper(
+		var hj *shipper.HTTPJSONShipper
+		hj, err = shipper.NewHTTPJSONShipper(
 			cfg.Shipper.Endpoint,
 			cfg.Shipper.TLS.Enabled,
 			cfg.Shipper.TLS.CertFile,
@@ -265,6 +506,17 @@ func setupShipper(cfg *config.Config) shipper.Shipper {
 		if err != nil {
 			log.Fatal().Err(err).Msg("Failed to create HTTP JSON shipper")
 		}
+		hj.SetHeaders(cfg.Shipper.RequestHeaders())
+		if err := hj.SetCompression(cfg.Shipper.Compression); err != nil {
+			log.Fatal().Err(err).Msg("Invalid HTTP JSON compression")
+		}
+		if err := hj.SetSchema(cfg.Shipper.JSONSchema); err != nil {
+			log.Fatal().Err(err).Msg("Invalid HTTP JSON schema")
+		}
+		if err := hj.SetTemplate(cfg.Shipper.JSONTemplate); err != nil {
+			log.Fatal().Err(err).Msg("Invalid HTTP JSON template")
+		}
+		shpr = hj
 		log.Info().
 			Str("type", "http_json").
 			Str("endpoint", cfg.Shipper.Endpoint).
@@ -315,17 +567,387 @@ func setupShipper(cfg *config.Config) shipper.Shipper {
 		}
 		logEvent.Msg("Shipper initialized")
 
+	case "otlp_grpc":
+		var og *shipper.OTLPGRPCShipper
+		og, err = shipper.NewOTLPGRPCShipper(
+			cfg.Shipper.Endpoint,
+			cfg.Shipper.TLS.Enabled,
+			cfg.Shipper.TLS.CertFile,
+			cfg.Shipper.TLS.KeyFile,
+			cfg.Shipper.TLS.CAFile,
+			cfg.Shipper.TLS.InsecureSkipVerify,
+			timeout,
+		)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to create OTLP/gRPC shipper")
+		}
+		if err := og.SetTemporality(cfg.Shipper.Temporality); err != nil {
+			log.Fatal().Err(err).Msg("Invalid OTLP temporality")
+		}
+		shpr = og
+		log.Info().
+			Str("type", "otlp_grpc").
+			Str("endpoint", cfg.Shipper.Endpoint).
+			Str("temporality", cfg.Shipper.Temporality).
+			Msg("Shipper initialized")
+
+	case "graphite":
+		shpr, err = shipper.NewGraphiteShipper(cfg.Shipper.Endpoint, cfg.Shipper.GraphitePrefix)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to create Graphite shipper")
+		}
+		log.Info().
+			Str("type", "graphite").
+			Str("endpoint", cfg.Shipper.Endpoint).
+			Str("prefix", cfg.Shipper.GraphitePrefix).
+			Msg("Shipper initialized")
+
+	case "kafka":
+		shpr, err = shipper.NewKafkaShipper(
+			cfg.Shipper.KafkaBrokers,
+			cfg.Shipper.KafkaTopic,
+			cfg.Shipper.KafkaFormat,
+			cfg.Shipper.KafkaAsync,
+			shipper.KafkaSASL{
+				Mechanism: cfg.Shipper.SASLMechanism,
+				Username:  cfg.Shipper.SASLUsername,
+				Password:  cfg.Shipper.SASLPassword,
+			},
+			cfg.Shipper.TLS.Enabled,
+			cfg.Shipper.TLS.CertFile,
+			cfg.Shipper.TLS.KeyFile,
+			cfg.Shipper.TLS.CAFile,
+			cfg.Shipper.TLS.InsecureSkipVerify,
+			timeout,
+		)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to create Kafka shipper")
+		}
+		log.Info().
+			Str("type", "kafka").
+			Strs("brokers", cfg.Shipper.KafkaBrokers).
+			Str("topic", cfg.Shipper.KafkaTopic).
+			Bool("async", cfg.Shipper.KafkaAsync).
+			Msg("Shipper initialized")
+
+	case "cloudwatch":
+		shpr, err = shipper.NewCloudWatchShipper(context.Background(), cfg.Shipper.CloudWatchRegion, cfg.Shipper.CloudWatchNamespace, cfg.Shipper.Endpoint, cfg.Shipper.Timeout)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to create CloudWatch shipper")
+		}
+		log.Info().
+			Str("type", "cloudwatch").
+			Str("region", cfg.Shipper.CloudWatchRegion).
+			Str("namespace", cfg.Shipper.CloudWatchNamespace).
+			Msg("Shipper initialized")
+
+	case "datadog":
+		shpr, err = shipper.NewDatadogShipper(os.ExpandEnv(cfg.Shipper.APIKey), cfg.Shipper.DatadogSite, timeout)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to create Datadog shipper")
+		}
+		log.Info().
+			Str("type", "datadog").
+			Str("site", cfg.Shipper.DatadogSite).
+			Msg("Shipper initialized")
+
+	case "pushgateway":
+		var pg *shipper.PushgatewayShipper
+		pg, err = shipper.NewPushgatewayShipper(cfg.Shipper.Endpoint, cfg.Shipper.PushgatewayJob, cfg.Shipper.PushgatewayGrouping, timeout)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to create Pushgateway shipper")
+		}
+		if err := pg.SetMethod(cfg.Shipper.PushgatewayMethod); err != nil {
+			log.Fatal().Err(err).Msg("Invalid Pushgateway method")
+		}
+		pg.SetDeleteOnShutdown(cfg.Shipper.DeleteOnShutdown)
+		shpr = pg
+		log.Info().
+			Str("type", "pushgateway").
+			Str("endpoint", cfg.Shipper.Endpoint).
+			Str("job", cfg.Shipper.PushgatewayJob).
+			Bool("delete_on_shutdown", cfg.Shipper.DeleteOnShutdown).
+			Msg("Shipper initialized")
+
 	default:
 		log.Fatal().Str("type", cfg.Shipper.Type).Msg("Unknown shipper type")
 	}
 
+	if uas, ok := shpr.(shipper.UserAgentSetter); ok {
+		uas.SetUserAgent(userAgent(cfg))
+	}
+	if ps, ok := shpr.(shipper.ProxySetter); ok {
+		ps.SetProxy(proxyConfig(cfg))
+	}
+	if cps, ok := shpr.(shipper.ConnPoolSetter); ok {
+		cps.SetConnPool(shipper.ConnPool{
+			MaxIdleConns:    cfg.Shipper.MaxIdleConns,
+			MaxConnsPerHost: cfg.Shipper.MaxConnsPerHost,
+			IdleConnTimeout: time.Duration(cfg.Shipper.IdleConnTimeoutSeconds) * time.Second,
+		})
+	}
+
+	selectors, err := shipperSelectors(cfg.Shipper)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid shipper match")
+	}
+	if len(selectors) > 0 {
+		shpr = shipper.WithMatch(shpr, selectors)
+		log.Info().Strs("match", cfg.Shipper.Match).Msg("Shipper limited to matching metrics")
+	}
+
+	if rps := cfg.Shipper.MaxRequestsPerSecond; rps > 0 {
+		shpr = shipper.WithRateLimit(shpr, shipper.NewRateLimiter(rps))
+		log.Info().Float64("max_requests_per_second", rps).Msg("Shipper rate limit enabled")
+	}
+
 	return shpr
 }
 
-func cleanupGPUCollector(registry *collector.Registry) {
-	// Note: In a production system, you would want to have a better way to manage
-	// lifecycle of collectors. For now, this is a simple cleanup approach.
-	log.Debug().Msg("Cleaning up GPU collector resources")
+// runValidate implements "metricsd validate": it loads the config and every
+// plugin definition it enables, prints each problem found and returns the exit
+// code, 1 when anything is invalid.
+func runValidate(args []string, w io.Writer) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	configPath := fs.String("config", defaultConfigPath, "Path to configuration file, or - to read it from stdin (MC_CONFIG_JSON takes precedence)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	// Only warnings and errors on stderr; the report goes to w
+	if _, err := setupLogging("warn", "console", "", 0, 0, os.Stderr); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	pluginCount, problems := validateAll(*configPath)
+	for _, p := range problems {
+		_, _ = fmt.Fprintf(w, "ERROR: %v\n", p)
+	}
+	if len(problems) > 0 {
+		_, _ = fmt.Fprintf(w, "%d problem(s) found\n", len(problems))
+		return 1
+	}
+	_, _ = fmt.Fprintf(w, "OK: configuration valid, %d plugin(s) loaded\n", pluginCount)
+	return 0
+}
+
+// userAgent returns the configured http_user_agent, or metricsd/<version>
+func userAgent(cfg *config.Config) string {
+	if cfg.HTTPUserAgent != "" {
+		return cfg.HTTPUserAgent
+	}
+	return "metricsd/" + Version
+}
+
+// proxyConfig returns the outbound HTTP proxy settings from cfg
+func proxyConfig(cfg *config.Config) collector.ProxyConfig {
+	return collector.ProxyConfig{HTTPProxy: cfg.HTTPProxy, HTTPSProxy: cfg.HTTPSProxy, NoProxy: cfg.NoProxy}
+}
+
+// shipperSelectors parses the shipper's match selectors
+func shipperSelectors(cfg config.ShipperConfig) ([]collector.Selector, error) {
+	selectors := make([]collector.Selector, 0, len(cfg.Match))
+	for _, m := range cfg.Match {
+		sel, err := collector.ParseSelector(m)
+		if err != nil {
+			return nil, fmt.Errorf("shipper match: %w", err)
+		}
+		selectors = append(selectors, sel)
+	}
+	return selectors, nil
+}
+
+// expandEndpointURLs expands host templates in an endpoint's url and urls
+func expandEndpointURLs(ep config.EndpointConfig, facts collector.HostFacts) (string, []string, error) {
+	url, err := collector.ExpandHostTemplate(ep.URL, facts)
+	if err != nil {
+		return "", nil, err
+	}
+	var urls []string
+	for _, u := range ep.URLs {
+		expanded, err := collector.ExpandHostTemplate(u, facts)
+		if err != nil {
+			return "", nil, err
+		}
+		urls = append(urls, expanded)
+	}
+	return url, urls, nil
+}
+
+// nameRewriteRules converts endpoint name_rewrite config to collector rules
+func nameRewriteRules(rules []config.NameRewriteRule) []collector.NameRewriteRule {
+	out := make([]collector.NameRewriteRule, 0, len(rules))
+	for _, r := range rules {
+		out = append(out, collector.NameRewriteRule{Match: r.Match, Replacement: r.Replacement})
+	}
+	return out
+}
+
+// commandAllowlist returns the executables plugins may run under the
+// configured command policy, or nil when any executable is allowed
+func commandAllowlist(cfg config.PluginSystemConfig) *plugin.CommandAllowlist {
+	if cfg.CommandPolicy != plugin.CommandPolicyAllowlist {
+		return nil
+	}
+	return plugin.NewCommandAllowlist(cfg.AllowedCommands, cfg.PluginsDir)
+}
+
+// validateAll loads the config and, when plugins are enabled, every plugin
+// definition and Go plugin. It returns the number of plugins that loaded and
+// all problems found rather than stopping at the first bad plugin.
+func validateAll(configPath string) (int, []error) {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return 0, []error{err}
+	}
+
+	var problems []error
+	facts := collector.CurrentHostFacts()
+	for _, ep := range cfg.Endpoints {
+		if _, _, err := expandEndpointURLs(ep, facts); err != nil {
+			problems = append(problems, fmt.Errorf("endpoint %s: %w", ep.Name, err))
+		}
+	}
+	if _, err := shipperSelectors(cfg.Shipper); err != nil {
+		problems = append(problems, err)
+	}
+	if !cfg.Collector.Plugins.Enabled {
+		return 0, problems
+	}
+
+	execPlugins, loadErrs, err := plugin.LoadPlugins(cfg.Collector.Plugins.PluginsDir, commandAllowlist(cfg.Collector.Plugins), log.Logger)
+	problems = append(problems, loadErrs...)
+	if err != nil {
+		problems = append(problems, fmt.Errorf("plugins_dir %s: %w", cfg.Collector.Plugins.PluginsDir, err))
+	}
+	count := len(execPlugins)
+	for _, gpCfg := range cfg.Collector.Plugins.GoPlugins {
+		c, err := newGoPlugin(gpCfg)
+		if err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		count++
+		if s, ok := c.(collector.Shutdowner); ok {
+			_ = s.Shutdown()
+		}
+	}
+	return count, problems
+}
+
+// printConfig writes cfg as indented JSON with secrets redacted
+func printConfig(cfg *config.Config, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cfg.Redacted())
+}
+
+// runOnce performs one collect-and-ship cycle, releases resources and returns
+// the process exit code.
+func runOnce(ctx context.Context, orch *orchestrator.Orchestrator, shpr shipper.Shipper) int {
+	err := orch.RunOnce(ctx)
+	orch.Stop()
+	_ = shpr.Close()
+
+	switch {
+	case errors.Is(err, orchestrator.ErrCollectionFailed):
+		log.Error().Err(err).Msg("One-shot collection failed")
+		return exitCollectionFailed
+	case errors.Is(err, orchestrator.ErrShipFailed):
+		log.Error().Err(err).Msg("One-shot shipping failed")
+		return exitShipFailed
+	case err != nil:
+		log.Error().Err(err).Msg("One-shot run failed")
+		return 1
+	}
+	log.Info().Msg("One-shot run completed")
+	return 0
+}
+
+// newOrchestrator creates an orchestrator with the metric pipeline configured
+// from cfg. shpr may be nil for a dry run, which never ships.
+func newOrchestrator(cfg *config.Config, registry *collector.Registry, shpr shipper.Shipper) *orchestrator.Orchestrator {
+	orch := orchestrator.NewOrchestrator(registry, shpr, cfg.GetCollectionInterval())
+	orch.SetLogger(log.Logger)
+	orch.SetGlobalLabels(cfg.GlobalLabels)
+	orch.SetMetricPrefix(cfg.MetricPrefix)
+	orch.SetMetricTypeOverrides(cfg.MetricTypeOverrides)
+	orch.SetDeduplicate(cfg.Deduplicate)
+	orch.SetSeriesLimits(cfg.MaxSeriesPerCycle, cfg.MaxLabelValueLength)
+	orch.SetTimestampLimits(
+		time.Duration(cfg.MaxFutureSkewSeconds)*time.Second,
+		time.Duration(cfg.MaxSampleAgeSeconds)*time.Second,
+		cfg.ClampTimestamps,
+	)
+	orch.SetBuildInfo(Version, Commit)
+	orch.SetCollectionTimeout(time.Duration(cfg.Collector.CollectionTimeoutSeconds) * time.Second)
+	return orch
+}
+
+// runDryRun runs one cycle's collection and processing and prints the
+// result as a table instead of shipping it.
+func runDryRun(ctx context.Context, orch *orchestrator.Orchestrator, w io.Writer) {
+	metrics, err := orch.DryRun(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Dry run collection failed")
+	}
+	orch.Stop()
+
+	printMetrics(w, metrics)
+	log.Info().Int("metric_count", len(metrics)).Msg("Dry run complete, nothing shipped")
+}
+
+// printMetrics renders metrics as an aligned table sorted by name and labels.
+func printMetrics(w io.Writer, metrics []collector.Metric) {
+	rows := make([][4]string, 0, len(metrics))
+	for _, m := range metrics {
+		keys := collector.SortedLabelNames(m.Labels)
+		pairs := make([]string, 0, len(keys))
+		for _, k := range keys {
+			pairs = append(pairs, fmt.Sprintf("%s=%q", k, m.Labels[k]))
+		}
+		rows = append(rows, [4]string{m.Name, "{" + strings.Join(pairs, ",") + "}", fmt.Sprintf("%g", m.Value), m.Type})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i][0] != rows[j][0] {
+			return rows[i][0] < rows[j][0]
+		}
+		return rows[i][1] < rows[j][1]
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAME\tLABELS\tVALUE\tTYPE")
+	for _, r := range rows {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r[0], r[1], r[2], r[3])
+	}
+	_ = tw.Flush()
+}
+
+// collectorTogglerAdapter exposes the registry to the admin endpoints
+type collectorTogglerAdapter struct {
+	registry *collector.Registry
+}
+
+func (a *collectorTogglerAdapter) SetCollectorEnabled(name string, enabled bool) error {
+	err := a.registry.SetCollectorEnabled(name, enabled)
+	if errors.Is(err, collector.ErrUnknownCollector) {
+		return fmt.Errorf("%w: %s", server.ErrUnknownCollector, name)
+	}
+	return err
+}
+
+// collectTriggerAdapter runs on-demand cycles for POST /admin/collect
+type collectTriggerAdapter struct {
+	orch *orchestrator.Orchestrator
+}
+
+func (a *collectTriggerAdapter) CollectNow(ctx context.Context) (server.CollectResult, error) {
+	result, err := a.orch.CollectNow(ctx)
+	if errors.Is(err, orchestrator.ErrCycleInProgress) {
+		return server.CollectResult{}, fmt.Errorf("%w: try again after the running cycle", server.ErrCollectInProgress)
+	}
+	return server.CollectResult{Collected: result.Collected, Shipped: result.Shipped}, err
 }
 
 type pluginHealthAdapter struct {
@@ -350,6 +972,115 @@ func (a *pluginHealthAdapter) GetHealthData() map[string]server.CollectorHealth
 	return result
 }
 
+// pluginStatusAdapter adapts plugin.Manager to server.PluginStatusProvider
+type pluginStatusAdapter struct {
+	mgr *plugin.Manager
+}
+
+func (a *pluginStatusAdapter) GetDebugPlugins() server.DebugPlugins {
+	statuses := a.mgr.Statuses()
+	result := server.DebugPlugins{Plugins: make([]server.DebugPlugin, 0, len(statuses))}
+	for _, st := range statuses {
+		result.Plugins = append(result.Plugins, server.DebugPlugin{
+			Name:            st.Name,
+			Source:          st.Source,
+			Metric:          st.Metric,
+			IntervalSeconds: st.Interval.Seconds(),
+			Status:          st.Status,
+			LastRun:         formatTime(st.LastRun),
+			NextRun:         formatTime(st.NextRun),
+			LastMetricCount: st.LastMetricCount,
+			LastValue:       st.LastValue,
+			LastError:       st.LastError,
+		})
+	}
+	return result
+}
+
+// orchestratorStatusAdapter adapts orchestrator.Status to server.StatusProvider
+type orchestratorStatusAdapter struct {
+	status *orchestrator.Status
+}
+
+func (a *orchestratorStatusAdapter) GetDebugStatus() server.DebugStatus {
+	cycle := a.status.LastCycle()
+	result := server.DebugStatus{
+		LastCycle:        formatTime(cycle.Time),
+		LastCycleSeconds: cycle.Duration.Seconds(),
+		LastCycleError:   cycle.Error,
+		Collectors:       make(map[string]server.DebugCollectorStatus, len(cycle.Collectors)),
+	}
+	for name, c := range cycle.Collectors {
+		result.Collectors[name] = server.DebugCollectorStatus{
+			MetricCount: c.MetricCount,
+			LastError:   c.LastError,
+			LastErrorAt: formatTime(c.LastErrorAt),
+			Disabled:    c.Disabled,
+			Paused:      c.Paused,
+		}
+	}
+	return result
+}
+
+// systemdNotifier sends sd_notify(3) state messages to the socket named by
+// NOTIFY_SOCKET. A nil notifier is valid and does nothing.
+type systemdNotifier struct {
+	conn  net.Conn
+	ready sync.Once
+}
+
+// newSystemdNotifier connects to NOTIFY_SOCKET, returning nil when it is unset
+// (not running under systemd, or Type= is not notify).
+func newSystemdNotifier() (*systemdNotifier, error) {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return nil, nil
+	}
+	// A leading @ names a socket in the abstract namespace
+	if strings.HasPrefix(addr, "@") {
+		addr = "\x00" + addr[1:]
+	}
+	conn, err := net.Dial("unixgram", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &systemdNotifier{conn: conn}, nil
+}
+
+// notify sends one state message; failures are logged and otherwise ignored
+func (n *systemdNotifier) notify(state string) {
+	if n == nil {
+		return
+	}
+	if _, err := n.conn.Write([]byte(state)); err != nil {
+		log.Warn().Err(err).Str("state", state).Msg("Failed to notify systemd")
+	}
+}
+
+// cycleSucceeded reports READY=1 after the first successful cycle and pings
+// the watchdog after every one.
+func (n *systemdNotifier) cycleSucceeded() {
+	n.ready.Do(func() { n.notify("READY=1") })
+	n.notify("WATCHDOG=1")
+}
+
+func (n *systemdNotifier) close() {
+	_ = n.conn.Close()
+}
+
+// systemdWatchdogInterval returns the WatchdogSec systemd passed us in
+// WATCHDOG_USEC, or 0 when the watchdog is disabled or meant for another PID.
+func systemdWatchdogInterval() time.Duration {
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
 func formatTime(t time.Time) string {
 	if t.IsZero() {
 		return ""
@@ -0,0 +1,75 @@
+package collector
+
+import "sync"
+
+// DefaultFailureThreshold is the number of consecutive failed cycles after
+// which the registry starts skipping a collector.
+const DefaultFailureThreshold = 3
+
+// maxBreakerSkipCycles caps how many cycles a failing collector is skipped for
+// before it is probed again.
+const maxBreakerSkipCycles = 32
+
+// breaker tracks one collector's consecutive failures. Once they reach the
+// threshold the collector is skipped for 1, 2, 4, ... cycles (capped at
+// maxBreakerSkipCycles), then probed once; a success closes the breaker.
+type breaker struct {
+	fails int // Consecutive failed attempts
+	skip  int // Cycles left to skip before the next probe
+}
+
+// breakers holds the per-collector circuit breakers of a Registry
+type breakers struct {
+	mu        sync.Mutex
+	threshold int
+	state     map[string]*breaker
+}
+
+// allow reports whether the collector should run this cycle, consuming one
+// skipped cycle if its breaker is open.
+func (b *breakers) allow(name string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.state[name]
+	if s == nil || s.skip == 0 {
+		return true
+	}
+	s.skip--
+	return false
+}
+
+// record updates the breaker after an attempt. It returns the number of cycles
+// the collector will now be skipped for (0 while the breaker is closed) and
+// whether a previously failing collector just recovered.
+func (b *breakers) record(name string, err error) (skip int, recovered bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == nil {
+		b.state = make(map[string]*breaker)
+	}
+	s := b.state[name]
+	if err == nil {
+		if s != nil {
+			recovered = s.fails >= b.thresholdLocked()
+			delete(b.state, name)
+		}
+		return 0, recovered
+	}
+
+	if s == nil {
+		s = &breaker{}
+		b.state[name] = s
+	}
+	s.fails++
+	if over := s.fails - b.thresholdLocked(); over >= 0 {
+		s.skip = min(1<<min(over, 5), maxBreakerSkipCycles)
+	}
+	return s.skip, false
+}
+
+func (b *breakers) thresholdLocked() int {
+	if b.threshold <= 0 {
+		return DefaultFailureThreshold
+	}
+	return b.threshold
+}
@@ -0,0 +1,71 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// countingCollector counts Collect calls and fails while err is set.
+type countingCollector struct {
+	mockCollector
+	calls int
+}
+
+func (c *countingCollector) Collect(ctx context.Context) ([]Metric, error) {
+	c.calls++
+	return c.mockCollector.Collect(ctx)
+}
+
+func TestRegistry_CircuitBreaker(t *testing.T) {
+	c := &countingCollector{mockCollector: mockCollector{name: "gpu", err: errors.New("NVML not found")}}
+	r := NewRegistry()
+	r.SetFailureThreshold(2)
+	r.Register(c)
+
+	cycle := func() CollectorResult {
+		t.Helper()
+		_, results, err := r.CollectAllParallelResults(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return results["gpu"]
+	}
+
+	// fail, fail (breaker opens, skip 1), skipped, probe fails (skip 2), skipped, skipped
+	wantDisabled := []bool{false, true, true, true, true, true}
+	wantCalls := []int{1, 2, 2, 3, 3, 3}
+	for i := range wantDisabled {
+		got := cycle()
+		if got.Disabled != wantDisabled[i] || c.calls != wantCalls[i] {
+			t.Fatalf("cycle %d: disabled=%v calls=%d, want disabled=%v calls=%d", i+1, got.Disabled, c.calls, wantDisabled[i], wantCalls[i])
+		}
+	}
+
+	// The next probe succeeds and closes the breaker
+	c.err = nil
+	c.metrics = []Metric{{Name: "gpu_count", Type: "gauge"}}
+	if got := cycle(); got.Disabled || got.Err != nil || got.MetricCount != 1 || c.calls != 4 {
+		t.Fatalf("expected recovery on probe, got %+v after %d calls", got, c.calls)
+	}
+	if got := cycle(); got.Disabled || c.calls != 5 {
+		t.Errorf("expected collector to run every cycle after recovery, got %+v after %d calls", got, c.calls)
+	}
+}
+
+func TestBreakers_BackoffCap(t *testing.T) {
+	var b breakers
+	var skip int
+	for i := 0; i < 20; i++ {
+		skip, _ = b.record("c", errors.New("boom"))
+	}
+	if skip != maxBreakerSkipCycles {
+		t.Errorf("expected skip capped at %d, got %d", maxBreakerSkipCycles, skip)
+	}
+	if _, recovered := b.record("c", nil); !recovered {
+		t.Error("expected success after an open breaker to report recovery")
+	}
+	if !b.allow("c") {
+		t.Error("expected closed breaker to allow collection")
+	}
+}
@@ -0,0 +1,261 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultCgroupRoot is where the cgroup hierarchy is mounted
+const DefaultCgroupRoot = "/sys/fs/cgroup"
+
+// ErrNoCgroup is returned by NewCgroupCollector when no cgroup v1 or v2
+// hierarchy is mounted, e.g. outside Linux
+var ErrNoCgroup = errors.New("no cgroup hierarchy found")
+
+// cgroupUnlimited is the smallest cgroup v1 limit treated as "no limit"; the
+// kernel reports unlimited memory as a page-rounded math.MaxInt64
+const cgroupUnlimited = 1 << 62
+
+var cgroupMetricDescriptors = []MetricDescriptor{
+	{Name: "container_cpu_usage_seconds_total", Type: "counter", Help: "CPU time consumed by the cgroup."},
+	{Name: "container_cpu_throttled_seconds_total", Type: "counter", Help: "Time the cgroup was throttled by its CPU quota."},
+	{Name: "container_cpu_limit_cores", Type: "gauge", Help: "CPU quota of the cgroup in cores; absent when unlimited."},
+	{Name: "container_memory_usage_bytes", Type: "gauge", Help: "Memory charged to the cgroup, page cache included."},
+	{Name: "container_memory_working_set_bytes", Type: "gauge", Help: "Memory usage minus inactive page cache, as used for OOM decisions."},
+	{Name: "container_memory_limit_bytes", Type: "gauge", Help: "Memory limit of the cgroup; absent when unlimited."},
+}
+
+var cgroupMetricHelp = helpIndex(cgroupMetricDescriptors)
+
+// CgroupCollector reports the CPU and memory usage and limits of the cgroup
+// metricsd runs in, which inside a container are the container's own
+// (Single Responsibility Principle)
+type CgroupCollector struct {
+	version int               // 1 or 2
+	dir     string            // cgroup v2 directory
+	v1Dirs  map[string]string // cgroup v1 directory per controller (cpu, cpuacct, memory)
+}
+
+// NewCgroupCollector detects the cgroup hierarchy (v2 unified or v1) and the
+// cgroup of the current process. It returns ErrNoCgroup when none is mounted.
+func NewCgroupCollector() (*CgroupCollector, error) {
+	return newCgroupCollector(DefaultCgroupRoot, hostProc("self", "cgroup"))
+}
+
+func newCgroupCollector(root, selfCgroup string) (*CgroupCollector, error) {
+	memberships := readCgroupMemberships(selfCgroup)
+
+	if fileExists(filepath.Join(root, "cgroup.controllers")) {
+		return &CgroupCollector{version: 2, dir: cgroupDir(root, memberships[""])}, nil
+	}
+
+	v1Dirs := make(map[string]string)
+	for _, controller := range []string{"cpu", "cpuacct", "memory"} {
+		for key, path := range memberships {
+			if !strings.Contains(","+key+",", ","+controller+",") {
+				continue
+			}
+			// cpu,cpuacct is mounted under its joint name with per-controller symlinks
+			for _, mount := range []string{controller, key} {
+				if base := filepath.Join(root, mount); fileExists(base) {
+					v1Dirs[controller] = cgroupDir(base, path)
+					break
+				}
+			}
+		}
+		if _, ok := v1Dirs[controller]; !ok {
+			if base := filepath.Join(root, controller); fileExists(base) {
+				v1Dirs[controller] = base
+			}
+		}
+	}
+	if len(v1Dirs) == 0 {
+		return nil, ErrNoCgroup
+	}
+	return &CgroupCollector{version: 1, v1Dirs: v1Dirs}, nil
+}
+
+// readCgroupMemberships parses /proc/self/cgroup ("id:controllers:path" per
+// line) into path by controller list. The v2 entry has an empty list.
+func readCgroupMemberships(path string) map[string]string {
+	memberships := make(map[string]string)
+	f, err := os.Open(path)
+	if err != nil {
+		return memberships
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) == 3 {
+			memberships[parts[1]] = parts[2]
+		}
+	}
+	return memberships
+}
+
+// cgroupDir returns base joined with the process's cgroup path. With a cgroup
+// namespace (as in most containers) the path is "/" or not visible under the
+// mount, and base itself is the process's cgroup.
+func cgroupDir(base, path string) string {
+	if path != "" && path != "/" {
+		if dir := filepath.Join(base, path); fileExists(dir) {
+			return dir
+		}
+	}
+	return base
+}
+
+// Name returns the collector name
+func (c *CgroupCollector) Name() string {
+	return "cgroup"
+}
+
+// Describe returns the metrics emitted for the cgroup
+func (c *CgroupCollector) Describe() []MetricDescriptor {
+	return cgroupMetricDescriptors
+}
+
+// Collect reads the cgroup's current usage and limits. Stats that can't be
+// read are skipped; it fails only when none can.
+func (c *CgroupCollector) Collect(ctx context.Context) ([]Metric, error) {
+	var metrics []Metric
+	if c.version == 2 {
+		metrics = c.collectV2()
+	} else {
+		metrics = c.collectV1()
+	}
+	if len(metrics) == 0 {
+		return nil, fmt.Errorf("no readable cgroup v%d stats", c.version)
+	}
+	applyHelp(metrics, cgroupMetricHelp)
+	return metrics, nil
+}
+
+func (c *CgroupCollector) collectV2() []Metric {
+	var metrics []Metric
+	if stat, err := readKeyedFile(filepath.Join(c.dir, "cpu.stat")); err == nil {
+		if usec, ok := stat["usage_usec"]; ok {
+			metrics = append(metrics, cgroupMetric("container_cpu_usage_seconds_total", "counter", float64(usec)/1e6))
+		}
+		if usec, ok := stat["throttled_usec"]; ok {
+			metrics = append(metrics, cgroupMetric("container_cpu_throttled_seconds_total", "counter", float64(usec)/1e6))
+		}
+	}
+	// cpu.max is "<quota> <period>", with quota "max" when unlimited
+	if data, err := os.ReadFile(filepath.Join(c.dir, "cpu.max")); err == nil {
+		if fields := strings.Fields(string(data)); len(fields) == 2 {
+			quota, qerr := strconv.ParseFloat(fields[0], 64)
+			period, perr := strconv.ParseFloat(fields[1], 64)
+			if qerr == nil && perr == nil && period > 0 {
+				metrics = append(metrics, cgroupMetric("container_cpu_limit_cores", "gauge", quota/period))
+			}
+		}
+	}
+
+	if usage, err := readCgroupUint(filepath.Join(c.dir, "memory.current")); err == nil {
+		metrics = append(metrics, cgroupMetric("container_memory_usage_bytes", "gauge", float64(usage)))
+		if stat, err := readKeyedFile(filepath.Join(c.dir, "memory.stat")); err == nil {
+			metrics = append(metrics, cgroupMetric("container_memory_working_set_bytes", "gauge", workingSet(usage, stat["inactive_file"])))
+		}
+	}
+	if limit, err := readCgroupUint(filepath.Join(c.dir, "memory.max")); err == nil {
+		metrics = append(metrics, cgroupMetric("container_memory_limit_bytes", "gauge", float64(limit)))
+	}
+	return metrics
+}
+
+func (c *CgroupCollector) collectV1() []Metric {
+	var metrics []Metric
+	if dir, ok := c.v1Dirs["cpuacct"]; ok {
+		if ns, err := readCgroupUint(filepath.Join(dir, "cpuacct.usage")); err == nil {
+			metrics = append(metrics, cgroupMetric("container_cpu_usage_seconds_total", "counter", float64(ns)/1e9))
+		}
+	}
+	if dir, ok := c.v1Dirs["cpu"]; ok {
+		if stat, err := readKeyedFile(filepath.Join(dir, "cpu.stat")); err == nil {
+			if ns, ok := stat["throttled_time"]; ok {
+				metrics = append(metrics, cgroupMetric("container_cpu_throttled_seconds_total", "counter", float64(ns)/1e9))
+			}
+		}
+		// A quota of -1 means unlimited
+		quota, qerr := readCgroupInt(filepath.Join(dir, "cpu.cfs_quota_us"))
+		period, perr := readCgroupInt(filepath.Join(dir, "cpu.cfs_period_us"))
+		if qerr == nil && perr == nil && quota > 0 && period > 0 {
+			metrics = append(metrics, cgroupMetric("container_cpu_limit_cores", "gauge", float64(quota)/float64(period)))
+		}
+	}
+	if dir, ok := c.v1Dirs["memory"]; ok {
+		if usage, err := readCgroupUint(filepath.Join(dir, "memory.usage_in_bytes")); err == nil {
+			metrics = append(metrics, cgroupMetric("container_memory_usage_bytes", "gauge", float64(usage)))
+			if stat, err := readKeyedFile(filepath.Join(dir, "memory.stat")); err == nil {
+				metrics = append(metrics, cgroupMetric("container_memory_working_set_bytes", "gauge", workingSet(usage, stat["total_inactive_file"])))
+			}
+		}
+		if limit, err := readCgroupUint(filepath.Join(dir, "memory.limit_in_bytes")); err == nil && limit < cgroupUnlimited {
+			metrics = append(metrics, cgroupMetric("container_memory_limit_bytes", "gauge", float64(limit)))
+		}
+	}
+	return metrics
+}
+
+// workingSet is usage minus inactive page cache, floored at zero
+func workingSet(usage, inactiveFile uint64) float64 {
+	if inactiveFile > usage {
+		return 0
+	}
+	return float64(usage - inactiveFile)
+}
+
+func cgroupMetric(name, typ string, value float64) Metric {
+	return Metric{Name: name, Labels: map[string]string{}, Value: value, Type: typ}
+}
+
+// readCgroupUint reads a single-value cgroup file. "max" (no limit) is an error
+// so that limits are only reported when set.
+func readCgroupUint(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+func readCgroupInt(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// readKeyedFile parses "key value" lines such as cpu.stat and memory.stat
+func readKeyedFile(path string) (map[string]uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]uint64)
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[fields[0]] = v
+		}
+	}
+	return values, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,163 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFileTree creates files (path relative to root -> content) under root
+func writeFileTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func collectCgroup(t *testing.T, c *CgroupCollector) map[string]float64 {
+	t.Helper()
+	metrics, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	values := make(map[string]float64, len(metrics))
+	for _, m := range metrics {
+		if m.Help == "" {
+			t.Errorf("%s has no help text", m.Name)
+		}
+		values[m.Name] = m.Value
+	}
+	return values
+}
+
+func TestCgroupCollector_V2(t *testing.T) {
+	root := t.TempDir()
+	writeFileTree(t, root, map[string]string{
+		"cgroup.controllers":                "cpu memory\n",
+		"system.slice/app/cpu.stat":         "usage_usec 2500000\nuser_usec 2000000\nsystem_usec 500000\nthrottled_usec 750000\n",
+		"system.slice/app/cpu.max":          "150000 100000\n",
+		"system.slice/app/memory.current":   "104857600\n",
+		"system.slice/app/memory.stat":      "anon 52428800\nfile 52428800\ninactive_file 20971520\n",
+		"system.slice/app/memory.max":       "268435456\n",
+		"system.slice/other/memory.current": "1\n",
+	})
+	selfCgroup := filepath.Join(t.TempDir(), "cgroup")
+	writeFileTree(t, filepath.Dir(selfCgroup), map[string]string{"cgroup": "0::/system.slice/app\n"})
+
+	c, err := newCgroupCollector(root, selfCgroup)
+	if err != nil {
+		t.Fatalf("newCgroupCollector: %v", err)
+	}
+	got := collectCgroup(t, c)
+
+	want := map[string]float64{
+		"container_cpu_usage_seconds_total":     2.5,
+		"container_cpu_throttled_seconds_total": 0.75,
+		"container_cpu_limit_cores":             1.5,
+		"container_memory_usage_bytes":          104857600,
+		"container_memory_working_set_bytes":    83886080,
+		"container_memory_limit_bytes":          268435456,
+	}
+	for name, v := range want {
+		if got[name] != v {
+			t.Errorf("%s: want %v, got %v", name, v, got[name])
+		}
+	}
+}
+
+func TestCgroupCollector_V2Unlimited(t *testing.T) {
+	// With a cgroup namespace /proc/self/cgroup shows "/" and the mount root is the container
+	root := t.TempDir()
+	writeFileTree(t, root, map[string]string{
+		"cgroup.controllers": "cpu memory\n",
+		"cpu.stat":           "usage_usec 1000000\n",
+		"cpu.max":            "max 100000\n",
+		"memory.current":     "4096\n",
+		"memory.max":         "max\n",
+	})
+	selfCgroup := filepath.Join(t.TempDir(), "cgroup")
+	writeFileTree(t, filepath.Dir(selfCgroup), map[string]string{"cgroup": "0::/\n"})
+
+	c, err := newCgroupCollector(root, selfCgroup)
+	if err != nil {
+		t.Fatalf("newCgroupCollector: %v", err)
+	}
+	got := collectCgroup(t, c)
+
+	for _, name := range []string{"container_cpu_limit_cores", "container_memory_limit_bytes"} {
+		if _, ok := got[name]; ok {
+			t.Errorf("%s should be omitted when unlimited", name)
+		}
+	}
+	if got["container_cpu_usage_seconds_total"] != 1 || got["container_memory_usage_bytes"] != 4096 {
+		t.Errorf("unexpected usage: %v", got)
+	}
+}
+
+func TestCgroupCollector_V1(t *testing.T) {
+	root := t.TempDir()
+	writeFileTree(t, root, map[string]string{
+		"cpu,cpuacct/docker/abc/cpuacct.usage":     "3000000000\n",
+		"cpu,cpuacct/docker/abc/cpu.stat":          "nr_periods 10\nnr_throttled 2\nthrottled_time 500000000\n",
+		"cpu,cpuacct/docker/abc/cpu.cfs_quota_us":  "50000\n",
+		"cpu,cpuacct/docker/abc/cpu.cfs_period_us": "100000\n",
+		"memory/memory.usage_in_bytes":             "8192\n",
+		"memory/memory.stat":                       "cache 4096\ntotal_inactive_file 2048\n",
+		"memory/memory.limit_in_bytes":             "9223372036854771712\n",
+	})
+	// memory is namespaced (path not visible under the mount), cpu is not
+	selfCgroup := filepath.Join(t.TempDir(), "cgroup")
+	writeFileTree(t, filepath.Dir(selfCgroup), map[string]string{
+		"cgroup": "12:memory:/docker/abc\n4:cpu,cpuacct:/docker/abc\n1:name=systemd:/docker/abc\n",
+	})
+
+	c, err := newCgroupCollector(root, selfCgroup)
+	if err != nil {
+		t.Fatalf("newCgroupCollector: %v", err)
+	}
+	got := collectCgroup(t, c)
+
+	want := map[string]float64{
+		"container_cpu_usage_seconds_total":     3,
+		"container_cpu_throttled_seconds_total": 0.5,
+		"container_cpu_limit_cores":             0.5,
+		"container_memory_usage_bytes":          8192,
+		"container_memory_working_set_bytes":    6144,
+	}
+	for name, v := range want {
+		if got[name] != v {
+			t.Errorf("%s: want %v, got %v", name, v, got[name])
+		}
+	}
+	if _, ok := got["container_memory_limit_bytes"]; ok {
+		t.Error("container_memory_limit_bytes should be omitted for the unlimited sentinel")
+	}
+}
+
+func TestCgroupCollector_NoCgroup(t *testing.T) {
+	_, err := newCgroupCollector(t.TempDir(), filepath.Join(t.TempDir(), "missing"))
+	if !errors.Is(err, ErrNoCgroup) {
+		t.Fatalf("want ErrNoCgroup, got %v", err)
+	}
+}
+
+func TestCgroupCollector_NoReadableStats(t *testing.T) {
+	root := t.TempDir()
+	writeFileTree(t, root, map[string]string{"cgroup.controllers": "\n"})
+
+	c, err := newCgroupCollector(root, filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatalf("newCgroupCollector: %v", err)
+	}
+	if _, err := c.Collect(context.Background()); err == nil {
+		t.Error("expected an error when no stats can be read")
+	}
+}
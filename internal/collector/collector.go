@@ -2,10 +2,25 @@ package collector
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
+)
+
+// MetricNameRegex and LabelNameRegex are the Prometheus metric and label name
+// grammars, shared by the packages validating names
+var (
+	MetricNameRegex = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	LabelNameRegex  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
 )
 
 // Metric represents a collected metric
@@ -13,7 +28,22 @@ type Metric struct {
 	Name   string
 	Labels map[string]string
 	Value  float64
-	Type   string // "gauge" or "counter"
+	Type   string // "gauge", "counter", or "histogram" (with Histogram set)
+	Help   string // Optional description, exported as # HELP / remote write metadata
+	// Timestamp is the sample time; zero means "now" at ship time
+	Timestamp time.Time
+	// Histogram holds a native histogram; remote write ships it in place of Value
+	Histogram *NativeHistogram
+	// Exemplars link the sample to traces; only remote write ships them
+	Exemplars []Exemplar
+}
+
+// Exemplar is an example observation attached to a sample, typically carrying
+// a trace_id label
+type Exemplar struct {
+	Labels    map[string]string
+	Value     float64
+	Timestamp time.Time // Zero when the exposition didn't include one
 }
 
 // Collector is the interface that all metric collectors must implement (Interface Segregation Principle)
@@ -22,9 +52,41 @@ type Collector interface {
 	Name() string
 }
 
+// MetricDescriptor describes a metric a collector may emit
+type MetricDescriptor struct {
+	Name string
+	Type string // "gauge" or "counter"
+	Help string
+}
+
+// Describer is implemented by collectors that can list the metrics they emit up front.
+// Collectors with dynamic metric names (HTTP scraping, plugins) don't implement it.
+type Describer interface {
+	Describe() []MetricDescriptor
+}
+
+// Shutdowner is implemented by collectors that hold resources which must be released on exit
+type Shutdowner interface {
+	Shutdown() error
+}
+
+// StateKeeper is implemented by collectors that synthesize counters from
+// state kept between collections, so the state can outlive a restart.
+// SaveState returns the state as JSON; RestoreState loads what an earlier
+// run saved.
+type StateKeeper interface {
+	SaveState() (json.RawMessage, error)
+	RestoreState(data json.RawMessage) error
+}
+
 // Registry holds all registered collectors (Dependency Inversion Principle)
 type Registry struct {
+	Logging
 	collectors []Collector
+	breakers   breakers
+
+	pausedMu sync.RWMutex
+	paused   map[string]bool // Collectors disabled at runtime by an operator
 }
 
 // NewRegistry creates a new collector registry
@@ -36,14 +98,164 @@ func NewRegistry() *Registry {
 
 // Register adds a collector to the registry
 func (r *Registry) Register(collector Collector) {
+	if r.logger != nil {
+		if s, ok := collector.(LoggerSetter); ok {
+			s.SetLogger(*r.logger)
+		}
+	}
 	r.collectors = append(r.collectors, collector)
 }
 
+// SetLogger sets the registry's logger and passes it on to every registered
+// collector implementing LoggerSetter, including ones registered later.
+func (r *Registry) SetLogger(logger zerolog.Logger) {
+	r.Logging.SetLogger(logger)
+	for _, c := range r.collectors {
+		if s, ok := c.(LoggerSetter); ok {
+			s.SetLogger(logger)
+		}
+	}
+}
+
+// ErrUnknownCollector is returned for a collector name that isn't registered
+var ErrUnknownCollector = errors.New("unknown collector")
+
+// SetCollectorEnabled pauses or resumes a registered collector at runtime.
+// Paused collectors are skipped by CollectAll and CollectAllParallelResults.
+func (r *Registry) SetCollectorEnabled(name string, enabled bool) error {
+	found := false
+	for _, c := range r.collectors {
+		if c.Name() == name {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("%w: %s", ErrUnknownCollector, name)
+	}
+
+	r.pausedMu.Lock()
+	defer r.pausedMu.Unlock()
+	if r.paused == nil {
+		r.paused = make(map[string]bool)
+	}
+	if enabled {
+		delete(r.paused, name)
+	} else {
+		r.paused[name] = true
+	}
+	return nil
+}
+
+// isPaused reports whether name was disabled with SetCollectorEnabled
+func (r *Registry) isPaused(name string) bool {
+	r.pausedMu.RLock()
+	defer r.pausedMu.RUnlock()
+	return r.paused[name]
+}
+
+// SetFailureThreshold sets how many consecutive failed cycles open a
+// collector's circuit breaker in CollectAllParallelResults. Values <= 0 use
+// DefaultFailureThreshold.
+func (r *Registry) SetFailureThreshold(n int) {
+	r.breakers.mu.Lock()
+	r.breakers.threshold = n
+	r.breakers.mu.Unlock()
+}
+
+// Shutdown calls Shutdown on every registered collector implementing Shutdowner.
+// All collectors are shut down even if some fail; the errors are joined.
+func (r *Registry) Shutdown() error {
+	var errs []error
+	for _, c := range r.collectors {
+		s, ok := c.(Shutdowner)
+		if !ok {
+			continue
+		}
+		if err := s.Shutdown(); err != nil {
+			errs = append(errs, fmt.Errorf("collector %s: %w", c.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SaveStates returns the state of every collector implementing StateKeeper,
+// keyed by collector name. Collectors failing to save are left out; the errors
+// are joined.
+func (r *Registry) SaveStates() (map[string]json.RawMessage, error) {
+	states := make(map[string]json.RawMessage)
+	var errs []error
+	for _, c := range r.collectors {
+		k, ok := c.(StateKeeper)
+		if !ok {
+			continue
+		}
+		data, err := k.SaveState()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("collector %s: %w", c.Name(), err))
+			continue
+		}
+		states[c.Name()] = data
+	}
+	return states, errors.Join(errs...)
+}
+
+// RestoreStates hands each collector implementing StateKeeper its entry of
+// states. States of collectors no longer registered are ignored; the errors
+// of collectors failing to restore are joined.
+func (r *Registry) RestoreStates(states map[string]json.RawMessage) error {
+	var errs []error
+	for _, c := range r.collectors {
+		k, ok := c.(StateKeeper)
+		if !ok {
+			continue
+		}
+		data, ok := states[c.Name()]
+		if !ok {
+			continue
+		}
+		if err := k.RestoreState(data); err != nil {
+			errs = append(errs, fmt.Errorf("collector %s: %w", c.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DescribeAll returns the metric catalog of every collector implementing Describer,
+// keyed by collector name, along with metric names declared by more than one collector.
+func (r *Registry) DescribeAll() (map[string][]MetricDescriptor, []string) {
+	catalog := make(map[string][]MetricDescriptor)
+	owners := make(map[string]string)
+	var collisions []string
+
+	for _, c := range r.collectors {
+		d, ok := c.(Describer)
+		if !ok {
+			continue
+		}
+		descriptors := d.Describe()
+		catalog[c.Name()] = descriptors
+		for _, desc := range descriptors {
+			owner, seen := owners[desc.Name]
+			if seen && owner != c.Name() {
+				collisions = append(collisions, desc.Name)
+				continue
+			}
+			owners[desc.Name] = c.Name()
+		}
+	}
+
+	return catalog, collisions
+}
+
 // CollectAll collects metrics from all registered collectors
 func (r *Registry) CollectAll(ctx context.Context) ([]Metric, error) {
 	allMetrics := make([]Metric, 0)
 
 	for _, collector := range r.collectors {
+		if r.isPaused(collector.Name()) {
+			continue
+		}
 		metrics, err := collector.Collect(ctx)
 		if err != nil {
 			// Continue collecting from other collectors even if one fails
@@ -56,29 +268,267 @@ func (r *Registry) CollectAll(ctx context.Context) ([]Metric, error) {
 	return allMetrics, nil
 }
 
+// CollectorResult is the outcome of a single collector's Collect call
+type CollectorResult struct {
+	MetricCount int
+	Err         error
+	Disabled    bool // Circuit breaker open: the collector keeps failing and is being skipped
+	Paused      bool // Disabled by an operator with SetCollectorEnabled
+}
+
 // CollectAllParallel collects from all registered collectors in parallel.
 func (r *Registry) CollectAllParallel(ctx context.Context) ([]Metric, error) {
+	metrics, _, err := r.CollectAllParallelResults(ctx)
+	return metrics, err
+}
+
+// CollectAllParallelResults is CollectAllParallel that also reports each
+// collector's outcome, keyed by collector name. A collector that keeps failing
+// is skipped for a growing number of calls (see SetFailureThreshold) and
+// reported as Disabled until a probe succeeds.
+func (r *Registry) CollectAllParallelResults(ctx context.Context) ([]Metric, map[string]CollectorResult, error) {
 	var mu sync.Mutex
 	var allMetrics []Metric
+	results := make(map[string]CollectorResult, len(r.collectors))
 	var wg sync.WaitGroup
 
 	for _, c := range r.collectors {
+		if r.isPaused(c.Name()) {
+			mu.Lock()
+			results[c.Name()] = CollectorResult{Paused: true}
+			mu.Unlock()
+			continue
+		}
+		if !r.breakers.allow(c.Name()) {
+			r.Logger().Debug().Str("collector", c.Name()).Msg("Skipping collector — circuit open")
+			mu.Lock()
+			results[c.Name()] = CollectorResult{Disabled: true}
+			mu.Unlock()
+			continue
+		}
+
 		wg.Add(1)
 		go func(col Collector) {
 			defer wg.Done()
 			metrics, err := col.Collect(ctx)
+			skip, recovered := r.breakers.record(col.Name(), err)
+			switch {
+			case skip > 0:
+				r.Logger().Warn().Err(err).Str("collector", col.Name()).Int("skip_cycles", skip).Msg("Collector keeps failing, disabling it for a while")
+			case err != nil:
+				r.Logger().Warn().Err(err).Str("collector", col.Name()).Msg("Collector failed during parallel collection")
+			case recovered:
+				r.Logger().Info().Str("collector", col.Name()).Msg("Collector recovered, circuit closed")
+			}
+			mu.Lock()
+			defer mu.Unlock()
 			if err != nil {
-				log.Warn().Err(err).Str("collector", col.Name()).Msg("Collector failed during parallel collection")
+				results[col.Name()] = CollectorResult{Err: err, Disabled: skip > 0}
 				return
 			}
-			mu.Lock()
+			results[col.Name()] = CollectorResult{MetricCount: len(metrics)}
 			allMetrics = append(allMetrics, metrics...)
-			mu.Unlock()
 		}(c)
 	}
 
 	wg.Wait()
-	return allMetrics, nil
+	return allMetrics, results, nil
+}
+
+// helpIndex maps metric names to their descriptor help text
+func helpIndex(lists ...[]MetricDescriptor) map[string]string {
+	index := make(map[string]string)
+	for _, list := range lists {
+		for _, d := range list {
+			index[d.Name] = d.Help
+		}
+	}
+	return index
+}
+
+// applyHelp fills in Help for metrics that don't already have one
+func applyHelp(metrics []Metric, help map[string]string) {
+	for i := range metrics {
+		if metrics[i].Help == "" {
+			metrics[i].Help = help[metrics[i].Name]
+		}
+	}
+}
+
+// copyLabels returns a copy of labels that can be extended without affecting the original
+func copyLabels(labels map[string]string) map[string]string {
+	out := make(map[string]string, len(labels)+2)
+	for k, v := range labels {
+		out[k] = v
+	}
+	return out
+}
+
+// ApplyGlobalLabels adds labels to every metric without overriding labels the
+// metric already has. Label maps are copied since collectors may share them.
+func ApplyGlobalLabels(metrics []Metric, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+	for i := range metrics {
+		merged := make(map[string]string, len(metrics[i].Labels)+len(labels))
+		for k, v := range labels {
+			merged[k] = v
+		}
+		for k, v := range metrics[i].Labels {
+			merged[k] = v
+		}
+		metrics[i].Labels = merged
+	}
+}
+
+// SortedLabelNames returns the label names in ascending order, the canonical
+// order for serialized labels
+func SortedLabelNames(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SeriesKey identifies a metric's series by name and sorted label pairs
+func SeriesKey(m Metric) string {
+	keys := SortedLabelNames(m.Labels)
+
+	var b strings.Builder
+	b.WriteString(m.Name)
+	for _, k := range keys {
+		b.WriteByte(0)
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(m.Labels[k])
+	}
+	return b.String()
+}
+
+// ApplyMetricPrefix prepends prefix to every metric name that doesn't already
+// start with it, so re-applying the prefix is a no-op.
+func ApplyMetricPrefix(metrics []Metric, prefix string) {
+	if prefix == "" {
+		return
+	}
+	for i := range metrics {
+		if !strings.HasPrefix(metrics[i].Name, prefix) {
+			metrics[i].Name = prefix + metrics[i].Name
+		}
+	}
+}
+
+// Deduplicate collapses metrics with the same name and labels into one entry,
+// keeping the position of the first and the value of the last. It returns the
+// deduplicated slice, which reuses metrics' backing array, and the number of
+// duplicates dropped.
+func Deduplicate(metrics []Metric) ([]Metric, int) {
+	index := make(map[string]int, len(metrics))
+	kept := metrics[:0]
+	for _, m := range metrics {
+		key := SeriesKey(m)
+		if i, ok := index[key]; ok {
+			kept[i] = m
+			continue
+		}
+		index[key] = len(kept)
+		kept = append(kept, m)
+	}
+	return kept, len(metrics) - len(kept)
+}
+
+// LimitSeries keeps the first maxSeries metrics and returns them along with
+// the number dropped. maxSeries <= 0 keeps everything.
+func LimitSeries(metrics []Metric, maxSeries int) ([]Metric, int) {
+	if maxSeries <= 0 || len(metrics) <= maxSeries {
+		return metrics, 0
+	}
+	return metrics[:maxSeries], len(metrics) - maxSeries
+}
+
+// CheckTimestamps handles samples timestamped more than maxFuture ahead of or
+// maxAge behind now: they are dropped, or with clamp their timestamp is cleared
+// so they ship as "now". It returns the kept metrics, which reuse metrics'
+// backing array, and how many were out of range. Metrics without a timestamp
+// are never affected, and a zero limit disables that side of the check.
+func CheckTimestamps(metrics []Metric, now time.Time, maxFuture, maxAge time.Duration, clamp bool) ([]Metric, int) {
+	if maxFuture <= 0 && maxAge <= 0 {
+		return metrics, 0
+	}
+	kept := metrics[:0]
+	rejected := 0
+	for _, m := range metrics {
+		if !m.Timestamp.IsZero() &&
+			(maxFuture > 0 && m.Timestamp.Sub(now) > maxFuture || maxAge > 0 && now.Sub(m.Timestamp) > maxAge) {
+			rejected++
+			if !clamp {
+				continue
+			}
+			m.Timestamp = time.Time{}
+		}
+		kept = append(kept, m)
+	}
+	return kept, rejected
+}
+
+// TruncateLabelValues cuts label values longer than maxLen bytes, at a UTF-8
+// boundary, and returns how many values were cut. Label maps are copied before
+// changing them since collectors may share them. maxLen <= 0 disables it.
+func TruncateLabelValues(metrics []Metric, maxLen int) int {
+	if maxLen <= 0 {
+		return 0
+	}
+	truncated := 0
+	for i := range metrics {
+		copied := false
+		for k, v := range metrics[i].Labels {
+			if len(v) <= maxLen {
+				continue
+			}
+			if !copied {
+				metrics[i].Labels = copyLabels(metrics[i].Labels)
+				copied = true
+			}
+			cut := maxLen
+			for cut > 0 && !utf8.RuneStart(v[cut]) {
+				cut--
+			}
+			metrics[i].Labels[k] = v[:cut]
+			truncated++
+		}
+	}
+	return truncated
+}
+
+// ApplyTypeOverrides sets the Type of every metric whose name is in overrides
+func ApplyTypeOverrides(metrics []Metric, overrides map[string]string) {
+	if len(overrides) == 0 {
+		return
+	}
+	for i := range metrics {
+		if typ, ok := overrides[metrics[i].Name]; ok {
+			metrics[i].Type = typ
+		}
+	}
+}
+
+// TimestampOr returns the metric's timestamp, or fallback when it has none
+func (m Metric) TimestampOr(fallback time.Time) time.Time {
+	if m.Timestamp.IsZero() {
+		return fallback
+	}
+	return m.Timestamp
+}
+
+// TimestampOr returns the exemplar's timestamp, or fallback when it has none
+func (e Exemplar) TimestampOr(fallback time.Time) time.Time {
+	if e.Timestamp.IsZero() {
+		return fallback
+	}
+	return e.Timestamp
 }
 
 // ToPrometheusMetrics converts collected metrics to Prometheus metric format
@@ -94,6 +544,15 @@ func ToPrometheusMetrics(metrics []Metric) []prometheus.Metric {
 			values = append(values, v)
 		}
 
+		desc := prometheus.NewDesc(m.Name, m.Help, labels, nil)
+		if h := m.Histogram; h != nil {
+			metric, err := prometheus.NewConstNativeHistogram(desc, h.Count, h.Sum, h.PositiveBuckets, h.NegativeBuckets, h.ZeroCount, h.Schema, h.ZeroThreshold, time.Time{}, values...)
+			if err == nil {
+				promMetrics = append(promMetrics, metric)
+			}
+			continue
+		}
+
 		var valueType prometheus.ValueType
 		if m.Type == "counter" {
 			valueType = prometheus.CounterValue
@@ -101,7 +560,6 @@ func ToPrometheusMetrics(metrics []Metric) []prometheus.Metric {
 			valueType = prometheus.GaugeValue
 		}
 
-		desc := prometheus.NewDesc(m.Name, "", labels, nil)
 		metric, err := prometheus.NewConstMetric(desc, valueType, m.Value, values...)
 		if err == nil {
 			promMetrics = append(promMetrics, metric)
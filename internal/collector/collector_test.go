@@ -2,8 +2,11 @@ package collector
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"testing"
+	"time"
 )
 
 type mockCollector struct {
@@ -84,6 +87,33 @@ func TestCollectAllParallel(t *testing.T) {
 	})
 }
 
+func TestCollectAllParallelResults(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&mockCollector{
+		name:    "ok",
+		metrics: []Metric{{Name: "m1", Type: "gauge"}, {Name: "m2", Type: "gauge"}},
+	})
+	r.Register(&mockCollector{
+		name:    "broken",
+		metrics: []Metric{{Name: "partial", Type: "gauge"}},
+		err:     fmt.Errorf("boom"),
+	})
+
+	metrics, results, err := r.CollectAllParallelResults(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(metrics) != 2 {
+		t.Errorf("expected 2 metrics, got %d", len(metrics))
+	}
+	if got := results["ok"]; got.MetricCount != 2 || got.Err != nil {
+		t.Errorf("unexpected result for ok: %+v", got)
+	}
+	if got := results["broken"]; got.MetricCount != 0 || got.Err == nil {
+		t.Errorf("unexpected result for broken: %+v", got)
+	}
+}
+
 func TestCollectAll(t *testing.T) {
 	t.Run("sequential collection works", func(t *testing.T) {
 		r := NewRegistry()
@@ -109,3 +139,264 @@ func TestCollectAll(t *testing.T) {
 		}
 	})
 }
+
+type shutdownCollector struct {
+	mockCollector
+	calls int
+	err   error
+}
+
+func (s *shutdownCollector) Shutdown() error {
+	s.calls++
+	return s.err
+}
+
+func TestRegistryShutdown(t *testing.T) {
+	r := NewRegistry()
+	ok := &shutdownCollector{mockCollector: mockCollector{name: "ok"}}
+	failing := &shutdownCollector{mockCollector: mockCollector{name: "failing"}, err: fmt.Errorf("nvml busy")}
+	r.Register(ok)
+	r.Register(&mockCollector{name: "plain"})
+	r.Register(failing)
+
+	err := r.Shutdown()
+	if err == nil {
+		t.Fatal("expected error from failing collector")
+	}
+	if ok.calls != 1 || failing.calls != 1 {
+		t.Errorf("expected each Shutdowner called once, got ok=%d failing=%d", ok.calls, failing.calls)
+	}
+}
+
+func TestRegistrySetCollectorEnabled(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&mockCollector{name: "system", metrics: []Metric{{Name: "cpu", Value: 1}}})
+	r.Register(&mockCollector{name: "plugins", metrics: []Metric{{Name: "p", Value: 2}}})
+
+	if err := r.SetCollectorEnabled("missing", false); !errors.Is(err, ErrUnknownCollector) {
+		t.Errorf("expected ErrUnknownCollector, got %v", err)
+	}
+	if err := r.SetCollectorEnabled("plugins", false); err != nil {
+		t.Fatalf("SetCollectorEnabled failed: %v", err)
+	}
+
+	metrics, results, _ := r.CollectAllParallelResults(context.Background())
+	if len(metrics) != 1 || metrics[0].Name != "cpu" {
+		t.Errorf("expected only system metrics while plugins is paused, got %v", metrics)
+	}
+	if !results["plugins"].Paused || results["system"].Paused {
+		t.Errorf("unexpected paused results: %+v", results)
+	}
+	if metrics, _ := r.CollectAll(context.Background()); len(metrics) != 1 {
+		t.Errorf("CollectAll should skip paused collectors, got %v", metrics)
+	}
+
+	r.SetCollectorEnabled("plugins", true)
+	if metrics, _ := r.CollectAllParallel(context.Background()); len(metrics) != 2 {
+		t.Errorf("expected both collectors after re-enabling, got %v", metrics)
+	}
+}
+
+type describingCollector struct {
+	mockCollector
+	descriptors []MetricDescriptor
+}
+
+func (d *describingCollector) Describe() []MetricDescriptor { return d.descriptors }
+
+func TestRegistryDescribeAll(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&describingCollector{
+		mockCollector: mockCollector{name: "a"},
+		descriptors:   []MetricDescriptor{{Name: "shared", Type: "gauge"}, {Name: "only_a", Type: "gauge"}},
+	})
+	r.Register(&describingCollector{
+		mockCollector: mockCollector{name: "b"},
+		descriptors:   []MetricDescriptor{{Name: "shared", Type: "counter"}},
+	})
+	r.Register(&mockCollector{name: "dynamic"})
+
+	catalog, collisions := r.DescribeAll()
+	if len(catalog) != 2 {
+		t.Errorf("expected 2 describable collectors, got %d", len(catalog))
+	}
+	if _, ok := catalog["dynamic"]; ok {
+		t.Error("non-describing collector should not appear in catalog")
+	}
+	if len(collisions) != 1 || collisions[0] != "shared" {
+		t.Errorf("expected collision on 'shared', got %v", collisions)
+	}
+}
+
+func TestApplyGlobalLabels(t *testing.T) {
+	shared := map[string]string{"env": "local"}
+	metrics := []Metric{
+		{Name: "a", Labels: shared},
+		{Name: "b"},
+	}
+
+	ApplyGlobalLabels(metrics, map[string]string{"env": "global", "host": "h1"})
+
+	if metrics[0].Labels["env"] != "local" || metrics[0].Labels["host"] != "h1" {
+		t.Errorf("unexpected labels on a: %v", metrics[0].Labels)
+	}
+	if metrics[1].Labels["env"] != "global" {
+		t.Errorf("unexpected labels on b: %v", metrics[1].Labels)
+	}
+	if len(shared) != 1 {
+		t.Error("original label map should not be mutated")
+	}
+}
+
+func TestApplyMetricPrefix(t *testing.T) {
+	metrics := []Metric{
+		{Name: "system_cpu_usage_percent"},
+		{Name: "acme_system_memory_used_bytes"},
+	}
+
+	ApplyMetricPrefix(metrics, "acme_")
+	ApplyMetricPrefix(metrics, "acme_") // re-applying must not double-prefix
+
+	want := []string{"acme_system_cpu_usage_percent", "acme_system_memory_used_bytes"}
+	for i, m := range metrics {
+		if m.Name != want[i] {
+			t.Errorf("metric %d: got %q, want %q", i, m.Name, want[i])
+		}
+	}
+
+	ApplyMetricPrefix(metrics, "")
+	if metrics[0].Name != want[0] {
+		t.Errorf("empty prefix should be a no-op, got %q", metrics[0].Name)
+	}
+}
+
+func TestSeriesKey(t *testing.T) {
+	a := SeriesKey(Metric{Name: "m", Labels: map[string]string{"a": "1", "b": "2"}})
+	b := SeriesKey(Metric{Name: "m", Labels: map[string]string{"b": "2", "a": "1"}})
+	if a != b {
+		t.Errorf("label order should not matter: %q vs %q", a, b)
+	}
+	if a == SeriesKey(Metric{Name: "m", Labels: map[string]string{"a": "1", "b": "3"}}) {
+		t.Error("different label values should give different keys")
+	}
+	if SeriesKey(Metric{Name: "m"}) == SeriesKey(Metric{Name: "n"}) {
+		t.Error("different names should give different keys")
+	}
+}
+
+func TestApplyTypeOverrides(t *testing.T) {
+	metrics := []Metric{
+		{Name: "queue_depth", Type: "counter"},
+		{Name: "jobs_processed", Type: "gauge"},
+		{Name: "cpu", Type: "gauge"},
+	}
+
+	ApplyTypeOverrides(metrics, map[string]string{"queue_depth": "gauge", "jobs_processed": "counter"})
+
+	want := []string{"gauge", "counter", "gauge"}
+	for i, m := range metrics {
+		if m.Type != want[i] {
+			t.Errorf("%s: got type %q, want %q", m.Name, m.Type, want[i])
+		}
+	}
+}
+
+func TestDeduplicate(t *testing.T) {
+	metrics := []Metric{
+		{Name: "up", Labels: map[string]string{"job": "a"}, Value: 1},
+		{Name: "up", Labels: map[string]string{"job": "b"}, Value: 1},
+		{Name: "cpu", Value: 10},
+		{Name: "up", Labels: map[string]string{"job": "a"}, Value: 0},
+	}
+
+	kept, dropped := Deduplicate(metrics)
+	if dropped != 1 || len(kept) != 3 {
+		t.Fatalf("expected 3 metrics and 1 duplicate, got %d and %d", len(kept), dropped)
+	}
+	if kept[0].Labels["job"] != "a" || kept[0].Value != 0 {
+		t.Errorf("expected the first position with the last value, got %+v", kept[0])
+	}
+}
+
+func TestLimitSeries(t *testing.T) {
+	metrics := []Metric{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	if kept, dropped := LimitSeries(metrics, 0); len(kept) != 3 || dropped != 0 {
+		t.Errorf("limit 0 should keep everything, got %d kept, %d dropped", len(kept), dropped)
+	}
+	kept, dropped := LimitSeries(metrics, 2)
+	if len(kept) != 2 || dropped != 1 || kept[1].Name != "b" {
+		t.Errorf("expected the first 2 kept and 1 dropped, got %v, %d", kept, dropped)
+	}
+}
+
+func TestCheckTimestamps(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	input := func() []Metric {
+		return []Metric{
+			{Name: "now"},
+			{Name: "recent", Timestamp: now.Add(-time.Minute)},
+			{Name: "future", Timestamp: now.Add(3 * time.Hour)},
+			{Name: "stale", Timestamp: now.Add(-48 * time.Hour)},
+		}
+	}
+	names := func(metrics []Metric) []string {
+		var out []string
+		for _, m := range metrics {
+			out = append(out, m.Name)
+		}
+		return out
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		kept, rejected := CheckTimestamps(input(), now, 0, 0, false)
+		if len(kept) != 4 || rejected != 0 {
+			t.Errorf("expected everything kept, got %v, %d rejected", names(kept), rejected)
+		}
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		kept, rejected := CheckTimestamps(input(), now, 10*time.Minute, 24*time.Hour, false)
+		if got := names(kept); rejected != 2 || !slices.Equal(got, []string{"now", "recent"}) {
+			t.Errorf("expected future and stale dropped, got %v, %d rejected", got, rejected)
+		}
+	})
+
+	t.Run("future only", func(t *testing.T) {
+		kept, rejected := CheckTimestamps(input(), now, 10*time.Minute, 0, false)
+		if got := names(kept); rejected != 1 || !slices.Equal(got, []string{"now", "recent", "stale"}) {
+			t.Errorf("expected only future dropped, got %v, %d rejected", got, rejected)
+		}
+	})
+
+	t.Run("clamp", func(t *testing.T) {
+		kept, rejected := CheckTimestamps(input(), now, 10*time.Minute, 24*time.Hour, true)
+		if len(kept) != 4 || rejected != 2 {
+			t.Fatalf("expected everything kept and 2 clamped, got %v, %d", names(kept), rejected)
+		}
+		if !kept[2].Timestamp.IsZero() || !kept[3].Timestamp.IsZero() {
+			t.Errorf("out of range timestamps not cleared: %v, %v", kept[2].Timestamp, kept[3].Timestamp)
+		}
+		if !kept[1].Timestamp.Equal(now.Add(-time.Minute)) {
+			t.Errorf("in range timestamp changed: %v", kept[1].Timestamp)
+		}
+	})
+}
+
+func TestTruncateLabelValues(t *testing.T) {
+	shared := map[string]string{"query": "SELECT * FROM t", "short": "ok", "name": "héllo"}
+	metrics := []Metric{{Name: "a", Labels: shared}}
+
+	if n := TruncateLabelValues(metrics, 0); n != 0 {
+		t.Errorf("limit 0 should not truncate, cut %d", n)
+	}
+	if n := TruncateLabelValues(metrics, 2); n != 2 {
+		t.Errorf("expected 2 values cut, got %d", n)
+	}
+	labels := metrics[0].Labels
+	if labels["query"] != "SE" || labels["short"] != "ok" || labels["name"] != "h" {
+		t.Errorf("unexpected truncated labels: %v", labels)
+	}
+	if shared["query"] != "SELECT * FROM t" {
+		t.Error("the shared label map was modified")
+	}
+}
@@ -0,0 +1,124 @@
+package collector
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// demoCores is the number of CPU cores the demo collector pretends to have
+	demoCores = 4
+	// demoCPUPeriod is the period of the CPU usage sine wave
+	demoCPUPeriod = 5 * time.Minute
+
+	demoMemoryMin = 1 << 30
+	demoMemoryMax = 3 << 30
+)
+
+var demoMetricDescriptors = []MetricDescriptor{
+	{Name: "demo_cpu_usage_percent", Type: "gauge", Help: "Synthetic CPU usage per core, following a sine wave."},
+	{Name: "demo_memory_used_bytes", Type: "gauge", Help: "Synthetic memory usage, a random walk between 1 and 3 GiB."},
+	{Name: "demo_http_requests_total", Type: "counter", Help: "Synthetic HTTP requests served, by status code."},
+	{Name: "demo_network_bytes_total", Type: "counter", Help: "Synthetic network traffic, by direction."},
+	{Name: "demo_uptime_seconds", Type: "gauge", Help: "Seconds since the demo collector was created."},
+}
+
+var demoMetricHelp = helpIndex(demoMetricDescriptors)
+
+// demoRequestWeights is the mean number of requests per collection by status
+var demoRequestWeights = []struct {
+	status string
+	mean   int
+}{
+	{"200", 100},
+	{"404", 5},
+	{"500", 1},
+}
+
+// DemoCollector emits synthetic system-like metrics without touching the
+// host, to try out shippers and dashboards or smoke test a deployment.
+// Gauges move smoothly and counters only ever increase, like real ones.
+type DemoCollector struct {
+	mu       sync.Mutex
+	start    time.Time
+	rng      *rand.Rand
+	memory   float64
+	requests map[string]float64
+	rxBytes  float64
+	txBytes  float64
+}
+
+// NewDemoCollector creates a new demo collector
+func NewDemoCollector() *DemoCollector {
+	return newDemoCollector(time.Now(), rand.Uint64())
+}
+
+func newDemoCollector(start time.Time, seed uint64) *DemoCollector {
+	return &DemoCollector{
+		start:    start,
+		rng:      rand.New(rand.NewPCG(seed, seed)),
+		memory:   (demoMemoryMin + demoMemoryMax) / 2,
+		requests: make(map[string]float64),
+	}
+}
+
+// Name returns the collector name
+func (c *DemoCollector) Name() string {
+	return "demo"
+}
+
+// Describe returns the metrics emitted by the demo collector
+func (c *DemoCollector) Describe() []MetricDescriptor {
+	return demoMetricDescriptors
+}
+
+// Collect returns the next set of synthetic values
+func (c *DemoCollector) Collect(ctx context.Context) ([]Metric, error) {
+	return c.collectAt(time.Now()), nil
+}
+
+func (c *DemoCollector) collectAt(now time.Time) []Metric {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elapsed := now.Sub(c.start).Seconds()
+	metrics := make([]Metric, 0, demoCores+len(demoRequestWeights)+4)
+
+	// Each core runs the same wave shifted by a fraction of the period
+	for core := range demoCores {
+		phase := 2 * math.Pi * (elapsed/demoCPUPeriod.Seconds() + float64(core)/demoCores)
+		metrics = append(metrics, c.metric("demo_cpu_usage_percent", "gauge", 50+40*math.Sin(phase),
+			map[string]string{"core": strconv.Itoa(core)}))
+	}
+
+	c.memory += (c.rng.Float64() - 0.5) * (64 << 20)
+	c.memory = math.Max(demoMemoryMin, math.Min(demoMemoryMax, c.memory))
+	metrics = append(metrics, c.metric("demo_memory_used_bytes", "gauge", c.memory, nil))
+
+	for _, w := range demoRequestWeights {
+		c.requests[w.status] += float64(c.rng.IntN(2*w.mean + 1))
+		metrics = append(metrics, c.metric("demo_http_requests_total", "counter", c.requests[w.status],
+			map[string]string{"status": w.status}))
+	}
+
+	c.rxBytes += float64(c.rng.IntN(1 << 20))
+	c.txBytes += float64(c.rng.IntN(256 << 10))
+	metrics = append(metrics,
+		c.metric("demo_network_bytes_total", "counter", c.rxBytes, map[string]string{"direction": "rx"}),
+		c.metric("demo_network_bytes_total", "counter", c.txBytes, map[string]string{"direction": "tx"}),
+		c.metric("demo_uptime_seconds", "gauge", math.Max(0, elapsed), nil),
+	)
+	applyHelp(metrics, demoMetricHelp)
+	return metrics
+}
+
+func (c *DemoCollector) metric(name, metricType string, value float64, labels map[string]string) Metric {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	return Metric{Name: name, Value: value, Type: metricType, Labels: labels}
+}
@@ -0,0 +1,83 @@
+package collector
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+// demoSeries keys the demo collector's metrics by name and labels
+func demoSeries(t *testing.T, metrics []Metric) map[string]float64 {
+	t.Helper()
+	series := make(map[string]float64, len(metrics))
+	for _, m := range metrics {
+		if m.Help == "" {
+			t.Errorf("%s has no help text", m.Name)
+		}
+		key := m.Name
+		for _, name := range SortedLabelNames(m.Labels) {
+			key += "," + name + "=" + m.Labels[name]
+		}
+		series[key] = m.Value
+	}
+	return series
+}
+
+func TestDemoCollector(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	c := newDemoCollector(start, 1)
+
+	// A quarter period in, core 0 peaks and core 2, half a period behind, bottoms out
+	first := demoSeries(t, c.collectAt(start.Add(demoCPUPeriod/4)))
+	if v := first["demo_cpu_usage_percent,core=0"]; math.Abs(v-90) > 1e-9 {
+		t.Errorf("core 0: want 90, got %v", v)
+	}
+	if v := first["demo_cpu_usage_percent,core=2"]; math.Abs(v-10) > 1e-9 {
+		t.Errorf("core 2: want 10, got %v", v)
+	}
+	if v := first["demo_uptime_seconds"]; v != demoCPUPeriod.Seconds()/4 {
+		t.Errorf("uptime: want %v, got %v", demoCPUPeriod.Seconds()/4, v)
+	}
+
+	prev := first
+	for i := 2; i <= 50; i++ {
+		next := demoSeries(t, c.collectAt(start.Add(time.Duration(i)*10*time.Second)))
+		for key, v := range next {
+			switch key {
+			case "demo_memory_used_bytes":
+				if v < demoMemoryMin || v > demoMemoryMax {
+					t.Fatalf("memory out of bounds: %v", v)
+				}
+			case "demo_http_requests_total,status=200", "demo_http_requests_total,status=404",
+				"demo_http_requests_total,status=500", "demo_network_bytes_total,direction=rx",
+				"demo_network_bytes_total,direction=tx":
+				if v < prev[key] {
+					t.Fatalf("counter %s decreased from %v to %v", key, prev[key], v)
+				}
+			}
+		}
+		prev = next
+	}
+	if prev["demo_http_requests_total,status=200"] == 0 {
+		t.Error("expected requests to have been counted")
+	}
+}
+
+func TestDemoCollector_Describe(t *testing.T) {
+	c := NewDemoCollector()
+	described := make(map[string]string)
+	for _, d := range c.Describe() {
+		described[d.Name] = d.Type
+	}
+
+	metrics, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	for _, m := range metrics {
+		if described[m.Name] != m.Type {
+			t.Errorf("%s: described as %q, emitted as %q", m.Name, described[m.Name], m.Type)
+		}
+	}
+}
@@ -6,19 +6,24 @@ package collector
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
 )
 
 // GPUCollector collects GPU metrics using NVML (Single Responsibility Principle)
 type GPUCollector struct {
+	mu          sync.Mutex // guards NVML init/shutdown against concurrent Collect calls
 	initialized bool
+	perProcess  bool
 }
 
-// NewGPUCollector creates a new GPU metrics collector
-func NewGPUCollector() *GPUCollector {
+// NewGPUCollector creates a new GPU metrics collector.
+// perProcess enables per-PID GPU memory metrics, which add one series per process.
+func NewGPUCollector(perProcess bool) *GPUCollector {
 	return &GPUCollector{
 		initialized: false,
+		perProcess:  perProcess,
 	}
 }
 
@@ -27,8 +32,42 @@ func (c *GPUCollector) Name() string {
 	return "gpu"
 }
 
+var gpuMetricDescriptors = []MetricDescriptor{
+	{Name: "system_gpu_count", Type: "gauge", Help: "Number of NVIDIA GPUs."},
+	{Name: "system_gpu_utilization_percent", Type: "gauge", Help: "GPU core utilization percentage."},
+	{Name: "system_gpu_memory_utilization_percent", Type: "gauge", Help: "GPU memory controller utilization percentage."},
+	{Name: "system_gpu_memory_total_bytes", Type: "gauge", Help: "Total GPU memory in bytes."},
+	{Name: "system_gpu_memory_used_bytes", Type: "gauge", Help: "Used GPU memory in bytes."},
+	{Name: "system_gpu_memory_free_bytes", Type: "gauge", Help: "Free GPU memory in bytes."},
+	{Name: "system_gpu_temperature_celsius", Type: "gauge", Help: "GPU core temperature in degrees Celsius."},
+	{Name: "system_gpu_power_usage_milliwatts", Type: "gauge", Help: "GPU power draw in milliwatts."},
+	{Name: "system_gpu_fan_speed_percent", Type: "gauge", Help: "GPU fan speed percentage."},
+	{Name: "system_gpu_clock_sm_mhz", Type: "gauge", Help: "GPU SM clock in MHz."},
+	{Name: "system_gpu_clock_memory_mhz", Type: "gauge", Help: "GPU memory clock in MHz."},
+	{Name: "system_gpu_ecc_errors_total", Type: "counter", Help: "GPU ECC memory errors by counter and error type."},
+	{Name: "system_gpu_throttle_reasons", Type: "gauge", Help: "1 if the GPU clock is currently throttled for the given reason."},
+}
+
+var gpuProcessMetricDescriptor = MetricDescriptor{
+	Name: "system_gpu_process_memory_bytes", Type: "gauge", Help: "GPU memory used by a process in bytes.",
+}
+
+var gpuMetricHelp = helpIndex(gpuMetricDescriptors, []MetricDescriptor{gpuProcessMetricDescriptor})
+
+// Describe returns the GPU metrics this collector may emit
+func (c *GPUCollector) Describe() []MetricDescriptor {
+	descriptors := append([]MetricDescriptor{}, gpuMetricDescriptors...)
+	if c.perProcess {
+		descriptors = append(descriptors, gpuProcessMetricDescriptor)
+	}
+	return descriptors
+}
+
 // Collect gathers GPU metrics
 func (c *GPUCollector) Collect(ctx context.Context) ([]Metric, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	// Initialize NVML if not already done
 	if !c.initialized {
 		ret := nvml.Init()
@@ -66,6 +105,8 @@ func (c *GPUCollector) Collect(ctx context.Context) ([]Metric, error) {
 		}
 	}
 
+	applyHelp(metrics, gpuMetricHelp)
+
 	return metrics, nil
 }
 
@@ -177,11 +218,145 @@ func (c *GPUCollector) collectDeviceMetrics(device nvml.Device, index int) ([]Me
 		})
 	}
 
+	metrics = append(metrics, c.collectECCMetrics(device, labels)...)
+	metrics = append(metrics, c.collectThrottleMetrics(device, labels)...)
+
+	if c.perProcess {
+		metrics = append(metrics, c.collectProcessMetrics(device, labels)...)
+	}
+
 	return metrics, nil
 }
 
+// gpuThrottleReasons maps NVML clocks-throttle-reason bits to label values.
+var gpuThrottleReasons = []struct {
+	bit    uint64
+	reason string
+}{
+	{nvml.ClocksThrottleReasonGpuIdle, "gpu_idle"},
+	{nvml.ClocksThrottleReasonApplicationsClocksSetting, "applications_clocks_setting"},
+	{nvml.ClocksThrottleReasonSwPowerCap, "sw_power_cap"},
+	{nvml.ClocksThrottleReasonHwSlowdown, "hw_slowdown"},
+	{nvml.ClocksThrottleReasonSyncBoost, "sync_boost"},
+	{nvml.ClocksThrottleReasonSwThermalSlowdown, "sw_thermal_slowdown"},
+	{nvml.ClocksThrottleReasonHwThermalSlowdown, "hw_thermal_slowdown"},
+	{nvml.ClocksThrottleReasonHwPowerBrakeSlowdown, "hw_power_brake_slowdown"},
+	{nvml.ClocksThrottleReasonDisplayClockSetting, "display_clock_setting"},
+}
+
+// collectECCMetrics reports volatile and aggregate ECC error counts.
+// Devices without ECC support or with ECC disabled yield no metrics.
+func (c *GPUCollector) collectECCMetrics(device nvml.Device, labels map[string]string) []Metric {
+	current, _, ret := device.GetEccMode()
+	if ret != nvml.SUCCESS || current != nvml.FEATURE_ENABLED {
+		return nil
+	}
+
+	counters := []struct {
+		counterType nvml.EccCounterType
+		name        string
+	}{
+		{nvml.VOLATILE_ECC, "volatile"},
+		{nvml.AGGREGATE_ECC, "aggregate"},
+	}
+	errorTypes := []struct {
+		errorType nvml.MemoryErrorType
+		name      string
+	}{
+		{nvml.MEMORY_ERROR_TYPE_CORRECTED, "correctable"},
+		{nvml.MEMORY_ERROR_TYPE_UNCORRECTED, "uncorrectable"},
+	}
+
+	metrics := make([]Metric, 0, len(counters)*len(errorTypes))
+	for _, counter := range counters {
+		for _, et := range errorTypes {
+			count, ret := device.GetTotalEccErrors(et.errorType, counter.counterType)
+			if ret != nvml.SUCCESS {
+				continue
+			}
+			eccLabels := copyLabels(labels)
+			eccLabels["counter"] = counter.name
+			eccLabels["error_type"] = et.name
+			metrics = append(metrics, Metric{
+				Name:   "system_gpu_ecc_errors_total",
+				Labels: eccLabels,
+				Value:  float64(count),
+				Type:   "counter",
+			})
+		}
+	}
+
+	return metrics
+}
+
+// collectThrottleMetrics decodes the clocks-throttle-reasons bitmask into one
+// 0/1 gauge per reason.
+func (c *GPUCollector) collectThrottleMetrics(device nvml.Device, labels map[string]string) []Metric {
+	reasons, ret := device.GetCurrentClocksThrottleReasons()
+	if ret != nvml.SUCCESS {
+		return nil
+	}
+
+	metrics := make([]Metric, 0, len(gpuThrottleReasons))
+	for _, r := range gpuThrottleReasons {
+		value := 0.0
+		if reasons&r.bit != 0 {
+			value = 1
+		}
+		reasonLabels := copyLabels(labels)
+		reasonLabels["reason"] = r.reason
+		metrics = append(metrics, Metric{
+			Name:   "system_gpu_throttle_reasons",
+			Labels: reasonLabels,
+			Value:  value,
+			Type:   "gauge",
+		})
+	}
+
+	return metrics
+}
+
+// collectProcessMetrics reports GPU memory held by each compute and graphics process.
+// Devices that don't support process queries (ERROR_NOT_SUPPORTED) yield no metrics.
+func (c *GPUCollector) collectProcessMetrics(device nvml.Device, labels map[string]string) []Metric {
+	usage := make(map[uint32]uint64)
+
+	for _, getProcs := range []func() ([]nvml.ProcessInfo, nvml.Return){
+		device.GetComputeRunningProcesses,
+		device.GetGraphicsRunningProcesses,
+	} {
+		procs, ret := getProcs()
+		if ret != nvml.SUCCESS {
+			continue
+		}
+		// A process using both compute and graphics is reported in both lists
+		for _, p := range procs {
+			if p.UsedGpuMemory > usage[p.Pid] {
+				usage[p.Pid] = p.UsedGpuMemory
+			}
+		}
+	}
+
+	metrics := make([]Metric, 0, len(usage))
+	for pid, used := range usage {
+		procLabels := copyLabels(labels)
+		procLabels["pid"] = fmt.Sprintf("%d", pid)
+		metrics = append(metrics, Metric{
+			Name:   "system_gpu_process_memory_bytes",
+			Labels: procLabels,
+			Value:  float64(used),
+			Type:   "gauge",
+		})
+	}
+
+	return metrics
+}
+
 // Shutdown cleans up NVML resources
 func (c *GPUCollector) Shutdown() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.initialized {
 		ret := nvml.Shutdown()
 		if ret != nvml.SUCCESS {
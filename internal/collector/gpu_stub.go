@@ -11,12 +11,15 @@ import (
 // GPUCollector collects GPU metrics using NVML (Single Responsibility Principle)
 type GPUCollector struct {
 	initialized bool
+	perProcess  bool
 }
 
-// NewGPUCollector creates a new GPU metrics collector
-func NewGPUCollector() *GPUCollector {
+// NewGPUCollector creates a new GPU metrics collector.
+// perProcess enables per-PID GPU memory metrics, which add one series per process.
+func NewGPUCollector(perProcess bool) *GPUCollector {
 	return &GPUCollector{
 		initialized: false,
+		perProcess:  perProcess,
 	}
 }
 
@@ -30,6 +33,11 @@ func (c *GPUCollector) Collect(ctx context.Context) ([]Metric, error) {
 	return nil, fmt.Errorf("GPU metrics not available: binary not built with NVIDIA support (use -tags nvidia)")
 }
 
+// Describe returns no metrics since the stub never emits any
+func (c *GPUCollector) Describe() []MetricDescriptor {
+	return nil
+}
+
 // Shutdown cleans up NVML resources (stub - no-op)
 func (c *GPUCollector) Shutdown() error {
 	return nil
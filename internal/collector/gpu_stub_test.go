@@ -9,14 +9,14 @@ import (
 )
 
 func TestGPUCollector_Name(t *testing.T) {
-	c := NewGPUCollector()
+	c := NewGPUCollector(false)
 	if got := c.Name(); got != "gpu" {
 		t.Errorf("Name() = %q, want %q", got, "gpu")
 	}
 }
 
 func TestGPUCollector_Collect(t *testing.T) {
-	c := NewGPUCollector()
+	c := NewGPUCollector(false)
 	metrics, err := c.Collect(context.Background())
 	if err == nil {
 		t.Error("Collect() expected an error for stub GPU collector, got nil")
@@ -27,7 +27,7 @@ func TestGPUCollector_Collect(t *testing.T) {
 }
 
 func TestGPUCollector_Shutdown(t *testing.T) {
-	c := NewGPUCollector()
+	c := NewGPUCollector(false)
 	if err := c.Shutdown(); err != nil {
 		t.Errorf("Shutdown() expected nil error, got %v", err)
 	}
@@ -0,0 +1,89 @@
+//go:build nvidia
+// +build nvidia
+
+package collector
+
+import (
+	"testing"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock"
+)
+
+// fakeGPU is a device that supports only the name, memory and process queries
+func fakeGPU() *mock.Device {
+	notSupported := func() (uint32, nvml.Return) { return 0, nvml.ERROR_NOT_SUPPORTED }
+	return &mock.Device{
+		GetNameFunc: func() (string, nvml.Return) { return "Tesla T4", nvml.SUCCESS },
+		GetMemoryInfoFunc: func() (nvml.Memory, nvml.Return) {
+			return nvml.Memory{Total: 16 << 30, Used: 4 << 30, Free: 12 << 30}, nvml.SUCCESS
+		},
+		GetUtilizationRatesFunc: func() (nvml.Utilization, nvml.Return) {
+			return nvml.Utilization{}, nvml.ERROR_NOT_SUPPORTED
+		},
+		GetTemperatureFunc: func(nvml.TemperatureSensors) (uint32, nvml.Return) { return notSupported() },
+		GetPowerUsageFunc:  notSupported,
+		GetFanSpeedFunc:    notSupported,
+		GetClockInfoFunc:   func(nvml.ClockType) (uint32, nvml.Return) { return notSupported() },
+		GetEccModeFunc: func() (nvml.EnableState, nvml.EnableState, nvml.Return) {
+			return 0, 0, nvml.ERROR_NOT_SUPPORTED
+		},
+		GetCurrentClocksThrottleReasonsFunc: func() (uint64, nvml.Return) { return 0, nvml.ERROR_NOT_SUPPORTED },
+		GetComputeRunningProcessesFunc: func() ([]nvml.ProcessInfo, nvml.Return) {
+			return []nvml.ProcessInfo{{Pid: 42, UsedGpuMemory: 1 << 30}}, nvml.SUCCESS
+		},
+		GetGraphicsRunningProcessesFunc: func() ([]nvml.ProcessInfo, nvml.Return) {
+			return []nvml.ProcessInfo{{Pid: 42, UsedGpuMemory: 2 << 30}, {Pid: 7, UsedGpuMemory: 1 << 20}}, nvml.SUCCESS
+		},
+	}
+}
+
+func TestGPUCollector_ProcessMetrics(t *testing.T) {
+	c := NewGPUCollector(true)
+	metrics, err := c.collectDeviceMetrics(fakeGPU(), 1)
+	if err != nil {
+		t.Fatalf("collectDeviceMetrics: %v", err)
+	}
+
+	byPID := make(map[string]Metric)
+	for _, m := range metrics {
+		if m.Name == "system_gpu_process_memory_bytes" {
+			byPID[m.Labels["pid"]] = m
+		}
+	}
+	if len(byPID) != 2 {
+		t.Fatalf("expected 2 processes, got %v", byPID)
+	}
+	// A process in both lists is reported once, with its larger usage
+	if m := byPID["42"]; m.Value != 2<<30 {
+		t.Errorf("pid 42: want %d bytes, got %v", 2<<30, m.Value)
+	}
+	for pid, m := range byPID {
+		if m.Labels["gpu"] != "1" || m.Labels["name"] != "Tesla T4" {
+			t.Errorf("pid %s: want the device's gpu and name labels, got %v", pid, m.Labels)
+		}
+	}
+
+	var memory Metric
+	for _, m := range metrics {
+		if m.Name == "system_gpu_memory_used_bytes" {
+			memory = m
+		}
+	}
+	if memory.Value != 4<<30 || memory.Labels["name"] != "Tesla T4" {
+		t.Errorf("unexpected memory metric: %+v", memory)
+	}
+}
+
+func TestGPUCollector_ProcessMetricsDisabled(t *testing.T) {
+	c := NewGPUCollector(false)
+	metrics, err := c.collectDeviceMetrics(fakeGPU(), 0)
+	if err != nil {
+		t.Fatalf("collectDeviceMetrics: %v", err)
+	}
+	for _, m := range metrics {
+		if m.Name == "system_gpu_process_memory_bytes" {
+			t.Fatalf("per-process metrics emitted while disabled: %+v", m)
+		}
+	}
+}
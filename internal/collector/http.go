@@ -5,96 +5,602 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
-
-	"github.com/rs/zerolog/log"
 )
 
 // HTTPCollector scrapes metrics from HTTP endpoints (Single Responsibility Principle)
 type HTTPCollector struct {
-	endpoints []EndpointConfig
-	client    *http.Client
+	Logging
+	endpoints      []EndpointConfig
+	client         *http.Client
+	maxConcurrency int
+	discovery      map[int]*dnsTargets // Discovery state keyed by index into endpoints
+	userAgent      string
+
+	parseMu     sync.Mutex
+	parseErrors map[string]uint64 // Cumulative parse failures keyed by endpoint name and URL
+
+	countersMu sync.Mutex
+	counters   map[string]map[string]*counterState // Counter continuity state by endpoint name and URL, then series
+
+	// invalidPatterns failed to compile in NewHTTPCollector, before a logger
+	// could be injected; the first Collect logs them
+	invalidPatterns []invalidPattern
+	reportInvalid   sync.Once
+}
+
+// invalidPattern is an endpoint pattern ignored because it doesn't compile
+type invalidPattern struct {
+	endpoint string
+	pattern  string
+	msg      string
+	err      error
 }
 
+// defaultHTTPMaxConcurrency bounds concurrent endpoint scrapes when unset
+const defaultHTTPMaxConcurrency = 10
+
 // EndpointConfig represents an HTTP endpoint to scrape
 type EndpointConfig struct {
-	Name string
-	URL  string
+	Name    string
+	URL     string
+	URLs    []string          // Further URLs scraped after URL; their metrics merge under the same endpoint
+	Method  string            // HTTP method, defaults to GET
+	Body    string            // Optional request body
+	Headers map[string]string // Optional request headers
+	Labels  map[string]string // Static labels added to every scraped metric
+
+	// MetricAllowlist and MetricDenylist are regexes matched against the full
+	// metric name after parsing. The denylist wins over the allowlist.
+	MetricAllowlist []string
+	MetricDenylist  []string
+
+	// NameRewrite renames metrics after filtering; the first matching rule wins
+	NameRewrite []NameRewriteRule
+
+	FollowRedirects *bool // Follow 3xx responses (default: true)
+	MaxRedirects    int   // Redirects followed before giving up (default: 10)
+
+	// AcceptStatus lists the status codes treated as a successful scrape
+	// (default: 200). An empty body with an accepted code yields no metrics.
+	AcceptStatus []int
+
+	// Exemplars keeps OpenMetrics exemplars on scraped samples instead of
+	// dropping them
+	Exemplars bool
+
+	// Discovery set to DiscoveryDNS scrapes every address DNSName (default: the
+	// URL host) resolves to, substituting it for the URL host. DNSType is
+	// DNSTypeA (default) or DNSTypeSRV.
+	Discovery       string
+	DNSName         string
+	DNSType         string
+	RefreshInterval time.Duration // How often to re-resolve (default: DefaultDiscoveryRefresh)
+
+	// Retries re-scrapes the endpoint up to this many times after a transient
+	// failure (request error, 429 or 5xx), waiting RetryBackoff (default:
+	// DefaultRetryBackoff) doubled each attempt. No retry outlives ctx.
+	Retries      int
+	RetryBackoff time.Duration
+
+	// ParseRegex, when set, replaces format auto-detection: every match in the
+	// body becomes a gauge. See parseRegexMetrics for the capture groups.
+	ParseRegex string
+
+	// CounterContinuity keeps scraped counters increasing across target
+	// restarts; see correctCounterResets
+	CounterContinuity bool
+
+	allow      []*regexp.Regexp
+	deny       []*regexp.Regexp
+	rewrites   []compiledRewrite
+	parseRegex *regexp.Regexp
+}
+
+// NameRewriteRule replaces metric names matching the anchored Match regex with
+// Replacement, which may reference capture groups as $1 or ${name}.
+type NameRewriteRule struct {
+	Match       string
+	Replacement string
+}
+
+type compiledRewrite struct {
+	re          *regexp.Regexp
+	replacement string
 }
 
-// NewHTTPCollector creates a new HTTP metrics collector
+// NewHTTPCollector creates a new HTTP metrics collector. Invalid filter patterns
+// are ignored and logged by the first Collect; config validation rejects them
+// before this point.
 func NewHTTPCollector(endpoints []EndpointConfig, timeout time.Duration) *HTTPCollector {
+	compiled := make([]EndpointConfig, len(endpoints))
+	discovery := make(map[int]*dnsTargets)
+	var invalid []invalidPattern
+	for i, ep := range endpoints {
+		ep.allow = compileMetricFilters(ep.Name, ep.MetricAllowlist, &invalid)
+		ep.deny = compileMetricFilters(ep.Name, ep.MetricDenylist, &invalid)
+		ep.rewrites = compileNameRewrites(ep.Name, ep.NameRewrite, &invalid)
+		ep.parseRegex = compileParseRegex(ep.Name, ep.ParseRegex, &invalid)
+		compiled[i] = ep
+		if ep.Discovery == DiscoveryDNS {
+			discovery[i] = &dnsTargets{endpoint: ep, resolver: net.DefaultResolver}
+		}
+	}
 	return &HTTPCollector{
-		endpoints: endpoints,
+		endpoints: compiled,
+		discovery: discovery,
 		client: &http.Client{
-			Timeout: timeout,
+			Timeout:       timeout,
+			Transport:     ProxyTransport(ProxyConfig{}),
+			CheckRedirect: checkRedirect,
 		},
+		maxConcurrency: defaultHTTPMaxConcurrency,
+
+		invalidPatterns: invalid,
 	}
 }
 
+// SetMaxConcurrency bounds how many endpoints are scraped at once. Values <= 0
+// reset the limit to the default of 10.
+func (c *HTTPCollector) SetMaxConcurrency(n int) {
+	if n <= 0 {
+		n = defaultHTTPMaxConcurrency
+	}
+	c.maxConcurrency = n
+}
+
+// SetUserAgent sets the User-Agent header of scrape requests. Endpoint headers
+// may still override it; empty keeps Go's default.
+func (c *HTTPCollector) SetUserAgent(ua string) {
+	c.userAgent = ua
+}
+
+// SetProxy routes scrapes through proxy instead of the environment's proxy
+func (c *HTTPCollector) SetProxy(proxy ProxyConfig) {
+	c.client.Transport = ProxyTransport(proxy)
+}
+
+// DefaultRetryBackoff is the delay before the first retry of a failed scrape
+const DefaultRetryBackoff = 500 * time.Millisecond
+
+// defaultMaxRedirects matches net/http's built-in limit
+const defaultMaxRedirects = 10
+
+// errTooManyRedirects is returned by checkRedirect once max_redirects is exceeded
+var errTooManyRedirects = errors.New("too many redirects")
+
+// endpointContextKey carries the EndpointConfig of a scrape so the shared
+// client's CheckRedirect can apply that endpoint's redirect policy
+type endpointContextKey struct{}
+
+// checkRedirect enforces the per-endpoint follow_redirects and max_redirects settings
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	endpoint, _ := req.Context().Value(endpointContextKey{}).(EndpointConfig)
+	if endpoint.FollowRedirects != nil && !*endpoint.FollowRedirects {
+		// Hand the 3xx back to scrapeEndpoint, which reports it as an error
+		return http.ErrUseLastResponse
+	}
+	maxRedirects := endpoint.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = defaultMaxRedirects
+	}
+	if len(via) > maxRedirects {
+		return fmt.Errorf("%w: stopped after %d redirects", errTooManyRedirects, maxRedirects)
+	}
+	return nil
+}
+
+// logInvalidPatterns logs the patterns NewHTTPCollector ignored
+func (c *HTTPCollector) logInvalidPatterns() {
+	for _, p := range c.invalidPatterns {
+		c.Logger().Error().Err(p.err).Str("endpoint", p.endpoint).Str("pattern", p.pattern).Msg(p.msg)
+	}
+}
+
+// compileMetricFilters anchors and compiles name filter patterns, adding the
+// ones that don't compile to invalid
+func compileMetricFilters(endpointName string, patterns []string, invalid *[]invalidPattern) []*regexp.Regexp {
+	var compiled []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile("^(?:" + p + ")$")
+		if err != nil {
+			*invalid = append(*invalid, invalidPattern{endpointName, p, "Ignoring invalid metric filter pattern", err})
+			continue
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled
+}
+
+// compileNameRewrites anchors and compiles name rewrite rules, adding the
+// ones that don't compile to invalid
+func compileNameRewrites(endpointName string, rules []NameRewriteRule, invalid *[]invalidPattern) []compiledRewrite {
+	var compiled []compiledRewrite
+	for _, rule := range rules {
+		re, err := regexp.Compile("^(?:" + rule.Match + ")$")
+		if err != nil {
+			*invalid = append(*invalid, invalidPattern{endpointName, rule.Match, "Ignoring invalid name rewrite pattern", err})
+			continue
+		}
+		compiled = append(compiled, compiledRewrite{re: re, replacement: rule.Replacement})
+	}
+	return compiled
+}
+
+// rewriteNames applies the endpoint's name rewrite rules. A rewrite that would
+// produce an illegal metric name is logged and the original name kept.
+func (c *HTTPCollector) rewriteNames(endpoint EndpointConfig, metrics []Metric) {
+	for i := range metrics {
+		for _, rw := range endpoint.rewrites {
+			if !rw.re.MatchString(metrics[i].Name) {
+				continue
+			}
+			name := rw.re.ReplaceAllString(metrics[i].Name, rw.replacement)
+			if !MetricNameRegex.MatchString(name) {
+				c.Logger().Warn().
+					Str("endpoint", endpoint.Name).
+					Str("metric", metrics[i].Name).
+					Str("rewritten", name).
+					Msg("Name rewrite produced an invalid metric name, keeping the original")
+			} else {
+				metrics[i].Name = name
+			}
+			break
+		}
+	}
+}
+
+// keep reports whether a metric name passes the endpoint's allow/deny lists
+func (e EndpointConfig) keep(name string) bool {
+	for _, re := range e.deny {
+		if re.MatchString(name) {
+			return false
+		}
+	}
+	if len(e.allow) == 0 {
+		return true
+	}
+	for _, re := range e.allow {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterMetrics drops metrics rejected by the endpoint's allow/deny lists
+func (e EndpointConfig) filterMetrics(metrics []Metric) []Metric {
+	if len(e.allow) == 0 && len(e.deny) == 0 {
+		return metrics
+	}
+	kept := metrics[:0]
+	for _, m := range metrics {
+		if e.keep(m.Name) {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
 // Name returns the collector name
 func (c *HTTPCollector) Name() string {
 	return "http"
 }
 
-// Collect scrapes metrics from all configured HTTP endpoints
+// Collect scrapes all configured HTTP endpoints, including the current targets
+// of discovery endpoints, concurrently and at most maxConcurrency at a time.
+// Failing endpoints are logged and skipped; results are merged in endpoint
+// order. Every scraped endpoint also reports endpoint_up,
+// endpoint_scrape_duration_seconds, endpoint_samples_parsed and
+// endpoint_parse_errors_total.
 func (c *HTTPCollector) Collect(ctx context.Context) ([]Metric, error) {
+	c.reportInvalid.Do(c.logInvalidPatterns)
+	targets := c.scrapeTargets(ctx)
+	results := make([][]Metric, len(targets))
+	sem := make(chan struct{}, c.maxConcurrency)
+	var wg sync.WaitGroup
+
+	for i, endpoint := range targets {
+		wg.Add(1)
+		go func(i int, endpoint EndpointConfig) {
+			defer wg.Done()
+
+			// An endpoint still waiting for a slot when ctx ends reports down
+			// rather than vanishing from the cycle
+			waitStart := time.Now()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				c.Logger().Warn().
+					Err(ctx.Err()).
+					Str("endpoint", endpoint.Name).
+					Msg("Skipped endpoint scrape, no slot before the collection deadline")
+				results[i] = append(endpoint.scrapeMetrics(0, time.Since(waitStart)), endpoint.parseMetrics(parseStats{}, c.addParseErrors(endpoint, 0))...)
+				return
+			}
+			defer func() { <-sem }()
+
+			start := time.Now()
+			endpointMetrics, parsed, up := c.scrapeAll(ctx, endpoint)
+			duration := time.Since(start)
+			results[i] = append(endpointMetrics, endpoint.scrapeMetrics(up, duration)...)
+			results[i] = append(results[i], endpoint.parseMetrics(parsed, c.addParseErrors(endpoint, parsed.errors))...)
+		}(i, endpoint)
+	}
+	wg.Wait()
+	c.evictCounterState(time.Now())
+
 	metrics := make([]Metric, 0)
+	for _, endpointMetrics := range results {
+		metrics = append(metrics, endpointMetrics...)
+	}
+	return metrics, nil
+}
+
+// scrapeURLs returns URL followed by URLs, skipping an empty URL
+func (e EndpointConfig) scrapeURLs() []string {
+	if e.URL == "" {
+		return e.URLs
+	}
+	return append([]string{e.URL}, e.URLs...)
+}
 
-	for _, endpoint := range c.endpoints {
-		endpointMetrics, err := c.scrapeEndpoint(ctx, endpoint)
+// scrapeAll scrapes every URL of endpoint in turn and concatenates their
+// metrics and parse stats. A failing URL is logged and skipped without
+// dropping the others; up is 1 only when all of them succeeded.
+func (c *HTTPCollector) scrapeAll(ctx context.Context, endpoint EndpointConfig) (metrics []Metric, parsed parseStats, up float64) {
+	up = 1
+	for _, u := range endpoint.scrapeURLs() {
+		target := endpoint
+		target.URL = u
+		urlMetrics, urlParsed, err := c.scrapeWithRetries(ctx, target)
+		parsed.samples += urlParsed.samples
+		parsed.errors += urlParsed.errors
 		if err != nil {
-			log.Warn().
+			c.Logger().Warn().
 				Err(err).
 				Str("endpoint", endpoint.Name).
-				Str("url", endpoint.URL).
+				Str("url", u).
 				Msg("Failed to scrape endpoint")
+			up = 0
 			continue
 		}
-		metrics = append(metrics, endpointMetrics...)
+		if endpoint.CounterContinuity {
+			c.correctCounterResets(target, urlMetrics, time.Now())
+		}
+		metrics = append(metrics, urlMetrics...)
 	}
+	return metrics, parsed, up
+}
 
-	return metrics, nil
+// transientError marks scrape failures worth retrying
+type transientError struct{ err error }
+
+func (e transientError) Error() string { return e.err.Error() }
+func (e transientError) Unwrap() error { return e.err }
+
+// scrapeWithRetries scrapes endpoint, retrying transient failures as
+// configured. A retry whose backoff would run past ctx's deadline is not
+// attempted, so the last error is returned in time.
+func (c *HTTPCollector) scrapeWithRetries(ctx context.Context, endpoint EndpointConfig) (metrics []Metric, parsed parseStats, err error) {
+	backoff := endpoint.RetryBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+	for attempt := 1; ; attempt++ {
+		metrics, parsed, err = c.scrapeEndpoint(ctx, endpoint)
+		if err == nil || attempt > endpoint.Retries || !errors.As(err, new(transientError)) || ctx.Err() != nil {
+			return metrics, parsed, err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < backoff {
+			return metrics, parsed, err
+		}
+
+		c.Logger().Debug().
+			Err(err).
+			Str("endpoint", endpoint.Name).
+			Int("attempt", attempt).
+			Dur("backoff", backoff).
+			Msg("Scrape failed, retrying")
+		select {
+		case <-ctx.Done():
+			return metrics, parsed, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
 }
 
-func (c *HTTPCollector) scrapeEndpoint(ctx context.Context, endpoint EndpointConfig) ([]Metric, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.URL, nil)
+// scrapeEndpoint fetches and parses one endpoint. parsed is filled in as far as
+// parsing got, including when the body could not be parsed at all.
+func (c *HTTPCollector) scrapeEndpoint(ctx context.Context, endpoint EndpointConfig) (metrics []Metric, parsed parseStats, err error) {
+	method := endpoint.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var reqBody io.Reader
+	if endpoint.Body != "" {
+		reqBody = strings.NewReader(endpoint.Body)
+	}
+
+	ctx = context.WithValue(ctx, endpointContextKey{}, endpoint)
+	req, err := http.NewRequestWithContext(ctx, method, endpoint.URL, reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, parsed, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	for k, v := range endpoint.Headers {
+		req.Header.Set(k, v)
 	}
 
 	resp, err := c.client.Do(req)
+	if errors.Is(err, errTooManyRedirects) {
+		// Retrying would only follow the same chain again
+		c.Logger().Warn().
+			Str("endpoint", endpoint.Name).
+			Err(err).
+			Msg("Endpoint exceeded max_redirects")
+		return nil, parsed, fmt.Errorf("failed to execute request: %w", err)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return nil, parsed, transientError{fmt.Errorf("failed to execute request: %w", err)}
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		location := resp.Header.Get("Location")
+		msg := "Endpoint redirected but follow_redirects is disabled"
+		if endpoint.FollowRedirects == nil || *endpoint.FollowRedirects {
+			// net/http hands back the redirects it cannot follow, e.g. without a Location
+			msg = "Endpoint returned a redirect that cannot be followed"
+		}
+		c.Logger().Warn().
+			Str("endpoint", endpoint.Name).
+			Int("status", resp.StatusCode).
+			Str("location", location).
+			Msg(msg)
+		return nil, parsed, fmt.Errorf("redirect not followed: status %d to %q", resp.StatusCode, location)
+	}
+
+	if !AcceptsStatus(endpoint.AcceptStatus, resp.StatusCode) {
+		err := fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			err = transientError{err}
+		}
+		return nil, parsed, err
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, parsed, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return []Metric{}, parsed, nil
 	}
 
 	// Auto-detect format and parse accordingly
-	if isPrometheusFormat(body) {
-		return c.parsePrometheusText(endpoint.Name, body), nil
+	switch {
+	case endpoint.parseRegex != nil:
+		metrics, parsed.errors = parseRegexMetrics(endpoint.Name, endpoint.parseRegex, body)
+	case isOpenMetrics(resp.Header.Get("Content-Type")):
+		metrics, parsed.errors = c.parseOpenMetricsText(endpoint.Name, body, endpoint.Exemplars)
+	case isPrometheusFormat(body):
+		metrics, parsed.errors = c.parsePrometheusText(endpoint.Name, body)
+	default:
+		// Try to parse as JSON metrics
+		var rawMetrics map[string]interface{}
+		if err := json.Unmarshal(body, &rawMetrics); err != nil {
+			parsed.errors = 1
+			return nil, parsed, fmt.Errorf("failed to parse response (not valid JSON or Prometheus format): %w", err)
+		}
+		metrics = c.parseMetrics(endpoint.Name, rawMetrics)
 	}
 
-	// Try to parse as JSON metrics
-	var rawMetrics map[string]interface{}
-	if err := json.Unmarshal(body, &rawMetrics); err != nil {
-		return nil, fmt.Errorf("failed to parse response (not valid JSON or Prometheus format): %w", err)
+	parsed.samples = len(metrics)
+
+	metrics = endpoint.filterMetrics(metrics)
+	c.rewriteNames(endpoint, metrics)
+	return endpoint.addLabels(metrics), parsed, nil
+}
+
+// AcceptsStatus reports whether code is in accept, or is 200 when accept is empty
+func AcceptsStatus(accept []int, code int) bool {
+	if len(accept) == 0 {
+		return code == http.StatusOK
 	}
+	return slices.Contains(accept, code)
+}
+
+// scrapeMetrics returns the synthetic up and duration gauges for one scrape
+func (e EndpointConfig) scrapeMetrics(up float64, duration time.Duration) []Metric {
+	return e.addLabels([]Metric{
+		{
+			Name:   "endpoint_up",
+			Labels: map[string]string{"endpoint": e.Name},
+			Value:  up,
+			Type:   "gauge",
+			Help:   "1 if the last scrape of the endpoint succeeded, 0 otherwise",
+		},
+		{
+			Name:   "endpoint_scrape_duration_seconds",
+			Labels: map[string]string{"endpoint": e.Name},
+			Value:  duration.Seconds(),
+			Type:   "gauge",
+			Help:   "Duration of the last scrape of the endpoint",
+		},
+	})
+}
 
-	return c.parseMetrics(endpoint.Name, rawMetrics), nil
+// parseStats counts what one scrape's parser made of the response body
+type parseStats struct {
+	samples int // Samples parsed, before metric filters
+	errors  int // Malformed lines, or 1 for a body that failed to parse entirely
+}
+
+// addParseErrors adds n to the endpoint's cumulative parse error count and
+// returns the new total
+func (c *HTTPCollector) addParseErrors(endpoint EndpointConfig, n int) uint64 {
+	c.parseMu.Lock()
+	defer c.parseMu.Unlock()
+	if c.parseErrors == nil {
+		c.parseErrors = make(map[string]uint64)
+	}
+	key := endpoint.Name + "\x00" + endpoint.URL
+	c.parseErrors[key] += uint64(n)
+	return c.parseErrors[key]
+}
+
+// parseMetrics returns the samples parsed gauge and parse errors counter for one scrape
+func (e EndpointConfig) parseMetrics(parsed parseStats, totalErrors uint64) []Metric {
+	return e.addLabels([]Metric{
+		{
+			Name:   "endpoint_samples_parsed",
+			Labels: map[string]string{"endpoint": e.Name},
+			Value:  float64(parsed.samples),
+			Type:   "gauge",
+			Help:   "Samples parsed from the last scrape of the endpoint",
+		},
+		{
+			Name:   "endpoint_parse_errors_total",
+			Labels: map[string]string{"endpoint": e.Name},
+			Value:  float64(totalErrors),
+			Type:   "counter",
+			Help:   "Malformed lines or unparseable responses seen from the endpoint",
+		},
+	})
+}
+
+// addLabels merges the endpoint's static labels into each metric. Labels
+// already present in the scrape are kept.
+func (e EndpointConfig) addLabels(metrics []Metric) []Metric {
+	if len(e.Labels) == 0 {
+		return metrics
+	}
+	for i := range metrics {
+		if metrics[i].Labels == nil {
+			metrics[i].Labels = make(map[string]string, len(e.Labels))
+		}
+		for k, v := range e.Labels {
+			if _, ok := metrics[i].Labels[k]; !ok {
+				metrics[i].Labels[k] = v
+			}
+		}
+	}
+	return metrics
 }
 
 // isPrometheusFormat checks if the body is in Prometheus text format
@@ -137,26 +643,38 @@ func isPrometheusFormat(body []byte) bool {
 	return false
 }
 
-// parsePrometheusText parses Prometheus text exposition format
-func (c *HTTPCollector) parsePrometheusText(endpointName string, body []byte) []Metric {
-	metrics := make([]Metric, 0)
+// parsePrometheusText parses Prometheus text exposition format. Lines that
+// don't parse are skipped and counted in malformed.
+func (c *HTTPCollector) parsePrometheusText(endpointName string, body []byte) (metrics []Metric, malformed int) {
+	metrics = make([]Metric, 0)
+	help := make(map[string]string)
 	scanner := bufio.NewScanner(bytes.NewReader(body))
 
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 
-		// Skip empty lines and comments (including HELP and TYPE)
+		// Record HELP text; skip empty lines and other comments (including TYPE)
+		if strings.HasPrefix(line, "# HELP ") {
+			if parts := strings.SplitN(strings.TrimPrefix(line, "# HELP "), " ", 2); len(parts) == 2 {
+				help[parts[0]] = parts[1]
+			}
+			continue
+		}
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
 
 		metric := c.parsePrometheusLine(endpointName, line)
-		if metric != nil {
-			metrics = append(metrics, *metric)
+		if metric == nil {
+			malformed++
+			continue
 		}
+		metrics = append(metrics, *metric)
 	}
 
-	return metrics
+	applyHelp(metrics, help)
+
+	return metrics, malformed
 }
 
 // parsePrometheusLine parses a single Prometheus metric line
@@ -166,11 +684,12 @@ func (c *HTTPCollector) parsePrometheusLine(endpointName, line string) *Metric {
 	var metricName string
 	var labelsStr string
 	var valueStr string
+	var timestampStr string
 
 	// Check if there are labels
 	if idx := strings.Index(line, "{"); idx != -1 {
 		metricName = line[:idx]
-		endIdx := strings.Index(line, "}")
+		endIdx := labelSetEnd(line, idx)
 		if endIdx == -1 {
 			return nil
 		}
@@ -181,6 +700,9 @@ func (c *HTTPCollector) parsePrometheusLine(endpointName, line string) *Metric {
 			return nil
 		}
 		valueStr = parts[0]
+		if len(parts) > 1 {
+			timestampStr = parts[1]
+		}
 	} else {
 		// No labels
 		parts := strings.Fields(line)
@@ -189,6 +711,9 @@ func (c *HTTPCollector) parsePrometheusLine(endpointName, line string) *Metric {
 		}
 		metricName = parts[0]
 		valueStr = parts[1]
+		if len(parts) > 2 {
+			timestampStr = parts[2]
+		}
 	}
 
 	// Parse value
@@ -202,24 +727,56 @@ func (c *HTTPCollector) parsePrometheusLine(endpointName, line string) *Metric {
 		"endpoint": endpointName,
 	}
 
-	if labelsStr != "" {
-		labelPairs := splitLabels(labelsStr)
-		for _, pair := range labelPairs {
-			kv := strings.SplitN(pair, "=", 2)
-			if len(kv) == 2 {
-				key := strings.TrimSpace(kv[0])
-				val := strings.Trim(strings.TrimSpace(kv[1]), "\"")
-				labels[key] = val
-			}
-		}
-	}
+	parseLabelPairs(labelsStr, labels)
 
-	return &Metric{
+	metric := &Metric{
 		Name:   metricName,
 		Labels: labels,
 		Value:  value,
 		Type:   "gauge",
 	}
+
+	// Optional timestamp in milliseconds since epoch
+	if timestampStr != "" {
+		if ms, err := strconv.ParseInt(timestampStr, 10, 64); err == nil {
+			metric.Timestamp = time.UnixMilli(ms)
+		}
+	}
+
+	return metric
+}
+
+// parseLabelPairs adds the pairs of a `key="value",...` label set to labels
+func parseLabelPairs(labelsStr string, labels map[string]string) {
+	for _, pair := range splitLabels(labelsStr) {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) == 2 {
+			key := strings.TrimSpace(kv[0])
+			val := strings.Trim(strings.TrimSpace(kv[1]), "\"")
+			labels[key] = val
+		}
+	}
+}
+
+// labelSetEnd returns the position of the `}` closing the label set opened at
+// open, skipping braces inside quoted label values, or -1
+func labelSetEnd(s string, open int) int {
+	inQuotes := false
+	for i := open + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if inQuotes {
+				i++
+			}
+		case '"':
+			inQuotes = !inQuotes
+		case '}':
+			if !inQuotes {
+				return i
+			}
+		}
+	}
+	return -1
 }
 
 // splitLabels splits label pairs handling quoted values with commas
@@ -231,6 +788,12 @@ func splitLabels(labelsStr string) []string {
 	for i := 0; i < len(labelsStr); i++ {
 		ch := labelsStr[i]
 		switch ch {
+		case '\\':
+			current.WriteByte(ch)
+			if inQuotes && i+1 < len(labelsStr) {
+				i++
+				current.WriteByte(labelsStr[i])
+			}
 		case '"':
 			inQuotes = !inQuotes
 			current.WriteByte(ch)
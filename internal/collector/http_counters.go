@@ -0,0 +1,93 @@
+package collector
+
+import (
+	"strings"
+	"time"
+)
+
+// counterContinuityStaleAfter is how long counter continuity remembers a
+// series, or a discovered URL, that is no longer scraped
+const counterContinuityStaleAfter = time.Hour
+
+// counterState is what counter continuity remembers about one series
+type counterState struct {
+	last   float64   // Raw value of the last scrape that had the series
+	offset float64   // Sum of the values lost to resets, added to every raw value
+	seen   time.Time // When the series was last scraped
+}
+
+// isCounterSeries reports whether counter continuity applies to m: counters
+// typed by OpenMetrics and, since the Prometheus text parser types every
+// sample as a gauge, samples named like counters
+func isCounterSeries(m Metric) bool {
+	if m.Histogram != nil {
+		return false
+	}
+	return m.Type == "counter" || strings.HasSuffix(m.Name, "_total")
+}
+
+// correctCounterResets makes the counters of one successful scrape of
+// endpoint's URL continuous across target restarts. A counter lower than on
+// the last scrape that had it is taken as a reset: the value it had is added
+// to an offset carried by every later sample, as Prometheus does when
+// computing rate(). Series missing from a scrape keep their state, since
+// labelled counters often only reappear after a restart once they increment.
+// A restart that outgrows the old value goes unnoticed.
+func (c *HTTPCollector) correctCounterResets(endpoint EndpointConfig, metrics []Metric, now time.Time) {
+	c.countersMu.Lock()
+	defer c.countersMu.Unlock()
+	if c.counters == nil {
+		c.counters = make(map[string]map[string]*counterState)
+	}
+
+	key := endpoint.Name + "\x00" + endpoint.URL
+	states := c.counters[key]
+	if states == nil {
+		states = make(map[string]*counterState)
+		c.counters[key] = states
+	}
+	resets := 0
+	for i := range metrics {
+		m := &metrics[i]
+		if !isCounterSeries(*m) {
+			continue
+		}
+		seriesKey := SeriesKey(*m)
+		state, ok := states[seriesKey]
+		if !ok {
+			state = &counterState{}
+			states[seriesKey] = state
+		} else if m.Value < state.last {
+			state.offset += state.last
+			resets++
+		}
+		state.last = m.Value
+		state.seen = now
+		m.Value += state.offset
+	}
+
+	if resets > 0 {
+		c.Logger().Debug().
+			Str("endpoint", endpoint.Name).
+			Str("url", endpoint.URL).
+			Int("series", resets).
+			Msg("Counter reset detected, continuing from the previous values")
+	}
+}
+
+// evictCounterState forgets the series, and URLs, not scraped for
+// counterContinuityStaleAfter
+func (c *HTTPCollector) evictCounterState(now time.Time) {
+	c.countersMu.Lock()
+	defer c.countersMu.Unlock()
+	for key, states := range c.counters {
+		for seriesKey, state := range states {
+			if now.Sub(state.seen) > counterContinuityStaleAfter {
+				delete(states, seriesKey)
+			}
+		}
+		if len(states) == 0 {
+			delete(c.counters, key)
+		}
+	}
+}
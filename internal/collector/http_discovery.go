@@ -0,0 +1,149 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Endpoint discovery modes and DNS record types
+const (
+	DiscoveryDNS = "dns"
+	DNSTypeA     = "a"   // A/AAAA records; the port comes from the endpoint URL
+	DNSTypeSRV   = "srv" // SRV records supply both host and port
+)
+
+// DefaultDiscoveryRefresh is how often discovered targets are re-resolved when unset
+const DefaultDiscoveryRefresh = 30 * time.Second
+
+// dnsResolver is the subset of *net.Resolver used for discovery; replaced in tests
+type dnsResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// dnsTargets resolves one discovery endpoint into scrape targets, caching the
+// result for the refresh interval. Failed lookups keep the last known set.
+type dnsTargets struct {
+	endpoint EndpointConfig
+	resolver dnsResolver
+
+	mu          sync.Mutex
+	targets     []EndpointConfig
+	lastRefresh time.Time
+}
+
+// resolve returns the current targets, re-resolving once the refresh interval
+// has passed
+func (d *dnsTargets) resolve(ctx context.Context, c *HTTPCollector) []EndpointConfig {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	refresh := d.endpoint.RefreshInterval
+	if refresh <= 0 {
+		refresh = DefaultDiscoveryRefresh
+	}
+	if !d.lastRefresh.IsZero() && time.Since(d.lastRefresh) < refresh {
+		return d.targets
+	}
+	d.lastRefresh = time.Now()
+
+	targets, err := d.lookup(ctx)
+	if err != nil {
+		c.Logger().Warn().
+			Err(err).
+			Str("endpoint", d.endpoint.Name).
+			Int("known_targets", len(d.targets)).
+			Msg("DNS discovery failed, keeping the last known targets")
+		return d.targets
+	}
+	if len(targets) != len(d.targets) {
+		c.Logger().Info().
+			Str("endpoint", d.endpoint.Name).
+			Int("targets", len(targets)).
+			Msg("DNS discovery updated scrape targets")
+	}
+	d.targets = targets
+	return targets
+}
+
+// lookup queries DNS and builds one endpoint per resolved host:port, sorted
+// so the scrape order is stable
+func (d *dnsTargets) lookup(ctx context.Context) ([]EndpointConfig, error) {
+	u, err := url.Parse(d.endpoint.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint URL: %w", err)
+	}
+	name := d.endpoint.DNSName
+	if name == "" {
+		name = u.Hostname()
+	}
+
+	var hosts []string
+	switch strings.ToLower(d.endpoint.DNSType) {
+	case DNSTypeSRV:
+		_, records, err := d.resolver.LookupSRV(ctx, "", "", name)
+		if err != nil {
+			return nil, fmt.Errorf("SRV lookup of %s: %w", name, err)
+		}
+		for _, srv := range records {
+			hosts = append(hosts, net.JoinHostPort(strings.TrimSuffix(srv.Target, "."), strconv.Itoa(int(srv.Port))))
+		}
+	default:
+		addrs, err := d.resolver.LookupHost(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("host lookup of %s: %w", name, err)
+		}
+		for _, addr := range addrs {
+			if port := u.Port(); port != "" {
+				hosts = append(hosts, net.JoinHostPort(addr, port))
+			} else if strings.Contains(addr, ":") {
+				hosts = append(hosts, "["+addr+"]")
+			} else {
+				hosts = append(hosts, addr)
+			}
+		}
+	}
+	sort.Strings(hosts)
+
+	targets := make([]EndpointConfig, 0, len(hosts))
+	for _, host := range hosts {
+		target := d.endpoint
+		targetURL := *u
+		targetURL.Host = host
+		target.URL = targetURL.String()
+
+		// Every target shares the endpoint name, so the instance label keeps their series apart
+		target.Labels = make(map[string]string, len(d.endpoint.Labels)+1)
+		for k, v := range d.endpoint.Labels {
+			target.Labels[k] = v
+		}
+		if _, ok := target.Labels["instance"]; !ok {
+			target.Labels["instance"] = host
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// scrapeTargets expands discovery endpoints into their current targets
+func (c *HTTPCollector) scrapeTargets(ctx context.Context) []EndpointConfig {
+	if len(c.discovery) == 0 {
+		return c.endpoints
+	}
+	targets := make([]EndpointConfig, 0, len(c.endpoints))
+	for i, ep := range c.endpoints {
+		if d, ok := c.discovery[i]; ok {
+			targets = append(targets, d.resolve(ctx, c)...)
+			continue
+		}
+		targets = append(targets, ep)
+	}
+	return targets
+}
@@ -0,0 +1,114 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeResolver answers lookups from fixed records and counts calls
+type fakeResolver struct {
+	hosts []string
+	srv   []*net.SRV
+	err   error
+	calls int
+}
+
+func (f *fakeResolver) LookupHost(context.Context, string) ([]string, error) {
+	f.calls++
+	return f.hosts, f.err
+}
+
+func (f *fakeResolver) LookupSRV(context.Context, string, string, string) (string, []*net.SRV, error) {
+	f.calls++
+	return "", f.srv, f.err
+}
+
+// upInstances returns the instance label of every successful endpoint_up
+func upInstances(metrics []Metric) []string {
+	var instances []string
+	for _, m := range metrics {
+		if m.Name == "endpoint_up" && m.Value == 1 {
+			instances = append(instances, m.Labels["instance"])
+		}
+	}
+	return instances
+}
+
+func TestHTTPCollector_DNSDiscovery(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("jobs_running 3\n"))
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	t.Run("a records keep the URL port", func(t *testing.T) {
+		col := newTestHTTPCollector([]EndpointConfig{{
+			Name: "pool", URL: "http://pool.internal:" + u.Port() + "/metrics", Discovery: DiscoveryDNS,
+		}})
+		col.discovery[0].resolver = &fakeResolver{hosts: []string{"127.0.0.1"}}
+
+		metrics, err := col.Collect(context.Background())
+		if err != nil {
+			t.Fatalf("Collect: %v", err)
+		}
+		if got := upInstances(metrics); len(got) != 1 || got[0] != u.Host {
+			t.Errorf("expected one healthy target %s, got %v", u.Host, got)
+		}
+		if m := findMetric(metrics, "jobs_running"); m == nil || m.Labels["instance"] != u.Host {
+			t.Errorf("expected scraped metric with instance label, got %+v", m)
+		}
+	})
+
+	t.Run("srv records supply the port", func(t *testing.T) {
+		col := newTestHTTPCollector([]EndpointConfig{{
+			Name: "pool", URL: "http://pool.internal/metrics", Discovery: DiscoveryDNS,
+			DNSName: "_metrics._tcp.pool.internal", DNSType: DNSTypeSRV,
+		}})
+		col.discovery[0].resolver = &fakeResolver{srv: []*net.SRV{{Target: "127.0.0.1.", Port: uint16(port)}}}
+
+		metrics, _ := col.Collect(context.Background())
+		if got := upInstances(metrics); len(got) != 1 || got[0] != u.Host {
+			t.Errorf("expected one healthy target %s, got %v", u.Host, got)
+		}
+	})
+
+	t.Run("failed lookups keep the last known targets", func(t *testing.T) {
+		col := newTestHTTPCollector([]EndpointConfig{{
+			Name: "pool", URL: "http://pool.internal:" + u.Port() + "/metrics", Discovery: DiscoveryDNS,
+			RefreshInterval: time.Nanosecond,
+		}})
+		resolver := &fakeResolver{hosts: []string{"127.0.0.1"}}
+		col.discovery[0].resolver = resolver
+		_, _ = col.Collect(context.Background())
+
+		resolver.err = errors.New("SERVFAIL")
+		metrics, _ := col.Collect(context.Background())
+		if resolver.calls != 2 {
+			t.Errorf("expected a re-resolve per cycle with a tiny refresh interval, got %d lookups", resolver.calls)
+		}
+		if got := upInstances(metrics); len(got) != 1 {
+			t.Errorf("expected the last known target to still be scraped, got %v", got)
+		}
+	})
+
+	t.Run("targets are cached for the refresh interval", func(t *testing.T) {
+		col := newTestHTTPCollector([]EndpointConfig{{
+			Name: "pool", URL: "http://pool.internal:" + u.Port() + "/metrics", Discovery: DiscoveryDNS,
+		}})
+		resolver := &fakeResolver{hosts: []string{"127.0.0.1"}}
+		col.discovery[0].resolver = resolver
+		_, _ = col.Collect(context.Background())
+		_, _ = col.Collect(context.Background())
+		if resolver.calls != 1 {
+			t.Errorf("expected one lookup within the refresh interval, got %d", resolver.calls)
+		}
+	})
+}
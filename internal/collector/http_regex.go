@@ -0,0 +1,114 @@
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Capture groups of EndpointConfig.ParseRegex with a special meaning
+const (
+	parseRegexValueGroup = "value"
+	parseRegexNameGroup  = "name"
+)
+
+// ValidateParseRegex reports why pattern is not a usable endpoint parse regex,
+// for config validation
+func ValidateParseRegex(pattern string) error {
+	_, err := checkParseRegex(pattern)
+	return err
+}
+
+// checkParseRegex compiles a parse regex, requiring a value group and named
+// groups usable as label names
+func checkParseRegex(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if re.SubexpIndex(parseRegexValueGroup) < 0 {
+		return nil, fmt.Errorf("parse regex %q has no (?P<%s>...) group", pattern, parseRegexValueGroup)
+	}
+	for _, group := range re.SubexpNames() {
+		switch {
+		case group == "", group == parseRegexValueGroup, group == parseRegexNameGroup:
+		case group == "endpoint":
+			return nil, fmt.Errorf("parse regex group %q clashes with the endpoint label", group)
+		case strings.HasPrefix(group, "__"), !LabelNameRegex.MatchString(group):
+			return nil, fmt.Errorf("parse regex group %q is not a usable label name", group)
+		}
+	}
+	return re, nil
+}
+
+// compileParseRegex compiles an endpoint's parse regex; nil when unset or
+// invalid, in which case it is added to invalid
+func compileParseRegex(endpointName, pattern string, invalid *[]invalidPattern) *regexp.Regexp {
+	if pattern == "" {
+		return nil
+	}
+	re, err := checkParseRegex(pattern)
+	if err != nil {
+		*invalid = append(*invalid, invalidPattern{endpointName, pattern, "Ignoring invalid parse regex", err})
+		return nil
+	}
+	return re
+}
+
+// parseRegexMetrics turns every match of re in body into a gauge. The value
+// group holds the sample value; the optional name group names the metric
+// app_<name>, otherwise it is app_<endpoint>. Every other named group becomes
+// a label, skipped when it matched nothing. Matches whose value is not a
+// number count as parse errors.
+func parseRegexMetrics(endpointName string, re *regexp.Regexp, body []byte) ([]Metric, int) {
+	valueIdx := re.SubexpIndex(parseRegexValueGroup)
+	nameIdx := re.SubexpIndex(parseRegexNameGroup)
+	groups := re.SubexpNames()
+
+	metrics := make([]Metric, 0)
+	errors := 0
+	for _, match := range re.FindAllSubmatch(body, -1) {
+		value, err := strconv.ParseFloat(strings.TrimSpace(string(match[valueIdx])), 64)
+		if err != nil {
+			errors++
+			continue
+		}
+
+		name := endpointName
+		if nameIdx >= 0 {
+			name = string(match[nameIdx])
+		}
+		if name == "" {
+			errors++
+			continue
+		}
+
+		labels := map[string]string{"endpoint": endpointName}
+		for i, group := range groups {
+			if i == valueIdx || i == nameIdx || group == "" || len(match[i]) == 0 {
+				continue
+			}
+			labels[group] = string(match[i])
+		}
+
+		metrics = append(metrics, Metric{
+			Name:   "app_" + sanitizeMetricName(name),
+			Labels: labels,
+			Value:  value,
+			Type:   "gauge",
+		})
+	}
+	return metrics, errors
+}
+
+// sanitizeMetricName replaces the characters not allowed in metric names with
+// underscores
+func sanitizeMetricName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == ':' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, s)
+}
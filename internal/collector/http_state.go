@@ -0,0 +1,69 @@
+package collector
+
+import (
+	"encoding/json"
+	"maps"
+	"time"
+)
+
+// httpState is the HTTP collector's persisted state: the parse error totals
+// and the counter continuity offsets, both keyed by endpoint name and URL
+type httpState struct {
+	ParseErrors map[string]uint64                       `json:"parse_errors,omitempty"`
+	Counters    map[string]map[string]savedCounterState `json:"counters,omitempty"`
+}
+
+type savedCounterState struct {
+	Last   float64   `json:"last"`
+	Offset float64   `json:"offset"`
+	Seen   time.Time `json:"seen"`
+}
+
+// SaveState returns endpoint_parse_errors_total and the counter continuity
+// state, so neither resets when metricsd restarts
+func (c *HTTPCollector) SaveState() (json.RawMessage, error) {
+	var state httpState
+
+	c.parseMu.Lock()
+	state.ParseErrors = maps.Clone(c.parseErrors)
+	c.parseMu.Unlock()
+
+	c.countersMu.Lock()
+	if len(c.counters) > 0 {
+		state.Counters = make(map[string]map[string]savedCounterState, len(c.counters))
+		for key, states := range c.counters {
+			saved := make(map[string]savedCounterState, len(states))
+			for seriesKey, s := range states {
+				saved[seriesKey] = savedCounterState{Last: s.last, Offset: s.offset, Seen: s.seen}
+			}
+			state.Counters[key] = saved
+		}
+	}
+	c.countersMu.Unlock()
+
+	return json.Marshal(state)
+}
+
+// RestoreState loads state saved by SaveState
+func (c *HTTPCollector) RestoreState(data json.RawMessage) error {
+	var state httpState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	c.parseMu.Lock()
+	c.parseErrors = state.ParseErrors
+	c.parseMu.Unlock()
+
+	c.countersMu.Lock()
+	c.counters = make(map[string]map[string]*counterState, len(state.Counters))
+	for key, saved := range state.Counters {
+		states := make(map[string]*counterState, len(saved))
+		for seriesKey, s := range saved {
+			states[seriesKey] = &counterState{last: s.Last, offset: s.Offset, seen: s.Seen}
+		}
+		c.counters[key] = states
+	}
+	c.countersMu.Unlock()
+	return nil
+}
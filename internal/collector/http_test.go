@@ -1,12 +1,22 @@
 package collector
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/rs/zerolog"
 )
 
 // ---------------------------------------------------------------------------
@@ -27,6 +37,22 @@ func findMetric(metrics []Metric, name string) *Metric {
 	return nil
 }
 
+// scrapeMetaNames are the per-endpoint metrics Collect adds to every scrape
+var scrapeMetaNames = []string{"endpoint_up", "endpoint_scrape_duration_seconds", "endpoint_samples_parsed", "endpoint_parse_errors_total"}
+
+// collectScraped runs Collect and drops the per-endpoint scrape meta-metrics,
+// leaving only what the endpoints served.
+func collectScraped(col *HTTPCollector) ([]Metric, error) {
+	metrics, err := col.Collect(context.Background())
+	kept := make([]Metric, 0, len(metrics))
+	for _, m := range metrics {
+		if !slices.Contains(scrapeMetaNames, m.Name) {
+			kept = append(kept, m)
+		}
+	}
+	return kept, err
+}
+
 // metricNames returns a sorted slice of metric names for deterministic assertions.
 func metricNames(metrics []Metric) []string {
 	names := make([]string, len(metrics))
@@ -56,7 +82,7 @@ process_cpu_seconds_total 1.5
 	defer srv.Close()
 
 	col := newTestHTTPCollector([]EndpointConfig{{Name: "test_prom", URL: srv.URL}})
-	metrics, err := col.Collect(context.Background())
+	metrics, err := collectScraped(col)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -98,7 +124,7 @@ func TestHTTPCollector_JSONFormat(t *testing.T) {
 	defer srv.Close()
 
 	col := newTestHTTPCollector([]EndpointConfig{{Name: "myapp", URL: srv.URL}})
-	metrics, err := col.Collect(context.Background())
+	metrics, err := collectScraped(col)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -196,7 +222,7 @@ func TestHTTPCollector_PrometheusWithLabels(t *testing.T) {
 	defer srv.Close()
 
 	col := newTestHTTPCollector([]EndpointConfig{{Name: "api", URL: srv.URL}})
-	metrics, err := col.Collect(context.Background())
+	metrics, err := collectScraped(col)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -235,7 +261,7 @@ func TestHTTPCollector_EmptyResponse(t *testing.T) {
 	defer srv.Close()
 
 	col := newTestHTTPCollector([]EndpointConfig{{Name: "empty", URL: srv.URL}})
-	metrics, err := col.Collect(context.Background())
+	metrics, err := collectScraped(col)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -256,7 +282,7 @@ func TestHTTPCollector_ServerError(t *testing.T) {
 
 	// scrapeEndpoint should return an error; Collect swallows it and returns empty slice.
 	col := newTestHTTPCollector([]EndpointConfig{{Name: "failing", URL: srv.URL}})
-	metrics, err := col.Collect(context.Background())
+	metrics, err := collectScraped(col)
 
 	// Collect never propagates endpoint errors — it logs and continues.
 	if err != nil {
@@ -268,7 +294,7 @@ func TestHTTPCollector_ServerError(t *testing.T) {
 
 	// Verify scrapeEndpoint itself returns the error.
 	scrapeErr := func() error {
-		_, e := col.scrapeEndpoint(context.Background(), EndpointConfig{Name: "failing", URL: srv.URL})
+		_, _, e := col.scrapeEndpoint(context.Background(), EndpointConfig{Name: "failing", URL: srv.URL})
 		return e
 	}()
 	if scrapeErr == nil {
@@ -290,13 +316,13 @@ func TestHTTPCollector_InvalidResponse(t *testing.T) {
 	col := newTestHTTPCollector([]EndpointConfig{{Name: "html_srv", URL: srv.URL}})
 
 	// Collect swallows the error; verify via scrapeEndpoint directly.
-	_, err := col.scrapeEndpoint(context.Background(), EndpointConfig{Name: "html_srv", URL: srv.URL})
+	_, _, err := col.scrapeEndpoint(context.Background(), EndpointConfig{Name: "html_srv", URL: srv.URL})
 	if err == nil {
 		t.Error("expected an error for HTML response, got nil")
 	}
 
 	// Collect should return empty and no error.
-	metrics, collectErr := col.Collect(context.Background())
+	metrics, collectErr := collectScraped(col)
 	if collectErr != nil {
 		t.Fatalf("Collect should not propagate the error, got: %v", collectErr)
 	}
@@ -325,7 +351,7 @@ func TestHTTPCollector_MultipleEndpoints(t *testing.T) {
 		{Name: "node2", URL: srv2.URL},
 	})
 
-	metrics, err := col.Collect(context.Background())
+	metrics, err := collectScraped(col)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -356,6 +382,98 @@ func TestHTTPCollector_MultipleEndpoints(t *testing.T) {
 	}
 }
 
+func TestHTTPCollector_Concurrency(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		mu.Unlock()
+
+		time.Sleep(100 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.Write([]byte("up 1\n"))
+	}))
+	defer srv.Close()
+
+	endpoints := make([]EndpointConfig, 6)
+	for i := range endpoints {
+		endpoints[i] = EndpointConfig{Name: fmt.Sprintf("ep%d", i), URL: srv.URL}
+	}
+	col := newTestHTTPCollector(endpoints)
+	col.SetMaxConcurrency(3)
+
+	start := time.Now()
+	metrics, err := collectScraped(col)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	elapsed := time.Since(start)
+
+	if len(metrics) != 6 {
+		t.Fatalf("expected 6 metrics, got %d", len(metrics))
+	}
+	for i, m := range metrics {
+		if want := fmt.Sprintf("ep%d", i); m.Labels["endpoint"] != want {
+			t.Errorf("metric %d: expected endpoint %s (endpoint order), got %s", i, want, m.Labels["endpoint"])
+		}
+	}
+	if peak > 3 {
+		t.Errorf("expected at most 3 concurrent scrapes, saw %d", peak)
+	}
+	if elapsed >= 500*time.Millisecond {
+		t.Errorf("scrapes do not appear to run concurrently: took %v", elapsed)
+	}
+}
+
+func TestHTTPCollector_SkippedOnDeadlineReportsDown(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	col := newTestHTTPCollector([]EndpointConfig{
+		{Name: "slow", URL: srv.URL},
+		{Name: "waiting", URL: srv.URL},
+	})
+	col.SetMaxConcurrency(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	metrics, err := col.Collect(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	up := make(map[string]float64)
+	durations := make(map[string]bool)
+	for _, m := range metrics {
+		switch m.Name {
+		case "endpoint_up":
+			up[m.Labels["endpoint"]] = m.Value
+		case "endpoint_scrape_duration_seconds":
+			durations[m.Labels["endpoint"]] = true
+		}
+	}
+	for _, name := range []string{"slow", "waiting"} {
+		if v, ok := up[name]; !ok || v != 0 {
+			t.Errorf("endpoint %s: expected endpoint_up 0, got %v (present %v)", name, v, ok)
+		}
+		if !durations[name] {
+			t.Errorf("endpoint %s: expected endpoint_scrape_duration_seconds", name)
+		}
+	}
+}
+
 // ---------------------------------------------------------------------------
 // 9. HTTPCollector.Name
 // ---------------------------------------------------------------------------
@@ -521,4 +639,764 @@ func TestParsePrometheusLine_EdgeCases(t *testing.T) {
 			t.Error("expected nil for labels with no value")
 		}
 	})
+
+	t.Run("brace and escaped quote in label value", func(t *testing.T) {
+		result := c.parsePrometheusLine("test", `metric{path="/a}b",q="x\"}",code="200"} 7`)
+		if result == nil {
+			t.Fatal("expected a metric")
+		}
+		if result.Value != 7 || result.Labels["path"] != "/a}b" || result.Labels["code"] != "200" {
+			t.Errorf("unexpected metric: %+v", result)
+		}
+	})
+}
+
+func TestHTTPCollector_RequestMethodBodyHeaders(t *testing.T) {
+	t.Run("defaults to GET without body", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				t.Errorf("expected GET, got %s", r.Method)
+			}
+			if r.ContentLength > 0 {
+				t.Errorf("expected no body, got %d bytes", r.ContentLength)
+			}
+			w.Write([]byte(`{"up": 1}`))
+		}))
+		defer srv.Close()
+
+		col := newTestHTTPCollector([]EndpointConfig{{Name: "get", URL: srv.URL}})
+		metrics, _ := collectScraped(col)
+		if len(metrics) != 1 {
+			t.Fatalf("expected 1 metric, got %d", len(metrics))
+		}
+	})
+
+	t.Run("POST with body and headers", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				t.Errorf("expected POST, got %s", r.Method)
+			}
+			if got := r.Header.Get("Content-Type"); got != "application/json" {
+				t.Errorf("expected Content-Type application/json, got %q", got)
+			}
+			if got := r.Header.Get("X-Api-Key"); got != "secret" {
+				t.Errorf("expected X-Api-Key secret, got %q", got)
+			}
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != `{"query":"up"}` {
+				t.Errorf("unexpected body: %s", body)
+			}
+			w.Write([]byte(`{"up": 1}`))
+		}))
+		defer srv.Close()
+
+		col := newTestHTTPCollector([]EndpointConfig{{
+			Name:   "post",
+			URL:    srv.URL,
+			Method: http.MethodPost,
+			Body:   `{"query":"up"}`,
+			Headers: map[string]string{
+				"Content-Type": "application/json",
+				"X-Api-Key":    "secret",
+			},
+		}})
+		metrics, _ := collectScraped(col)
+		if len(metrics) != 1 {
+			t.Fatalf("expected 1 metric, got %d", len(metrics))
+		}
+	})
+}
+
+func TestParsePrometheusText_Help(t *testing.T) {
+	c := &HTTPCollector{}
+	body := []byte(`# HELP go_goroutines Number of goroutines that currently exist.
+# TYPE go_goroutines gauge
+go_goroutines 42
+no_help_metric 1
+`)
+	metrics, _ := c.parsePrometheusText("test", body)
+
+	if m := findMetric(metrics, "go_goroutines"); m == nil || m.Help != "Number of goroutines that currently exist." {
+		t.Errorf("expected help text on go_goroutines, got %+v", m)
+	}
+	if m := findMetric(metrics, "no_help_metric"); m == nil || m.Help != "" {
+		t.Errorf("expected empty help on no_help_metric, got %+v", m)
+	}
+}
+
+func TestParsePrometheusLine_Timestamp(t *testing.T) {
+	c := &HTTPCollector{}
+
+	withLabels := c.parsePrometheusLine("test", `http_requests_total{code="200"} 1027 1395066363000`)
+	if withLabels == nil {
+		t.Fatal("expected metric")
+	}
+	if got := withLabels.Timestamp.UnixMilli(); got != 1395066363000 {
+		t.Errorf("expected timestamp 1395066363000, got %d", got)
+	}
+
+	noLabels := c.parsePrometheusLine("test", "up 1 1395066363000")
+	if noLabels == nil || noLabels.Timestamp.UnixMilli() != 1395066363000 {
+		t.Errorf("expected timestamp on unlabelled metric, got %+v", noLabels)
+	}
+
+	if m := c.parsePrometheusLine("test", "up 1"); m == nil || !m.Timestamp.IsZero() {
+		t.Errorf("expected zero timestamp when absent, got %+v", m)
+	}
+}
+
+func TestHTTPCollector_MetricFilters(t *testing.T) {
+	body := `# TYPE go_goroutines gauge
+go_goroutines 42
+go_gc_duration_seconds{quantile="0.5"} 0.001
+process_cpu_seconds_total 1.5
+http_requests_total{code="200"} 10
+http_requests_in_flight 3
+`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name  string
+		allow []string
+		deny  []string
+		want  []string
+	}{
+		{"no filters", nil, nil, []string{"go_gc_duration_seconds", "go_goroutines", "http_requests_in_flight", "http_requests_total", "process_cpu_seconds_total"}},
+		{"allowlist", []string{"http_.*"}, nil, []string{"http_requests_in_flight", "http_requests_total"}},
+		{"denylist", nil, []string{"go_.*"}, []string{"http_requests_in_flight", "http_requests_total", "process_cpu_seconds_total"}},
+		{"deny wins over allow", []string{"http_.*", "go_goroutines"}, []string{".*_in_flight", "go_.*"}, []string{"http_requests_total"}},
+		{"patterns are anchored", []string{"requests"}, nil, []string{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			col := newTestHTTPCollector([]EndpointConfig{{
+				Name:            "filtered",
+				URL:             srv.URL,
+				MetricAllowlist: tc.allow,
+				MetricDenylist:  tc.deny,
+			}})
+			metrics, err := collectScraped(col)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := metricNames(metrics)
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("got %v, want %v", got, tc.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPCollector_NameRewrite(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("nginx_http_requests_total 10\nnginx_connections_active 3\ngo_goroutines 42\n"))
+	}))
+	defer srv.Close()
+
+	col := newTestHTTPCollector([]EndpointConfig{{
+		Name:           "nginx",
+		URL:            srv.URL,
+		MetricDenylist: []string{"go_.*"},
+		NameRewrite: []NameRewriteRule{
+			{Match: "nginx_http_(.*)", Replacement: "web_$1"},
+			{Match: "nginx_(.*)", Replacement: "9bad_$1"},
+			{Match: "nginx_.*", Replacement: "never_reached"},
+		},
+	}})
+	metrics, err := collectScraped(col)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := metricNames(metrics)
+	want := []string{"nginx_connections_active", "web_requests_total"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestHTTPCollector_UserAgent(t *testing.T) {
+	got := make(chan string, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- r.Header.Get("User-Agent")
+		w.Write([]byte("up 1\n"))
+	}))
+	defer srv.Close()
+
+	col := newTestHTTPCollector([]EndpointConfig{{Name: "app", URL: srv.URL}})
+	col.SetUserAgent("metricsd/1.2.3")
+	if _, _, err := col.scrapeEndpoint(context.Background(), col.endpoints[0]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ua := <-got; ua != "metricsd/1.2.3" {
+		t.Errorf("User-Agent = %q, want metricsd/1.2.3", ua)
+	}
+
+	override := EndpointConfig{Name: "app", URL: srv.URL, Headers: map[string]string{"User-Agent": "custom"}}
+	if _, _, err := col.scrapeEndpoint(context.Background(), override); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ua := <-got; ua != "custom" {
+		t.Errorf("User-Agent = %q, want endpoint header to win", ua)
+	}
+}
+
+func TestHTTPCollector_Redirects(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("up 1\n"))
+	}))
+	defer target.Close()
+
+	// /hop/N redirects N more times before landing on the target.
+	var redirector *httptest.Server
+	redirector = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		fmt.Sscanf(r.URL.Path, "/hop/%d", &n)
+		if n <= 1 {
+			http.Redirect(w, r, target.URL, http.StatusMovedPermanently)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("%s/hop/%d", redirector.URL, n-1), http.StatusFound)
+	}))
+	defer redirector.Close()
+
+	no := false
+	tests := []struct {
+		name    string
+		hops    int
+		follow  *bool
+		max     int
+		wantErr string
+		wantLog string
+	}{
+		{"followed by default", 1, nil, 0, "", ""},
+		{"disabled", 1, &no, 0, "redirect not followed", "follow_redirects is disabled"},
+		{"within limit", 3, nil, 3, "", ""},
+		{"over limit", 3, nil, 2, "stopped after 2 redirects", "exceeded max_redirects"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			col := newTestHTTPCollector([]EndpointConfig{{
+				Name:            "redir",
+				URL:             fmt.Sprintf("%s/hop/%d", redirector.URL, tc.hops),
+				FollowRedirects: tc.follow,
+				MaxRedirects:    tc.max,
+			}})
+			var logs bytes.Buffer
+			col.SetLogger(zerolog.New(&logs))
+			metrics, _, err := col.scrapeEndpoint(context.Background(), col.endpoints[0])
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				if errors.As(err, new(transientError)) {
+					t.Errorf("redirect failure should not be retried: %v", err)
+				}
+				if !strings.Contains(logs.String(), tc.wantLog) {
+					t.Errorf("expected log containing %q, got %q", tc.wantLog, logs.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if findMetric(metrics, "up") == nil {
+				t.Error("expected metric from redirect target")
+			}
+		})
+	}
+}
+
+func TestHTTPCollector_StaticLabels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("requests_total{instance=\"scraped\"} 10\nup 1\n"))
+	}))
+	defer srv.Close()
+
+	col := newTestHTTPCollector([]EndpointConfig{{
+		Name:   "api",
+		URL:    srv.URL,
+		Labels: map[string]string{"instance": "api-1", "service_version": "1.2.3", "endpoint": "override"},
+	}})
+	metrics, err := collectScraped(col)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(metrics) != 2 {
+		t.Fatalf("expected 2 metrics, got %d", len(metrics))
+	}
+
+	for _, m := range metrics {
+		if m.Labels["service_version"] != "1.2.3" {
+			t.Errorf("%s: expected static service_version label, got %v", m.Name, m.Labels)
+		}
+		if m.Labels["endpoint"] != "api" {
+			t.Errorf("%s: static labels must not clobber endpoint, got %q", m.Name, m.Labels["endpoint"])
+		}
+		want := "api-1"
+		if m.Name == "requests_total" {
+			want = "scraped"
+		}
+		if m.Labels["instance"] != want {
+			t.Errorf("%s: expected instance %q, got %q", m.Name, want, m.Labels["instance"])
+		}
+	}
+}
+
+func TestHTTPCollector_ScrapeMetrics(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("up 1\n"))
+	}))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	col := newTestHTTPCollector([]EndpointConfig{
+		{Name: "ok", URL: ok.URL, Labels: map[string]string{"instance": "a"}},
+		{Name: "failing", URL: failing.URL},
+	})
+	metrics, err := col.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]float64{"ok": 1, "failing": 0}
+	durations := 0
+	for _, m := range metrics {
+		switch m.Name {
+		case "endpoint_up":
+			if m.Value != want[m.Labels["endpoint"]] {
+				t.Errorf("endpoint_up{endpoint=%q} = %v, want %v", m.Labels["endpoint"], m.Value, want[m.Labels["endpoint"]])
+			}
+			if m.Labels["endpoint"] == "ok" && m.Labels["instance"] != "a" {
+				t.Errorf("expected static labels on endpoint_up, got %v", m.Labels)
+			}
+			delete(want, m.Labels["endpoint"])
+		case "endpoint_scrape_duration_seconds":
+			if m.Value < 0 {
+				t.Errorf("negative scrape duration: %v", m.Value)
+			}
+			durations++
+		}
+	}
+	if len(want) != 0 {
+		t.Errorf("missing endpoint_up for %v", want)
+	}
+	if durations != 2 {
+		t.Errorf("expected 2 duration gauges, got %d", durations)
+	}
+}
+
+func TestHTTPCollector_ParseMetrics(t *testing.T) {
+	partial := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("# TYPE good gauge\ngood 1\nbroken{a=\"b\" 2\nalso_broken x\nother 3\n"))
+	}))
+	defer partial.Close()
+	garbage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>oops</html>"))
+	}))
+	defer garbage.Close()
+
+	col := newTestHTTPCollector([]EndpointConfig{
+		{Name: "partial", URL: partial.URL},
+		{Name: "garbage", URL: garbage.URL},
+	})
+	for i := 1; i <= 2; i++ {
+		metrics, err := col.Collect(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := map[string]float64{
+			"endpoint_samples_parsed/partial":     2,
+			"endpoint_parse_errors_total/partial": float64(2 * i),
+			"endpoint_samples_parsed/garbage":     0,
+			"endpoint_parse_errors_total/garbage": float64(i),
+		}
+		for _, m := range metrics {
+			key := m.Name + "/" + m.Labels["endpoint"]
+			if v, ok := want[key]; ok {
+				if m.Value != v {
+					t.Errorf("scrape %d: %s = %v, want %v", i, key, m.Value, v)
+				}
+				delete(want, key)
+			}
+		}
+		if len(want) != 0 {
+			t.Errorf("scrape %d: missing %v", i, want)
+		}
+	}
+}
+
+func TestHTTPCollector_AcceptStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/partial":
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte("jobs 3\n"))
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+	col := newTestHTTPCollector(nil)
+
+	tests := []struct {
+		name    string
+		path    string
+		accept  []int
+		want    int
+		wantErr bool
+	}{
+		{"206 rejected by default", "/partial", nil, 0, true},
+		{"206 accepted", "/partial", []int{200, 206}, 1, false},
+		{"204 empty body accepted", "/empty", []int{204}, 0, false},
+		{"204 not in set", "/empty", []int{206}, 0, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			metrics, _, err := col.scrapeEndpoint(context.Background(), EndpointConfig{Name: "api", URL: srv.URL + tc.path, AcceptStatus: tc.accept})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("scrapeEndpoint() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if len(metrics) != tc.want {
+				t.Errorf("expected %d metrics, got %d", tc.want, len(metrics))
+			}
+		})
+	}
+}
+
+func TestHTTPCollector_ParseRegex(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Looks like Prometheus text, which the regex must override
+		w.Write([]byte("queue mail 12\nqueue web-hooks 3\nqueue broken n/a\nuptime 3600\n"))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		regex      string
+		want       map[string]float64 // Metric name{queue} -> value
+		wantErrors int
+	}{
+		{
+			name:       "value and label groups",
+			regex:      `(?m)^queue (?P<queue>\S+) (?P<value>\S+)$`,
+			want:       map[string]float64{"app_status{mail}": 12, "app_status{web-hooks}": 3},
+			wantErrors: 1,
+		},
+		{
+			name:  "name group",
+			regex: `(?m)^(?P<name>uptime) (?P<value>\d+)$`,
+			want:  map[string]float64{"app_uptime{}": 3600},
+		},
+		{
+			name:  "name group sanitized",
+			regex: `(?m)^(?P<name>queue web-hooks) (?P<value>\d+)$`,
+			want:  map[string]float64{"app_queue_web_hooks{}": 3},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			col := newTestHTTPCollector([]EndpointConfig{{Name: "status", URL: srv.URL, ParseRegex: tc.regex}})
+			metrics, parsed, err := col.scrapeEndpoint(context.Background(), col.endpoints[0])
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := make(map[string]float64)
+			for _, m := range metrics {
+				if m.Labels["endpoint"] != "status" || m.Type != "gauge" {
+					t.Errorf("metric %s: labels %v, type %q", m.Name, m.Labels, m.Type)
+				}
+				got[m.Name+"{"+m.Labels["queue"]+"}"] = m.Value
+			}
+			if !maps.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+			if parsed.errors != tc.wantErrors {
+				t.Errorf("parse errors = %d, want %d", parsed.errors, tc.wantErrors)
+			}
+		})
+	}
+}
+
+func TestHTTPCollector_InvalidPatternsLogged(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("up 1\n"))
+	}))
+	defer srv.Close()
+
+	col := newTestHTTPCollector([]EndpointConfig{{Name: "status", URL: srv.URL, ParseRegex: `(\d+`}})
+	var buf bytes.Buffer
+	col.SetLogger(zerolog.New(&buf))
+
+	col.Collect(context.Background())
+	col.Collect(context.Background())
+	if n := strings.Count(buf.String(), `"endpoint":"status"`); n != 1 {
+		t.Errorf("expected the invalid pattern logged once on the injected logger, got %d in %q", n, buf.String())
+	}
+}
+
+func TestCheckParseRegex(t *testing.T) {
+	tests := []struct {
+		regex   string
+		wantErr bool
+	}{
+		{`(?P<name>\w+)=(?P<value>\d+)`, false},
+		{`(?P<value>\d+) (?P<queue>\w+)`, false},
+		{`(\d+)`, true},
+		{`(?P<value>\d+`, true},
+		{`(?P<value>\d+) (?P<endpoint>\w+)`, true},
+		{`(?P<value>\d+) (?P<__queue>\w+)`, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.regex, func(t *testing.T) {
+			if _, err := checkParseRegex(tc.regex); (err != nil) != tc.wantErr {
+				t.Errorf("checkParseRegex() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPCollector_Retries(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int // Returned by the first `failures` requests
+		failures     int
+		retries      int
+		wantErr      bool
+		wantRequests int
+	}{
+		{"no retries by default", http.StatusServiceUnavailable, 1, 0, true, 1},
+		{"503 retried until success", http.StatusServiceUnavailable, 2, 3, false, 3},
+		{"429 retried", http.StatusTooManyRequests, 1, 1, false, 2},
+		{"budget exhausted", http.StatusBadGateway, 5, 2, true, 3},
+		{"404 not retried", http.StatusNotFound, 1, 3, true, 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests int
+			var mu sync.Mutex
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				requests++
+				n := requests
+				mu.Unlock()
+				if n <= tc.failures {
+					w.WriteHeader(tc.status)
+					return
+				}
+				w.Write([]byte("jobs 3\n"))
+			}))
+			defer srv.Close()
+
+			col := newTestHTTPCollector([]EndpointConfig{{Name: "api", URL: srv.URL, Retries: tc.retries, RetryBackoff: time.Millisecond}})
+			metrics, _, err := col.scrapeWithRetries(context.Background(), col.endpoints[0])
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("scrapeWithRetries() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && len(metrics) != 1 {
+				t.Errorf("expected 1 metric, got %d", len(metrics))
+			}
+			if requests != tc.wantRequests {
+				t.Errorf("expected %d requests, got %d", tc.wantRequests, requests)
+			}
+		})
+	}
+}
+
+func TestHTTPCollector_RetriesRespectDeadline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	col := newTestHTTPCollector([]EndpointConfig{{Name: "api", URL: srv.URL, Retries: 5, RetryBackoff: time.Second}})
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, _, err := col.scrapeWithRetries(ctx, col.endpoints[0]); err == nil {
+		t.Fatal("expected an error")
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("retry waited past the deadline: took %v", elapsed)
+	}
+}
+
+func TestHTTPCollector_MultipleURLs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/metrics":
+			w.Write([]byte("jobs_queued 3\n"))
+		case "/metrics/extra":
+			w.Write([]byte("jobs_failed 1\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		url    string
+		urls   []string
+		want   []string
+		wantUp float64
+	}{
+		{"url and urls", srv.URL + "/metrics", []string{srv.URL + "/metrics/extra"}, []string{"jobs_failed", "jobs_queued"}, 1},
+		{"urls only", "", []string{srv.URL + "/metrics", srv.URL + "/metrics/extra"}, []string{"jobs_failed", "jobs_queued"}, 1},
+		{"failing url keeps the others", srv.URL + "/metrics", []string{srv.URL + "/missing"}, []string{"jobs_queued"}, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			col := newTestHTTPCollector([]EndpointConfig{{Name: "jobs", URL: tc.url, URLs: tc.urls, Labels: map[string]string{"team": "infra"}}})
+			metrics, err := col.Collect(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var scraped []Metric
+			for _, m := range metrics {
+				if m.Name == "endpoint_up" && m.Value != tc.wantUp {
+					t.Errorf("endpoint_up = %v, want %v", m.Value, tc.wantUp)
+				}
+				if !slices.Contains(scrapeMetaNames, m.Name) {
+					scraped = append(scraped, m)
+				}
+			}
+			if got := metricNames(scraped); strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+			for _, m := range scraped {
+				if m.Labels["endpoint"] != "jobs" || m.Labels["team"] != "infra" {
+					t.Errorf("metric %s: unexpected labels %v", m.Name, m.Labels)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPCollector_CounterContinuity(t *testing.T) {
+	// Scrapes of a target restarting twice; 0 fails the scrape
+	requests := []float64{10, 25, 3, 8, 0, 2, 4}
+	tests := []struct {
+		name       string
+		continuity bool
+		want       []float64 // requests_total after every successful scrape
+	}{
+		{"disabled", false, []float64{10, 25, 3, 8, 2, 4}},
+		{"enabled", true, []float64{10, 25, 28, 33, 35, 37}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var n int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				v := requests[n]
+				n++
+				if v == 0 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				fmt.Fprintf(w, "# TYPE requests_total counter\nrequests_total %v\n# TYPE inflight gauge\ninflight %v\n", v, v)
+			}))
+			defer srv.Close()
+
+			col := newTestHTTPCollector([]EndpointConfig{{Name: "api", URL: srv.URL, CounterContinuity: tc.continuity}})
+			var got []float64
+			for range requests {
+				metrics, _, up := col.scrapeAll(context.Background(), col.endpoints[0])
+				if up == 0 {
+					continue
+				}
+				values := make(map[string]float64)
+				for _, m := range metrics {
+					values[m.Name] = m.Value
+				}
+				got = append(got, values["requests_total"])
+				if values["inflight"] != requests[n-1] {
+					t.Errorf("gauge changed: want %v, got %v", requests[n-1], values["inflight"])
+				}
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("requests_total: want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestHTTPCollector_CounterContinuityAbsentSeries(t *testing.T) {
+	// The target restarts after the first scrape; its labelled counter only
+	// reappears once incremented
+	bodies := []string{
+		"requests_total{path=\"/a\"} 50\nuptime_total 100\n",
+		"uptime_total 5\n",
+		"requests_total{path=\"/a\"} 1\nuptime_total 10\n",
+	}
+	var n int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(bodies[n]))
+		n++
+	}))
+	defer srv.Close()
+
+	col := newTestHTTPCollector([]EndpointConfig{{Name: "api", URL: srv.URL, CounterContinuity: true}})
+	var metrics []Metric
+	for range bodies {
+		metrics, _, _ = col.scrapeAll(context.Background(), col.endpoints[0])
+	}
+	values := make(map[string]float64)
+	for _, m := range metrics {
+		values[m.Name] = m.Value
+	}
+	if values["requests_total"] != 51 {
+		t.Errorf("requests_total: want 51, got %v", values["requests_total"])
+	}
+	if values["uptime_total"] != 110 {
+		t.Errorf("uptime_total: want 110, got %v", values["uptime_total"])
+	}
+
+	col.evictCounterState(time.Now().Add(counterContinuityStaleAfter / 2))
+	if len(col.counters) != 1 || len(col.counters["api\x00"+srv.URL]) != 2 {
+		t.Fatalf("expected recent state to be kept, got %v", col.counters)
+	}
+	col.evictCounterState(time.Now().Add(counterContinuityStaleAfter + time.Minute))
+	if len(col.counters) != 0 {
+		t.Errorf("expected stale state to be evicted, got %v", col.counters)
+	}
+}
+
+func TestHTTPCollector_SaveRestoreState(t *testing.T) {
+	ep := EndpointConfig{Name: "api", URL: "http://app:9100/metrics", CounterContinuity: true}
+	col := newTestHTTPCollector([]EndpointConfig{ep})
+	now := time.Now()
+	col.addParseErrors(ep, 3)
+	col.correctCounterResets(ep, []Metric{{Name: "requests_total", Value: 50, Labels: map[string]string{}}}, now)
+	col.correctCounterResets(ep, []Metric{{Name: "requests_total", Value: 5, Labels: map[string]string{}}}, now)
+	data, err := col.SaveState()
+	if err != nil {
+		t.Fatalf("SaveState: %v", err)
+	}
+
+	restored := newTestHTTPCollector([]EndpointConfig{ep})
+	if err := restored.RestoreState(data); err != nil {
+		t.Fatalf("RestoreState: %v", err)
+	}
+	if total := restored.addParseErrors(ep, 1); total != 4 {
+		t.Errorf("parse errors total = %d, want 4", total)
+	}
+	metrics := []Metric{{Name: "requests_total", Value: 7, Labels: map[string]string{}}}
+	restored.correctCounterResets(ep, metrics, now)
+	if metrics[0].Value != 57 {
+		t.Errorf("requests_total = %v, want 57 continuing the saved offset", metrics[0].Value)
+	}
 }
@@ -0,0 +1,30 @@
+package collector
+
+import (
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// LoggerSetter is implemented by components that accept an injected logger
+type LoggerSetter interface {
+	SetLogger(logger zerolog.Logger)
+}
+
+// Logging can be embedded to give a component an injectable logger. The zero
+// value logs through the zerolog package-global logger.
+type Logging struct {
+	logger *zerolog.Logger
+}
+
+// SetLogger replaces the logger used by the component
+func (l *Logging) SetLogger(logger zerolog.Logger) {
+	l.logger = &logger
+}
+
+// Logger returns the injected logger, or the zerolog global when none was set
+func (l *Logging) Logger() *zerolog.Logger {
+	if l.logger == nil {
+		return &log.Logger
+	}
+	return l.logger
+}
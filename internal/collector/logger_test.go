@@ -0,0 +1,54 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestLoggingDefaultsToGlobal(t *testing.T) {
+	var l Logging
+	if l.Logger() != &log.Logger {
+		t.Error("zero-value Logging should use the zerolog global logger")
+	}
+
+	var buf bytes.Buffer
+	l.SetLogger(zerolog.New(&buf))
+	l.Logger().Info().Msg("hello")
+	if !strings.Contains(buf.String(), "hello") {
+		t.Errorf("injected logger not used, got %q", buf.String())
+	}
+}
+
+// loggingCollector records the logger injected by the registry.
+type loggingCollector struct {
+	Logging
+}
+
+func (c *loggingCollector) Name() string { return "logging" }
+func (c *loggingCollector) Collect(_ context.Context) ([]Metric, error) {
+	c.Logger().Info().Msg("collecting")
+	return nil, nil
+}
+
+func TestRegistrySetLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+
+	before, after := &loggingCollector{}, &loggingCollector{}
+	r := NewRegistry()
+	r.Register(before)
+	r.SetLogger(logger)
+	r.Register(after)
+
+	if _, err := r.CollectAll(context.Background()); err != nil {
+		t.Fatalf("CollectAll: %v", err)
+	}
+	if got := strings.Count(buf.String(), "collecting"); got != 2 {
+		t.Errorf("expected both collectors to log through the injected logger, got %d lines:\n%s", got, buf.String())
+	}
+}
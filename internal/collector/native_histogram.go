@@ -0,0 +1,134 @@
+package collector
+
+import (
+	"math"
+	"sort"
+)
+
+// Native histogram schema bounds and defaults, as defined by Prometheus
+const (
+	MinNativeHistogramSchema     = -4
+	MaxNativeHistogramSchema     = 8
+	DefaultNativeHistogramSchema = 3 // 8 buckets per power of two, ~9% relative error
+	// DefaultNativeHistogramZeroThreshold is Prometheus' default width of the zero bucket
+	DefaultNativeHistogramZeroThreshold = 2.938735877055719e-39 // 2^-128
+)
+
+// NativeHistogram is a Prometheus native (sparse, exponential) histogram.
+// Bucket i of a schema s covers (base^(i-1), base^i] with base = 2^(2^-s);
+// buckets maps bucket index to its (non-cumulative) observation count.
+// Observe is not safe for concurrent use; snapshot with Metric before shipping.
+type NativeHistogram struct {
+	Schema          int32
+	ZeroThreshold   float64
+	ZeroCount       uint64
+	Count           uint64
+	Sum             float64
+	PositiveBuckets map[int]int64
+	NegativeBuckets map[int]int64
+}
+
+// NewNativeHistogram returns an empty histogram. The schema is clamped to
+// [MinNativeHistogramSchema, MaxNativeHistogramSchema].
+func NewNativeHistogram(schema int32) *NativeHistogram {
+	schema = max(MinNativeHistogramSchema, min(schema, MaxNativeHistogramSchema))
+	return &NativeHistogram{
+		Schema:          schema,
+		ZeroThreshold:   DefaultNativeHistogramZeroThreshold,
+		PositiveBuckets: make(map[int]int64),
+		NegativeBuckets: make(map[int]int64),
+	}
+}
+
+// Observe records one sample. NaN samples are ignored.
+func (h *NativeHistogram) Observe(v float64) {
+	if math.IsNaN(v) {
+		return
+	}
+	h.Count++
+	h.Sum += v
+
+	abs := math.Abs(v)
+	if abs <= h.ZeroThreshold {
+		h.ZeroCount++
+		return
+	}
+	key := h.bucketIndex(abs)
+	if v > 0 {
+		h.PositiveBuckets[key]++
+	} else {
+		h.NegativeBuckets[key]++
+	}
+}
+
+// bucketIndex returns the index of the bucket whose upper bound is the
+// smallest bound >= v, i.e. ceil(log2(v) * 2^schema)
+func (h *NativeHistogram) bucketIndex(v float64) int {
+	if math.IsInf(v, 0) {
+		return math.MaxInt32
+	}
+	return int(math.Ceil(math.Ldexp(math.Log2(v), int(h.Schema))))
+}
+
+// Metric returns a "histogram" metric carrying a copy of h, so h can keep
+// observing while the metric is shipped. Value holds the observation count
+// for shippers without native histogram support.
+func (h *NativeHistogram) Metric(name string, labels map[string]string) Metric {
+	snapshot := *h
+	snapshot.PositiveBuckets = copyBuckets(h.PositiveBuckets)
+	snapshot.NegativeBuckets = copyBuckets(h.NegativeBuckets)
+	return Metric{
+		Name:      name,
+		Labels:    labels,
+		Value:     float64(h.Count),
+		Type:      "histogram",
+		Histogram: &snapshot,
+	}
+}
+
+func copyBuckets(buckets map[int]int64) map[int]int64 {
+	out := make(map[int]int64, len(buckets))
+	for k, v := range buckets {
+		out[k] = v
+	}
+	return out
+}
+
+// BucketSpan is a run of consecutive buckets in the sparse encoding
+type BucketSpan struct {
+	Offset int32  // Gap to the previous span's end, or the first index for the first span
+	Length uint32 // Number of consecutive buckets
+}
+
+// SparseBuckets encodes buckets as Prometheus spans plus delta-encoded counts,
+// the representation used by remote write. Empty buckets are omitted.
+func SparseBuckets(buckets map[int]int64) ([]BucketSpan, []int64) {
+	keys := make([]int, 0, len(buckets))
+	for k, v := range buckets {
+		if v != 0 {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	sort.Ints(keys)
+
+	spans := make([]BucketSpan, 0, 1)
+	deltas := make([]int64, 0, len(keys))
+	var prevKey int
+	var prevCount int64
+	for i, k := range keys {
+		switch {
+		case i == 0:
+			spans = append(spans, BucketSpan{Offset: int32(k), Length: 1})
+		case k == prevKey+1:
+			spans[len(spans)-1].Length++
+		default:
+			spans = append(spans, BucketSpan{Offset: int32(k - prevKey - 1), Length: 1})
+		}
+		deltas = append(deltas, buckets[k]-prevCount)
+		prevKey, prevCount = k, buckets[k]
+	}
+	return spans, deltas
+}
@@ -0,0 +1,65 @@
+package collector
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestNativeHistogram_Observe(t *testing.T) {
+	h := NewNativeHistogram(0) // Bucket i covers (2^(i-1), 2^i]
+	for _, v := range []float64{1, 1.5, 2, 3, 0, -4, math.NaN()} {
+		h.Observe(v)
+	}
+
+	if h.Count != 6 || h.Sum != 3.5 {
+		t.Errorf("expected count 6 and sum 3.5, got %d and %v", h.Count, h.Sum)
+	}
+	if h.ZeroCount != 1 {
+		t.Errorf("expected one zero observation, got %d", h.ZeroCount)
+	}
+	wantPositive := map[int]int64{0: 1, 1: 2, 2: 1}
+	if !reflect.DeepEqual(h.PositiveBuckets, wantPositive) {
+		t.Errorf("positive buckets: got %v, want %v", h.PositiveBuckets, wantPositive)
+	}
+	if !reflect.DeepEqual(h.NegativeBuckets, map[int]int64{2: 1}) {
+		t.Errorf("negative buckets: got %v", h.NegativeBuckets)
+	}
+
+	if NewNativeHistogram(20).Schema != MaxNativeHistogramSchema {
+		t.Error("schema should be clamped to the maximum")
+	}
+}
+
+func TestNativeHistogram_MetricSnapshot(t *testing.T) {
+	h := NewNativeHistogram(DefaultNativeHistogramSchema)
+	h.Observe(0.25)
+
+	m := h.Metric("latency_seconds", map[string]string{"path": "/"})
+	h.Observe(0.25)
+
+	if m.Type != "histogram" || m.Value != 1 || m.Histogram.Count != 1 {
+		t.Errorf("unexpected metric: %+v", m)
+	}
+	for _, count := range m.Histogram.PositiveBuckets {
+		if count != 1 {
+			t.Errorf("snapshot buckets changed after a later Observe: %v", m.Histogram.PositiveBuckets)
+		}
+	}
+}
+
+func TestSparseBuckets(t *testing.T) {
+	spans, deltas := SparseBuckets(map[int]int64{-1: 2, 0: 5, 1: 3, 4: 1, 7: 0})
+
+	wantSpans := []BucketSpan{{Offset: -1, Length: 3}, {Offset: 2, Length: 1}}
+	if !reflect.DeepEqual(spans, wantSpans) {
+		t.Errorf("spans: got %v, want %v", spans, wantSpans)
+	}
+	if want := []int64{2, 3, -2, -2}; !reflect.DeepEqual(deltas, want) {
+		t.Errorf("deltas: got %v, want %v", deltas, want)
+	}
+
+	if spans, deltas := SparseBuckets(nil); spans != nil || deltas != nil {
+		t.Errorf("expected no spans for empty buckets, got %v %v", spans, deltas)
+	}
+}
@@ -0,0 +1,186 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// openMetricsContentType is the media type served by OpenMetrics exporters
+const openMetricsContentType = "application/openmetrics-text"
+
+// isOpenMetrics reports whether a Content-Type header announces OpenMetrics
+func isOpenMetrics(contentType string) bool {
+	return strings.HasPrefix(strings.TrimSpace(strings.ToLower(contentType)), openMetricsContentType)
+}
+
+// openMetricsSuffixes are sample name suffixes that belong to a metric family
+var openMetricsSuffixes = []string{"_total", "_created", "_count", "_sum", "_bucket", "_gcount", "_gsum", "_info"}
+
+// parseOpenMetricsText parses the OpenMetrics text format. Compared to the
+// Prometheus text format it reads timestamps as (fractional) Unix seconds,
+// skips `# EOF` / `# UNIT` and the `_created` series of counters, histograms
+// and summaries, and resolves HELP by family name. Exemplars are kept when
+// exemplars is set and dropped otherwise; a malformed exemplar is dropped
+// without affecting its sample. Like parsePrometheusText it also returns the
+// number of malformed sample lines.
+func (c *HTTPCollector) parseOpenMetricsText(endpointName string, body []byte, exemplars bool) (metrics []Metric, malformed int) {
+	metrics = make([]Metric, 0)
+	help := make(map[string]string)
+	types := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			// # HELP family text / # TYPE family type; # UNIT and # EOF are ignored
+			fields := strings.SplitN(line, " ", 4)
+			if len(fields) == 4 && fields[1] == "HELP" {
+				help[fields[2]] = fields[3]
+			} else if len(fields) >= 4 && fields[1] == "TYPE" {
+				types[fields[2]] = fields[3]
+			}
+			continue
+		}
+
+		// Split off the exemplar: "name{...} value [ts] # {labels} value [ts]"
+		var exemplar string
+		if idx := exemplarIndex(line); idx != -1 {
+			exemplar = line[idx+len(" # "):]
+			line = strings.TrimSpace(line[:idx])
+		}
+
+		sample, timestamp := splitOpenMetricsTimestamp(line)
+		metric := c.parsePrometheusLine(endpointName, sample)
+		if metric == nil {
+			malformed++
+			continue
+		}
+
+		family := openMetricsFamily(metric.Name, types)
+		if strings.HasSuffix(metric.Name, "_created") && family != metric.Name {
+			switch types[family] {
+			case "counter", "histogram", "summary", "gaugehistogram":
+				continue
+			}
+		}
+		if types[family] == "counter" {
+			metric.Type = "counter"
+		}
+		if metric.Help == "" {
+			metric.Help = help[family]
+		}
+		if timestamp != "" {
+			if ts, ok := parseOpenMetricsTimestamp(timestamp); ok {
+				metric.Timestamp = ts
+			}
+		}
+		if exemplars && exemplar != "" {
+			if ex, ok := parseExemplar(exemplar); ok {
+				metric.Exemplars = []Exemplar{ex}
+			}
+		}
+
+		metrics = append(metrics, *metric)
+	}
+
+	return metrics, malformed
+}
+
+// exemplarIndex returns the position of the " # " exemplar separator outside
+// the label set, or -1
+func exemplarIndex(line string) int {
+	start := 0
+	open := strings.Index(line, "{")
+	if open != -1 && open < strings.IndexAny(line, " \t") {
+		if end := labelSetEnd(line, open); end != -1 {
+			start = end
+		}
+	}
+	if idx := strings.Index(line[start:], " # "); idx != -1 {
+		return start + idx
+	}
+	return -1
+}
+
+// parseExemplar parses `{labels} value [timestamp]`
+func parseExemplar(s string) (Exemplar, bool) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "{") {
+		return Exemplar{}, false
+	}
+	end := labelSetEnd(s, 0)
+	if end == -1 {
+		return Exemplar{}, false
+	}
+	fields := strings.Fields(s[end+1:])
+	if len(fields) == 0 || len(fields) > 2 {
+		return Exemplar{}, false
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return Exemplar{}, false
+	}
+
+	ex := Exemplar{Labels: make(map[string]string), Value: value}
+	parseLabelPairs(s[1:end], ex.Labels)
+	if len(fields) == 2 {
+		ts, ok := parseOpenMetricsTimestamp(fields[1])
+		if !ok {
+			return Exemplar{}, false
+		}
+		ex.Timestamp = ts
+	}
+	return ex, true
+}
+
+// parseOpenMetricsTimestamp parses (fractional) Unix seconds
+func parseOpenMetricsTimestamp(s string) (time.Time, bool) {
+	secs, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	whole, frac := math.Modf(secs)
+	return time.Unix(int64(whole), int64(frac*1e9)), true
+}
+
+// splitOpenMetricsTimestamp separates the optional timestamp so the sample can go
+// through the Prometheus line parser, which expects millisecond timestamps
+func splitOpenMetricsTimestamp(line string) (string, string) {
+	valueStart := strings.IndexAny(line, " \t")
+	if open := strings.Index(line, "{"); open != -1 && (valueStart == -1 || open < valueStart) {
+		if end := labelSetEnd(line, open); end != -1 {
+			valueStart = end + 1
+		}
+	}
+	if valueStart == -1 {
+		return line, ""
+	}
+	fields := strings.Fields(line[valueStart:])
+	if len(fields) < 2 {
+		return line, ""
+	}
+	return strings.TrimSpace(line[:valueStart]) + " " + fields[0], fields[1]
+}
+
+// openMetricsFamily maps a sample name to the family it belongs to, using the
+// declared TYPEs to decide whether a suffix is significant
+func openMetricsFamily(name string, types map[string]string) string {
+	if _, ok := types[name]; ok {
+		return name
+	}
+	for _, suffix := range openMetricsSuffixes {
+		if base, ok := strings.CutSuffix(name, suffix); ok {
+			if _, declared := types[base]; declared {
+				return base
+			}
+		}
+	}
+	return name
+}
@@ -0,0 +1,189 @@
+package collector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const openMetricsSample = `# TYPE acme_http_router_request_seconds summary
+# UNIT acme_http_router_request_seconds seconds
+# HELP acme_http_router_request_seconds Latency though all of ACME's HTTP request router.
+acme_http_router_request_seconds_sum{path="/api/v1",method="GET"} 9036.32
+acme_http_router_request_seconds_count{path="/api/v1",method="GET"} 807283.0
+acme_http_router_request_seconds_created{path="/api/v1",method="GET"} 1605281325.0
+# TYPE go_goroutines gauge
+# HELP go_goroutines Number of goroutines.
+go_goroutines 69
+# TYPE process_cpu_seconds counter
+# UNIT process_cpu_seconds seconds
+# HELP process_cpu_seconds Total user and system CPU time spent in seconds.
+process_cpu_seconds_total 4.20072246e+06 1605281700.5 # {trace_id="KOO5S4vxi0o"} 0.67 1605281699.0
+process_cpu_seconds_created 1605281325.0
+# TYPE job_created gauge
+job_created 1.7e+09
+# EOF
+`
+
+func TestHTTPCollector_OpenMetrics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+		w.Write([]byte(openMetricsSample))
+	}))
+	defer srv.Close()
+
+	col := newTestHTTPCollector([]EndpointConfig{{Name: "om", URL: srv.URL}})
+	metrics, err := collectScraped(col)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		"acme_http_router_request_seconds_count",
+		"acme_http_router_request_seconds_sum",
+		"go_goroutines",
+		"job_created",
+		"process_cpu_seconds_total",
+	}
+	got := metricNames(metrics)
+	if len(got) != len(want) {
+		t.Fatalf("metric names = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("metric names = %v, want %v", got, want)
+		}
+	}
+
+	cpu := findMetric(metrics, "process_cpu_seconds_total")
+	if cpu.Value != 4.20072246e+06 {
+		t.Errorf("process_cpu_seconds_total value: got %v", cpu.Value)
+	}
+	if cpu.Type != "counter" {
+		t.Errorf("process_cpu_seconds_total type: want counter, got %q", cpu.Type)
+	}
+	if cpu.Help != "Total user and system CPU time spent in seconds." {
+		t.Errorf("process_cpu_seconds_total help: got %q", cpu.Help)
+	}
+	if want := time.Unix(1605281700, 500000000); !cpu.Timestamp.Equal(want) {
+		t.Errorf("timestamp: want %v (seconds), got %v", want, cpu.Timestamp)
+	}
+	if _, ok := cpu.Labels["trace_id"]; ok {
+		t.Error("exemplar labels must not leak into the sample")
+	}
+
+	sum := findMetric(metrics, "acme_http_router_request_seconds_sum")
+	if sum.Labels["path"] != "/api/v1" || sum.Labels["endpoint"] != "om" {
+		t.Errorf("unexpected labels: %v", sum.Labels)
+	}
+	if sum.Help == "" {
+		t.Error("expected family HELP to apply to _sum sample")
+	}
+}
+
+func TestHTTPCollector_OpenMetricsExemplars(t *testing.T) {
+	body := `# TYPE req_seconds histogram
+req_seconds_bucket{le="0.5"} 10 # {trace_id="abc",span_id="def"} 0.25 1605281699.5
+req_seconds_bucket{le="1"} 12 # {trace_id="ghi"} 0.75
+req_seconds_bucket{le="+Inf"} 13 # {trace_id="broken"} not-a-number
+req_seconds_count 13
+# EOF
+`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0")
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	col := newTestHTTPCollector([]EndpointConfig{{Name: "om", URL: srv.URL, Exemplars: true}})
+	metrics, err := collectScraped(col)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	byLE := make(map[string]Metric)
+	for _, m := range metrics {
+		if m.Name == "req_seconds_bucket" {
+			byLE[m.Labels["le"]] = m
+		}
+	}
+	if len(byLE) != 3 {
+		t.Fatalf("expected 3 buckets, got %v", metricNames(metrics))
+	}
+
+	ex := byLE["0.5"].Exemplars
+	if len(ex) != 1 || ex[0].Labels["trace_id"] != "abc" || ex[0].Labels["span_id"] != "def" || ex[0].Value != 0.25 {
+		t.Fatalf("unexpected exemplar: %+v", ex)
+	}
+	if want := time.Unix(1605281699, 500000000); !ex[0].Timestamp.Equal(want) {
+		t.Errorf("exemplar timestamp: want %v, got %v", want, ex[0].Timestamp)
+	}
+	if ex := byLE["1"].Exemplars; len(ex) != 1 || !ex[0].Timestamp.IsZero() {
+		t.Errorf("exemplar without timestamp: %+v", ex)
+	}
+	if b := byLE["+Inf"]; b.Value != 13 || b.Exemplars != nil {
+		t.Errorf("a malformed exemplar should be dropped and its sample kept, got %+v", b)
+	}
+	if m := findMetric(metrics, "req_seconds_count"); m.Exemplars != nil {
+		t.Errorf("sample without exemplar got %+v", m.Exemplars)
+	}
+
+	// Without the option exemplars are dropped as before
+	col = newTestHTTPCollector([]EndpointConfig{{Name: "om", URL: srv.URL}})
+	metrics, err = collectScraped(col)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, m := range metrics {
+		if m.Exemplars != nil {
+			t.Errorf("%s kept exemplars with exemplars disabled", m.Name)
+		}
+	}
+}
+
+func TestHTTPCollector_OpenMetricsBraceInLabel(t *testing.T) {
+	body := `# TYPE req_seconds histogram
+req_seconds_bucket{le="0.5",path="/a}b # c"} 10 1605281699.5 # {trace_id="x}y"} 0.25
+# EOF
+`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0")
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	col := newTestHTTPCollector([]EndpointConfig{{Name: "om", URL: srv.URL, Exemplars: true}})
+	metrics, err := collectScraped(col)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m := findMetric(metrics, "req_seconds_bucket")
+	if m.Value != 10 || m.Labels["path"] != "/a}b # c" {
+		t.Fatalf("unexpected sample: %+v", m)
+	}
+	if want := time.Unix(1605281699, 500000000); !m.Timestamp.Equal(want) {
+		t.Errorf("timestamp: want %v, got %v", want, m.Timestamp)
+	}
+	if len(m.Exemplars) != 1 || m.Exemplars[0].Labels["trace_id"] != "x}y" || m.Exemplars[0].Value != 0.25 {
+		t.Errorf("unexpected exemplar: %+v", m.Exemplars)
+	}
+}
+
+func TestIsOpenMetrics(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{"application/openmetrics-text; version=1.0.0; charset=utf-8", true},
+		{"Application/OpenMetrics-Text", true},
+		{"text/plain; version=0.0.4", false},
+		{"", false},
+	}
+	for _, tc := range tests {
+		if got := isOpenMetrics(tc.contentType); got != tc.want {
+			t.Errorf("isOpenMetrics(%q) = %v, want %v", tc.contentType, got, tc.want)
+		}
+	}
+}
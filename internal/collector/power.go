@@ -0,0 +1,68 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var powerMetricDescriptors = []MetricDescriptor{
+	{Name: "system_battery_percent", Type: "gauge", Help: "Battery charge as a percentage of full capacity."},
+	{Name: "system_power_ac_online", Type: "gauge", Help: "1 when the external power supply is connected, otherwise 0."},
+}
+
+// collectPower reads batteries and external supplies from
+// /sys/class/power_supply. Peripherals (scope "Device", e.g. a wireless
+// mouse) are skipped, and servers without supplies yield no metrics.
+func (c *SystemCollector) collectPower() ([]Metric, error) {
+	dirs, err := filepath.Glob(hostSys("class", "power_supply", "*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var metrics []Metric
+	for _, dir := range dirs {
+		if readSysString(filepath.Join(dir, "scope")) == "Device" {
+			continue
+		}
+		labels := map[string]string{"supply": filepath.Base(dir)}
+
+		switch readSysString(filepath.Join(dir, "type")) {
+		case "Battery":
+			if capacity, err := strconv.ParseFloat(readSysString(filepath.Join(dir, "capacity")), 64); err == nil {
+				metrics = append(metrics, Metric{Name: "system_battery_percent", Labels: labels, Value: capacity, Type: "gauge"})
+			}
+		case "Mains", "USB":
+			if online, err := strconv.ParseFloat(readSysString(filepath.Join(dir, "online")), 64); err == nil {
+				metrics = append(metrics, Metric{Name: "system_power_ac_online", Labels: labels, Value: online, Type: "gauge"})
+			}
+		}
+	}
+	return metrics, nil
+}
+
+// OnBattery reports whether a battery in /sys/class/power_supply is
+// discharging, i.e. the host runs on battery. Hosts without one never do.
+func OnBattery() bool {
+	dirs, _ := filepath.Glob(hostSys("class", "power_supply", "*"))
+	for _, dir := range dirs {
+		if readSysString(filepath.Join(dir, "scope")) == "Device" {
+			continue
+		}
+		if readSysString(filepath.Join(dir, "type")) == "Battery" && readSysString(filepath.Join(dir, "status")) == "Discharging" {
+			return true
+		}
+	}
+	return false
+}
+
+// readSysString returns the trimmed content of a sysfs attribute, or "" if it
+// can't be read
+func readSysString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
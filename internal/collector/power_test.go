@@ -0,0 +1,92 @@
+package collector
+
+import (
+	"testing"
+)
+
+func TestSystemCollector_CollectPower(t *testing.T) {
+	sysRoot := t.TempDir()
+	writeFileTree(t, sysRoot, map[string]string{
+		"class/power_supply/BAT0/type":                "Battery\n",
+		"class/power_supply/BAT0/capacity":            "42\n",
+		"class/power_supply/AC/type":                  "Mains\n",
+		"class/power_supply/AC/online":                "1\n",
+		"class/power_supply/usb/type":                 "USB\n",
+		"class/power_supply/usb/online":               "0\n",
+		"class/power_supply/hidpp_battery_0/type":     "Battery\n",
+		"class/power_supply/hidpp_battery_0/scope":    "Device\n",
+		"class/power_supply/hidpp_battery_0/capacity": "90\n",
+		"class/power_supply/BAT1/type":                "Battery\n", // no capacity attribute
+	})
+	t.Setenv("HOST_SYS", sysRoot)
+
+	c := NewSystemCollector(false, false, false, false)
+	metrics, err := c.collectPower()
+	if err != nil {
+		t.Fatalf("collectPower: %v", err)
+	}
+
+	got := make(map[string]float64)
+	for _, m := range metrics {
+		got[m.Name+"/"+m.Labels["supply"]] = m.Value
+	}
+	want := map[string]float64{
+		"system_battery_percent/BAT0": 42,
+		"system_power_ac_online/AC":   1,
+		"system_power_ac_online/usb":  0,
+	}
+	for key, v := range want {
+		if value, ok := got[key]; !ok || value != v {
+			t.Errorf("%s: want %v, got %v (present=%v)", key, v, value, ok)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("unexpected metrics: %v", got)
+	}
+}
+
+func TestSystemCollector_CollectPowerNoSupplies(t *testing.T) {
+	t.Setenv("HOST_SYS", t.TempDir())
+
+	c := NewSystemCollector(false, false, false, false)
+	metrics, err := c.collectPower()
+	if err != nil {
+		t.Fatalf("collectPower: %v", err)
+	}
+	if len(metrics) != 0 {
+		t.Errorf("expected no metrics without power supplies, got %+v", metrics)
+	}
+}
+
+func TestOnBattery(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  bool
+	}{
+		{"no supplies", map[string]string{}, false},
+		{"charging", map[string]string{
+			"class/power_supply/BAT0/type":   "Battery\n",
+			"class/power_supply/BAT0/status": "Charging\n",
+		}, false},
+		{"discharging", map[string]string{
+			"class/power_supply/BAT0/type":   "Battery\n",
+			"class/power_supply/BAT0/status": "Discharging\n",
+		}, true},
+		{"discharging peripheral", map[string]string{
+			"class/power_supply/hidpp_battery_0/type":   "Battery\n",
+			"class/power_supply/hidpp_battery_0/scope":  "Device\n",
+			"class/power_supply/hidpp_battery_0/status": "Discharging\n",
+		}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sysRoot := t.TempDir()
+			writeFileTree(t, sysRoot, tc.files)
+			t.Setenv("HOST_SYS", sysRoot)
+			if got := OnBattery(); got != tc.want {
+				t.Errorf("OnBattery() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// ProcessCollector collects per-process metrics for processes whose name matches
+// one of the configured patterns (Single Responsibility Principle)
+type ProcessCollector struct {
+	patterns []*regexp.Regexp
+
+	mu      sync.Mutex
+	lastCPU map[int32]cpuSample // Previous CPU time per PID, for cpu percent between cycles
+}
+
+// cpuSample is a process's cumulative CPU time at a point in time
+type cpuSample struct {
+	total float64
+	at    time.Time
+}
+
+var processMetricDescriptors = []MetricDescriptor{
+	{Name: "process_cpu_percent", Type: "gauge", Help: "Process CPU usage percentage since the previous collection (100 = one full core)."},
+	{Name: "process_memory_rss_bytes", Type: "gauge", Help: "Process resident set size in bytes."},
+	{Name: "process_open_fds", Type: "gauge", Help: "Number of file descriptors open by the process."},
+	{Name: "process_threads", Type: "gauge", Help: "Number of threads in the process."},
+	{Name: "process_uptime_seconds", Type: "gauge", Help: "Seconds since the process started."},
+}
+
+var processMetricHelp = helpIndex(processMetricDescriptors)
+
+// NewProcessCollector creates a process collector. Each pattern is a regular
+// expression matched against the process name.
+func NewProcessCollector(patterns []string) (*ProcessCollector, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid process pattern %q: %w", p, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return &ProcessCollector{
+		patterns: compiled,
+		lastCPU:  make(map[int32]cpuSample),
+	}, nil
+}
+
+// Name returns the collector name
+func (c *ProcessCollector) Name() string {
+	return "process"
+}
+
+// Describe returns the metrics emitted for each matched process
+func (c *ProcessCollector) Describe() []MetricDescriptor {
+	return processMetricDescriptors
+}
+
+// Collect gathers metrics for every running process matching a pattern
+func (c *ProcessCollector) Collect(ctx context.Context) ([]Metric, error) {
+	procs, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	seen := make(map[int32]struct{})
+	metrics := make([]Metric, 0)
+
+	for _, p := range procs {
+		name, err := p.NameWithContext(ctx)
+		if err != nil || !c.matches(name) {
+			continue
+		}
+		seen[p.Pid] = struct{}{}
+		metrics = append(metrics, c.collectProcess(ctx, p, name, now)...)
+	}
+
+	// Forget PIDs that have exited so the map doesn't grow forever
+	for pid := range c.lastCPU {
+		if _, ok := seen[pid]; !ok {
+			delete(c.lastCPU, pid)
+		}
+	}
+
+	applyHelp(metrics, processMetricHelp)
+
+	return metrics, nil
+}
+
+func (c *ProcessCollector) matches(name string) bool {
+	for _, re := range c.patterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectProcess emits metrics for one process; values that can't be read
+// (e.g. fds of another user's process) are skipped.
+func (c *ProcessCollector) collectProcess(ctx context.Context, p *process.Process, name string, now time.Time) []Metric {
+	labels := map[string]string{
+		"process": name,
+		"pid":     strconv.Itoa(int(p.Pid)),
+	}
+	gauge := func(metricName string, value float64) Metric {
+		return Metric{Name: metricName, Labels: copyLabels(labels), Value: value, Type: "gauge"}
+	}
+
+	var metrics []Metric
+
+	if times, err := p.TimesWithContext(ctx); err == nil {
+		total := times.User + times.System
+		if prev, ok := c.lastCPU[p.Pid]; ok {
+			if elapsed := now.Sub(prev.at).Seconds(); elapsed > 0 {
+				metrics = append(metrics, gauge("process_cpu_percent", (total-prev.total)/elapsed*100))
+			}
+		} else if percent, err := p.CPUPercentWithContext(ctx); err == nil {
+			// First sighting: fall back to the average over the process lifetime
+			metrics = append(metrics, gauge("process_cpu_percent", percent))
+		}
+		c.lastCPU[p.Pid] = cpuSample{total: total, at: now}
+	}
+
+	if memInfo, err := p.MemoryInfoWithContext(ctx); err == nil {
+		metrics = append(metrics, gauge("process_memory_rss_bytes", float64(memInfo.RSS)))
+	}
+
+	if fds, err := p.NumFDsWithContext(ctx); err == nil {
+		metrics = append(metrics, gauge("process_open_fds", float64(fds)))
+	}
+
+	if threads, err := p.NumThreadsWithContext(ctx); err == nil {
+		metrics = append(metrics, gauge("process_threads", float64(threads)))
+	}
+
+	if created, err := p.CreateTimeWithContext(ctx); err == nil {
+		metrics = append(metrics, gauge("process_uptime_seconds", now.Sub(time.UnixMilli(created)).Seconds()))
+	}
+
+	return metrics
+}
@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+func TestNewProcessCollector(t *testing.T) {
+	t.Run("valid patterns", func(t *testing.T) {
+		c, err := NewProcessCollector([]string{"^nginx$", "postgres"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if c.Name() != "process" {
+			t.Errorf("Name() = %q, want %q", c.Name(), "process")
+		}
+		if len(c.patterns) != 2 {
+			t.Errorf("expected 2 patterns, got %d", len(c.patterns))
+		}
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		if _, err := NewProcessCollector([]string{"("}); err == nil {
+			t.Error("expected error for invalid regex")
+		}
+	})
+}
+
+func TestProcessCollector_CollectSelf(t *testing.T) {
+	self, err := process.NewProcess(int32(os.Getpid()))
+	if err != nil {
+		t.Skipf("cannot inspect own process: %v", err)
+	}
+	name, err := self.Name()
+	if err != nil {
+		t.Skipf("cannot read own process name: %v", err)
+	}
+
+	c, err := NewProcessCollector([]string{"^" + regexp.QuoteMeta(name) + "$"})
+	if err != nil {
+		t.Fatalf("NewProcessCollector: %v", err)
+	}
+
+	pid := strconv.Itoa(os.Getpid())
+	for cycle := 0; cycle < 2; cycle++ {
+		metrics, err := c.Collect(context.Background())
+		if err != nil {
+			t.Fatalf("Collect: %v", err)
+		}
+
+		found := make(map[string]bool)
+		for _, m := range metrics {
+			if m.Labels["pid"] != pid {
+				continue
+			}
+			if m.Labels["process"] != name {
+				t.Errorf("%s process label = %q, want %q", m.Name, m.Labels["process"], name)
+			}
+			if m.Help == "" {
+				t.Errorf("%s has no help text", m.Name)
+			}
+			found[m.Name] = true
+		}
+		for _, want := range []string{"process_cpu_percent", "process_memory_rss_bytes", "process_threads", "process_uptime_seconds"} {
+			if !found[want] {
+				t.Errorf("cycle %d: missing %s for own pid", cycle, want)
+			}
+		}
+	}
+}
+
+func TestProcessCollector_NoMatch(t *testing.T) {
+	c, err := NewProcessCollector([]string{"^no-such-process-name-xyz$"})
+	if err != nil {
+		t.Fatalf("NewProcessCollector: %v", err)
+	}
+	metrics, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	if len(metrics) != 0 {
+		t.Errorf("expected no metrics, got %d", len(metrics))
+	}
+}
@@ -151,3 +151,34 @@ func TestToPrometheusMetrics_DescString(t *testing.T) {
 		t.Errorf("descriptor string %q does not contain metric name 'my_special_metric'", desc)
 	}
 }
+
+func TestToPrometheusMetrics_Help(t *testing.T) {
+	result := ToPrometheusMetrics([]Metric{{Name: "with_help", Value: 1, Type: "gauge", Help: "A helpful description."}})
+	if len(result) != 1 {
+		t.Fatalf("expected 1 prometheus metric, got %d", len(result))
+	}
+	if desc := result[0].Desc().String(); !strings.Contains(desc, "A helpful description.") {
+		t.Errorf("expected help in descriptor, got %s", desc)
+	}
+}
+
+func TestToPrometheusMetrics_SortedLabels(t *testing.T) {
+	result := ToPrometheusMetrics([]Metric{{Name: "up", Value: 1, Type: "gauge", Labels: map[string]string{"zone": "a", "app": "api", "job": "x"}}})
+	if len(result) != 1 {
+		t.Fatalf("expected 1 prometheus metric, got %d", len(result))
+	}
+	var out dto.Metric
+	if err := result[0].Write(&out); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	var names []string
+	for _, lp := range out.GetLabel() {
+		names = append(names, lp.GetName())
+	}
+	if got := strings.Join(names, ","); got != "app,job,zone" {
+		t.Errorf("expected labels in sorted order, got %s", got)
+	}
+	if got := SortedLabelNames(map[string]string{"zone": "a", "app": "api"}); strings.Join(got, ",") != "app,zone" {
+		t.Errorf("SortedLabelNames: got %v", got)
+	}
+}
@@ -0,0 +1,44 @@
+package collector
+
+import (
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// ProxyConfig selects the proxy for outbound HTTP requests. Empty fields fall
+// back to HTTP_PROXY, HTTPS_PROXY and NO_PROXY (or their lowercase forms).
+type ProxyConfig struct {
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+}
+
+// ProxyFunc returns a function for http.Transport.Proxy. As with net/http's
+// environment handling, requests to localhost are never proxied.
+func (p ProxyConfig) ProxyFunc() func(*http.Request) (*url.URL, error) {
+	cfg := httpproxy.FromEnvironment()
+	if p.HTTPProxy != "" {
+		cfg.HTTPProxy = p.HTTPProxy
+	}
+	if p.HTTPSProxy != "" {
+		cfg.HTTPSProxy = p.HTTPSProxy
+	}
+	if p.NoProxy != "" {
+		cfg.NoProxy = p.NoProxy
+	}
+	proxy := cfg.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}
+}
+
+// ProxyTransport returns a copy of http.DefaultTransport that uses proxy.
+// It also serves unix:// URLs, which are never proxied.
+func ProxyTransport(proxy ProxyConfig) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = proxy.ProxyFunc()
+	t.RegisterProtocol(UnixSocketScheme, &unixSocketTransport{})
+	return t
+}
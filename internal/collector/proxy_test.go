@@ -0,0 +1,59 @@
+package collector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestProxyConfig_ProxyFunc(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "http://env-proxy:3128")
+	t.Setenv("HTTPS_PROXY", "")
+	t.Setenv("NO_PROXY", "")
+
+	tests := []struct {
+		name   string
+		config ProxyConfig
+		target string
+		want   string
+	}{
+		{"env fallback", ProxyConfig{}, "http://api.example.com/", "http://env-proxy:3128"},
+		{"config wins over env", ProxyConfig{HTTPProxy: "http://proxy:8080"}, "http://api.example.com/", "http://proxy:8080"},
+		{"https proxy", ProxyConfig{HTTPSProxy: "http://tls-proxy:8080"}, "https://api.example.com/", "http://tls-proxy:8080"},
+		{"no_proxy domain", ProxyConfig{HTTPProxy: "http://proxy:8080", NoProxy: ".internal"}, "http://db.internal/", ""},
+		{"localhost never proxied", ProxyConfig{HTTPProxy: "http://proxy:8080"}, "http://localhost:9100/", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			target, _ := url.Parse(tc.target)
+			got, err := tc.config.ProxyFunc()(&http.Request{URL: target})
+			if err != nil {
+				t.Fatalf("ProxyFunc() error: %v", err)
+			}
+			if (got == nil && tc.want != "") || (got != nil && got.String() != tc.want) {
+				t.Errorf("ProxyFunc() = %v, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHTTPCollector_SetProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.Write([]byte("up 1\n"))
+	}))
+	defer proxy.Close()
+
+	col := newTestHTTPCollector([]EndpointConfig{{Name: "app", URL: "http://app.example.com/metrics"}})
+	col.SetProxy(ProxyConfig{HTTPProxy: proxy.URL})
+	metrics, _, err := col.scrapeEndpoint(context.Background(), col.endpoints[0])
+	if err != nil {
+		t.Fatalf("scrapeEndpoint() error: %v", err)
+	}
+	if proxied != "http://app.example.com/metrics" || len(metrics) != 1 {
+		t.Errorf("expected the scrape to go through the proxy, proxied %q, got %d metrics", proxied, len(metrics))
+	}
+}
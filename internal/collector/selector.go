@@ -0,0 +1,158 @@
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Label matcher operators, as in PromQL
+const (
+	MatchEqual     = "="
+	MatchNotEqual  = "!="
+	MatchRegexp    = "=~"
+	MatchNotRegexp = "!~"
+)
+
+// labelMatcher is one condition of a Selector. The metric name is matched as
+// the __name__ label.
+type labelMatcher struct {
+	name  string
+	op    string
+	value string
+	re    *regexp.Regexp // Anchored; set for the regex operators
+}
+
+func (lm labelMatcher) matches(value string) bool {
+	switch lm.op {
+	case MatchEqual:
+		return value == lm.value
+	case MatchNotEqual:
+		return value != lm.value
+	case MatchRegexp:
+		return lm.re.MatchString(value)
+	default:
+		return !lm.re.MatchString(value)
+	}
+}
+
+// Selector matches metrics by name and labels like a PromQL series selector,
+// e.g. `system_cpu_usage_percent{core!="total"}` or `{__name__=~"system_.*"}`.
+// A missing label matches as the empty string.
+type Selector struct {
+	matchers []labelMatcher
+}
+
+// ParseSelector parses a series selector: an optional metric name followed by
+// optional {label<op>"value", ...} matchers with =, !=, =~ or !~. Regexes are
+// anchored. At least one of the name and the matchers is required.
+func ParseSelector(s string) (Selector, error) {
+	var sel Selector
+	rest := strings.TrimSpace(s)
+
+	name := rest
+	if i := strings.IndexByte(rest, '{'); i >= 0 {
+		name = strings.TrimSpace(rest[:i])
+		rest = rest[i:]
+	} else {
+		rest = ""
+	}
+	if name != "" {
+		if !MetricNameRegex.MatchString(name) {
+			return Selector{}, fmt.Errorf("invalid metric name %q in selector %q", name, s)
+		}
+		sel.matchers = append(sel.matchers, labelMatcher{name: "__name__", op: MatchEqual, value: name})
+	}
+
+	if rest != "" {
+		if !strings.HasSuffix(rest, "}") {
+			return Selector{}, fmt.Errorf("selector %q: missing closing brace", s)
+		}
+		matchers, err := parseLabelMatchers(rest[1 : len(rest)-1])
+		if err != nil {
+			return Selector{}, fmt.Errorf("selector %q: %w", s, err)
+		}
+		sel.matchers = append(sel.matchers, matchers...)
+	}
+
+	if len(sel.matchers) == 0 {
+		return Selector{}, fmt.Errorf("selector %q matches nothing: a metric name or label matcher is required", s)
+	}
+	return sel, nil
+}
+
+// parseLabelMatchers parses the comma separated matchers between the braces
+func parseLabelMatchers(s string) ([]labelMatcher, error) {
+	var matchers []labelMatcher
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return matchers, nil
+		}
+
+		end := strings.IndexAny(s, "=!")
+		if end <= 0 {
+			return nil, fmt.Errorf("expected label matcher at %q", s)
+		}
+		lm := labelMatcher{name: strings.TrimSpace(s[:end])}
+		if !LabelNameRegex.MatchString(lm.name) {
+			return nil, fmt.Errorf("invalid label name %q", lm.name)
+		}
+		s = s[end:]
+		for _, op := range []string{MatchRegexp, MatchNotRegexp, MatchNotEqual, MatchEqual} {
+			if strings.HasPrefix(s, op) {
+				lm.op = op
+				break
+			}
+		}
+		if lm.op == "" {
+			return nil, fmt.Errorf("invalid operator for label %s", lm.name)
+		}
+		s = strings.TrimLeft(s[len(lm.op):], " \t")
+
+		quoted, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("label %s: value must be a quoted string", lm.name)
+		}
+		lm.value, _ = strconv.Unquote(quoted)
+		if lm.op == MatchRegexp || lm.op == MatchNotRegexp {
+			if lm.re, err = regexp.Compile("^(?:" + lm.value + ")$"); err != nil {
+				return nil, fmt.Errorf("label %s: %w", lm.name, err)
+			}
+		}
+		matchers = append(matchers, lm)
+
+		s = strings.TrimLeft(s[len(quoted):], " \t")
+		if s != "" {
+			if s[0] != ',' {
+				return nil, fmt.Errorf("expected ',' or '}' at %q", s)
+			}
+			s = s[1:]
+		}
+	}
+}
+
+// Matches reports whether m satisfies every matcher of the selector
+func (s Selector) Matches(m Metric) bool {
+	for _, lm := range s.matchers {
+		value := m.Labels[lm.name]
+		if lm.name == "__name__" {
+			value = m.Name
+		}
+		if !lm.matches(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchAny reports whether m satisfies at least one of selectors
+func MatchAny(selectors []Selector, m Metric) bool {
+	for _, s := range selectors {
+		if s.Matches(m) {
+			return true
+		}
+	}
+	return false
+}
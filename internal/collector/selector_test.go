@@ -0,0 +1,61 @@
+package collector
+
+import "testing"
+
+func TestParseSelector(t *testing.T) {
+	cpu := Metric{Name: "system_cpu_usage_percent", Labels: map[string]string{"core": "0", "job": "node"}}
+	app := Metric{Name: "http_requests_total", Labels: map[string]string{"job": "api"}}
+
+	tests := []struct {
+		selector string
+		cpu, app bool
+	}{
+		{`system_cpu_usage_percent`, true, false},
+		{`{__name__=~"system_.*"}`, true, false},
+		{`{job="api"}`, false, true},
+		{`{job!="api"}`, true, false},
+		{`{job=~"api|node"}`, true, true},
+		{`{job!~"n.*"}`, false, true},
+		{`system_cpu_usage_percent{core="0", job="node"}`, true, false},
+		{`system_cpu_usage_percent{core="1"}`, false, false},
+		{`{ core = "" }`, false, true}, // Missing labels match as empty
+		{`{job=~"no"}`, false, false},  // Regexes are anchored
+		{`{job="a\"b",}`, false, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.selector, func(t *testing.T) {
+			sel, err := ParseSelector(tc.selector)
+			if err != nil {
+				t.Fatalf("ParseSelector: %v", err)
+			}
+			if got := sel.Matches(cpu); got != tc.cpu {
+				t.Errorf("Matches(cpu) = %v, want %v", got, tc.cpu)
+			}
+			if got := sel.Matches(app); got != tc.app {
+				t.Errorf("Matches(app) = %v, want %v", got, tc.app)
+			}
+		})
+	}
+
+	for _, bad := range []string{``, `{}`, `9cpu`, `cpu{job="x"`, `{job}`, `{job=x}`, `{job=~"("}`, `{job="a" core="b"}`, `{1job="a"}`} {
+		if _, err := ParseSelector(bad); err == nil {
+			t.Errorf("ParseSelector(%q): expected error", bad)
+		}
+	}
+}
+
+func TestMatchAny(t *testing.T) {
+	system, _ := ParseSelector(`{__name__=~"system_.*"}`)
+	api, _ := ParseSelector(`{job="api"}`)
+	selectors := []Selector{system, api}
+
+	if !MatchAny(selectors, Metric{Name: "system_load1"}) {
+		t.Error("expected system_load1 to match the first selector")
+	}
+	if !MatchAny(selectors, Metric{Name: "requests", Labels: map[string]string{"job": "api"}}) {
+		t.Error("expected job=api to match the second selector")
+	}
+	if MatchAny(selectors, Metric{Name: "requests"}) {
+		t.Error("expected no match")
+	}
+}
@@ -3,6 +3,11 @@ package collector
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/shirou/gopsutil/v3/cpu"
@@ -19,6 +24,13 @@ type SystemCollector struct {
 	enableMemory  bool
 	enableDisk    bool
 	enableNetwork bool
+	enableTCP     bool
+	enableDiskIO  bool
+	enableThermal bool
+	enablePower   bool
+
+	mu         sync.Mutex
+	lastDiskIO map[string]disk.IOCountersStat // Previous counters per device, for latency between cycles
 }
 
 // NewSystemCollector creates a new system metrics collector
@@ -31,11 +43,130 @@ func NewSystemCollector(enableCPU, enableMemory, enableDisk, enableNetwork bool)
 	}
 }
 
+// SetTCPStats enables TCP connection state metrics read from /proc/net/tcp{,6}
+func (c *SystemCollector) SetTCPStats(enabled bool) {
+	c.enableTCP = enabled
+}
+
+// SetThermal enables temperature sensor metrics
+func (c *SystemCollector) SetThermal(enabled bool) {
+	c.enableThermal = enabled
+}
+
+// SetPower enables battery and external power supply metrics
+func (c *SystemCollector) SetPower(enabled bool) {
+	c.enablePower = enabled
+}
+
+// SetDiskIO enables disk I/O time and latency metrics (requires disk collection)
+func (c *SystemCollector) SetDiskIO(enabled bool) {
+	c.enableDiskIO = enabled
+}
+
 // Name returns the collector name
 func (c *SystemCollector) Name() string {
 	return "system"
 }
 
+var (
+	hostMetricDescriptors = []MetricDescriptor{
+		{Name: "system_uptime_seconds", Type: "counter", Help: "Seconds since the host booted."},
+		{Name: "system_boot_time_seconds", Type: "gauge", Help: "Host boot time as a Unix timestamp."},
+		{Name: "system_load_1", Type: "gauge", Help: "1-minute load average."},
+		{Name: "system_load_5", Type: "gauge", Help: "5-minute load average."},
+		{Name: "system_load_15", Type: "gauge", Help: "15-minute load average."},
+		{Name: "system_procs_running", Type: "gauge", Help: "Number of running processes."},
+		{Name: "system_procs_blocked", Type: "gauge", Help: "Number of processes blocked on I/O."},
+		{Name: "system_procs_total", Type: "gauge", Help: "Total number of processes."},
+	}
+	cpuMetricDescriptors = []MetricDescriptor{
+		{Name: "system_cpu_usage_percent", Type: "gauge", Help: "CPU usage percentage per core."},
+		{Name: "system_cpu_usage_total_percent", Type: "gauge", Help: "CPU usage percentage averaged across all cores."},
+		{Name: "system_cpu_count", Type: "gauge", Help: "Number of logical CPU cores."},
+	}
+	memoryMetricDescriptors = []MetricDescriptor{
+		{Name: "system_memory_total_bytes", Type: "gauge", Help: "Total physical memory in bytes."},
+		{Name: "system_memory_used_bytes", Type: "gauge", Help: "Used physical memory in bytes."},
+		{Name: "system_memory_free_bytes", Type: "gauge", Help: "Free physical memory in bytes."},
+		{Name: "system_memory_available_bytes", Type: "gauge", Help: "Memory available for new allocations in bytes."},
+		{Name: "system_memory_usage_percent", Type: "gauge", Help: "Physical memory usage percentage."},
+		{Name: "system_swap_total_bytes", Type: "gauge", Help: "Total swap space in bytes."},
+		{Name: "system_swap_used_bytes", Type: "gauge", Help: "Used swap space in bytes."},
+		{Name: "system_swap_usage_percent", Type: "gauge", Help: "Swap usage percentage."},
+		{Name: "system_memory_pressure_some_avg10", Type: "gauge", Help: "Percentage of time over the last 10s at least one task stalled on memory (Linux PSI)."},
+		{Name: "system_memory_pressure_full_avg10", Type: "gauge", Help: "Percentage of time over the last 10s all non-idle tasks stalled on memory (Linux PSI)."},
+	}
+	diskMetricDescriptors = []MetricDescriptor{
+		{Name: "system_disk_total_bytes", Type: "gauge", Help: "Total size of the filesystem in bytes."},
+		{Name: "system_disk_used_bytes", Type: "gauge", Help: "Used space on the filesystem in bytes."},
+		{Name: "system_disk_free_bytes", Type: "gauge", Help: "Free space on the filesystem in bytes."},
+		{Name: "system_disk_usage_percent", Type: "gauge", Help: "Filesystem usage percentage."},
+		{Name: "system_disk_read_bytes_total", Type: "counter", Help: "Total bytes read from the device."},
+		{Name: "system_disk_write_bytes_total", Type: "counter", Help: "Total bytes written to the device."},
+		{Name: "system_disk_read_count_total", Type: "counter", Help: "Total read operations on the device."},
+		{Name: "system_disk_write_count_total", Type: "counter", Help: "Total write operations on the device."},
+	}
+	diskIOMetricDescriptors = []MetricDescriptor{
+		{Name: "system_disk_io_time_seconds_total", Type: "counter", Help: "Total seconds the device spent doing I/O."},
+		{Name: "system_disk_read_time_seconds_total", Type: "counter", Help: "Total seconds spent on reads."},
+		{Name: "system_disk_write_time_seconds_total", Type: "counter", Help: "Total seconds spent on writes."},
+		{Name: "system_disk_read_latency_seconds", Type: "gauge", Help: "Average read latency since the previous collection."},
+		{Name: "system_disk_write_latency_seconds", Type: "gauge", Help: "Average write latency since the previous collection."},
+	}
+	networkMetricDescriptors = []MetricDescriptor{
+		{Name: "system_network_bytes_sent_total", Type: "counter", Help: "Total bytes sent on the interface."},
+		{Name: "system_network_bytes_recv_total", Type: "counter", Help: "Total bytes received on the interface."},
+		{Name: "system_network_packets_sent_total", Type: "counter", Help: "Total packets sent on the interface."},
+		{Name: "system_network_packets_recv_total", Type: "counter", Help: "Total packets received on the interface."},
+		{Name: "system_network_errors_in_total", Type: "counter", Help: "Total receive errors on the interface."},
+		{Name: "system_network_errors_out_total", Type: "counter", Help: "Total transmit errors on the interface."},
+		{Name: "system_network_drop_in_total", Type: "counter", Help: "Total inbound packets dropped on the interface."},
+		{Name: "system_network_drop_out_total", Type: "counter", Help: "Total outbound packets dropped on the interface."},
+	}
+)
+
+var systemMetricHelp = helpIndex(
+	hostMetricDescriptors,
+	cpuMetricDescriptors,
+	memoryMetricDescriptors,
+	diskMetricDescriptors,
+	diskIOMetricDescriptors,
+	networkMetricDescriptors,
+	tcpMetricDescriptors,
+	thermalMetricDescriptors,
+	powerMetricDescriptors,
+)
+
+// Describe returns the metrics emitted with the current set of enabled subsystems
+func (c *SystemCollector) Describe() []MetricDescriptor {
+	descriptors := append([]MetricDescriptor{}, hostMetricDescriptors...)
+	if c.enableCPU {
+		descriptors = append(descriptors, cpuMetricDescriptors...)
+	}
+	if c.enableMemory {
+		descriptors = append(descriptors, memoryMetricDescriptors...)
+	}
+	if c.enableDisk {
+		descriptors = append(descriptors, diskMetricDescriptors...)
+		if c.enableDiskIO {
+			descriptors = append(descriptors, diskIOMetricDescriptors...)
+		}
+	}
+	if c.enableNetwork {
+		descriptors = append(descriptors, networkMetricDescriptors...)
+	}
+	if c.enableTCP {
+		descriptors = append(descriptors, tcpMetricDescriptors...)
+	}
+	if c.enableThermal {
+		descriptors = append(descriptors, thermalMetricDescriptors...)
+	}
+	if c.enablePower {
+		descriptors = append(descriptors, powerMetricDescriptors...)
+	}
+	return descriptors
+}
+
 // Collect gathers system metrics
 func (c *SystemCollector) Collect(ctx context.Context) ([]Metric, error) {
 	metrics := make([]Metric, 0)
@@ -74,6 +205,29 @@ func (c *SystemCollector) Collect(ctx context.Context) ([]Metric, error) {
 		}
 	}
 
+	if c.enableTCP {
+		tcpMetrics, err := c.collectTCPStats()
+		if err == nil {
+			metrics = append(metrics, tcpMetrics...)
+		}
+	}
+
+	if c.enableThermal {
+		thermalMetrics, err := c.collectThermal(ctx)
+		if err == nil {
+			metrics = append(metrics, thermalMetrics...)
+		}
+	}
+
+	if c.enablePower {
+		powerMetrics, err := c.collectPower()
+		if err == nil {
+			metrics = append(metrics, powerMetrics...)
+		}
+	}
+
+	applyHelp(metrics, systemMetricHelp)
+
 	return metrics, nil
 }
 
@@ -164,9 +318,12 @@ func (c *SystemCollector) collectMemory(ctx context.Context) ([]Metric, error) {
 		},
 	)
 
-	// Swap memory
+	// Swap memory; hosts without swap report zero totals
 	swapStat, err := mem.SwapMemoryWithContext(ctx)
 	if err == nil {
+		if swapStat.Total == 0 {
+			swapStat.UsedPercent = 0
+		}
 		metrics = append(metrics,
 			Metric{
 				Name:   "system_swap_total_bytes",
@@ -189,9 +346,68 @@ func (c *SystemCollector) collectMemory(ctx context.Context) ([]Metric, error) {
 		)
 	}
 
+	// Memory pressure is only available on Linux kernels with PSI enabled
+	if pressure, err := readMemoryPressure(); err == nil {
+		metrics = append(metrics, pressure...)
+	}
+
+	return metrics, nil
+}
+
+// readMemoryPressure reads the avg10 values from /proc/pressure/memory, e.g.
+//
+//	some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func readMemoryPressure() ([]Metric, error) {
+	data, err := os.ReadFile(hostProc("pressure", "memory"))
+	if err != nil {
+		return nil, err
+	}
+
+	var metrics []Metric
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || (fields[0] != "some" && fields[0] != "full") {
+			continue
+		}
+		for _, field := range fields[1:] {
+			value, ok := strings.CutPrefix(field, "avg10=")
+			if !ok {
+				continue
+			}
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid PSI value %q: %w", field, err)
+			}
+			metrics = append(metrics, Metric{
+				Name:   "system_memory_pressure_" + fields[0] + "_avg10",
+				Labels: map[string]string{},
+				Value:  v,
+				Type:   "gauge",
+			})
+		}
+	}
 	return metrics, nil
 }
 
+// hostProc joins elem onto the proc root, honoring HOST_PROC like gopsutil does
+func hostProc(elem ...string) string {
+	root := os.Getenv("HOST_PROC")
+	if root == "" {
+		root = "/proc"
+	}
+	return filepath.Join(append([]string{root}, elem...)...)
+}
+
+// hostSys joins elem onto the sysfs root, honoring HOST_SYS like gopsutil does
+func hostSys(elem ...string) string {
+	root := os.Getenv("HOST_SYS")
+	if root == "" {
+		root = "/sys"
+	}
+	return filepath.Join(append([]string{root}, elem...)...)
+}
+
 func (c *SystemCollector) collectDisk(ctx context.Context) ([]Metric, error) {
 	metrics := make([]Metric, 0)
 
@@ -274,11 +490,74 @@ func (c *SystemCollector) collectDisk(ctx context.Context) ([]Metric, error) {
 				},
 			)
 		}
+
+		if c.enableDiskIO {
+			metrics = append(metrics, c.diskIOTimeMetrics(ioStats)...)
+		}
 	}
 
 	return metrics, nil
 }
 
+// diskIOTimeMetrics emits I/O time counters and the average per-operation latency
+// since the previous cycle. Latency is skipped on a device's first cycle and when
+// its counters went backwards (device reset or replaced).
+func (c *SystemCollector) diskIOTimeMetrics(ioStats map[string]disk.IOCountersStat) []Metric {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	metrics := make([]Metric, 0)
+	for device, stats := range ioStats {
+		labels := map[string]string{"device": device}
+		metrics = append(metrics,
+			Metric{
+				Name:   "system_disk_io_time_seconds_total",
+				Labels: labels,
+				Value:  float64(stats.IoTime) / 1000,
+				Type:   "counter",
+			},
+			Metric{
+				Name:   "system_disk_read_time_seconds_total",
+				Labels: labels,
+				Value:  float64(stats.ReadTime) / 1000,
+				Type:   "counter",
+			},
+			Metric{
+				Name:   "system_disk_write_time_seconds_total",
+				Labels: labels,
+				Value:  float64(stats.WriteTime) / 1000,
+				Type:   "counter",
+			},
+		)
+
+		if prev, ok := c.lastDiskIO[device]; ok {
+			if read, ok := averageLatency(prev.ReadTime, stats.ReadTime, prev.ReadCount, stats.ReadCount); ok {
+				metrics = append(metrics, Metric{Name: "system_disk_read_latency_seconds", Labels: labels, Value: read, Type: "gauge"})
+			}
+			if write, ok := averageLatency(prev.WriteTime, stats.WriteTime, prev.WriteCount, stats.WriteCount); ok {
+				metrics = append(metrics, Metric{Name: "system_disk_write_latency_seconds", Labels: labels, Value: write, Type: "gauge"})
+			}
+		}
+	}
+
+	c.lastDiskIO = ioStats
+	return metrics
+}
+
+// averageLatency returns seconds per operation between two counter readings
+// (times in milliseconds). Zero operations yield zero latency; counters that
+// went backwards yield ok=false.
+func averageLatency(prevMs, curMs, prevOps, curOps uint64) (float64, bool) {
+	if curMs < prevMs || curOps < prevOps {
+		return 0, false
+	}
+	ops := curOps - prevOps
+	if ops == 0 {
+		return 0, true
+	}
+	return float64(curMs-prevMs) / 1000 / float64(ops), true
+}
+
 func (c *SystemCollector) collectNetwork(ctx context.Context) ([]Metric, error) {
 	metrics := make([]Metric, 0)
 
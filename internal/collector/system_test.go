@@ -2,8 +2,12 @@ package collector
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/shirou/gopsutil/v3/disk"
 )
 
 func TestNewSystemCollector(t *testing.T) {
@@ -144,3 +148,119 @@ func TestSystemCollector_CollectNothingEnabled(t *testing.T) {
 		}
 	}
 }
+
+func TestSystemCollector_DescribeCoversCollected(t *testing.T) {
+	c := NewSystemCollector(true, true, true, true)
+	c.SetTCPStats(true)
+	c.SetDiskIO(true)
+
+	described := make(map[string]string)
+	for _, d := range c.Describe() {
+		described[d.Name] = d.Type
+	}
+
+	metrics, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	for _, m := range metrics {
+		typ, ok := described[m.Name]
+		if !ok {
+			t.Errorf("metric %s collected but not described", m.Name)
+			continue
+		}
+		if typ != m.Type {
+			t.Errorf("metric %s: described type %s, collected type %s", m.Name, typ, m.Type)
+		}
+	}
+
+	if got := len(NewSystemCollector(false, false, false, false).Describe()); got != len(hostMetricDescriptors) {
+		t.Errorf("expected only host metrics described when nothing enabled, got %d", got)
+	}
+}
+
+func TestReadMemoryPressure(t *testing.T) {
+	procRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(procRoot, "pressure"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := "some avg10=1.50 avg60=0.80 avg300=0.20 total=12345\nfull avg10=0.25 avg60=0.10 avg300=0.00 total=678\n"
+	if err := os.WriteFile(filepath.Join(procRoot, "pressure", "memory"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOST_PROC", procRoot)
+
+	metrics, err := readMemoryPressure()
+	if err != nil {
+		t.Fatalf("readMemoryPressure: %v", err)
+	}
+	got := make(map[string]float64)
+	for _, m := range metrics {
+		got[m.Name] = m.Value
+	}
+	if got["system_memory_pressure_some_avg10"] != 1.5 {
+		t.Errorf("some_avg10: want 1.5, got %v", got["system_memory_pressure_some_avg10"])
+	}
+	if got["system_memory_pressure_full_avg10"] != 0.25 {
+		t.Errorf("full_avg10: want 0.25, got %v", got["system_memory_pressure_full_avg10"])
+	}
+
+	t.Run("no PSI support", func(t *testing.T) {
+		t.Setenv("HOST_PROC", filepath.Join(procRoot, "missing"))
+		if _, err := readMemoryPressure(); err == nil {
+			t.Error("expected error when /proc/pressure/memory is absent")
+		}
+	})
+}
+
+func TestAverageLatency(t *testing.T) {
+	tests := []struct {
+		name                           string
+		prevMs, curMs, prevOps, curOps uint64
+		want                           float64
+		wantOK                         bool
+	}{
+		{"steady", 1000, 1500, 100, 200, 0.005, true},
+		{"idle device", 1000, 1000, 100, 100, 0, true},
+		{"counter reset", 1000, 10, 100, 5, 0, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := averageLatency(tc.prevMs, tc.curMs, tc.prevOps, tc.curOps)
+			if ok != tc.wantOK || got != tc.want {
+				t.Errorf("averageLatency() = (%v, %v), want (%v, %v)", got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestSystemCollector_DiskIOTimeMetrics(t *testing.T) {
+	c := NewSystemCollector(false, false, true, false)
+	c.SetDiskIO(true)
+
+	first := c.diskIOTimeMetrics(map[string]disk.IOCountersStat{
+		"sda": {ReadCount: 10, ReadTime: 100, WriteCount: 20, WriteTime: 400, IoTime: 2500},
+	})
+	for _, m := range first {
+		if strings.HasSuffix(m.Name, "_latency_seconds") {
+			t.Errorf("latency must not be reported on the first cycle, got %s", m.Name)
+		}
+		if m.Name == "system_disk_io_time_seconds_total" && m.Value != 2.5 {
+			t.Errorf("io_time: want 2.5s, got %v", m.Value)
+		}
+	}
+
+	second := c.diskIOTimeMetrics(map[string]disk.IOCountersStat{
+		"sda": {ReadCount: 20, ReadTime: 150, WriteCount: 20, WriteTime: 400, IoTime: 2600},
+	})
+	got := make(map[string]float64)
+	for _, m := range second {
+		got[m.Name] = m.Value
+	}
+	if got["system_disk_read_latency_seconds"] != 0.005 {
+		t.Errorf("read latency: want 0.005, got %v", got["system_disk_read_latency_seconds"])
+	}
+	if v, ok := got["system_disk_write_latency_seconds"]; !ok || v != 0 {
+		t.Errorf("write latency with no writes: want 0, got %v (present=%v)", v, ok)
+	}
+}
@@ -0,0 +1,52 @@
+package collector
+
+// tcpStates maps the hex state column of /proc/net/tcp to its name. The names
+// are also the state labels reported on Windows.
+var tcpStates = map[string]string{
+	"01": "ESTABLISHED",
+	"02": "SYN_SENT",
+	"03": "SYN_RECV",
+	"04": "FIN_WAIT1",
+	"05": "FIN_WAIT2",
+	"06": "TIME_WAIT",
+	"07": "CLOSE",
+	"08": "CLOSE_WAIT",
+	"09": "LAST_ACK",
+	"0A": "LISTEN",
+	"0B": "CLOSING",
+	"0C": "NEW_SYN_RECV",
+}
+
+var tcpMetricDescriptors = []MetricDescriptor{
+	{Name: "system_tcp_connections", Type: "gauge", Help: "TCP sockets (IPv4 and IPv6) by connection state."},
+	{Name: "system_tcp_sockets", Type: "gauge", Help: "Total TCP sockets (IPv4 and IPv6) in any state."},
+}
+
+// collectTCPStats counts TCP sockets (IPv4 and IPv6) per state. Every state is
+// reported, with zero for states without sockets, so the series stay stable.
+func (c *SystemCollector) collectTCPStats() ([]Metric, error) {
+	counts, err := readTCPStateCounts()
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := make([]Metric, 0, len(tcpStates)+1)
+	total := 0
+	for _, state := range tcpStates {
+		metrics = append(metrics, Metric{
+			Name:   "system_tcp_connections",
+			Labels: map[string]string{"state": state},
+			Value:  float64(counts[state]),
+			Type:   "gauge",
+		})
+		total += counts[state]
+	}
+	metrics = append(metrics, Metric{
+		Name:   "system_tcp_sockets",
+		Labels: map[string]string{},
+		Value:  float64(total),
+		Type:   "gauge",
+	})
+
+	return metrics, nil
+}
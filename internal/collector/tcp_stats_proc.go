@@ -0,0 +1,56 @@
+//go:build !windows
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// readTCPStateCounts counts sockets per state from /proc/net/tcp and tcp6. The
+// files are streamed line by line and no socket-to-process lookup is done, so
+// the cost stays linear in the number of sockets.
+func readTCPStateCounts() (map[string]int, error) {
+	counts := make(map[string]int, len(tcpStates))
+	read := 0
+	for _, name := range []string{"tcp", "tcp6"} {
+		f, err := os.Open(hostProc("net", name))
+		if err != nil {
+			continue // tcp6 is absent when IPv6 is disabled
+		}
+		err = countTCPStates(f, counts)
+		_ = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read /proc/net/%s: %w", name, err)
+		}
+		read++
+	}
+	if read == 0 {
+		return nil, fmt.Errorf("no TCP socket tables found under %s", hostProc("net"))
+	}
+	return counts, nil
+}
+
+// countTCPStates adds the sockets listed in a /proc/net/tcp style table to counts
+func countTCPStates(r io.Reader, counts map[string]int) error {
+	scanner := bufio.NewScanner(r)
+	header := true
+	for scanner.Scan() {
+		if header {
+			header = false
+			continue
+		}
+		// sl local_address rem_address st ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		if state, ok := tcpStates[strings.ToUpper(fields[3])]; ok {
+			counts[state]++
+		}
+	}
+	return scanner.Err()
+}
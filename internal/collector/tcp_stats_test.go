@@ -0,0 +1,79 @@
+//go:build !windows
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const procNetTCPSample = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1001 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:1F90 0100007F:D3C2 01 00000000:00000000 00:00000000 00000000     0        0 1002 1 0000000000000000 20 4 30 10 -1
+   2: 0100007F:D3C2 0100007F:1F90 01 00000000:00000000 00:00000000 00000000     0        0 1003 1 0000000000000000 20 4 30 10 -1
+   3: 0100007F:D3C4 0100007F:1F90 06 00000000:00000000 03:00000F9F 00000000     0        0 0 3 0000000000000000
+   4: 0100007F:D3C6 0100007F:1F90 08 00000000:00000000 00:00000000 00000000     0        0 1004 1 0000000000000000 20 4 30 10 -1
+`
+
+func TestCountTCPStates(t *testing.T) {
+	counts := make(map[string]int)
+	if err := countTCPStates(strings.NewReader(procNetTCPSample), counts); err != nil {
+		t.Fatalf("countTCPStates: %v", err)
+	}
+
+	want := map[string]int{"LISTEN": 1, "ESTABLISHED": 2, "TIME_WAIT": 1, "CLOSE_WAIT": 1}
+	for state, n := range want {
+		if counts[state] != n {
+			t.Errorf("%s: want %d, got %d", state, n, counts[state])
+		}
+	}
+	if len(counts) != len(want) {
+		t.Errorf("unexpected states counted: %v", counts)
+	}
+}
+
+func TestSystemCollector_CollectTCPStats(t *testing.T) {
+	procRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(procRoot, "net"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Only tcp, no tcp6: a host with IPv6 disabled must still report.
+	if err := os.WriteFile(filepath.Join(procRoot, "net", "tcp"), []byte(procNetTCPSample), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOST_PROC", procRoot)
+
+	c := NewSystemCollector(false, false, false, false)
+	metrics, err := c.collectTCPStats()
+	if err != nil {
+		t.Fatalf("collectTCPStats: %v", err)
+	}
+
+	byState := make(map[string]float64)
+	var total float64 = -1
+	for _, m := range metrics {
+		switch m.Name {
+		case "system_tcp_connections":
+			byState[m.Labels["state"]] = m.Value
+		case "system_tcp_sockets":
+			total = m.Value
+		}
+	}
+	if total != 5 {
+		t.Errorf("system_tcp_sockets: want 5, got %v", total)
+	}
+	if byState["ESTABLISHED"] != 2 {
+		t.Errorf("ESTABLISHED: want 2, got %v", byState["ESTABLISHED"])
+	}
+	// States with no sockets are still reported as zero for stable series.
+	if v, ok := byState["SYN_SENT"]; !ok || v != 0 {
+		t.Errorf("SYN_SENT: want 0, got %v (present=%v)", v, ok)
+	}
+
+	t.Setenv("HOST_PROC", filepath.Join(procRoot, "missing"))
+	if _, err := c.collectTCPStats(); err == nil {
+		t.Error("expected error when no TCP tables exist")
+	}
+}
@@ -0,0 +1,42 @@
+//go:build windows
+
+package collector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// windowsTCPStates maps the MIB_TCP_STATE names gopsutil reports on Windows to
+// the Linux state names used as labels
+var windowsTCPStates = map[string]string{
+	"ESTABLISHED":  "ESTABLISHED",
+	"SYN_SENT":     "SYN_SENT",
+	"SYN_RECEIVED": "SYN_RECV",
+	"FIN_WAIT_1":   "FIN_WAIT1",
+	"FIN_WAIT_2":   "FIN_WAIT2",
+	"TIME_WAIT":    "TIME_WAIT",
+	"CLOSED":       "CLOSE",
+	"CLOSE_WAIT":   "CLOSE_WAIT",
+	"LAST_ACK":     "LAST_ACK",
+	"LISTEN":       "LISTEN",
+	"CLOSING":      "CLOSING",
+}
+
+// readTCPStateCounts counts sockets per state from the IP helper TCP tables,
+// which gopsutil reads with GetExtendedTcpTable.
+func readTCPStateCounts() (map[string]int, error) {
+	conns, err := net.ConnectionsWithContext(context.Background(), "tcp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TCP tables: %w", err)
+	}
+	counts := make(map[string]int, len(tcpStates))
+	for _, conn := range conns {
+		if state, ok := windowsTCPStates[conn.Status]; ok {
+			counts[state]++
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,30 @@
+//go:build windows
+
+package collector
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestWindowsTCPStatesUseLinuxLabels(t *testing.T) {
+	names := make([]string, 0, len(tcpStates))
+	for _, name := range tcpStates {
+		names = append(names, name)
+	}
+	for winState, label := range windowsTCPStates {
+		if !slices.Contains(names, label) {
+			t.Errorf("%s maps to %q, which is not a reported state", winState, label)
+		}
+	}
+}
+
+func TestCollectTCPStats(t *testing.T) {
+	metrics, err := NewSystemCollector(false, false, false, false).collectTCPStats()
+	if err != nil {
+		t.Fatalf("collectTCPStats: %v", err)
+	}
+	if len(metrics) != len(tcpStates)+1 {
+		t.Errorf("expected one series per state plus the total, got %d", len(metrics))
+	}
+}
@@ -0,0 +1,76 @@
+package collector
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// HostFacts are the values available to config templates such as
+// {{.Hostname}} or {{.Env.DATACENTER}}
+type HostFacts struct {
+	Hostname string
+	OS       string
+	Arch     string
+	Env      map[string]string
+}
+
+// secretEnvMarkers are substrings of environment variable names whose values
+// are kept out of templates
+var secretEnvMarkers = []string{"PASSWORD", "PASSWD", "SECRET", "TOKEN", "API_KEY", "APIKEY", "ACCESS_KEY", "PRIVATE_KEY", "CREDENTIAL"}
+
+// CurrentHostFacts returns the facts of the running host
+func CurrentHostFacts() HostFacts {
+	hostname, _ := os.Hostname()
+	return HostFacts{
+		Hostname: hostname,
+		OS:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+		Env:      templateEnv(os.Environ()),
+	}
+}
+
+// templateEnv returns the variables of environ that templates may use. It
+// leaves out metricsd's own MC_* settings and anything named like a secret,
+// so a plugin definition can't copy credentials into its arguments or labels.
+func templateEnv(environ []string) map[string]string {
+	env := make(map[string]string)
+	for _, kv := range environ {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || strings.HasPrefix(k, "MC_") {
+			continue
+		}
+		upper := strings.ToUpper(k)
+		if slices.ContainsFunc(secretEnvMarkers, func(marker string) bool { return strings.Contains(upper, marker) }) {
+			continue
+		}
+		env[k] = v
+	}
+	return env
+}
+
+// ExpandHostTemplate renders s as a text/template over facts. Strings without
+// "{{" are returned unchanged. Unknown fields and unset environment variables
+// are errors, as is a result containing NUL or a line break, so a fact can't
+// smuggle extra arguments or headers into what it is substituted into.
+func ExpandHostTemplate(s string, facts HostFacts) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+	tmpl, err := template.New("").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %q: %w", s, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, facts); err != nil {
+		return "", fmt.Errorf("template %q: %w", s, err)
+	}
+	out := b.String()
+	if strings.ContainsAny(out, "\x00\r\n") {
+		return "", fmt.Errorf("template %q expands to a value containing NUL or a line break", s)
+	}
+	return out, nil
+}
@@ -0,0 +1,65 @@
+package collector
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestExpandHostTemplate(t *testing.T) {
+	facts := HostFacts{
+		Hostname: "web-1",
+		OS:       "linux",
+		Arch:     "amd64",
+		Env:      map[string]string{"DC": "eu1", "BAD": "a\nb"},
+	}
+
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr string
+	}{
+		{name: "no template", in: "http://{host}/metrics", want: "http://{host}/metrics"},
+		{name: "facts", in: "http://{{.Hostname}}:9100/{{.OS}}-{{.Arch}}", want: "http://web-1:9100/linux-amd64"},
+		{name: "env", in: "--dc={{.Env.DC}}", want: "--dc=eu1"},
+		{name: "unset env", in: "{{.Env.MISSING}}", wantErr: "MISSING"},
+		{name: "unknown field", in: "{{.Region}}", wantErr: "Region"},
+		{name: "parse error", in: "{{.Hostname", wantErr: "invalid template"},
+		{name: "line break", in: "{{.Env.BAD}}", wantErr: "line break"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandHostTemplate(tt.in, facts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateEnv(t *testing.T) {
+	env := templateEnv([]string{
+		"DATACENTER=eu1",
+		"PATH=/usr/bin",
+		"MC_SHIPPER_ENDPOINT=http://x",
+		"MC_SASL_PASSWORD=hunter2",
+		"DD_API_KEY=abc",
+		"AWS_SECRET_ACCESS_KEY=def",
+		"GITHUB_TOKEN=ghi",
+		"db_password=jkl",
+	})
+	want := map[string]string{"DATACENTER": "eu1", "PATH": "/usr/bin"}
+	if !maps.Equal(env, want) {
+		t.Errorf("got %v, want %v", env, want)
+	}
+}
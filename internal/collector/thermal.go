@@ -0,0 +1,40 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+var thermalMetricDescriptors = []MetricDescriptor{
+	{Name: "system_temperature_celsius", Type: "gauge", Help: "Temperature reported by a hardware sensor."},
+}
+
+// collectThermal reads temperature sensors (hwmon, or thermal zones where there
+// is no hwmon, on Linux). Hosts without sensors yield no metrics rather than an
+// error, and unreadable sensors are skipped.
+func (c *SystemCollector) collectThermal(ctx context.Context) ([]Metric, error) {
+	temps, err := host.SensorsTemperaturesWithContext(ctx)
+	if len(temps) == 0 {
+		return nil, err
+	}
+
+	metrics := make([]Metric, 0, len(temps))
+	seen := make(map[string]int, len(temps))
+	for _, t := range temps {
+		// Chips without per-input labels can share a key; keep each as its own series
+		sensor := t.SensorKey
+		seen[sensor]++
+		if n := seen[sensor]; n > 1 {
+			sensor = fmt.Sprintf("%s_%d", sensor, n)
+		}
+		metrics = append(metrics, Metric{
+			Name:   "system_temperature_celsius",
+			Labels: map[string]string{"sensor": sensor},
+			Value:  t.Temperature,
+			Type:   "gauge",
+		})
+	}
+	return metrics, nil
+}
@@ -0,0 +1,86 @@
+//go:build linux
+
+package collector
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSystemCollector_CollectThermal(t *testing.T) {
+	sysRoot := t.TempDir()
+	writeFileTree(t, sysRoot, map[string]string{
+		"class/hwmon/hwmon0/name":        "coretemp\n",
+		"class/hwmon/hwmon0/temp1_input": "54000\n",
+		"class/hwmon/hwmon0/temp1_label": "Package id 0\n",
+		"class/hwmon/hwmon0/temp2_input": "51500\n",
+		"class/hwmon/hwmon0/temp2_label": "Core 0\n",
+		// Two unlabeled inputs on the same chip share a sensor key
+		"class/hwmon/hwmon1/name":        "acpitz\n",
+		"class/hwmon/hwmon1/temp1_input": "40000\n",
+		"class/hwmon/hwmon1/temp2_input": "41000\n",
+	})
+	t.Setenv("HOST_SYS", sysRoot)
+
+	c := NewSystemCollector(false, false, false, false)
+	metrics, err := c.collectThermal(context.Background())
+	if err != nil {
+		t.Fatalf("collectThermal: %v", err)
+	}
+
+	got := make(map[string]float64)
+	for _, m := range metrics {
+		if m.Name != "system_temperature_celsius" {
+			t.Errorf("unexpected metric %s", m.Name)
+		}
+		got[m.Labels["sensor"]] = m.Value
+	}
+	want := map[string]float64{
+		"coretemp_package_id_0": 54,
+		"coretemp_core_0":       51.5,
+		"acpitz":                40,
+		"acpitz_2":              41,
+	}
+	for sensor, v := range want {
+		if got[sensor] != v {
+			t.Errorf("%s: want %v, got %v", sensor, v, got[sensor])
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("unexpected sensors: %v", got)
+	}
+}
+
+func TestSystemCollector_CollectThermalThermalZone(t *testing.T) {
+	sysRoot := t.TempDir()
+	writeFileTree(t, sysRoot, map[string]string{
+		"class/thermal/thermal_zone0/type": "cpu-thermal\n",
+		"class/thermal/thermal_zone0/temp": "47234\n",
+	})
+	t.Setenv("HOST_SYS", sysRoot)
+
+	c := NewSystemCollector(false, false, false, false)
+	metrics, err := c.collectThermal(context.Background())
+	if err != nil {
+		t.Fatalf("collectThermal: %v", err)
+	}
+	if len(metrics) != 1 || metrics[0].Labels["sensor"] != "cpu-thermal" || metrics[0].Value != 47.234 {
+		t.Errorf("unexpected metrics: %+v", metrics)
+	}
+}
+
+func TestSystemCollector_CollectThermalNoSensors(t *testing.T) {
+	t.Setenv("HOST_SYS", t.TempDir())
+
+	c := NewSystemCollector(false, false, false, false)
+	c.SetThermal(true)
+	metrics, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	for _, m := range metrics {
+		if m.Name == "system_temperature_celsius" {
+			t.Errorf("unexpected temperature metric on a host without sensors: %+v", m)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// UnixSocketScheme is the URL scheme of HTTP endpoints listening on a UNIX
+// domain socket, e.g. unix:///var/run/app.sock:/metrics
+const UnixSocketScheme = "unix"
+
+// SplitUnixSocketURL splits a unix:// URL into the socket path and the request
+// URI sent over it. The URI follows the socket path after a colon and
+// defaults to "/"; the query string is kept.
+func SplitUnixSocketURL(u *url.URL) (socket, requestURI string, err error) {
+	if u.Scheme != UnixSocketScheme {
+		return "", "", fmt.Errorf("not a %s:// URL: %s", UnixSocketScheme, u)
+	}
+	if u.Host != "" || !strings.HasPrefix(u.Path, "/") {
+		return "", "", fmt.Errorf("%s:// URL needs an absolute socket path, e.g. unix:///var/run/app.sock:/metrics", UnixSocketScheme)
+	}
+	socket, requestURI, _ = strings.Cut(u.Path, ":")
+	if requestURI == "" {
+		requestURI = "/"
+	}
+	if !strings.HasPrefix(requestURI, "/") {
+		return "", "", fmt.Errorf("request path %q after the socket path must start with /", requestURI)
+	}
+	if u.RawQuery != "" {
+		requestURI += "?" + u.RawQuery
+	}
+	return socket, requestURI, nil
+}
+
+// unixSocketTransport serves unix:// requests. It is registered on
+// transports as a protocol handler, so proxies never apply to it.
+type unixSocketTransport struct {
+	mu         sync.Mutex
+	transports map[string]*http.Transport // Per socket path, to reuse connections
+}
+
+func (u *unixSocketTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	socket, requestURI, err := SplitUnixSocketURL(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	target, err := url.Parse("http://localhost" + requestURI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request path %q: %w", requestURI, err)
+	}
+
+	out := req.Clone(req.Context())
+	out.URL = target
+	out.Host = "localhost"
+	return u.transport(socket).RoundTrip(out)
+}
+
+// transport returns the HTTP transport dialing socket
+func (u *unixSocketTransport) transport(socket string) *http.Transport {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if t, ok := u.transports[socket]; ok {
+		return t
+	}
+	if u.transports == nil {
+		u.transports = make(map[string]*http.Transport)
+	}
+	var dialer net.Dialer
+	t := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socket)
+		},
+		MaxIdleConns:    http.DefaultTransport.(*http.Transport).MaxIdleConns,
+		IdleConnTimeout: http.DefaultTransport.(*http.Transport).IdleConnTimeout,
+	}
+	u.transports[socket] = t
+	return t
+}
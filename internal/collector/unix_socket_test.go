@@ -0,0 +1,95 @@
+package collector
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// serveUnixSocket serves handler on a UNIX socket and returns its path
+func serveUnixSocket(t *testing.T, handler http.Handler) string {
+	t.Helper()
+	// Socket paths are limited to ~100 bytes, too short for t.TempDir on some systems
+	dir, err := os.MkdirTemp("", "metricsd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "app.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("UNIX sockets unavailable: %v", err)
+	}
+	srv := &http.Server{Handler: handler}
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(func() { _ = srv.Close() })
+	return socket
+}
+
+func TestSplitUnixSocketURL(t *testing.T) {
+	tests := []struct {
+		raw        string
+		socket     string
+		requestURI string
+		wantErr    bool
+	}{
+		{"unix:///var/run/app.sock", "/var/run/app.sock", "/", false},
+		{"unix:///var/run/app.sock:/metrics", "/var/run/app.sock", "/metrics", false},
+		{"unix:///var/run/app.sock:/stats?format=prometheus", "/var/run/app.sock", "/stats?format=prometheus", false},
+		{"unix://app.sock", "", "", true},
+		{"unix:///var/run/app.sock:metrics", "", "", true},
+		{"http://localhost/metrics", "", "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.raw, func(t *testing.T) {
+			u, err := url.Parse(tc.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			socket, requestURI, err := SplitUnixSocketURL(u)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("SplitUnixSocketURL error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if socket != tc.socket || requestURI != tc.requestURI {
+				t.Errorf("SplitUnixSocketURL = %q, %q, want %q, %q", socket, requestURI, tc.socket, tc.requestURI)
+			}
+		})
+	}
+}
+
+func TestHTTPCollector_UnixSocket(t *testing.T) {
+	var gotURI, gotHost string
+	socket := serveUnixSocket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURI, gotHost = r.RequestURI, r.Host
+		_, _ = w.Write([]byte("app_requests_total 42\n"))
+	}))
+
+	c := NewHTTPCollector([]EndpointConfig{{Name: "sidecar", URL: "unix://" + socket + ":/metrics?x=1"}}, 5*time.Second)
+	// Proxies must not apply to socket requests
+	c.SetProxy(ProxyConfig{HTTPProxy: "http://127.0.0.1:1"})
+	metrics, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+
+	if gotURI != "/metrics?x=1" || gotHost != "localhost" {
+		t.Errorf("request URI %q host %q, want /metrics?x=1 and localhost", gotURI, gotHost)
+	}
+	var found bool
+	for _, m := range metrics {
+		if m.Name == "app_requests_total" && m.Value == 42 {
+			found = true
+		}
+		if m.Name == "endpoint_up" && m.Value != 1 {
+			t.Errorf("endpoint_up = %v, want 1", m.Value)
+		}
+	}
+	if !found {
+		t.Errorf("app_requests_total not scraped: %+v", metrics)
+	}
+}
@@ -3,9 +3,16 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/0x524A/metricsd/internal/collector"
 )
 
 // Config represents the application configuration
@@ -14,23 +21,81 @@ type Config struct {
 	Collector CollectorConfig  `json:"collector"`
 	Shipper   ShipperConfig    `json:"shipper"`
 	Endpoints []EndpointConfig `json:"endpoints"`
+	// GlobalLabels are added to every shipped metric that doesn't already set them
+	GlobalLabels map[string]string `json:"global_labels,omitempty"`
+	// MetricPrefix is prepended to every shipped metric name, e.g. "acme_"
+	MetricPrefix string `json:"metric_prefix,omitempty"`
+	// MetricTypeOverrides maps collected metric names to "counter" or "gauge",
+	// fixing sources that report the wrong type
+	MetricTypeOverrides map[string]string `json:"metric_type_overrides,omitempty"`
+	// Deduplicate drops repeated series (same name and labels) before shipping, keeping the last value
+	Deduplicate bool `json:"deduplicate,omitempty"`
+	// MaxSeriesPerCycle drops collected series past this count each cycle (0: unlimited)
+	MaxSeriesPerCycle int `json:"max_series_per_cycle,omitempty"`
+	// MaxLabelValueLength cuts longer label values to this many bytes (0: unlimited)
+	MaxLabelValueLength int `json:"max_label_value_length,omitempty"`
+	// MaxFutureSkewSeconds and MaxSampleAgeSeconds reject samples timestamped
+	// further ahead of or behind the wall clock (0: no limit)
+	MaxFutureSkewSeconds int `json:"max_future_skew_seconds,omitempty"`
+	MaxSampleAgeSeconds  int `json:"max_sample_age_seconds,omitempty"`
+	// ClampTimestamps ships rejected samples with the current time instead of dropping them
+	ClampTimestamps bool `json:"clamp_timestamps,omitempty"`
+	// StateFile persists metricsd's own and synthesized counters across restarts (default: not persisted)
+	StateFile string `json:"state_file,omitempty"`
+	// HTTPUserAgent is sent on every scrape, plugin http source and shipper request (default: metricsd/<version>)
+	HTTPUserAgent string `json:"http_user_agent,omitempty"`
+	// Proxies for outbound HTTP; empty values fall back to HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	HTTPProxy  string `json:"http_proxy,omitempty"`
+	HTTPSProxy string `json:"https_proxy,omitempty"`
+	NoProxy    string `json:"no_proxy,omitempty"`
 }
 
 // ServerConfig contains HTTP server settings
 type ServerConfig struct {
-	Host string `json:"host"`
-	Port int    `json:"port"`
+	Host                      string     `json:"host"`
+	Port                      int        `json:"port"`
+	ReadinessFailureThreshold int        `json:"readiness_failure_threshold,omitempty"` // Consecutive ship failures before /readyz reports not-ready (default: 3)
+	EnablePprof               bool       `json:"enable_pprof,omitempty"`                // Mount net/http/pprof under /debug/pprof/
+	EnableAdmin               bool       `json:"enable_admin,omitempty"`                // Mount /admin/collectors/{name}/{enable,disable}; requires auth
+	TLS                       TLSConfig  `json:"tls,omitempty"`                         // Serve HTTPS; ca_file requires client certificates
+	Auth                      AuthConfig `json:"auth,omitempty"`                        // Protect everything but /healthz and /readyz
+}
+
+// AuthConfig holds the credentials accepted by the HTTP server. Either basic
+// auth, a bearer token, or both may be set; an empty config disables auth.
+type AuthConfig struct {
+	Username    string `json:"username,omitempty"`
+	Password    string `json:"password,omitempty"`
+	BearerToken string `json:"bearer_token,omitempty"`
 }
 
 // CollectorConfig contains metrics collection settings
 type CollectorConfig struct {
-	IntervalSeconds int                `json:"interval_seconds"`
-	EnableCPU       bool               `json:"enable_cpu"`
-	EnableMemory    bool               `json:"enable_memory"`
-	EnableDisk      bool               `json:"enable_disk"`
-	EnableNetwork   bool               `json:"enable_network"`
-	EnableGPU       bool               `json:"enable_gpu"`
-	Plugins         PluginSystemConfig `json:"plugins,omitempty"`
+	IntervalSeconds    int                `json:"interval_seconds"`
+	EnableCPU          bool               `json:"enable_cpu"`
+	EnableMemory       bool               `json:"enable_memory"`
+	EnableDisk         bool               `json:"enable_disk"`
+	DiskIO             bool               `json:"disk_io,omitempty"` // Disk I/O time and latency metrics (with enable_disk)
+	EnableNetwork      bool               `json:"enable_network"`
+	EnableGPU          bool               `json:"enable_gpu"`
+	EnableTCPStats     bool               `json:"enable_tcp_stats,omitempty"`     // TCP connection counts by state (Linux only)
+	EnableThermal      bool               `json:"enable_thermal,omitempty"`       // Temperature sensors (hwmon or thermal zones)
+	EnablePower        bool               `json:"enable_power,omitempty"`         // Battery charge and external power state
+	EnableCgroup       bool               `json:"enable_cgroup,omitempty"`        // CPU and memory usage and limits of metricsd's own cgroup
+	GPUPerProcess      bool               `json:"gpu_per_process,omitempty"`      // Per-PID GPU memory metrics (high cardinality)
+	Processes          []string           `json:"processes,omitempty"`            // Regex patterns of process names to monitor
+	HTTPMaxConcurrency int                `json:"http_max_concurrency,omitempty"` // Endpoints scraped at once (default: 10)
+	FailureThreshold   int                `json:"failure_threshold,omitempty"`    // Consecutive failures before a collector is skipped (default: 3)
+	Plugins            PluginSystemConfig `json:"plugins,omitempty"`
+
+	// CollectionTimeoutSeconds is the deadline for one collect-and-ship cycle (default: the interval)
+	CollectionTimeoutSeconds int `json:"collection_timeout_seconds,omitempty"`
+
+	// IdleIntervalSeconds replaces the interval while on battery
+	// (IdleOnBattery) or after IdleAfterShipFailures failed ships in a row
+	IdleIntervalSeconds   int  `json:"idle_interval_seconds,omitempty"`
+	IdleOnBattery         bool `json:"idle_on_battery,omitempty"`
+	IdleAfterShipFailures int  `json:"idle_after_ship_failures,omitempty"`
 }
 
 // GoPluginEntry configures a compile-time registered Go plugin.
@@ -45,20 +110,66 @@ type PluginSystemConfig struct {
 	PluginsDir            string          `json:"plugins_dir"`
 	DefaultTimeoutSeconds int             `json:"default_timeout_seconds,omitempty"`
 	ValidateOnStartup     bool            `json:"validate_on_startup,omitempty"`
+	StrictPlugins         bool            `json:"strict_plugins,omitempty"`       // Exit at startup if any plugin fails to load instead of skipping it
+	CommandPolicy         string          `json:"command_policy,omitempty"`       // "any" (default) or "allowlist"
+	AllowedCommands       []string        `json:"allowed_commands,omitempty"`     // Executables permitted by the allowlist policy
+	MaxConcurrency        int             `json:"max_concurrency,omitempty"`      // Max plugins run at once (default: GOMAXPROCS)
+	MinIntervalSeconds    int             `json:"min_interval_seconds,omitempty"` // Plugin intervals below this are raised to it (default: no floor)
+	MaxOutputBytes        int64           `json:"max_output_bytes,omitempty"`     // Output limit of plugins that don't set their own (default: 5MB)
 	GoPlugins             []GoPluginEntry `json:"go_plugins,omitempty"`
 }
 
 // ShipperConfig contains remote endpoint settings
 type ShipperConfig struct {
-	Type     string        `json:"type"` // "prometheus_remote_write", "http_json", "json_file", or "splunk_hec"
+	Type     string        `json:"type"` // "prometheus_remote_write", "http_json", "json_file", "splunk_hec", "otlp_grpc", "graphite", "cloudwatch", "kafka", "datadog", or "pushgateway"
 	Endpoint string        `json:"endpoint"`
 	TLS      TLSConfig     `json:"tls"`
 	Timeout  time.Duration `json:"timeout"`
+	// Match limits the shipper to metrics matching any of these series
+	// selectors, e.g. `{__name__=~"system_.*"}` (default: everything)
+	Match []string `json:"match,omitempty"`
+	// MaxRequestsPerSecond caps outbound shipping requests, retries included (default: unlimited)
+	MaxRequestsPerSecond float64 `json:"max_requests_per_second,omitempty"`
+	// Connection pooling of HTTP shippers (defaults: 100 idle, unlimited per host, 90s)
+	MaxIdleConns           int `json:"max_idle_conns,omitempty"`
+	MaxConnsPerHost        int `json:"max_conns_per_host,omitempty"`
+	IdleConnTimeoutSeconds int `json:"idle_conn_timeout_seconds,omitempty"`
+	// Remote write specific settings
+	Compression       string `json:"compression,omitempty"`          // "snappy" (default) or "none"; http_json: "gzip" or "none" (default)
+	MaxSamplesPerSend int    `json:"max_samples_per_send,omitempty"` // Split larger batches into several requests (default: 2000)
+	// OTLP specific settings
+	Temporality string `json:"temporality,omitempty"` // Sum temporality: "cumulative" (default) or "delta"
+	// HTTP JSON specific settings; header values support ${ENV} expansion
+	Headers      map[string]string `json:"headers,omitempty"`
+	APIKey       string            `json:"api_key,omitempty"`
+	APIKeyHeader string            `json:"api_key_header,omitempty"` // Header carrying api_key (default: X-API-Key)
+	JSONSchema   string            `json:"json_schema,omitempty"`    // "nested" (default) or "flat"
+	JSONTemplate string            `json:"json_template,omitempty"`  // Go text/template for the body; wins over json_schema
 	// File shipper specific settings
 	File FileShipperConfig `json:"file,omitempty"`
 	// Splunk HEC specific settings
 	HECToken     string `json:"hec_token,omitempty"`
 	DebugLogFile string `json:"debug_log_file,omitempty"` // Optional file path to log payloads for debugging
+	// Graphite specific settings
+	GraphitePrefix string `json:"graphite_prefix,omitempty"` // Prepended to every metric path, e.g. "acme.prod"
+	// CloudWatch specific settings; endpoint is an optional service URL override
+	CloudWatchRegion    string `json:"cloudwatch_region,omitempty"` // Defaults to AWS_REGION / shared config
+	CloudWatchNamespace string `json:"cloudwatch_namespace,omitempty"`
+	// Kafka specific settings; TLS comes from the tls section
+	KafkaBrokers  []string `json:"kafka_brokers,omitempty"`
+	KafkaTopic    string   `json:"kafka_topic,omitempty"`
+	KafkaFormat   string   `json:"kafka_format,omitempty"`   // "json" (default) or "protobuf" (OTLP)
+	KafkaAsync    bool     `json:"kafka_async,omitempty"`    // Don't wait for acks; failures are reported on the next cycle
+	SASLMechanism string   `json:"sasl_mechanism,omitempty"` // "plain", "scram-sha-256" or "scram-sha-512"
+	SASLUsername  string   `json:"sasl_username,omitempty"`
+	SASLPassword  string   `json:"sasl_password,omitempty"`
+	// Datadog specific settings; api_key (with ${ENV} expansion) or DD_API_KEY holds the key
+	DatadogSite string `json:"datadog_site,omitempty"` // e.g. "datadoghq.eu" (default: datadoghq.com)
+	// Pushgateway specific settings; endpoint is the Pushgateway base URL
+	PushgatewayJob      string            `json:"pushgateway_job,omitempty"`      // Job of the grouping key (default: metricsd)
+	PushgatewayGrouping map[string]string `json:"pushgateway_grouping,omitempty"` // Further grouping key labels, e.g. {"instance": "web-1"}
+	PushgatewayMethod   string            `json:"pushgateway_method,omitempty"`   // "put" (default) replaces the group, "post" only same-named metrics
+	DeleteOnShutdown    bool              `json:"delete_on_shutdown,omitempty"`   // Delete the group when metricsd stops
 }
 
 // FileShipperConfig contains file shipper settings for Splunk Universal Forwarder integration
@@ -80,17 +191,102 @@ type TLSConfig struct {
 
 // EndpointConfig represents an application endpoint to scrape
 type EndpointConfig struct {
-	Name string `json:"name"`
-	URL  string `json:"url"`
+	Name    string            `json:"name"`
+	URL     string            `json:"url"`
+	URLs    []string          `json:"urls,omitempty"`    // Further URLs whose metrics merge into this endpoint
+	Method  string            `json:"method,omitempty"`  // HTTP method (default: GET)
+	Body    string            `json:"body,omitempty"`    // Optional request body, e.g. a JSON query
+	Headers map[string]string `json:"headers,omitempty"` // Optional request headers
+	Labels  map[string]string `json:"labels,omitempty"`  // Static labels added to every scraped metric
+	// Regexes matched against full metric names; the denylist wins over the allowlist
+	MetricAllowlist []string `json:"metric_allowlist,omitempty"`
+	MetricDenylist  []string `json:"metric_denylist,omitempty"`
+	// Renames metrics after the allow/deny lists; the first matching rule wins
+	NameRewrite     []NameRewriteRule `json:"name_rewrite,omitempty"`
+	FollowRedirects *bool             `json:"follow_redirects,omitempty"` // Follow 3xx responses (default: true)
+	MaxRedirects    int               `json:"max_redirects,omitempty"`    // Redirects followed before failing (default: 10)
+	AcceptStatus    []int             `json:"accept_status,omitempty"`    // Status codes treated as success (default: [200])
+	Exemplars       bool              `json:"exemplars,omitempty"`        // Keep OpenMetrics exemplars for remote write
+	// Discovery set to "dns" scrapes every address dns_name (default: the URL host) resolves to
+	Discovery              string `json:"discovery,omitempty"`
+	DNSName                string `json:"dns_name,omitempty"`
+	DNSType                string `json:"dns_type,omitempty"`                 // "a" (default; port from the URL) or "srv"
+	RefreshIntervalSeconds int    `json:"refresh_interval_seconds,omitempty"` // How often to re-resolve (default: 30)
+	// Retries of a scrape failing with a request error, 429 or 5xx, waiting
+	// retry_backoff_seconds (default 0.5) doubled after each attempt
+	Retries             int     `json:"retries,omitempty"`
+	RetryBackoffSeconds float64 `json:"retry_backoff_seconds,omitempty"`
+	// ParseRegex replaces format auto-detection: each match is a metric from the
+	// "value" group, named by the optional "name" group, labelled by the others
+	ParseRegex string `json:"parse_regex,omitempty"`
+	// CounterContinuity corrects counter resets of restarting targets, keeping
+	// per-series state between scrapes
+	CounterContinuity bool `json:"counter_continuity,omitempty"`
 }
 
-// Load reads configuration from a JSON file and applies environment variable overrides
-func Load(configPath string) (*Config, error) {
-	// Read config file
+// NameRewriteRule maps metric names matching Match (anchored) to Replacement,
+// which may use $1 / ${name} capture group references
+type NameRewriteRule struct {
+	Match       string `json:"match"`
+	Replacement string `json:"replacement"`
+}
+
+// readConfig returns the raw config body for Load
+func readConfig(configPath string) ([]byte, error) {
+	if val := os.Getenv(ConfigJSONEnv); val != "" {
+		return []byte(val), nil
+	}
+	if configPath == StdinPath {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config from stdin: %w", err)
+		}
+		return data, nil
+	}
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
+	return data, nil
+}
+
+// Defaults applied by Load to fields left unset
+const (
+	DefaultServerHost     = "0.0.0.0"
+	DefaultServerPort     = 8080
+	DefaultShipperTimeout = 30 * time.Second
+)
+
+// applyDefaults fills unset server host/port and shipper timeout
+func applyDefaults(cfg *Config) {
+	if cfg.Server.Host == "" {
+		cfg.Server.Host = DefaultServerHost
+	}
+	if cfg.Server.Port == 0 {
+		cfg.Server.Port = DefaultServerPort
+	}
+	if cfg.Shipper.Timeout == 0 {
+		cfg.Shipper.Timeout = DefaultShipperTimeout
+	}
+}
+
+// Alternative config sources accepted by Load
+const (
+	ConfigJSONEnv = "MC_CONFIG_JSON" // Env var holding the full JSON config; wins over the path
+	StdinPath     = "-"              // Path that reads the JSON config from stdin
+)
+
+// stdin is where Load reads from for StdinPath; replaced in tests
+var stdin io.Reader = os.Stdin
+
+// Load reads configuration from a JSON file and applies environment variable overrides.
+// The config body comes from MC_CONFIG_JSON when set, from stdin when configPath is "-",
+// and from the file at configPath otherwise.
+func Load(configPath string) (*Config, error) {
+	data, err := readConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
 
 	var cfg Config
 	if err := json.Unmarshal(data, &cfg); err != nil {
@@ -100,6 +296,9 @@ func Load(configPath string) (*Config, error) {
 	// Apply environment variable overrides
 	applyEnvOverrides(&cfg)
 
+	// Fill in defaults for fields still unset
+	applyDefaults(&cfg)
+
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
@@ -123,6 +322,26 @@ func applyEnvOverrides(cfg *Config) {
 			cfg.Collector.IntervalSeconds = interval
 		}
 	}
+	// Collector toggles
+	for env, flag := range map[string]*bool{
+		"MC_COLLECTOR_ENABLE_CPU":       &cfg.Collector.EnableCPU,
+		"MC_COLLECTOR_ENABLE_MEMORY":    &cfg.Collector.EnableMemory,
+		"MC_COLLECTOR_ENABLE_DISK":      &cfg.Collector.EnableDisk,
+		"MC_COLLECTOR_DISK_IO":          &cfg.Collector.DiskIO,
+		"MC_COLLECTOR_ENABLE_NETWORK":   &cfg.Collector.EnableNetwork,
+		"MC_COLLECTOR_ENABLE_GPU":       &cfg.Collector.EnableGPU,
+		"MC_COLLECTOR_ENABLE_TCP_STATS": &cfg.Collector.EnableTCPStats,
+		"MC_COLLECTOR_ENABLE_CGROUP":    &cfg.Collector.EnableCgroup,
+		"MC_COLLECTOR_ENABLE_THERMAL":   &cfg.Collector.EnableThermal,
+		"MC_COLLECTOR_ENABLE_POWER":     &cfg.Collector.EnablePower,
+		"MC_COLLECTOR_GPU_PER_PROCESS":  &cfg.Collector.GPUPerProcess,
+	} {
+		if val := os.Getenv(env); val != "" {
+			if enabled, err := strconv.ParseBool(val); err == nil {
+				*flag = enabled
+			}
+		}
+	}
 	if val := os.Getenv("MC_SHIPPER_TYPE"); val != "" {
 		cfg.Shipper.Type = val
 	}
@@ -147,6 +366,16 @@ func applyEnvOverrides(cfg *Config) {
 	if val := os.Getenv("MC_HEC_TOKEN"); val != "" {
 		cfg.Shipper.HECToken = val
 	}
+	if val := os.Getenv("MC_SASL_PASSWORD"); val != "" {
+		cfg.Shipper.SASLPassword = val
+	}
+	// Server auth secrets, kept out of the config file
+	if val := os.Getenv("MC_SERVER_PASSWORD"); val != "" {
+		cfg.Server.Auth.Password = val
+	}
+	if val := os.Getenv("MC_SERVER_BEARER_TOKEN"); val != "" {
+		cfg.Server.Auth.BearerToken = val
+	}
 	// Plugin configuration environment variable overrides
 	if val := os.Getenv("MC_PLUGINS_ENABLED"); val != "" {
 		if enabled, err := strconv.ParseBool(val); err == nil {
@@ -185,6 +414,58 @@ func applyEnvOverrides(cfg *Config) {
 	}
 }
 
+// redactedValue replaces secrets in Redacted output
+const redactedValue = "<redacted>"
+
+// secretHeaderMarkers are substrings of header names, lower case, whose values
+// Redacted replaces
+var secretHeaderMarkers = []string{"authorization", "cookie", "token", "key", "secret", "password", "auth"}
+
+// redactHeaders returns a copy of headers with auth-like values replaced
+func redactHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(headers))
+	for name, value := range headers {
+		lower := strings.ToLower(name)
+		if slices.ContainsFunc(secretHeaderMarkers, func(marker string) bool { return strings.Contains(lower, marker) }) {
+			value = redactedValue
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+// Redacted returns a copy of the config with secret values (HEC token, API key,
+// SASL password, auth-like headers) replaced, for printing. File paths such as
+// TLS key paths are kept.
+func (c Config) Redacted() Config {
+	if c.Shipper.HECToken != "" {
+		c.Shipper.HECToken = redactedValue
+	}
+	if c.Shipper.APIKey != "" {
+		c.Shipper.APIKey = redactedValue
+	}
+	if c.Shipper.SASLPassword != "" {
+		c.Shipper.SASLPassword = redactedValue
+	}
+	if c.Server.Auth.Password != "" {
+		c.Server.Auth.Password = redactedValue
+	}
+	if c.Server.Auth.BearerToken != "" {
+		c.Server.Auth.BearerToken = redactedValue
+	}
+	c.Shipper.Headers = redactHeaders(c.Shipper.Headers)
+	if c.Endpoints != nil {
+		c.Endpoints = slices.Clone(c.Endpoints)
+		for i := range c.Endpoints {
+			c.Endpoints[i].Headers = redactHeaders(c.Endpoints[i].Headers)
+		}
+	}
+	return c
+}
+
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
 	if c.Server.Port <= 0 || c.Server.Port > 65535 {
@@ -194,9 +475,23 @@ func (c *Config) Validate() error {
 	if c.Collector.IntervalSeconds <= 0 {
 		return fmt.Errorf("collector interval must be positive")
 	}
+	if c.Collector.CollectionTimeoutSeconds < 0 {
+		return fmt.Errorf("collector.collection_timeout_seconds must not be negative")
+	}
+	if c.Collector.IdleAfterShipFailures < 0 {
+		return fmt.Errorf("collector.idle_after_ship_failures must not be negative")
+	}
+	if idle := c.Collector.IdleIntervalSeconds; idle != 0 {
+		if idle <= c.Collector.IntervalSeconds {
+			return fmt.Errorf("collector.idle_interval_seconds (%d) must be longer than collector.interval_seconds (%d)", idle, c.Collector.IntervalSeconds)
+		}
+		if !c.Collector.IdleOnBattery && c.Collector.IdleAfterShipFailures == 0 {
+			return fmt.Errorf("collector.idle_interval_seconds requires idle_on_battery or idle_after_ship_failures")
+		}
+	}
 
-	if c.Shipper.Type != "prometheus_remote_write" && c.Shipper.Type != "http_json" && c.Shipper.Type != "json_file" && c.Shipper.Type != "splunk_hec" {
-		return fmt.Errorf("invalid shipper type: %s (must be 'prometheus_remote_write', 'http_json', 'json_file', or 'splunk_hec')", c.Shipper.Type)
+	if c.Shipper.Type != "prometheus_remote_write" && c.Shipper.Type != "http_json" && c.Shipper.Type != "json_file" && c.Shipper.Type != "splunk_hec" && c.Shipper.Type != "otlp_grpc" && c.Shipper.Type != "graphite" && c.Shipper.Type != "cloudwatch" && c.Shipper.Type != "kafka" && c.Shipper.Type != "datadog" && c.Shipper.Type != "pushgateway" {
+		return fmt.Errorf("invalid shipper type: %s (must be 'prometheus_remote_write', 'http_json', 'json_file', 'splunk_hec', 'otlp_grpc', 'graphite', 'cloudwatch', 'kafka', 'datadog', or 'pushgateway')", c.Shipper.Type)
 	}
 
 	// Validate based on shipper type
@@ -208,6 +503,21 @@ func (c *Config) Validate() error {
 		if c.Shipper.File.Format != "" && c.Shipper.File.Format != "single" && c.Shipper.File.Format != "multi" {
 			return fmt.Errorf("invalid file format: %s (must be 'single' or 'multi')", c.Shipper.File.Format)
 		}
+	} else if c.Shipper.Type == "kafka" {
+		if len(c.Shipper.KafkaBrokers) == 0 || c.Shipper.KafkaTopic == "" {
+			return fmt.Errorf("kafka shipper requires kafka_brokers and kafka_topic")
+		}
+		if c.Shipper.KafkaFormat != "" && c.Shipper.KafkaFormat != "json" && c.Shipper.KafkaFormat != "protobuf" {
+			return fmt.Errorf("invalid kafka_format: %s (must be 'json' or 'protobuf')", c.Shipper.KafkaFormat)
+		}
+	} else if c.Shipper.Type == "cloudwatch" {
+		if c.Shipper.CloudWatchNamespace == "" {
+			return fmt.Errorf("cloudwatch shipper requires cloudwatch_namespace")
+		}
+	} else if c.Shipper.Type == "datadog" {
+		if c.Shipper.APIKey == "" && os.Getenv("DD_API_KEY") == "" {
+			return fmt.Errorf("datadog shipper requires api_key or the DD_API_KEY environment variable")
+		}
 	} else {
 		if c.Shipper.Endpoint == "" {
 			return fmt.Errorf("shipper endpoint is required")
@@ -216,6 +526,138 @@ func (c *Config) Validate() error {
 		if c.Shipper.Type == "splunk_hec" && c.Shipper.HECToken == "" {
 			return fmt.Errorf("splunk_hec shipper requires a HEC token")
 		}
+		if c.Shipper.Type == "pushgateway" {
+			if m := strings.ToLower(c.Shipper.PushgatewayMethod); m != "" && m != "put" && m != "post" {
+				return fmt.Errorf("invalid pushgateway_method: %s (must be 'put' or 'post')", c.Shipper.PushgatewayMethod)
+			}
+			for name := range c.Shipper.PushgatewayGrouping {
+				if name == "job" {
+					return fmt.Errorf("pushgateway_grouping must not contain job; use pushgateway_job")
+				}
+				if err := ValidateLabelName(name); err != nil {
+					return fmt.Errorf("invalid pushgateway_grouping: %w", err)
+				}
+			}
+		}
+	}
+
+	for _, ep := range c.Endpoints {
+		if ep.URL == "" && len(ep.URLs) == 0 {
+			return fmt.Errorf("endpoint %s requires url or urls", ep.Name)
+		}
+		if ep.Discovery != "" && len(ep.URLs) > 0 {
+			return fmt.Errorf("endpoint %s: urls cannot be combined with discovery", ep.Name)
+		}
+		if ep.Discovery != "" && ep.Discovery != "dns" {
+			return fmt.Errorf("invalid discovery %q for endpoint %s (must be 'dns')", ep.Discovery, ep.Name)
+		}
+		if ep.Discovery == "dns" {
+			if t := strings.ToLower(ep.DNSType); t != "" && t != "a" && t != "srv" {
+				return fmt.Errorf("invalid dns_type %q for endpoint %s (must be 'a' or 'srv')", ep.DNSType, ep.Name)
+			}
+			// Templated URLs ({{.Hostname}} etc.) only parse once expanded at startup
+			if u, err := url.Parse(ep.URL); !strings.Contains(ep.URL, "{{") && (err != nil || u.Host == "") {
+				return fmt.Errorf("endpoint %s with dns discovery requires an absolute url", ep.Name)
+			}
+		}
+		for _, code := range ep.AcceptStatus {
+			if code < 100 || code > 599 {
+				return fmt.Errorf("invalid accept_status code %d for endpoint %s", code, ep.Name)
+			}
+		}
+		for _, pattern := range append(append([]string{}, ep.MetricAllowlist...), ep.MetricDenylist...) {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("invalid metric filter pattern %q for endpoint %s: %w", pattern, ep.Name, err)
+			}
+		}
+		for _, rule := range ep.NameRewrite {
+			if _, err := regexp.Compile(rule.Match); err != nil || rule.Match == "" {
+				return fmt.Errorf("invalid name_rewrite match %q for endpoint %s", rule.Match, ep.Name)
+			}
+			// Replacements with group references are checked per metric at scrape time
+			if !strings.Contains(rule.Replacement, "$") && !collector.MetricNameRegex.MatchString(rule.Replacement) {
+				return fmt.Errorf("name_rewrite replacement %q for endpoint %s is not a valid metric name", rule.Replacement, ep.Name)
+			}
+		}
+		if ep.Retries < 0 || ep.RetryBackoffSeconds < 0 {
+			return fmt.Errorf("retries and retry_backoff_seconds for endpoint %s must not be negative", ep.Name)
+		}
+		if ep.ParseRegex != "" {
+			if err := collector.ValidateParseRegex(ep.ParseRegex); err != nil {
+				return fmt.Errorf("invalid parse_regex for endpoint %s: %w", ep.Name, err)
+			}
+		}
+	}
+
+	for _, pattern := range c.Collector.Processes {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid collector.processes pattern %q: %w", pattern, err)
+		}
+	}
+
+	if c.Shipper.MaxRequestsPerSecond < 0 {
+		return fmt.Errorf("shipper max_requests_per_second must not be negative")
+	}
+	if c.Shipper.MaxIdleConns < 0 || c.Shipper.MaxConnsPerHost < 0 || c.Shipper.IdleConnTimeoutSeconds < 0 {
+		return fmt.Errorf("shipper max_idle_conns, max_conns_per_host and idle_conn_timeout_seconds must not be negative")
+	}
+
+	if c.Shipper.JSONSchema != "" && c.Shipper.JSONSchema != "nested" && c.Shipper.JSONSchema != "flat" {
+		return fmt.Errorf("invalid shipper json_schema: %s (must be 'nested' or 'flat')", c.Shipper.JSONSchema)
+	}
+
+	if c.Shipper.Compression != "" && c.Shipper.Compression != "snappy" && c.Shipper.Compression != "none" && c.Shipper.Compression != "gzip" {
+		return fmt.Errorf("invalid shipper compression: %s (must be 'snappy', 'gzip' or 'none')", c.Shipper.Compression)
+	}
+	if c.Shipper.Compression == "gzip" && c.Shipper.Type != "http_json" {
+		return fmt.Errorf("gzip compression is only supported by the http_json shipper")
+	}
+	if c.Shipper.Compression == "snappy" && c.Shipper.Type == "http_json" {
+		return fmt.Errorf("snappy compression is not supported by the http_json shipper (use 'gzip')")
+	}
+
+	if c.Shipper.Temporality != "" && c.Shipper.Temporality != "cumulative" && c.Shipper.Temporality != "delta" {
+		return fmt.Errorf("invalid shipper temporality: %s (must be 'cumulative' or 'delta')", c.Shipper.Temporality)
+	}
+	if c.Shipper.Temporality == "delta" && c.Shipper.Type != "otlp_grpc" {
+		return fmt.Errorf("delta temporality is only supported by the otlp_grpc shipper")
+	}
+
+	// A prefix that is itself a legal name keeps any legal metric name legal
+	if c.MetricPrefix != "" && !collector.MetricNameRegex.MatchString(c.MetricPrefix) {
+		return fmt.Errorf("invalid metric_prefix %q: must match %s", c.MetricPrefix, collector.MetricNameRegex.String())
+	}
+
+	if strings.ContainsAny(c.HTTPUserAgent, "\r\n") {
+		return fmt.Errorf("http_user_agent must not contain line breaks")
+	}
+
+	for name, proxy := range map[string]string{"http_proxy": c.HTTPProxy, "https_proxy": c.HTTPSProxy} {
+		if proxy == "" {
+			continue
+		}
+		if u, err := url.Parse(proxy); err != nil || u.Host == "" {
+			return fmt.Errorf("invalid %s %q: must be a URL such as http://proxy:3128", name, proxy)
+		}
+	}
+
+	if c.MaxSeriesPerCycle < 0 || c.MaxLabelValueLength < 0 {
+		return fmt.Errorf("max_series_per_cycle and max_label_value_length cannot be negative")
+	}
+	if c.MaxFutureSkewSeconds < 0 || c.MaxSampleAgeSeconds < 0 {
+		return fmt.Errorf("max_future_skew_seconds and max_sample_age_seconds cannot be negative")
+	}
+
+	for name, typ := range c.MetricTypeOverrides {
+		if typ != "counter" && typ != "gauge" {
+			return fmt.Errorf("invalid metric_type_overrides type %q for %s (must be 'counter' or 'gauge')", typ, name)
+		}
+	}
+
+	for key := range c.GlobalLabels {
+		if err := ValidateLabelName(key); err != nil {
+			return fmt.Errorf("invalid global_labels key: %w", err)
+		}
 	}
 
 	if c.Shipper.TLS.Enabled {
@@ -224,6 +666,30 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if c.Server.TLS.Enabled {
+		if c.Server.TLS.CertFile == "" || c.Server.TLS.KeyFile == "" {
+			return fmt.Errorf("server TLS cert and key files are required when server TLS is enabled")
+		}
+	}
+
+	if (c.Server.Auth.Username == "") != (c.Server.Auth.Password == "") {
+		return fmt.Errorf("server auth requires both username and password")
+	}
+	if c.Server.EnableAdmin && c.Server.Auth.Username == "" && c.Server.Auth.BearerToken == "" {
+		return fmt.Errorf("server enable_admin requires server auth to be configured")
+	}
+
+	if c.Collector.Plugins.MinIntervalSeconds < 0 {
+		return fmt.Errorf("plugins min_interval_seconds cannot be negative")
+	}
+	if c.Collector.Plugins.MaxOutputBytes < 0 {
+		return fmt.Errorf("plugins max_output_bytes cannot be negative")
+	}
+
+	if p := c.Collector.Plugins.CommandPolicy; p != "" && p != "any" && p != "allowlist" {
+		return fmt.Errorf("invalid plugins command_policy: %s (must be 'any' or 'allowlist')", p)
+	}
+
 	// Apply plugin configuration defaults
 	if c.Collector.Plugins.Enabled {
 		if c.Collector.Plugins.PluginsDir == "" {
@@ -237,6 +703,35 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// ValidateLabelName checks a label name against the Prometheus label grammar
+// and rejects names reserved for internal use (those starting with "__").
+func ValidateLabelName(name string) error {
+	if !collector.LabelNameRegex.MatchString(name) {
+		return fmt.Errorf("label name %q must match %s", name, collector.LabelNameRegex.String())
+	}
+	if strings.HasPrefix(name, "__") {
+		return fmt.Errorf("label name %q is reserved (names starting with __ are internal)", name)
+	}
+	return nil
+}
+
+// RequestHeaders returns the configured shipper headers plus the API key header,
+// with ${ENV} references in values expanded.
+func (s *ShipperConfig) RequestHeaders() map[string]string {
+	headers := make(map[string]string, len(s.Headers)+1)
+	for k, v := range s.Headers {
+		headers[k] = os.ExpandEnv(v)
+	}
+	if s.APIKey != "" {
+		name := s.APIKeyHeader
+		if name == "" {
+			name = "X-API-Key"
+		}
+		headers[name] = os.ExpandEnv(s.APIKey)
+	}
+	return headers
+}
+
 // GetCollectionInterval returns the collection interval as a duration
 func (c *Config) GetCollectionInterval() time.Duration {
 	return time.Duration(c.Collector.IntervalSeconds) * time.Second
@@ -0,0 +1,145 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+// RotatingFile is an io.Writer for log output that rotates the file once it
+// reaches a size limit. Write failures are counted instead of returned so a
+// full or unavailable disk never takes the daemon down.
+type RotatingFile struct {
+	path         string
+	maxSizeBytes int64
+	maxFiles     int
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	failures atomic.Uint64
+}
+
+// NewRotatingFile opens path for appending, creating its directory if needed.
+// maxSizeMB and maxFiles default to 100 and 5 when <= 0.
+func NewRotatingFile(path string, maxSizeMB, maxFiles int) (*RotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	if maxSizeMB <= 0 {
+		maxSizeMB = 100
+	}
+	if maxFiles <= 0 {
+		maxFiles = 5
+	}
+
+	r := &RotatingFile{
+		path:         path,
+		maxSizeBytes: int64(maxSizeMB) * 1024 * 1024,
+		maxFiles:     maxFiles,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file %s: %w", r.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file %s: %w", r.path, err)
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// Write appends p to the log file, rotating first if p would exceed the size
+// limit. It always reports success; failures are available via Failures.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file != nil && r.size > 0 && r.size+int64(len(p)) > r.maxSizeBytes {
+		if err := r.rotate(); err != nil {
+			r.failures.Add(1)
+		}
+	}
+	if r.file == nil {
+		// A previous rotation lost the file; try to get it back
+		if err := r.open(); err != nil {
+			r.failures.Add(1)
+			return len(p), nil
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	if err != nil {
+		r.failures.Add(1)
+	}
+	return len(p), nil
+}
+
+func (r *RotatingFile) rotate() error {
+	err := r.file.Close()
+	r.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to close log file for rotation: %w", err)
+	}
+
+	_ = os.Remove(fmt.Sprintf("%s.%d", r.path, r.maxFiles))
+	for i := r.maxFiles - 1; i >= 1; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	if err := os.Rename(r.path, r.path+".1"); err != nil {
+		return fmt.Errorf("failed to rename log file: %w", err)
+	}
+	return r.open()
+}
+
+// Failures returns the number of log writes or rotations that failed
+func (r *RotatingFile) Failures() uint64 {
+	return r.failures.Load()
+}
+
+// Close closes the underlying file
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// Name implements collector.Collector
+func (r *RotatingFile) Name() string {
+	return "log_file"
+}
+
+// Collect reports log write failures so they show up alongside other metricsd metrics
+func (r *RotatingFile) Collect(_ context.Context) ([]collector.Metric, error) {
+	return []collector.Metric{
+		{
+			Name:   "metricsd_log_write_failures_total",
+			Value:  float64(r.Failures()),
+			Type:   "counter",
+			Help:   "Log lines or rotations that could not be written to the log file",
+			Labels: map[string]string{},
+		},
+	}, nil
+}
@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotatingFile_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "metricsd.log")
+	r, err := NewRotatingFile(path, 1, 2)
+	if err != nil {
+		t.Fatalf("NewRotatingFile: %v", err)
+	}
+	defer r.Close()
+
+	line := append(bytes.Repeat([]byte("x"), 1023), '\n')
+	// 3 MB of lines with a 1 MB limit and 2 kept files
+	for i := 0; i < 3*1024; i++ {
+		if _, err := r.Write(line); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	for _, p := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("expected %s to exist: %v", p, err)
+		}
+		if info.Size() > 1024*1024 {
+			t.Errorf("%s is %d bytes, larger than the 1 MB limit", p, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected only 2 rotated files, found %s.3", path)
+	}
+	if r.Failures() != 0 {
+		t.Errorf("expected no failures, got %d", r.Failures())
+	}
+}
+
+func TestRotatingFile_WriteFailureIsCounted(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "metricsd.log")
+	r, err := NewRotatingFile(path, 1, 1)
+	if err != nil {
+		t.Fatalf("NewRotatingFile: %v", err)
+	}
+
+	// Closing the file underneath the writer makes the next write fail
+	r.file.Close()
+	n, err := r.Write([]byte("lost\n"))
+	if err != nil || n != 5 {
+		t.Fatalf("Write should swallow failures, got n=%d err=%v", n, err)
+	}
+	if r.Failures() != 1 {
+		t.Errorf("expected 1 failure, got %d", r.Failures())
+	}
+
+	metrics, err := r.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	if len(metrics) != 1 || metrics[0].Name != "metricsd_log_write_failures_total" || metrics[0].Value != 1 {
+		t.Errorf("unexpected metrics: %+v", metrics)
+	}
+}
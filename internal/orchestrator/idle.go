@@ -0,0 +1,75 @@
+package orchestrator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+// IdleCondition reports whether collection should slow down to the idle
+// interval, with a reason for the log when it should. It is called from the
+// ticker loop once per collection interval, so it should be cheap.
+type IdleCondition func() (idle bool, reason string)
+
+// OnBattery is idle while the host runs on battery
+func OnBattery() IdleCondition {
+	return func() (bool, string) {
+		if collector.OnBattery() {
+			return true, "running on battery"
+		}
+		return false, ""
+	}
+}
+
+// ShipFailures is idle once the last n ships in a row failed, e.g. while the
+// shipper endpoint is unreachable. The next successful ship ends it.
+func ShipFailures(status *Status, n int) IdleCondition {
+	return func() (bool, string) {
+		if fails := status.ConsecutiveShipFailures(); fails >= n {
+			return true, fmt.Sprintf("%d consecutive ship failures", fails)
+		}
+		return false, ""
+	}
+}
+
+// SetIdleInterval makes the ticker loop collect only every interval while any
+// of conditions holds, e.g. to save power on a laptop. Conditions are checked
+// on every regular tick, so the normal interval resumes within one tick of
+// them clearing. An interval not longer than the collection interval, or no
+// conditions, disables idling.
+func (o *Orchestrator) SetIdleInterval(interval time.Duration, conditions ...IdleCondition) {
+	if interval <= o.interval || len(conditions) == 0 {
+		o.idleInterval, o.idleConditions = 0, nil
+		return
+	}
+	o.idleInterval = interval
+	o.idleConditions = conditions
+}
+
+// skipIdleTick reports whether the tick at now should be skipped because the
+// orchestrator is idle and the idle interval has not passed since lastTick, the
+// last tick that started a cycle. Logs when idling starts and stops.
+func (o *Orchestrator) skipIdleTick(now, lastTick time.Time) bool {
+	if o.idleInterval <= 0 {
+		return false
+	}
+
+	idle, reason := false, ""
+	for _, cond := range o.idleConditions {
+		if idle, reason = cond(); idle {
+			break
+		}
+	}
+	if idle != o.idle {
+		o.idle = idle
+		if idle {
+			o.Logger().Info().Str("reason", reason).Dur("idle_interval", o.idleInterval).Msg("Entering idle mode, collecting less often")
+		} else {
+			o.Logger().Info().Dur("interval", o.interval).Msg("Leaving idle mode, back to the normal interval")
+		}
+	}
+
+	// Ticks drift slightly, so allow half an interval of slack
+	return idle && now.Sub(lastTick)+o.interval/2 < o.idleInterval
+}
@@ -2,91 +2,383 @@ package orchestrator
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
 
 	"github.com/0x524A/metricsd/internal/collector"
 	"github.com/0x524A/metricsd/internal/shipper"
 )
 
+// Errors returned by RunOnce, distinguishing which stage of the cycle failed
+var (
+	ErrCollectionFailed = errors.New("metrics collection failed")
+	ErrShipFailed       = errors.New("metrics shipping failed")
+)
+
+// Errors returned by CollectNow when no cycle can be started
+var (
+	ErrCycleInProgress = errors.New("collection cycle already in progress")
+	ErrStopped         = errors.New("orchestrator stopped")
+)
+
+// CycleResult counts the metrics of one collect-and-ship cycle. Shipped
+// includes metricsd's own metrics and is 0 when shipping failed.
+type CycleResult struct {
+	Collected int
+	Shipped   int
+}
+
 // Orchestrator coordinates the collection and shipping of metrics (Single Responsibility Principle)
 type Orchestrator struct {
+	collector.Logging
 	registry         *collector.Registry
 	shipper          shipper.Shipper
 	interval         time.Duration
 	stopChan         chan struct{}
+	stopOnce         sync.Once
 	lastShipDuration time.Duration
+	globalLabels     map[string]string
+	metricPrefix     string
+	typeOverrides    map[string]string
+	deduplicate      bool
+	duplicates       uint64 // Duplicate series dropped since startup
+
+	maxSeries      int    // Series shipped per cycle before the rest are dropped (0: unlimited)
+	maxLabelLength int    // Label values are cut to this many bytes (0: unlimited)
+	droppedSeries  uint64 // Series dropped by maxSeries since startup
+
+	maxFutureSkew      time.Duration // Samples timestamped further ahead are rejected (0: no limit)
+	maxSampleAge       time.Duration // Samples timestamped further back are rejected (0: no limit)
+	clampTimestamps    bool          // Rejected samples ship with the current time instead of being dropped
+	rejectedTimestamps uint64        // Samples rejected for their timestamp since startup
+
+	stateFile       string                     // Where counters persist across restarts; empty disables
+	savedCounters   map[string]uint64          // Counters as last written to stateFile
+	savedCollectors map[string]json.RawMessage // Collector states as last written to stateFile
+
+	buildInfo map[string]string // Labels of metricsd_build_info; nil until SetBuildInfo
+	startTime time.Time         // When the orchestrator was created, reported as metricsd_start_time_seconds
+
+	collectionTimeout time.Duration  // Deadline for one collect-and-ship cycle (default: interval)
+	running           atomic.Bool    // Set while a cycle is in flight
+	cycles            sync.WaitGroup // Tracks in-flight cycles so Stop can wait for them
+	timeouts          atomic.Uint64  // Cycles that hit collectionTimeout
+	status            *Status
+	onCycleSuccess    func() // Called after every successful collect-and-ship cycle
+
+	idleInterval   time.Duration   // Interval while any idle condition holds (0: never idle)
+	idleConditions []IdleCondition // See SetIdleInterval
+	idle           bool            // Whether the ticker loop is idling; owned by Start
 }
 
 // NewOrchestrator creates a new orchestrator
 func NewOrchestrator(registry *collector.Registry, shpr shipper.Shipper, interval time.Duration) *Orchestrator {
 	return &Orchestrator{
-		registry: registry,
-		shipper:  shpr,
-		interval: interval,
-		stopChan: make(chan struct{}),
+		registry:  registry,
+		shipper:   shpr,
+		interval:  interval,
+		stopChan:  make(chan struct{}),
+		status:    newStatus(),
+		startTime: time.Now(),
 	}
 }
 
+// Status returns the orchestrator's health state for readiness probes
+func (o *Orchestrator) Status() *Status {
+	return o.status
+}
+
+// SetReadinessFailureThreshold sets how many consecutive ship failures make
+// the orchestrator report not-ready. Values <= 0 use the default of 3.
+func (o *Orchestrator) SetReadinessFailureThreshold(n int) {
+	o.status.setFailureThreshold(n)
+}
+
+// SetGlobalLabels sets labels added to every shipped metric. Labels already
+// present on a metric are not overwritten.
+func (o *Orchestrator) SetGlobalLabels(labels map[string]string) {
+	o.globalLabels = labels
+}
+
+// SetCollectionTimeout sets the deadline for a single collect-and-ship cycle.
+// Values <= 0 mean the collection interval is used.
+func (o *Orchestrator) SetCollectionTimeout(d time.Duration) {
+	o.collectionTimeout = d
+}
+
+// SetLogger sets the logger used by the orchestrator and passes it on to the
+// registry and shipper when they accept one.
+func (o *Orchestrator) SetLogger(logger zerolog.Logger) {
+	o.Logging.SetLogger(logger)
+	o.registry.SetLogger(logger)
+	if s, ok := o.shipper.(collector.LoggerSetter); ok {
+		s.SetLogger(logger)
+	}
+}
+
+// SetCycleSuccessHook sets a function called after every successful
+// collect-and-ship cycle, e.g. to ping a watchdog. Cycles never overlap, so
+// fn is not called concurrently.
+func (o *Orchestrator) SetCycleSuccessHook(fn func()) {
+	o.onCycleSuccess = fn
+}
+
+// SetMetricPrefix sets a prefix prepended to every shipped metric name
+func (o *Orchestrator) SetMetricPrefix(prefix string) {
+	o.metricPrefix = prefix
+}
+
+// SetMetricTypeOverrides sets a metric name to type ("counter" or "gauge")
+// mapping applied to collected metrics before the prefix is added
+func (o *Orchestrator) SetMetricTypeOverrides(overrides map[string]string) {
+	o.typeOverrides = overrides
+}
+
+// SetDeduplicate enables dropping repeated series (same name and labels) before
+// shipping, keeping the last value
+func (o *Orchestrator) SetDeduplicate(enabled bool) {
+	o.deduplicate = enabled
+}
+
+// SetSeriesLimits guards the backend against cardinality bombs: collected
+// label values are cut to maxLabelValueLength bytes and series past
+// maxSeries per cycle are dropped. Zero disables either limit.
+func (o *Orchestrator) SetSeriesLimits(maxSeries, maxLabelValueLength int) {
+	o.maxSeries = maxSeries
+	o.maxLabelLength = maxLabelValueLength
+}
+
+// SetTimestampLimits protects the backend from samples with skewed clocks,
+// typically from plugins that set their own timestamps. Samples more than
+// maxFutureSkew ahead or maxSampleAge behind the wall clock are dropped, or
+// with clamp shipped with the current time. Zero disables either limit.
+func (o *Orchestrator) SetTimestampLimits(maxFutureSkew, maxSampleAge time.Duration, clamp bool) {
+	o.maxFutureSkew = maxFutureSkew
+	o.maxSampleAge = maxSampleAge
+	o.clampTimestamps = clamp
+}
+
+// SetBuildInfo makes every cycle report a metricsd_build_info gauge of 1
+// labelled with the running version and commit
+func (o *Orchestrator) SetBuildInfo(version, commit string) {
+	o.buildInfo = map[string]string{"version": version, "commit": commit}
+}
+
 // Start begins the periodic collection and shipping of metrics
 func (o *Orchestrator) Start(ctx context.Context) error {
 	ticker := time.NewTicker(o.interval)
 	defer ticker.Stop()
 
-	log.Info().
+	o.Logger().Info().
 		Dur("interval", o.interval).
 		Msg("Orchestrator started")
 
-	// Collect and ship immediately on start
-	o.collectAndShip(ctx)
+	o.logMetricCatalog()
+
+	// Collect and ship immediately on start; failures are logged inside the cycle
+	o.startCycle(ctx)
+	lastTick := time.Now()
+	defer o.cycles.Wait()
 
 	for {
 		select {
 		case <-ctx.Done():
-			log.Info().Msg("Orchestrator stopping due to context cancellation")
+			o.Logger().Info().Msg("Orchestrator stopping due to context cancellation")
 			return ctx.Err()
 		case <-o.stopChan:
-			log.Info().Msg("Orchestrator stopped")
+			o.Logger().Info().Msg("Orchestrator stopped")
 			return nil
-		case <-ticker.C:
-			o.collectAndShip(ctx)
+		case now := <-ticker.C:
+			if o.skipIdleTick(now, lastTick) {
+				continue
+			}
+			lastTick = now
+			o.startCycle(ctx)
 		}
 	}
 }
 
-// Stop stops the orchestrator
-func (o *Orchestrator) Stop() {
-	close(o.stopChan)
+// startCycle runs collectAndShip in the background unless the previous cycle is
+// still in flight, in which case the tick is skipped so slow backends can't pile
+// up goroutines.
+func (o *Orchestrator) startCycle(ctx context.Context) {
+	if !o.running.CompareAndSwap(false, true) {
+		o.Logger().Warn().
+			Dur("interval", o.interval).
+			Msg("Previous collection cycle still running, skipping this tick")
+		return
+	}
+
+	o.cycles.Add(1)
+	go func() {
+		defer o.cycles.Done()
+		defer o.running.Store(false)
+		_ = o.collectAndShip(ctx)
+	}()
 }
 
-func (o *Orchestrator) collectAndShip(ctx context.Context) {
-	startTime := time.Now()
+// RunOnce performs a single collect-and-ship cycle without starting the ticker loop.
+// The returned error wraps ErrCollectionFailed or ErrShipFailed.
+func (o *Orchestrator) RunOnce(ctx context.Context) error {
+	return o.collectAndShip(ctx)
+}
 
-	log.Debug().Msg("Starting metrics collection")
+// CollectNow runs a collect-and-ship cycle immediately, outside the ticker. It
+// returns ErrCycleInProgress instead of waiting when a cycle is already in
+// flight, and ErrStopped after Stop or Shutdown.
+func (o *Orchestrator) CollectNow(ctx context.Context) (CycleResult, error) {
+	select {
+	case <-o.stopChan:
+		return CycleResult{}, ErrStopped
+	default:
+	}
+	if !o.running.CompareAndSwap(false, true) {
+		return CycleResult{}, ErrCycleInProgress
+	}
+	o.cycles.Add(1)
+	defer o.cycles.Done()
+	defer o.running.Store(false)
+	return o.cycle(ctx)
+}
 
-	// Collect metrics from all collectors in parallel
-	metrics, err := o.registry.CollectAllParallel(ctx)
+// DryRun collects from every collector once and returns the metrics a cycle
+// would ship, without shipping them or recording the cycle in Status.
+func (o *Orchestrator) DryRun(parent context.Context) ([]collector.Metric, error) {
+	ctx, cancel := context.WithTimeout(parent, o.cycleTimeout())
+	defer cancel()
+
+	startTime := time.Now()
+	metrics, results, err := o.registry.CollectAllParallelResults(ctx)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to collect metrics")
-		return
+		return nil, fmt.Errorf("%w: %v", ErrCollectionFailed, err)
 	}
+	return o.prepare(metrics, results, time.Since(startTime)), nil
+}
 
-	collectDuration := time.Since(startTime)
+// Stop stops the orchestrator and shuts down collectors that hold resources (e.g. NVML).
+// Safe to call more than once.
+func (o *Orchestrator) Stop() {
+	o.stopOnce.Do(func() {
+		close(o.stopChan)
+		o.cycles.Wait()
+		o.shutdownCollectors()
+	})
+}
 
-	// Deadline warning: if collection took >80% of interval, warn
-	threshold := time.Duration(float64(o.interval) * 0.8)
-	if collectDuration > threshold {
-		log.Warn().
-			Dur("collection_duration", collectDuration).
-			Dur("interval", o.interval).
-			Msg("Collection duration exceeds 80% of interval — consider increasing interval or reducing collectors")
+// Shutdown stops the orchestrator like Stop, but first runs one final
+// collect-and-ship cycle and flushes shipper buffers, both bounded by ctx.
+// The cycle is skipped if ctx is already done. Safe to call more than once
+// and after Stop, in which case it does nothing.
+func (o *Orchestrator) Shutdown(ctx context.Context) error {
+	var err error
+	o.stopOnce.Do(func() {
+		close(o.stopChan)
+		o.cycles.Wait()
+		defer o.shutdownCollectors()
+
+		// A tick racing with the stop may have started one last cycle; let it
+		// stand in for the final one
+		if !o.running.CompareAndSwap(false, true) {
+			o.cycles.Wait()
+		} else if ctx.Err() == nil {
+			o.Logger().Info().Msg("Running final collection cycle before shutdown")
+			err = o.collectAndShip(ctx)
+		}
+
+		if f, ok := o.shipper.(shipper.Flusher); ok {
+			if flushErr := f.Flush(ctx); flushErr != nil {
+				o.Logger().Error().Err(flushErr).Msg("Failed to flush shipper")
+				err = errors.Join(err, flushErr)
+			}
+		}
+	})
+	return err
+}
+
+func (o *Orchestrator) shutdownCollectors() {
+	if err := o.registry.Shutdown(); err != nil {
+		o.Logger().Error().Err(err).Msg("Failed to shut down collectors")
 	}
+}
 
-	log.Debug().
-		Int("metric_count", len(metrics)).
-		Dur("duration", collectDuration).
-		Msg("Metrics collected")
+// logMetricCatalog logs the metrics declared by describable collectors and
+// warns about names declared by more than one collector.
+func (o *Orchestrator) logMetricCatalog() {
+	catalog, collisions := o.registry.DescribeAll()
+	for name, descriptors := range catalog {
+		o.Logger().Info().Str("collector", name).Int("metric_count", len(descriptors)).Msg("Collector metric catalog")
+		for _, d := range descriptors {
+			o.Logger().Debug().Str("collector", name).Str("metric", d.Name).Str("type", d.Type).Str("help", d.Help).Msg("Declared metric")
+		}
+	}
+	for _, name := range collisions {
+		o.Logger().Warn().Str("metric", name).Msg("Metric name declared by more than one collector")
+	}
+}
+
+// limitMetrics applies the timestamp and series limits to collected metrics, before
+// metricsd's own metrics are added so those are never the ones dropped
+func (o *Orchestrator) limitMetrics(metrics []collector.Metric) []collector.Metric {
+	metrics, rejected := collector.CheckTimestamps(metrics, time.Now(), o.maxFutureSkew, o.maxSampleAge, o.clampTimestamps)
+	if rejected > 0 {
+		o.rejectedTimestamps += uint64(rejected)
+		o.Logger().Warn().
+			Int("samples", rejected).
+			Dur("max_future_skew", o.maxFutureSkew).
+			Dur("max_sample_age", o.maxSampleAge).
+			Bool("clamped", o.clampTimestamps).
+			Msg("Samples timestamped outside the accepted range")
+	}
+	if n := collector.TruncateLabelValues(metrics, o.maxLabelLength); n > 0 {
+		o.Logger().Warn().Int("label_values", n).Int("max_length", o.maxLabelLength).Msg("Truncated overlong label values")
+	}
+
+	metrics, dropped := collector.LimitSeries(metrics, o.maxSeries)
+	if dropped > 0 {
+		o.droppedSeries += uint64(dropped)
+		o.Logger().Warn().Int("dropped", dropped).Int("max_series", o.maxSeries).Msg("Too many series collected, dropping the excess")
+	}
+	return metrics
+}
+
+// dedupMetrics drops duplicate series and appends the running duplicate count
+func (o *Orchestrator) dedupMetrics(metrics []collector.Metric) []collector.Metric {
+	metrics, dropped := collector.Deduplicate(metrics)
+	if dropped > 0 {
+		o.duplicates += uint64(dropped)
+		o.Logger().Warn().Int("duplicates", dropped).Msg("Dropped duplicate series before shipping")
+	}
+
+	counter := []collector.Metric{{
+		Name:   "metricsd_duplicate_series_total",
+		Value:  float64(o.duplicates),
+		Type:   "counter",
+		Labels: map[string]string{},
+	}}
+	collector.ApplyMetricPrefix(counter, o.metricPrefix)
+	collector.ApplyGlobalLabels(counter, o.globalLabels)
+	return append(metrics, counter...)
+}
+
+// cycleTimeout is the collection timeout, defaulting to the interval
+func (o *Orchestrator) cycleTimeout() time.Duration {
+	if o.collectionTimeout > 0 {
+		return o.collectionTimeout
+	}
+	return o.interval
+}
+
+// prepare applies the limits, appends metricsd's own metrics and applies the
+// type overrides, prefix, global labels and dedup to a cycle's collected metrics
+func (o *Orchestrator) prepare(metrics []collector.Metric, results map[string]collector.CollectorResult, collectDuration time.Duration) []collector.Metric {
+	metrics = o.limitMetrics(metrics)
 
 	// Append internal metrics about metricsd itself
 	internalMetrics := []collector.Metric{
@@ -96,6 +388,65 @@ func (o *Orchestrator) collectAndShip(ctx context.Context) {
 			Type:   "gauge",
 			Labels: map[string]string{},
 		},
+		{
+			Name:   "metricsd_collection_timeouts_total",
+			Value:  float64(o.timeouts.Load()),
+			Type:   "counter",
+			Labels: map[string]string{},
+		},
+		{
+			Name:   "metricsd_start_time_seconds",
+			Value:  float64(o.startTime.UnixNano()) / 1e9,
+			Type:   "gauge",
+			Labels: map[string]string{},
+		},
+		{
+			Name:   "metricsd_uptime_seconds",
+			Value:  time.Since(o.startTime).Seconds(),
+			Type:   "gauge",
+			Labels: map[string]string{},
+		},
+	}
+
+	// One series per collector so an open circuit breaker can be alerted on
+	for name, r := range results {
+		disabled := 0.0
+		if r.Disabled {
+			disabled = 1
+		}
+		internalMetrics = append(internalMetrics, collector.Metric{
+			Name:   "metricsd_collector_disabled",
+			Value:  disabled,
+			Type:   "gauge",
+			Labels: map[string]string{"collector": name},
+		})
+	}
+
+	if o.buildInfo != nil {
+		internalMetrics = append(internalMetrics, collector.Metric{
+			Name:   "metricsd_build_info",
+			Value:  1,
+			Type:   "gauge",
+			Labels: maps.Clone(o.buildInfo),
+		})
+	}
+
+	if o.maxSeries > 0 {
+		internalMetrics = append(internalMetrics, collector.Metric{
+			Name:   "metricsd_dropped_series_total",
+			Value:  float64(o.droppedSeries),
+			Type:   "counter",
+			Labels: map[string]string{},
+		})
+	}
+
+	if o.maxFutureSkew > 0 || o.maxSampleAge > 0 {
+		internalMetrics = append(internalMetrics, collector.Metric{
+			Name:   "metricsd_rejected_timestamps_total",
+			Value:  float64(o.rejectedTimestamps),
+			Type:   "counter",
+			Labels: map[string]string{},
+		})
 	}
 
 	// Include last ship duration from previous cycle (avoids chicken-and-egg)
@@ -109,31 +460,110 @@ func (o *Orchestrator) collectAndShip(ctx context.Context) {
 	}
 
 	metrics = append(metrics, internalMetrics...)
+	collector.ApplyTypeOverrides(metrics, o.typeOverrides)
+	collector.ApplyMetricPrefix(metrics, o.metricPrefix)
+	collector.ApplyGlobalLabels(metrics, o.globalLabels)
+
+	if o.deduplicate {
+		metrics = o.dedupMetrics(metrics)
+	}
+	return metrics
+}
+
+func (o *Orchestrator) collectAndShip(parent context.Context) error {
+	_, err := o.cycle(parent)
+	return err
+}
+
+// cycle collects from every collector and ships the result
+func (o *Orchestrator) cycle(parent context.Context) (result CycleResult, err error) {
+	startTime := time.Now()
+	var results map[string]collector.CollectorResult
+	defer func() {
+		o.status.recordCycle(startTime, time.Since(startTime), err, results)
+		o.saveState()
+	}()
+
+	timeout := o.cycleTimeout()
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	timedOut := false
+	checkTimeout := func(stage string) {
+		if !timedOut && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			timedOut = true
+			o.timeouts.Add(1)
+			o.Logger().Warn().
+				Dur("collection_timeout", timeout).
+				Str("stage", stage).
+				Msg("Collection cycle hit its timeout")
+		}
+	}
+	defer checkTimeout("ship")
+
+	o.Logger().Debug().Msg("Starting metrics collection")
+
+	// Collect metrics from all collectors in parallel
+	metrics, results, err := o.registry.CollectAllParallelResults(ctx)
+	checkTimeout("collect")
+	if err != nil {
+		o.Logger().Error().Err(err).Msg("Failed to collect metrics")
+		return result, fmt.Errorf("%w: %v", ErrCollectionFailed, err)
+	}
+
+	collectDuration := time.Since(startTime)
+	result.Collected = len(metrics)
+
+	// Deadline warning: if collection took >80% of interval, warn
+	threshold := time.Duration(float64(o.interval) * 0.8)
+	if collectDuration > threshold {
+		o.Logger().Warn().
+			Dur("collection_duration", collectDuration).
+			Dur("interval", o.interval).
+			Msg("Collection duration exceeds 80% of interval — consider increasing interval or reducing collectors")
+	}
+
+	o.Logger().Debug().
+		Int("metric_count", len(metrics)).
+		Dur("duration", collectDuration).
+		Msg("Metrics collected")
+
+	metrics = o.prepare(metrics, results, collectDuration)
 
 	// Ship metrics with one retry on failure
 	shipStart := time.Now()
 	if err := o.shipper.Ship(ctx, metrics); err != nil {
-		log.Warn().Err(err).Msg("Ship failed, retrying in 1s")
+		o.Logger().Warn().Err(err).Msg("Ship failed, retrying in 1s")
 
 		// Context-aware backoff — don't block if shutting down
 		select {
 		case <-ctx.Done():
-			log.Warn().Msg("Ship retry cancelled — context done")
+			o.Logger().Warn().Msg("Ship retry cancelled — context done")
 			o.lastShipDuration = time.Since(shipStart)
-			return
+			o.status.recordShipFailure(err)
+			return result, fmt.Errorf("%w: %v", ErrShipFailed, err)
 		case <-time.After(1 * time.Second):
 		}
 
 		if err := o.shipper.Ship(ctx, metrics); err != nil {
-			log.Error().Err(err).Msg("Ship retry failed")
+			o.Logger().Error().Err(err).Msg("Ship retry failed")
 			o.lastShipDuration = time.Since(shipStart)
-			return
+			o.status.recordShipFailure(err)
+			return result, fmt.Errorf("%w: %v", ErrShipFailed, err)
 		}
 	}
 	o.lastShipDuration = time.Since(shipStart)
+	o.status.recordShipSuccess()
+	result.Shipped = len(metrics)
 
-	log.Info().
+	o.Logger().Info().
 		Int("metric_count", len(metrics)).
 		Dur("total_duration", time.Since(startTime)).
 		Msg("Collection and shipping cycle completed successfully")
+
+	if o.onCycleSuccess != nil {
+		o.onCycleSuccess()
+	}
+
+	return result, nil
 }
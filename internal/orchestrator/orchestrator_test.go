@@ -1,12 +1,20 @@
 package orchestrator
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/rs/zerolog"
+
 	"github.com/0x524A/metricsd/internal/collector"
 )
 
@@ -53,6 +61,21 @@ func (m *mockCollector) Collect(_ context.Context) ([]collector.Metric, error) {
 	return m.metrics, m.err
 }
 
+// statefulCollector counts its collections and persists the count
+type statefulCollector struct {
+	runs int
+}
+
+func (s *statefulCollector) Name() string { return "stateful" }
+func (s *statefulCollector) Collect(_ context.Context) ([]collector.Metric, error) {
+	s.runs++
+	return []collector.Metric{{Name: "runs_total", Value: float64(s.runs), Type: "counter", Labels: map[string]string{}}}, nil
+}
+func (s *statefulCollector) SaveState() (json.RawMessage, error) { return json.Marshal(s.runs) }
+func (s *statefulCollector) RestoreState(data json.RawMessage) error {
+	return json.Unmarshal(data, &s.runs)
+}
+
 // TestNewOrchestrator verifies that NewOrchestrator sets fields correctly.
 func TestNewOrchestrator(t *testing.T) {
 	reg := collector.NewRegistry()
@@ -340,3 +363,702 @@ func TestCollectAndShip_LastShipDurationIncluded(t *testing.T) {
 		t.Error("expected metricsd_ship_duration_seconds in second cycle batch")
 	}
 }
+
+// shutdownCollector records Shutdown calls made by Orchestrator.Stop.
+type shutdownCollector struct {
+	mockCollector
+	mu    sync.Mutex
+	calls int
+}
+
+func (s *shutdownCollector) Shutdown() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	return nil
+}
+
+// TestStop_ShutsDownCollectors verifies that Stop releases collector resources
+// exactly once, even when called repeatedly.
+func TestStop_ShutsDownCollectors(t *testing.T) {
+	reg := collector.NewRegistry()
+	sc := &shutdownCollector{mockCollector: mockCollector{name: "gpu"}}
+	reg.Register(sc)
+
+	o := NewOrchestrator(reg, &mockShipper{}, time.Minute)
+	o.Stop()
+	o.Stop()
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.calls != 1 {
+		t.Errorf("expected Shutdown called once, got %d", sc.calls)
+	}
+}
+
+// flushingShipper records Flush calls.
+type flushingShipper struct {
+	mockShipper
+	flushes int
+}
+
+func (f *flushingShipper) Flush(_ context.Context) error {
+	f.flushes++
+	return nil
+}
+
+func TestShutdown_FinalFlush(t *testing.T) {
+	t.Run("ships and flushes", func(t *testing.T) {
+		reg := collector.NewRegistry()
+		sc := &shutdownCollector{mockCollector: mockCollector{name: "gpu", metrics: []collector.Metric{{Name: "cpu", Value: 1, Type: "gauge"}}}}
+		reg.Register(sc)
+		shpr := &flushingShipper{}
+
+		o := NewOrchestrator(reg, shpr, 10*time.Minute)
+		if err := o.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Shutdown returned error: %v", err)
+		}
+		if shpr.calls() != 1 || shpr.flushes != 1 {
+			t.Errorf("expected 1 final ship and 1 flush, got %d and %d", shpr.calls(), shpr.flushes)
+		}
+
+		// Already stopped: neither Stop nor a second Shutdown do anything
+		o.Stop()
+		_ = o.Shutdown(context.Background())
+		if shpr.calls() != 1 || shpr.flushes != 1 || sc.calls != 1 {
+			t.Errorf("expected shutdown to run once, got %d ships, %d flushes, %d collector shutdowns", shpr.calls(), shpr.flushes, sc.calls)
+		}
+	})
+
+	t.Run("expired context skips the final cycle", func(t *testing.T) {
+		shpr := &mockShipper{}
+		o := NewOrchestrator(collector.NewRegistry(), shpr, 10*time.Minute)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_ = o.Shutdown(ctx)
+		if shpr.calls() != 0 {
+			t.Errorf("expected no ship with an expired context, got %d", shpr.calls())
+		}
+	})
+}
+
+// TestGlobalLabels verifies that global labels are added to shipped metrics
+// without overriding labels set by collectors.
+func TestGlobalLabels(t *testing.T) {
+	shared := map[string]string{"env": "collector"}
+	reg := collector.NewRegistry()
+	reg.Register(&mockCollector{
+		name: "c",
+		metrics: []collector.Metric{
+			{Name: "a", Value: 1, Type: "gauge", Labels: shared},
+			{Name: "b", Value: 2, Type: "gauge"},
+		},
+	})
+	shpr := &mockShipper{}
+	o := NewOrchestrator(reg, shpr, time.Minute)
+	o.SetGlobalLabels(map[string]string{"env": "global", "region": "eu"})
+
+	o.collectAndShip(context.Background())
+
+	for _, m := range shpr.firstBatch() {
+		if m.Labels["region"] != "eu" {
+			t.Errorf("metric %s: expected region=eu, got %q", m.Name, m.Labels["region"])
+		}
+		if m.Name == "a" && m.Labels["env"] != "collector" {
+			t.Errorf("metric a: collector label should win, got env=%q", m.Labels["env"])
+		}
+	}
+	if _, ok := shared["region"]; ok {
+		t.Error("collector label map should not be mutated")
+	}
+}
+
+// TestDryRun verifies that DryRun returns the processed metrics a cycle would
+// ship without shipping them.
+func TestDryRun(t *testing.T) {
+	reg := collector.NewRegistry()
+	reg.Register(&mockCollector{
+		name: "c",
+		metrics: []collector.Metric{
+			{Name: "a", Value: 1, Type: "gauge", Labels: map[string]string{}},
+			{Name: "a", Value: 2, Type: "gauge", Labels: map[string]string{}},
+		},
+	})
+	shpr := &mockShipper{}
+	o := NewOrchestrator(reg, shpr, time.Minute)
+	o.SetMetricPrefix("app_")
+	o.SetGlobalLabels(map[string]string{"region": "eu"})
+	o.SetMetricTypeOverrides(map[string]string{"a": "counter"})
+	o.SetDeduplicate(true)
+
+	metrics, err := o.DryRun(context.Background())
+	if err != nil {
+		t.Fatalf("DryRun returned error: %v", err)
+	}
+	if shpr.calls() != 0 {
+		t.Errorf("expected no Ship calls, got %d", shpr.calls())
+	}
+
+	var user int
+	var internal bool
+	for _, m := range metrics {
+		internal = internal || m.Name == "app_metricsd_collection_duration_seconds"
+		if m.Labels["region"] != "eu" {
+			t.Errorf("metric %s: expected region=eu, got %q", m.Name, m.Labels["region"])
+		}
+		if m.Name == "app_a" {
+			user++
+			if m.Type != "counter" {
+				t.Errorf("app_a: expected type override to counter, got %q", m.Type)
+			}
+		}
+	}
+	if user != 1 {
+		t.Errorf("expected the duplicate series dropped, got %d app_a series", user)
+	}
+	if !internal {
+		t.Error("expected metricsd's own metrics in the dry run")
+	}
+}
+
+// TestRunOnce verifies that RunOnce reports success and wraps ship failures
+// in ErrShipFailed.
+func TestRunOnce(t *testing.T) {
+	userMetrics := []collector.Metric{
+		{Name: "cpu", Value: 1.0, Type: "gauge", Labels: map[string]string{}},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		reg := collector.NewRegistry()
+		reg.Register(&mockCollector{name: "test", metrics: userMetrics})
+		shpr := &mockShipper{}
+
+		o := NewOrchestrator(reg, shpr, 10*time.Minute)
+		if err := o.RunOnce(context.Background()); err != nil {
+			t.Fatalf("RunOnce returned error: %v", err)
+		}
+		if shpr.calls() != 1 {
+			t.Errorf("expected 1 Ship call, got %d", shpr.calls())
+		}
+	})
+
+	t.Run("ship failure", func(t *testing.T) {
+		reg := collector.NewRegistry()
+		reg.Register(&mockCollector{name: "test", metrics: userMetrics})
+		shpr := &retryShipper{failUntil: 999}
+
+		o := NewOrchestrator(reg, shpr, 10*time.Minute)
+		err := o.RunOnce(context.Background())
+		if !errors.Is(err, ErrShipFailed) {
+			t.Fatalf("expected ErrShipFailed, got %v", err)
+		}
+		if errors.Is(err, ErrCollectionFailed) {
+			t.Error("ship failure must not be reported as collection failure")
+		}
+	})
+}
+
+// TestCollectNow verifies the counts reported by an on-demand cycle and that it
+// refuses to overlap a running cycle or run after Stop.
+func TestCollectNow(t *testing.T) {
+	userMetrics := []collector.Metric{
+		{Name: "cpu", Value: 1.0, Type: "gauge", Labels: map[string]string{}},
+		{Name: "mem", Value: 2.0, Type: "gauge", Labels: map[string]string{}},
+	}
+	newOrch := func(shpr *mockShipper) *Orchestrator {
+		reg := collector.NewRegistry()
+		reg.Register(&mockCollector{name: "test", metrics: userMetrics})
+		return NewOrchestrator(reg, shpr, 10*time.Minute)
+	}
+
+	t.Run("success", func(t *testing.T) {
+		shpr := &mockShipper{}
+		o := newOrch(shpr)
+		result, err := o.CollectNow(context.Background())
+		if err != nil {
+			t.Fatalf("CollectNow returned error: %v", err)
+		}
+		if result.Collected != 2 {
+			t.Errorf("Collected = %d, want 2", result.Collected)
+		}
+		// Shipped includes metricsd's own metrics
+		if want := len(shpr.firstBatch()); result.Shipped != want || want <= 2 {
+			t.Errorf("Shipped = %d, want %d (> 2)", result.Shipped, want)
+		}
+		if o.running.Load() {
+			t.Error("running flag must be cleared after the cycle")
+		}
+	})
+
+	t.Run("cycle in progress", func(t *testing.T) {
+		shpr := &mockShipper{}
+		o := newOrch(shpr)
+		o.running.Store(true)
+		if _, err := o.CollectNow(context.Background()); !errors.Is(err, ErrCycleInProgress) {
+			t.Fatalf("expected ErrCycleInProgress, got %v", err)
+		}
+		if shpr.calls() != 0 {
+			t.Errorf("expected no Ship call, got %d", shpr.calls())
+		}
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		shpr := &mockShipper{}
+		o := newOrch(shpr)
+		o.Stop()
+		if _, err := o.CollectNow(context.Background()); !errors.Is(err, ErrStopped) {
+			t.Fatalf("expected ErrStopped, got %v", err)
+		}
+	})
+}
+
+func TestCycleSuccessHook(t *testing.T) {
+	reg := collector.NewRegistry()
+	reg.Register(&mockCollector{name: "test", metrics: []collector.Metric{{Name: "cpu", Value: 1, Type: "gauge"}}})
+	shpr := &retryShipper{failUntil: 2} // First cycle fails including its retry
+
+	o := NewOrchestrator(reg, shpr, 10*time.Minute)
+	calls := 0
+	o.SetCycleSuccessHook(func() { calls++ })
+
+	_ = o.RunOnce(context.Background())
+	if calls != 0 {
+		t.Errorf("hook must not run after a failed cycle, got %d calls", calls)
+	}
+
+	if err := o.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce returned error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 hook call after a successful cycle, got %d", calls)
+	}
+}
+
+// loggingShipper records the logger injected through SetLogger.
+type loggingShipper struct {
+	mockShipper
+	collector.Logging
+}
+
+func TestSetLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+
+	reg := collector.NewRegistry()
+	reg.Register(&mockCollector{name: "ok", metrics: []collector.Metric{{Name: "cpu", Type: "gauge", Labels: map[string]string{}}}})
+	reg.Register(&mockCollector{name: "broken", err: errors.New("boom")})
+	shpr := &loggingShipper{}
+
+	o := NewOrchestrator(reg, shpr, 10*time.Minute)
+	o.SetLogger(logger)
+	if err := o.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce returned error: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"Collector failed during parallel collection", "Collection and shipping cycle completed successfully"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("injected logger missing %q, got:\n%s", want, out)
+		}
+	}
+
+	shpr.Logger().Info().Msg("from shipper")
+	if !strings.Contains(buf.String(), "from shipper") {
+		t.Error("logger was not passed on to the shipper")
+	}
+}
+
+// slowCollector blocks until its context is done.
+type slowCollector struct{}
+
+func (slowCollector) Name() string { return "slow" }
+func (slowCollector) Collect(ctx context.Context) ([]collector.Metric, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// TestCollectAndShip_CollectionTimeout verifies that a cycle is bounded by the
+// collection timeout and that metricsd_collection_timeouts_total counts it.
+func TestCollectAndShip_CollectionTimeout(t *testing.T) {
+	reg := collector.NewRegistry()
+	reg.Register(slowCollector{})
+	shpr := &mockShipper{}
+
+	o := NewOrchestrator(reg, shpr, 10*time.Minute)
+	o.SetCollectionTimeout(50 * time.Millisecond)
+
+	start := time.Now()
+	o.collectAndShip(context.Background())
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("cycle was not bounded by the timeout, took %v", elapsed)
+	}
+
+	found := false
+	for _, m := range shpr.firstBatch() {
+		if m.Name == "metricsd_collection_timeouts_total" {
+			found = true
+			if m.Value != 1 || m.Type != "counter" {
+				t.Errorf("timeouts counter: got value=%v type=%q, want 1 counter", m.Value, m.Type)
+			}
+		}
+	}
+	if !found {
+		t.Error("metricsd_collection_timeouts_total not shipped")
+	}
+}
+
+// TestStartCycle_SkipsWhileRunning verifies the non-overlap guard: a tick that
+// arrives while a cycle is in flight doesn't start another.
+func TestStartCycle_SkipsWhileRunning(t *testing.T) {
+	reg := collector.NewRegistry()
+	reg.Register(&mockCollector{name: "test", metrics: []collector.Metric{{Name: "cpu", Value: 1, Type: "gauge"}}})
+	shpr := &mockShipper{}
+
+	o := NewOrchestrator(reg, shpr, 10*time.Minute)
+	o.running.Store(true)
+	o.startCycle(context.Background())
+	o.cycles.Wait()
+	if shpr.calls() != 0 {
+		t.Errorf("expected skipped cycle, got %d Ship calls", shpr.calls())
+	}
+
+	o.running.Store(false)
+	o.startCycle(context.Background())
+	o.cycles.Wait()
+	if shpr.calls() != 1 {
+		t.Errorf("expected 1 Ship call, got %d", shpr.calls())
+	}
+	if o.running.Load() {
+		t.Error("running flag should be cleared after the cycle")
+	}
+}
+
+// TestMetricPrefix verifies that the prefix is applied to every shipped metric,
+// including internal ones.
+func TestMetricPrefix(t *testing.T) {
+	reg := collector.NewRegistry()
+	reg.Register(&mockCollector{name: "c", metrics: []collector.Metric{{Name: "system_cpu", Value: 1, Type: "gauge"}}})
+	shpr := &mockShipper{}
+	o := NewOrchestrator(reg, shpr, time.Minute)
+	o.SetMetricPrefix("acme_")
+
+	o.collectAndShip(context.Background())
+
+	for _, m := range shpr.firstBatch() {
+		if !strings.HasPrefix(m.Name, "acme_") {
+			t.Errorf("metric %s was not prefixed", m.Name)
+		}
+	}
+}
+
+func TestDeduplicate(t *testing.T) {
+	reg := collector.NewRegistry()
+	reg.Register(&mockCollector{name: "http", metrics: []collector.Metric{{Name: "queue_depth", Value: 1, Type: "gauge"}}})
+	reg.Register(&mockCollector{name: "plugin", metrics: []collector.Metric{{Name: "queue_depth", Value: 1, Type: "gauge"}}})
+	shpr := &mockShipper{}
+	o := NewOrchestrator(reg, shpr, time.Minute)
+	o.SetDeduplicate(true)
+
+	if err := o.collectAndShip(context.Background()); err != nil {
+		t.Fatalf("collectAndShip: %v", err)
+	}
+
+	count := 0
+	var duplicates *collector.Metric
+	for _, m := range shpr.firstBatch() {
+		switch m.Name {
+		case "queue_depth":
+			count++
+		case "metricsd_duplicate_series_total":
+			duplicates = &m
+		}
+	}
+	if count != 1 {
+		t.Errorf("expected queue_depth once, shipped %d times", count)
+	}
+	if duplicates == nil || duplicates.Value != 1 {
+		t.Errorf("expected metricsd_duplicate_series_total of 1, got %+v", duplicates)
+	}
+}
+
+func TestSeriesLimits(t *testing.T) {
+	var bomb []collector.Metric
+	for i := 0; i < 10; i++ {
+		bomb = append(bomb, collector.Metric{Name: "req", Value: 1, Type: "gauge", Labels: map[string]string{"id": fmt.Sprintf("request-%d", i)}})
+	}
+	reg := collector.NewRegistry()
+	reg.Register(&mockCollector{name: "http", metrics: bomb})
+	shpr := &mockShipper{}
+	o := NewOrchestrator(reg, shpr, time.Minute)
+	o.SetSeriesLimits(4, 7)
+
+	if err := o.collectAndShip(context.Background()); err != nil {
+		t.Fatalf("collectAndShip: %v", err)
+	}
+
+	shipped := 0
+	var dropped *collector.Metric
+	for _, m := range shpr.firstBatch() {
+		switch m.Name {
+		case "req":
+			shipped++
+			if len(m.Labels["id"]) > 7 {
+				t.Errorf("label value not truncated: %q", m.Labels["id"])
+			}
+		case "metricsd_dropped_series_total":
+			dropped = &m
+		}
+	}
+	if shipped != 4 {
+		t.Errorf("expected 4 req series, shipped %d", shipped)
+	}
+	if dropped == nil || dropped.Value != 6 {
+		t.Errorf("expected metricsd_dropped_series_total of 6, got %+v", dropped)
+	}
+}
+
+func TestTimestampLimits(t *testing.T) {
+	reg := collector.NewRegistry()
+	reg.Register(&mockCollector{name: "plugin", metrics: []collector.Metric{
+		{Name: "ok", Value: 1, Type: "gauge"},
+		{Name: "skewed", Value: 1, Type: "gauge", Timestamp: time.Now().Add(time.Hour)},
+	}})
+	shpr := &mockShipper{}
+	o := NewOrchestrator(reg, shpr, time.Minute)
+	o.SetTimestampLimits(5*time.Minute, 0, false)
+
+	if err := o.collectAndShip(context.Background()); err != nil {
+		t.Fatalf("collectAndShip: %v", err)
+	}
+
+	var rejected *collector.Metric
+	for _, m := range shpr.firstBatch() {
+		switch m.Name {
+		case "skewed":
+			t.Error("sample an hour in the future was shipped")
+		case "metricsd_rejected_timestamps_total":
+			rejected = &m
+		}
+	}
+	if rejected == nil || rejected.Value != 1 {
+		t.Errorf("expected metricsd_rejected_timestamps_total of 1, got %+v", rejected)
+	}
+}
+
+func TestBuildInfo(t *testing.T) {
+	reg := collector.NewRegistry()
+	reg.Register(&mockCollector{name: "cpu", metrics: []collector.Metric{{Name: "cpu_usage", Value: 1, Type: "gauge"}}})
+	shpr := &mockShipper{}
+	o := NewOrchestrator(reg, shpr, time.Minute)
+	o.SetBuildInfo("1.2.3", "abc123")
+	o.SetGlobalLabels(map[string]string{"env": "prod"})
+
+	for i := 0; i < 2; i++ {
+		if err := o.collectAndShip(context.Background()); err != nil {
+			t.Fatalf("collectAndShip: %v", err)
+		}
+	}
+
+	var info *collector.Metric
+	for _, m := range shpr.firstBatch() {
+		if m.Name == "metricsd_build_info" {
+			info = &m
+		}
+	}
+	if info == nil || info.Value != 1 || info.Labels["version"] != "1.2.3" || info.Labels["commit"] != "abc123" {
+		t.Fatalf("unexpected metricsd_build_info: %+v", info)
+	}
+	if _, ok := o.buildInfo["env"]; ok {
+		t.Error("global labels leaked into the stored build info labels")
+	}
+}
+
+func TestStartTimeAndUptime(t *testing.T) {
+	reg := collector.NewRegistry()
+	shpr := &mockShipper{}
+	o := NewOrchestrator(reg, shpr, time.Minute)
+	o.startTime = time.Now().Add(-time.Hour)
+
+	if err := o.collectAndShip(context.Background()); err != nil {
+		t.Fatalf("collectAndShip: %v", err)
+	}
+
+	got := map[string]float64{}
+	for _, m := range shpr.firstBatch() {
+		got[m.Name] = m.Value
+	}
+	if start := got["metricsd_start_time_seconds"]; start != float64(o.startTime.UnixNano())/1e9 {
+		t.Errorf("metricsd_start_time_seconds = %v, want %v", start, float64(o.startTime.Unix()))
+	}
+	if uptime := got["metricsd_uptime_seconds"]; uptime < 3600 || uptime > 3660 {
+		t.Errorf("metricsd_uptime_seconds = %v, want about 3600", uptime)
+	}
+}
+
+// TestStateFile verifies that counters are saved after a cycle and restored by
+// a new orchestrator, and that missing or corrupt files start from zero.
+func TestStateFile(t *testing.T) {
+	dup := []collector.Metric{
+		{Name: "cpu", Value: 1, Type: "gauge", Labels: map[string]string{}},
+		{Name: "cpu", Value: 2, Type: "gauge", Labels: map[string]string{}},
+	}
+	newOrch := func() *Orchestrator {
+		reg := collector.NewRegistry()
+		reg.Register(&mockCollector{name: "test", metrics: dup})
+		o := NewOrchestrator(reg, &mockShipper{}, 10*time.Minute)
+		o.SetDeduplicate(true)
+		return o
+	}
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	t.Run("missing file", func(t *testing.T) {
+		o := newOrch()
+		if err := o.SetStateFile(path); err != nil {
+			t.Fatalf("SetStateFile: %v", err)
+		}
+		if err := o.RunOnce(context.Background()); err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+		if err := o.RunOnce(context.Background()); err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+		if o.duplicates != 2 {
+			t.Fatalf("duplicates = %d, want 2", o.duplicates)
+		}
+	})
+
+	t.Run("restored", func(t *testing.T) {
+		o := newOrch()
+		if err := o.SetStateFile(path); err != nil {
+			t.Fatalf("SetStateFile: %v", err)
+		}
+		if o.duplicates != 2 {
+			t.Errorf("restored duplicates = %d, want 2", o.duplicates)
+		}
+		shpr := o.shipper.(*mockShipper)
+		if err := o.RunOnce(context.Background()); err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+		for _, m := range shpr.firstBatch() {
+			if m.Name == "metricsd_duplicate_series_total" && m.Value != 3 {
+				t.Errorf("metricsd_duplicate_series_total = %v, want 3", m.Value)
+			}
+		}
+	})
+
+	t.Run("corrupt file", func(t *testing.T) {
+		if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		o := newOrch()
+		if err := o.SetStateFile(path); err == nil {
+			t.Error("expected an error for a corrupt state file")
+		}
+		if o.duplicates != 0 {
+			t.Errorf("duplicates = %d, want 0", o.duplicates)
+		}
+		if err := o.RunOnce(context.Background()); err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+		// The next save replaces the corrupt file
+		if err := newOrch().SetStateFile(path); err != nil {
+			t.Errorf("state file not rewritten: %v", err)
+		}
+	})
+
+	t.Run("collector state", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "state.json")
+		newStateful := func() (*Orchestrator, *statefulCollector) {
+			c := &statefulCollector{}
+			reg := collector.NewRegistry()
+			reg.Register(c)
+			o := NewOrchestrator(reg, &mockShipper{}, 10*time.Minute)
+			if err := o.SetStateFile(path); err != nil {
+				t.Fatalf("SetStateFile: %v", err)
+			}
+			return o, c
+		}
+
+		o, _ := newStateful()
+		for range 2 {
+			if err := o.RunOnce(context.Background()); err != nil {
+				t.Fatalf("RunOnce: %v", err)
+			}
+		}
+		_, restored := newStateful()
+		if restored.runs != 2 {
+			t.Errorf("restored collector state = %d, want 2", restored.runs)
+		}
+	})
+}
+
+func TestIdleInterval(t *testing.T) {
+	o := NewOrchestrator(collector.NewRegistry(), &mockShipper{}, time.Minute)
+	idle := false
+	o.SetIdleInterval(5*time.Minute, func() (bool, string) { return idle, "test" })
+
+	start := time.Now()
+	tick := func(n int) time.Time { return start.Add(time.Duration(n) * time.Minute) }
+
+	if o.skipIdleTick(tick(1), tick(0)) {
+		t.Error("tick skipped while not idle")
+	}
+
+	idle = true
+	for n := 2; n <= 5; n++ {
+		if !o.skipIdleTick(tick(n), tick(1)) {
+			t.Errorf("tick %d not skipped while idle", n)
+		}
+	}
+	// Ticks arrive slightly early; the idle interval still counts as elapsed
+	if o.skipIdleTick(tick(6).Add(-time.Second), tick(1)) {
+		t.Error("tick after the idle interval skipped")
+	}
+
+	idle = false
+	if o.skipIdleTick(tick(7), tick(6)) || o.idle {
+		t.Error("normal interval not resumed after the condition cleared")
+	}
+}
+
+func TestIdleInterval_Disabled(t *testing.T) {
+	always := func() (bool, string) { return true, "test" }
+	tests := []struct {
+		name       string
+		interval   time.Duration
+		conditions []IdleCondition
+	}{
+		{"unset", 0, []IdleCondition{always}},
+		{"not longer than the interval", time.Minute, []IdleCondition{always}},
+		{"no conditions", 5 * time.Minute, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			o := NewOrchestrator(collector.NewRegistry(), &mockShipper{}, time.Minute)
+			o.SetIdleInterval(tc.interval, tc.conditions...)
+			now := time.Now()
+			if o.skipIdleTick(now, now) {
+				t.Error("tick skipped with idling disabled")
+			}
+		})
+	}
+}
+
+func TestShipFailuresIdleCondition(t *testing.T) {
+	status := newStatus()
+	cond := ShipFailures(status, 2)
+
+	status.recordShipFailure(errors.New("connection refused"))
+	if idle, _ := cond(); idle {
+		t.Error("idle after a single failure")
+	}
+	status.recordShipFailure(errors.New("connection refused"))
+	if idle, reason := cond(); !idle || reason == "" {
+		t.Errorf("expected idle with a reason after 2 failures, got %v %q", idle, reason)
+	}
+	status.recordShipSuccess()
+	if idle, _ := cond(); idle {
+		t.Error("still idle after a successful ship")
+	}
+}
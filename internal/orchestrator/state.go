@@ -0,0 +1,129 @@
+package orchestrator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// stateVersion is bumped when the state file format changes incompatibly
+const stateVersion = 1
+
+// counterState is the state file's content: metricsd's own counters, keyed by
+// their unprefixed metric name, and the state of collectors implementing
+// collector.StateKeeper, keyed by collector name
+type counterState struct {
+	Version    int                        `json:"version"`
+	SavedAt    time.Time                  `json:"saved_at"`
+	Counters   map[string]uint64          `json:"counters"`
+	Collectors map[string]json.RawMessage `json:"collectors,omitempty"`
+}
+
+// SetStateFile persists metricsd's own counters (duplicate, dropped and
+// rejected series, collection timeouts) and the state of registered
+// collectors implementing collector.StateKeeper, such as plugin histograms, to
+// path after every cycle that changed them, so they keep counting up across
+// restarts. State saved in path is restored now, so collectors must be
+// registered first. A missing file starts from zero; an unreadable or corrupt
+// one does too, and is reported in the returned error but still overwritten
+// by the next save.
+func (o *Orchestrator) SetStateFile(path string) error {
+	o.stateFile = path
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	var state counterState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("corrupt state file %s: %w", path, err)
+	}
+	if state.Version != stateVersion {
+		return fmt.Errorf("state file %s has unsupported version %d", path, state.Version)
+	}
+
+	o.duplicates = state.Counters["metricsd_duplicate_series_total"]
+	o.droppedSeries = state.Counters["metricsd_dropped_series_total"]
+	o.rejectedTimestamps = state.Counters["metricsd_rejected_timestamps_total"]
+	o.timeouts.Store(state.Counters["metricsd_collection_timeouts_total"])
+	o.savedCounters = o.counters()
+	if err := o.registry.RestoreStates(state.Collectors); err != nil {
+		return fmt.Errorf("state file %s: %w", path, err)
+	}
+	o.savedCollectors = state.Collectors
+	return nil
+}
+
+// counters returns the current values of the persisted counters
+func (o *Orchestrator) counters() map[string]uint64 {
+	return map[string]uint64{
+		"metricsd_duplicate_series_total":    o.duplicates,
+		"metricsd_dropped_series_total":      o.droppedSeries,
+		"metricsd_rejected_timestamps_total": o.rejectedTimestamps,
+		"metricsd_collection_timeouts_total": o.timeouts.Load(),
+	}
+}
+
+// saveState writes the counters and collector states to the state file when
+// they changed since the last save. The file is replaced atomically so a crash
+// never leaves it torn.
+func (o *Orchestrator) saveState() {
+	if o.stateFile == "" {
+		return
+	}
+	counters := o.counters()
+	collectors, err := o.registry.SaveStates()
+	if err != nil {
+		o.Logger().Warn().Err(err).Msg("Failed to save collector state")
+	}
+	if o.savedCounters != nil && maps.Equal(counters, o.savedCounters) &&
+		maps.EqualFunc(collectors, o.savedCollectors, func(a, b json.RawMessage) bool { return bytes.Equal(a, b) }) {
+		return
+	}
+
+	data, err := json.Marshal(counterState{Version: stateVersion, SavedAt: time.Now(), Counters: counters, Collectors: collectors})
+	if err == nil {
+		err = writeFileAtomic(o.stateFile, data)
+	}
+	if err != nil {
+		o.Logger().Warn().Err(err).Str("state_file", o.stateFile).Msg("Failed to save counter state")
+		return
+	}
+	o.savedCounters = counters
+	o.savedCollectors = collectors
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// over path
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
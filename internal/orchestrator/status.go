@@ -0,0 +1,137 @@
+package orchestrator
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+// defaultReadinessFailureThreshold is the number of consecutive ship failures
+// after which the daemon reports not-ready
+const defaultReadinessFailureThreshold = 3
+
+// Status is the orchestrator's health state, shared with the HTTP server for
+// readiness probes. It is safe for concurrent use.
+type Status struct {
+	mu                      sync.RWMutex
+	shippedOnce             bool
+	consecutiveShipFailures int
+	failureThreshold        int
+	lastShipError           string
+	lastCycle               CycleStatus
+}
+
+func newStatus() *Status {
+	return &Status{failureThreshold: defaultReadinessFailureThreshold}
+}
+
+// Ready reports whether at least one cycle has shipped successfully and the
+// consecutive ship failures have not reached the threshold. When not ready,
+// reason explains why.
+func (s *Status) Ready() (ready bool, reason string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.shippedOnce {
+		return false, "no successful collect-and-ship cycle yet"
+	}
+	if s.consecutiveShipFailures >= s.failureThreshold {
+		return false, fmt.Sprintf("%d consecutive ship failures, last: %s", s.consecutiveShipFailures, s.lastShipError)
+	}
+	return true, ""
+}
+
+// ConsecutiveShipFailures returns how many ships in a row have failed
+func (s *Status) ConsecutiveShipFailures() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.consecutiveShipFailures
+}
+
+func (s *Status) setFailureThreshold(n int) {
+	if n <= 0 {
+		n = defaultReadinessFailureThreshold
+	}
+	s.mu.Lock()
+	s.failureThreshold = n
+	s.mu.Unlock()
+}
+
+func (s *Status) recordShipSuccess() {
+	s.mu.Lock()
+	s.shippedOnce = true
+	s.consecutiveShipFailures = 0
+	s.lastShipError = ""
+	s.mu.Unlock()
+}
+
+func (s *Status) recordShipFailure(err error) {
+	s.mu.Lock()
+	s.consecutiveShipFailures++
+	s.lastShipError = err.Error()
+	s.mu.Unlock()
+}
+
+// CollectorStatus is a collector's metric count in the last cycle and the most
+// recent error it returned, which is kept until the collector fails again.
+type CollectorStatus struct {
+	MetricCount int
+	LastError   string
+	LastErrorAt time.Time
+	Disabled    bool // Skipped by the registry's circuit breaker
+	Paused      bool // Disabled by an operator
+}
+
+// CycleStatus describes the most recent collect-and-ship cycle
+type CycleStatus struct {
+	Time       time.Time
+	Duration   time.Duration
+	Error      string
+	Collectors map[string]CollectorStatus
+}
+
+// LastCycle returns a copy of the most recent cycle's status. Time is zero
+// before the first cycle completes.
+func (s *Status) LastCycle() CycleStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c := s.lastCycle
+	c.Collectors = make(map[string]CollectorStatus, len(s.lastCycle.Collectors))
+	for name, cs := range s.lastCycle.Collectors {
+		c.Collectors[name] = cs
+	}
+	return c
+}
+
+// recordCycle stores the outcome of a cycle. results is nil when collection
+// did not run, in which case the previous per-collector status is kept.
+func (s *Status) recordCycle(start time.Time, duration time.Duration, err error, results map[string]collector.CollectorResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastCycle.Time = start
+	s.lastCycle.Duration = duration
+	s.lastCycle.Error = ""
+	if err != nil {
+		s.lastCycle.Error = err.Error()
+	}
+	if results == nil {
+		return
+	}
+
+	collectors := make(map[string]CollectorStatus, len(results))
+	for name, r := range results {
+		cs := CollectorStatus{MetricCount: r.MetricCount, Disabled: r.Disabled, Paused: r.Paused}
+		if prev, ok := s.lastCycle.Collectors[name]; ok {
+			cs.LastError, cs.LastErrorAt = prev.LastError, prev.LastErrorAt
+		}
+		if r.Err != nil {
+			cs.LastError, cs.LastErrorAt = r.Err.Error(), start
+		}
+		collectors[name] = cs
+	}
+	s.lastCycle.Collectors = collectors
+}
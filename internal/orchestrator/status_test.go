@@ -0,0 +1,88 @@
+package orchestrator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+func TestStatus_Ready(t *testing.T) {
+	s := newStatus()
+	s.setFailureThreshold(2)
+
+	if ready, reason := s.Ready(); ready || reason == "" {
+		t.Fatalf("expected not ready before the first cycle, got ready=%v reason=%q", ready, reason)
+	}
+
+	s.recordShipSuccess()
+	if ready, _ := s.Ready(); !ready {
+		t.Fatal("expected ready after a successful cycle")
+	}
+
+	s.recordShipFailure(errors.New("backend down"))
+	if ready, _ := s.Ready(); !ready {
+		t.Fatal("expected still ready below the failure threshold")
+	}
+
+	s.recordShipFailure(errors.New("backend down"))
+	if ready, reason := s.Ready(); ready || reason == "" {
+		t.Fatalf("expected not ready at the failure threshold, got ready=%v reason=%q", ready, reason)
+	}
+
+	s.recordShipSuccess()
+	if ready, _ := s.Ready(); !ready {
+		t.Fatal("expected ready again after a successful cycle")
+	}
+}
+
+func TestRunOnce_UpdatesStatus(t *testing.T) {
+	reg := collector.NewRegistry()
+	reg.Register(&mockCollector{name: "test"})
+	shpr := &retryShipper{failUntil: 999}
+
+	o := NewOrchestrator(reg, shpr, 10*time.Minute)
+	o.SetReadinessFailureThreshold(1)
+	o.status.recordShipSuccess()
+
+	_ = o.RunOnce(t.Context())
+	if ready, _ := o.Status().Ready(); ready {
+		t.Error("expected not ready after a failed ship with threshold 1")
+	}
+}
+
+func TestRunOnce_RecordsLastCycle(t *testing.T) {
+	reg := collector.NewRegistry()
+	broken := &mockCollector{name: "broken", err: errors.New("boom")}
+	reg.Register(&mockCollector{name: "ok", metrics: []collector.Metric{
+		{Name: "a", Type: "gauge", Labels: map[string]string{}},
+		{Name: "b", Type: "gauge", Labels: map[string]string{}},
+	}})
+	reg.Register(broken)
+
+	o := NewOrchestrator(reg, &mockShipper{}, 10*time.Minute)
+	if err := o.RunOnce(t.Context()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	cycle := o.Status().LastCycle()
+	if cycle.Time.IsZero() || cycle.Duration <= 0 {
+		t.Errorf("expected cycle time and duration to be set, got %+v", cycle)
+	}
+	if got := cycle.Collectors["ok"].MetricCount; got != 2 {
+		t.Errorf("expected 2 metrics from ok, got %d", got)
+	}
+	if got := cycle.Collectors["broken"].LastError; got != "boom" {
+		t.Errorf("expected last error boom, got %q", got)
+	}
+
+	// The last error sticks after the collector recovers
+	broken.err = nil
+	if err := o.RunOnce(t.Context()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if got := o.Status().LastCycle().Collectors["broken"]; got.LastError != "boom" || got.LastErrorAt.IsZero() {
+		t.Errorf("expected last error to be kept after recovery, got %+v", got)
+	}
+}
@@ -0,0 +1,83 @@
+// internal/plugin/aggregate.go
+package plugin
+
+import (
+	"math"
+	"time"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+// Aggregation functions for aggregate_func.
+const (
+	AggregateAvg  = "avg"
+	AggregateMin  = "min"
+	AggregateMax  = "max"
+	AggregateLast = "last"
+)
+
+// aggregate accumulates the samples of one series within the current window.
+type aggregate struct {
+	metric collector.Metric // Last sample, carrying name, labels, type and help
+	sum    float64
+	min    float64
+	max    float64
+	count  int
+}
+
+// aggregateWindow accumulates metrics until the aggregation window has passed
+// since it started, then returns one sample per series and starts a new
+// window; within the window it returns nothing. Counters are cumulative, so
+// they always ship their last value whatever the function.
+func (e *ExecPlugin) aggregateWindow(metrics []collector.Metric, now time.Time) []collector.Metric {
+	window := e.config.GetAggregateWindow()
+	fn := e.config.GetAggregateFunc()
+
+	e.aggMu.Lock()
+	defer e.aggMu.Unlock()
+
+	if e.aggregates == nil {
+		e.aggregates = make(map[string]*aggregate)
+		e.aggStart = now
+	}
+	for _, m := range metrics {
+		key := collector.SeriesKey(m)
+		a, ok := e.aggregates[key]
+		if !ok {
+			a = &aggregate{min: math.Inf(1), max: math.Inf(-1)}
+			e.aggregates[key] = a
+			e.aggOrder = append(e.aggOrder, key)
+		}
+		a.metric = m
+		a.sum += m.Value
+		a.min = math.Min(a.min, m.Value)
+		a.max = math.Max(a.max, m.Value)
+		a.count++
+	}
+
+	if now.Sub(e.aggStart) < window {
+		return []collector.Metric{}
+	}
+
+	out := make([]collector.Metric, 0, len(e.aggOrder))
+	for _, key := range e.aggOrder {
+		a := e.aggregates[key]
+		m := a.metric
+		m.Timestamp = time.Time{}
+		if m.Type != "counter" {
+			switch fn {
+			case AggregateAvg:
+				m.Value = a.sum / float64(a.count)
+			case AggregateMin:
+				m.Value = a.min
+			case AggregateMax:
+				m.Value = a.max
+			}
+		}
+		out = append(out, m)
+	}
+	e.aggregates = make(map[string]*aggregate)
+	e.aggOrder = nil
+	e.aggStart = now
+	return out
+}
@@ -0,0 +1,62 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+func TestAggregateWindow(t *testing.T) {
+	sample := func(v float64, typ string) []collector.Metric {
+		return []collector.Metric{{Name: "plugin_p_temp", Labels: map[string]string{"plugin": "p"}, Value: v, Type: typ}}
+	}
+	start := time.Unix(1700000000, 0)
+
+	for _, tc := range []struct {
+		fn   string
+		typ  string
+		want float64
+	}{
+		{"", "gauge", 4},
+		{AggregateMin, "gauge", 2},
+		{AggregateMax, "gauge", 7},
+		{AggregateLast, "gauge", 3},
+		{AggregateMax, "counter", 3}, // counters ship their last value
+	} {
+		t.Run(tc.fn+"/"+tc.typ, func(t *testing.T) {
+			ep := NewExecPlugin(PluginConfig{Name: "p", AggregateWindowSeconds: 10, AggregateFunc: tc.fn})
+
+			for i, v := range []float64{2, 7} {
+				if got := ep.aggregateWindow(sample(v, tc.typ), start.Add(time.Duration(i)*time.Second)); len(got) != 0 {
+					t.Fatalf("run %d within the window shipped %+v", i, got)
+				}
+			}
+			got := ep.aggregateWindow(sample(3, tc.typ), start.Add(10*time.Second))
+			if len(got) != 1 || got[0].Value != tc.want || got[0].Type != tc.typ {
+				t.Fatalf("want one %s sample of %v, got %+v", tc.typ, tc.want, got)
+			}
+
+			// The next window starts empty
+			if got := ep.aggregateWindow(sample(100, tc.typ), start.Add(11*time.Second)); len(got) != 0 {
+				t.Errorf("new window shipped early: %+v", got)
+			}
+			if got := ep.aggregateWindow(nil, start.Add(20*time.Second)); len(got) != 1 || got[0].Value != 100 {
+				t.Errorf("second window: want 100, got %+v", got)
+			}
+		})
+	}
+}
+
+func TestAggregateWindow_SeriesOrder(t *testing.T) {
+	ep := NewExecPlugin(PluginConfig{Name: "p", AggregateWindowSeconds: 1})
+	now := time.Unix(1700000000, 0)
+	ep.aggregateWindow([]collector.Metric{
+		{Name: "b", Labels: map[string]string{}, Value: 1},
+		{Name: "a", Labels: map[string]string{}, Value: 1},
+	}, now)
+	got := ep.aggregateWindow([]collector.Metric{{Name: "c", Labels: map[string]string{}, Value: 1}}, now.Add(time.Second))
+	if len(got) != 3 || got[0].Name != "b" || got[1].Name != "a" || got[2].Name != "c" {
+		t.Errorf("expected series in first-seen order, got %+v", got)
+	}
+}
@@ -16,8 +16,84 @@ type PluginConfig struct {
 	WorkingDir string   `json:"working_dir,omitempty"`
 	Enabled    *bool    `json:"enabled,omitempty"` // Pointer to distinguish unset from false
 	Interval   int      `json:"interval_seconds,omitempty"`
+	// AllowShell permits running a shell executable with -c and an inline script in Args
+	AllowShell bool `json:"allow_shell,omitempty"`
+	// CacheSeconds reuses the last successful result for this long instead of re-running
+	CacheSeconds int `json:"cache_seconds,omitempty"`
+	// MaxOutputBytes fails a run whose output (stdout, response body, file or
+	// socket reply) is larger (default: collector.plugins.max_output_bytes)
+	MaxOutputBytes int64 `json:"max_output_bytes,omitempty"`
+	// Scale and Offset transform every value as raw*scale + offset, e.g. KiB to bytes
+	Scale  *float64 `json:"scale,omitempty"` // Pointer to distinguish unset (1.0) from 0
+	Offset float64  `json:"offset,omitempty"`
+	// Rate ships the per-second rate of each (cumulative) value as a gauge
+	Rate bool `json:"rate,omitempty"`
+	// Observe records each value as a raw sample into a per-series histogram and
+	// ships its _bucket, _sum and _count series instead of the value
+	Observe bool      `json:"observe,omitempty"`
+	Buckets []float64 `json:"buckets,omitempty"` // Histogram upper bounds (default: DefaultBuckets)
+	// AggregateWindowSeconds accumulates values across runs and ships one
+	// sample per series per window, combined with AggregateFunc (default: avg)
+	AggregateWindowSeconds int    `json:"aggregate_window_seconds,omitempty"`
+	AggregateFunc          string `json:"aggregate_func,omitempty"`
+	// TCP configures a socket source instead of an executable. Mutually
+	// exclusive with Path/Args/Env/WorkingDir.
+	TCP *TCPSource `json:"tcp,omitempty"`
+	// HTTP and File configure a URL or file source instead of an executable,
+	// with the same exclusions as TCP.
+	HTTP *HTTPSource `json:"http,omitempty"`
+	File *FileSource `json:"file,omitempty"`
 }
 
+// TCPSource reads plugin output from a TCP socket.
+// The reply is read until Delimiter (default "\n") or EOF and parsed either as
+// the standard JSON metric array or as a single bare number.
+type TCPSource struct {
+	Address    string `json:"address"`               // host:port to dial
+	Send       string `json:"send,omitempty"`        // Optional payload written after connecting
+	Delimiter  string `json:"delimiter,omitempty"`   // Reply terminator (default "\n")
+	MetricName string `json:"metric_name,omitempty"` // Name for bare numeric replies (default "value")
+}
+
+// HTTPSource fetches plugin output with a GET request. A body with an
+// accepted status is parsed like TCP replies: the standard JSON metric array or a single bare number.
+// Header values, BearerToken, Username and Password expand ${ENV} references
+// so secrets can stay out of the definition file.
+type HTTPSource struct {
+	URL         string            `json:"url"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	BearerToken string            `json:"bearer_token,omitempty"`
+	Username    string            `json:"username,omitempty"` // Basic auth, with Password
+	Password    string            `json:"password,omitempty"`
+	MetricName  string            `json:"metric_name,omitempty"` // Name for bare numeric bodies (default "value")
+	// AcceptStatus lists the status codes whose body is parsed (default: 200)
+	AcceptStatus []int `json:"accept_status,omitempty"`
+}
+
+// FileSource reads plugin output from a file, parsed like TCP replies. Large
+// append-only files can be read partially: TailLines keeps only the last
+// lines (read backwards from the end), MaxBytes only the first bytes.
+// Glob reads every matching file instead of Path, e.g. sysfs trees.
+type FileSource struct {
+	Path string `json:"path,omitempty"`
+	Glob string `json:"glob,omitempty"`
+	// PathLabels is a regex over each globbed path whose named groups become
+	// labels, e.g. "thermal_zone(?P<zone>[0-9]+)" (default: a "path" label)
+	PathLabels string `json:"path_labels,omitempty"`
+	TailLines  int    `json:"tail_lines,omitempty"`
+	MaxBytes   int64  `json:"max_bytes,omitempty"`
+	MetricName string `json:"metric_name,omitempty"` // Name for bare numeric content (default "value")
+}
+
+// Plugin source types returned by detectSource.
+const (
+	SourceExec = "exec"
+	SourceTCP  = "tcp"
+	SourceHTTP = "http"
+	SourceFile = "file"
+	SourceGo   = "go" // Compiled-in Go plugins, reported by Manager.Statuses
+)
+
 // GetTimeout returns the timeout as a Duration, defaulting to fallback if unset.
 func (c PluginConfig) GetTimeout(fallback time.Duration) time.Duration {
 	if c.Timeout > 0 {
@@ -26,6 +102,46 @@ func (c PluginConfig) GetTimeout(fallback time.Duration) time.Duration {
 	return fallback
 }
 
+// GetCacheTTL returns the result cache TTL, zero when caching is disabled.
+func (c PluginConfig) GetCacheTTL() time.Duration {
+	if c.CacheSeconds > 0 {
+		return time.Duration(c.CacheSeconds) * time.Second
+	}
+	return 0
+}
+
+// GetScale returns the value multiplier, defaulting to 1.0 if unset.
+func (c PluginConfig) GetScale() float64 {
+	if c.Scale == nil {
+		return 1.0
+	}
+	return *c.Scale
+}
+
+// GetBuckets returns the histogram upper bounds for observe, defaulting to DefaultBuckets.
+func (c PluginConfig) GetBuckets() []float64 {
+	if len(c.Buckets) == 0 {
+		return DefaultBuckets
+	}
+	return c.Buckets
+}
+
+// GetAggregateWindow returns the aggregation window, zero when aggregation is disabled.
+func (c PluginConfig) GetAggregateWindow() time.Duration {
+	if c.AggregateWindowSeconds > 0 {
+		return time.Duration(c.AggregateWindowSeconds) * time.Second
+	}
+	return 0
+}
+
+// GetAggregateFunc returns the aggregation function, defaulting to AggregateAvg.
+func (c PluginConfig) GetAggregateFunc() string {
+	if c.AggregateFunc == "" {
+		return AggregateAvg
+	}
+	return c.AggregateFunc
+}
+
 // IsEnabled returns whether the plugin is enabled, defaulting to true if unset.
 func (c PluginConfig) IsEnabled() bool {
 	if c.Enabled == nil {
@@ -40,6 +156,9 @@ type PluginMetric struct {
 	Labels map[string]string `json:"labels,omitempty"`
 	Value  float64           `json:"value"`
 	Type   string            `json:"type,omitempty"` // "gauge" or "counter", defaults to "gauge"
+	Help   string            `json:"help,omitempty"` // Optional description of the metric
+	// Timestamp is an optional Unix time in seconds (fractional allowed) for delayed samples
+	Timestamp float64 `json:"timestamp,omitempty"`
 }
 
 // PluginHealth tracks the runtime health state of a single plugin.
@@ -55,6 +174,20 @@ type PluginHealth struct {
 	CircuitOpenUntil time.Time // Zero means circuit closed
 }
 
+// PluginStatus describes a loaded plugin for diagnostics.
+type PluginStatus struct {
+	Name            string
+	Source          string        // SourceExec, SourceTCP, SourceHTTP, SourceFile or SourceGo
+	Metric          string        // Name of bare numeric values (tcp, http and file sources)
+	Interval        time.Duration // 0 runs the plugin every collection cycle
+	Status          string        // As in PluginHealth
+	LastRun         time.Time     // Last successful run
+	NextRun         time.Time     // Zero means the next collection cycle
+	LastMetricCount int
+	LastValue       *float64 // Set when the last run returned a single sample
+	LastError       string
+}
+
 // DefaultTimeout is the fallback plugin timeout.
 const DefaultTimeout = 30 * time.Second
 
@@ -34,6 +34,16 @@ func TestPluginConfig_GetTimeout(t *testing.T) {
 	})
 }
 
+func TestPluginConfig_GetScale(t *testing.T) {
+	zero := 0.0
+	if got := (PluginConfig{}).GetScale(); got != 1.0 {
+		t.Errorf("GetScale() unset = %v, want 1.0", got)
+	}
+	if got := (PluginConfig{Scale: &zero}).GetScale(); got != 0 {
+		t.Errorf("GetScale() explicit zero = %v, want 0", got)
+	}
+}
+
 func TestPluginConfig_IsEnabled(t *testing.T) {
 	t.Run("nil pointer defaults to true", func(t *testing.T) {
 		cfg := PluginConfig{Enabled: nil}
@@ -2,37 +2,55 @@ package plugin
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
+
+	"github.com/0x524A/metricsd/internal/collector"
 )
 
 var skipExtensions = []string{".json", ".md", ".txt", ".example", ".bak", ".log", ".old", ".swp", ".tmp"}
 
 // DiscoverPlugins scans pluginsDir for executable files and returns ExecPlugin instances.
-func DiscoverPlugins(pluginsDir string, defaultTimeout time.Duration, validate bool) ([]*ExecPlugin, error) {
+// Files that fail to load are logged to logger and skipped; see LoadPlugins.
+func DiscoverPlugins(pluginsDir string, defaultTimeout time.Duration, validate bool, logger zerolog.Logger) ([]*ExecPlugin, error) {
+	plugins, loadErrs, err := LoadPlugins(pluginsDir, nil, logger)
+	for _, loadErr := range loadErrs {
+		logger.Warn().Err(loadErr).Msg("Skipping plugin")
+	}
+	for _, ep := range plugins {
+		ep.SetDefaultTimeout(defaultTimeout)
+	}
+	return plugins, err
+}
+
+// LoadPlugins scans pluginsDir like DiscoverPlugins but returns the problems
+// instead of logging them: one error per file that was skipped, each naming
+// the file. Executables not permitted by allow (nil permits all) are errors.
+// err is set only when the directory itself can't be read. What was found is
+// logged to logger.
+func LoadPlugins(pluginsDir string, allow *CommandAllowlist, logger zerolog.Logger) (plugins []*ExecPlugin, loadErrs []error, err error) {
 	info, err := os.Stat(pluginsDir)
 	if os.IsNotExist(err) {
-		log.Info().Str("dir", pluginsDir).Msg("Plugins directory does not exist, skipping")
-		return nil, nil
+		logger.Info().Str("dir", pluginsDir).Msg("Plugins directory does not exist, skipping")
+		return nil, nil, nil
 	}
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if !info.IsDir() {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	entries, err := os.ReadDir(pluginsDir)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	var plugins []*ExecPlugin
-
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
@@ -41,6 +59,17 @@ func DiscoverPlugins(pluginsDir string, defaultTimeout time.Duration, validate b
 		name := entry.Name()
 		rawPath := filepath.Join(pluginsDir, name)
 
+		// Standalone definitions (e.g. tcp sources) have no executable next to them
+		if strings.HasSuffix(name, ".json") {
+			ep, err := loadSourceDefinition(rawPath, &logger)
+			if err != nil {
+				loadErrs = append(loadErrs, fmt.Errorf("%s: %w", name, err))
+			} else if ep != nil {
+				plugins = append(plugins, ep)
+			}
+			continue
+		}
+
 		skip := false
 		for _, ext := range skipExtensions {
 			if strings.HasSuffix(name, ext) {
@@ -52,65 +81,163 @@ func DiscoverPlugins(pluginsDir string, defaultTimeout time.Duration, validate b
 			continue
 		}
 
-		resolvedPath, err := ValidatePluginPath(rawPath, pluginsDir)
+		ep, err := loadExecutable(rawPath, pluginsDir, allow, &logger)
 		if err != nil {
-			log.Warn().Str("file", name).Err(err).Msg("Skipping plugin — path validation failed")
-			continue
+			loadErrs = append(loadErrs, fmt.Errorf("%s: %w", name, err))
+		} else if ep != nil {
+			plugins = append(plugins, ep)
 		}
+	}
 
-		fileInfo, err := os.Stat(resolvedPath)
-		if err != nil {
-			log.Warn().Str("file", name).Err(err).Msg("Skipping plugin — stat failed")
-			continue
+	return plugins, loadErrs, nil
+}
+
+// loadExecutable loads an executable plugin and its optional <file>.json
+// sidecar. Returns nil without an error for non-executable and disabled files.
+func loadExecutable(rawPath, pluginsDir string, allow *CommandAllowlist, logger *zerolog.Logger) (*ExecPlugin, error) {
+	name := filepath.Base(rawPath)
+	resolvedPath, err := ValidatePluginPath(rawPath, pluginsDir)
+	if err != nil {
+		return nil, fmt.Errorf("path validation failed: %w", err)
+	}
+
+	fileInfo, err := os.Stat(resolvedPath)
+	if err != nil {
+		return nil, fmt.Errorf("stat failed: %w", err)
+	}
+	if fileInfo.Mode()&0111 == 0 {
+		return nil, nil
+	}
+	if fileInfo.Mode()&0002 != 0 {
+		logger.Warn().
+			Str("plugin", resolvedPath).
+			Msg("Plugin is world-writable — this is a security risk")
+	}
+	if !allow.Allows(resolvedPath) {
+		return nil, fmt.Errorf("%s is not in allowed_commands", resolvedPath)
+	}
+
+	defaultName := strings.TrimSuffix(name, filepath.Ext(name))
+	config := PluginConfig{
+		Name: defaultName,
+		Path: resolvedPath,
+	}
+
+	configPath := rawPath + ".json"
+	if data, err := os.ReadFile(configPath); err == nil {
+		// Path is not read from JSON, so the sidecar can't redirect the executable
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse plugin config %s: %w", filepath.Base(configPath), err)
 		}
-		if fileInfo.Mode()&0111 == 0 {
-			continue
+		if config.Name == "" {
+			config.Name = defaultName
 		}
+	}
 
-		config := PluginConfig{
-			Name: strings.TrimSuffix(name, filepath.Ext(name)),
-			Path: resolvedPath,
-		}
-
-		configPath := rawPath + ".json"
-		if data, err := os.ReadFile(configPath); err == nil {
-			var fileCfg PluginConfig
-			if err := json.Unmarshal(data, &fileCfg); err != nil {
-				log.Warn().Str("config", configPath).Err(err).Msg("Failed to parse plugin config")
-			} else {
-				if fileCfg.Name != "" {
-					config.Name = fileCfg.Name
-				}
-				if fileCfg.Timeout > 0 {
-					config.Timeout = fileCfg.Timeout
-				}
-				if len(fileCfg.Args) > 0 {
-					config.Args = fileCfg.Args
-				}
-				if len(fileCfg.Env) > 0 {
-					config.Env = fileCfg.Env
-				}
-				if fileCfg.WorkingDir != "" {
-					config.WorkingDir = fileCfg.WorkingDir
-				}
-				if fileCfg.Interval > 0 {
-					config.Interval = fileCfg.Interval
-				}
-				if fileCfg.Enabled != nil {
-					config.Enabled = fileCfg.Enabled
-				}
-			}
+	if err := expandPluginConfig(&config, collector.CurrentHostFacts()); err != nil {
+		return nil, err
+	}
+	if err := validatePluginDefinition(config); err != nil {
+		return nil, err
+	}
+	if !config.IsEnabled() {
+		logger.Info().Str("plugin", config.Name).Msg("Plugin disabled, skipping")
+		return nil, nil
+	}
+
+	logger.Info().Str("plugin", config.Name).Str("path", resolvedPath).Msg("Discovered plugin")
+	return NewExecPlugin(config), nil
+}
+
+// loadSourceDefinition loads a standalone JSON plugin definition that reads from
+// a non-executable source. Returns nil without an error for sidecar configs of
+// executables and for disabled definitions.
+func loadSourceDefinition(configPath string, logger *zerolog.Logger) (*ExecPlugin, error) {
+	if _, err := os.Stat(strings.TrimSuffix(configPath, ".json")); err == nil {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	var config PluginConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse plugin definition: %w", err)
+	}
+	if detectSource(config) == SourceExec {
+		return nil, nil
+	}
+	if config.Name == "" {
+		config.Name = strings.TrimSuffix(filepath.Base(configPath), ".json")
+	}
+
+	if err := expandPluginConfig(&config, collector.CurrentHostFacts()); err != nil {
+		return nil, err
+	}
+	if err := validatePluginDefinition(config); err != nil {
+		return nil, fmt.Errorf("invalid definition: %w", err)
+	}
+	if !config.IsEnabled() {
+		logger.Info().Str("plugin", config.Name).Msg("Plugin disabled, skipping")
+		return nil, nil
+	}
+
+	logger.Info().Str("plugin", config.Name).Str("source", detectSource(config)).Msg("Discovered plugin")
+	return NewExecPlugin(config), nil
+}
+
+// expandPluginConfig renders host fact templates in the args, env values,
+// working_dir, tcp address/send, http url and file path of a definition. The
+// executable path comes from discovery and is never templated.
+func expandPluginConfig(config *PluginConfig, facts collector.HostFacts) error {
+	expand := func(field string, s *string) error {
+		out, err := collector.ExpandHostTemplate(*s, facts)
+		if err != nil {
+			return fmt.Errorf("plugin %s: %s: %w", config.Name, field, err)
 		}
+		*s = out
+		return nil
+	}
 
-		if !config.IsEnabled() {
-			log.Info().Str("plugin", config.Name).Msg("Plugin disabled, skipping")
+	for i := range config.Args {
+		if err := expand("args", &config.Args[i]); err != nil {
+			return err
+		}
+	}
+	for i, kv := range config.Env {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
 			continue
 		}
-
-		ep := NewExecPlugin(config)
-		plugins = append(plugins, ep)
-		log.Info().Str("plugin", config.Name).Str("path", resolvedPath).Msg("Discovered plugin")
+		if err := expand("env "+k, &v); err != nil {
+			return err
+		}
+		config.Env[i] = k + "=" + v
 	}
-
-	return plugins, nil
+	if err := expand("working_dir", &config.WorkingDir); err != nil {
+		return err
+	}
+	if config.TCP != nil {
+		if err := expand("tcp.address", &config.TCP.Address); err != nil {
+			return err
+		}
+		if err := expand("tcp.send", &config.TCP.Send); err != nil {
+			return err
+		}
+	}
+	if config.HTTP != nil {
+		if err := expand("http.url", &config.HTTP.URL); err != nil {
+			return err
+		}
+	}
+	if config.File != nil {
+		if err := expand("file.path", &config.File.Path); err != nil {
+			return err
+		}
+		if err := expand("file.glob", &config.File.Glob); err != nil {
+			return err
+		}
+	}
+	return nil
 }
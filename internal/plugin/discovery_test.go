@@ -1,10 +1,15 @@
 package plugin
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/rs/zerolog"
 )
 
 func TestDiscoverPlugins(t *testing.T) {
@@ -21,7 +26,7 @@ func TestDiscoverPlugins(t *testing.T) {
 		os.WriteFile(filepath.Join(tmpDir, "plugin_a.json"), []byte(`{"name":"custom_a"}`), 0644)
 		os.WriteFile(filepath.Join(tmpDir, "README.md"), []byte("docs"), 0644)
 
-		plugins, err := DiscoverPlugins(tmpDir, 30*time.Second, false)
+		plugins, err := DiscoverPlugins(tmpDir, 30*time.Second, false, zerolog.Nop())
 		if err != nil {
 			t.Fatalf("DiscoverPlugins failed: %v", err)
 		}
@@ -41,7 +46,7 @@ func TestDiscoverPlugins(t *testing.T) {
 	})
 
 	t.Run("non-existent dir returns empty", func(t *testing.T) {
-		plugins, err := DiscoverPlugins("/nonexistent", 30*time.Second, false)
+		plugins, err := DiscoverPlugins("/nonexistent", 30*time.Second, false, zerolog.Nop())
 		if err != nil {
 			t.Fatalf("expected nil error, got: %v", err)
 		}
@@ -56,7 +61,7 @@ func TestDiscoverPlugins(t *testing.T) {
 		writeTestPlugin(t, disDir, "disabled_p", "#!/bin/bash\necho '[]'")
 		os.WriteFile(filepath.Join(disDir, "disabled_p.json"), []byte(`{"enabled":false}`), 0644)
 
-		plugins, err := DiscoverPlugins(disDir, 30*time.Second, false)
+		plugins, err := DiscoverPlugins(disDir, 30*time.Second, false, zerolog.Nop())
 		if err != nil {
 			t.Fatalf("DiscoverPlugins failed: %v", err)
 		}
@@ -70,7 +75,7 @@ func TestDiscoverPlugins(t *testing.T) {
 		defer os.RemoveAll(symDir)
 		os.Symlink("/usr/bin/env", filepath.Join(symDir, "escape"))
 
-		plugins, err := DiscoverPlugins(symDir, 30*time.Second, false)
+		plugins, err := DiscoverPlugins(symDir, 30*time.Second, false, zerolog.Nop())
 		if err != nil {
 			t.Fatalf("DiscoverPlugins failed: %v", err)
 		}
@@ -85,7 +90,7 @@ func TestDiscoverPlugins(t *testing.T) {
 		writeTestPlugin(t, cfgDir, "timed", "#!/bin/bash\necho '[]'")
 		os.WriteFile(filepath.Join(cfgDir, "timed.json"), []byte(`{"timeout":45}`), 0644)
 
-		plugins, err := DiscoverPlugins(cfgDir, 30*time.Second, false)
+		plugins, err := DiscoverPlugins(cfgDir, 30*time.Second, false, zerolog.Nop())
 		if err != nil {
 			t.Fatalf("DiscoverPlugins failed: %v", err)
 		}
@@ -99,3 +104,96 @@ func TestDiscoverPlugins(t *testing.T) {
 		}
 	})
 }
+
+func TestLoadPlugins_CollectsAllErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeTestPlugin(t, dir, "good", "#!/bin/bash\necho '[]'")
+	os.WriteFile(filepath.Join(dir, "good.json"), []byte(`{"scale":1024,"observe":true}`), 0644)
+	writeTestPlugin(t, dir, "conflict", "#!/bin/bash\necho '[]'")
+	os.WriteFile(filepath.Join(dir, "conflict.json"), []byte(`{"rate":true,"observe":true}`), 0644)
+	writeTestPlugin(t, dir, "garbled", "#!/bin/bash\necho '[]'")
+	os.WriteFile(filepath.Join(dir, "garbled.json"), []byte(`{nope`), 0644)
+	os.WriteFile(filepath.Join(dir, "sensor.json"), []byte(`{"tcp":{"address":"no-port"}}`), 0644)
+
+	plugins, loadErrs, err := LoadPlugins(dir, nil, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("LoadPlugins failed: %v", err)
+	}
+	if len(plugins) != 1 || plugins[0].config.Name != "good" {
+		t.Fatalf("expected only the good plugin, got %d", len(plugins))
+	}
+	if len(loadErrs) != 3 {
+		t.Errorf("expected an error per bad file, got %v", loadErrs)
+	}
+
+	// Every sidecar field applies, not just the scheduling ones
+	if cfg := plugins[0].config; cfg.GetScale() != 1024 || !cfg.Observe {
+		t.Errorf("sidecar fields not applied: %+v", cfg)
+	}
+}
+
+func TestLoadPlugins_WarnsOnInjectedLogger(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestPlugin(t, dir, "ww", "#!/bin/bash\necho '[]'")
+	if err := os.Chmod(path, 0757); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	plugins, _, err := LoadPlugins(dir, nil, zerolog.New(&buf))
+	if err != nil {
+		t.Fatalf("LoadPlugins failed: %v", err)
+	}
+	if len(plugins) != 1 {
+		t.Fatalf("expected the world-writable plugin to load, got %d", len(plugins))
+	}
+	if !strings.Contains(buf.String(), "world-writable") {
+		t.Errorf("expected a world-writable warning on the passed logger, got %q", buf.String())
+	}
+}
+
+func TestLoadPlugins_Templates(t *testing.T) {
+	t.Setenv("METRICSD_TEST_DC", "eu1")
+	hostname, _ := os.Hostname()
+
+	dir := t.TempDir()
+	writeTestPlugin(t, dir, "tmpl", "#!/bin/bash\necho '[]'")
+	os.WriteFile(filepath.Join(dir, "tmpl.json"), []byte(`{
+		"args": ["--host={{.Hostname}}", "--dc={{.Env.METRICSD_TEST_DC}}"],
+		"env": ["TARGET_OS={{.OS}}"]
+	}`), 0644)
+	writeTestPlugin(t, dir, "unset", "#!/bin/bash\necho '[]'")
+	os.WriteFile(filepath.Join(dir, "unset.json"), []byte(`{"args":["{{.Env.METRICSD_TEST_UNSET}}"]}`), 0644)
+
+	plugins, loadErrs, err := LoadPlugins(dir, nil, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("LoadPlugins failed: %v", err)
+	}
+	if len(plugins) != 1 || len(loadErrs) != 1 {
+		t.Fatalf("expected one plugin and one error, got %d and %v", len(plugins), loadErrs)
+	}
+	cfg := plugins[0].config
+	if cfg.Args[0] != "--host="+hostname || cfg.Args[1] != "--dc=eu1" {
+		t.Errorf("args not expanded: %v", cfg.Args)
+	}
+	if cfg.Env[0] != "TARGET_OS="+runtime.GOOS {
+		t.Errorf("env not expanded: %v", cfg.Env)
+	}
+}
+
+func TestLoadPlugins_Allowlist(t *testing.T) {
+	dir := t.TempDir()
+	writeTestPlugin(t, dir, "allowed", "#!/bin/bash\necho '[]'")
+	writeTestPlugin(t, dir, "blocked", "#!/bin/bash\necho '[]'")
+
+	plugins, loadErrs, err := LoadPlugins(dir, NewCommandAllowlist([]string{"allowed"}, dir), zerolog.Nop())
+	if err != nil {
+		t.Fatalf("LoadPlugins failed: %v", err)
+	}
+	if len(plugins) != 1 || plugins[0].config.Name != "allowed" {
+		t.Fatalf("expected only the allowed plugin, got %d", len(plugins))
+	}
+	if len(loadErrs) != 1 || !strings.Contains(loadErrs[0].Error(), "blocked") {
+		t.Errorf("expected an error for the blocked plugin, got %v", loadErrs)
+	}
+}
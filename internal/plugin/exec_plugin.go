@@ -7,36 +7,116 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
+	"net/http"
+	"os"
 	"os/exec"
+	"runtime"
 	"sync"
-	"syscall"
 	"time"
 
-	"github.com/rs/zerolog/log"
-
 	"github.com/0x524A/metricsd/internal/collector"
 )
 
-const defaultMaxOutputBytes = 5 * 1024 * 1024 // 5MB
+// DefaultMaxOutputBytes bounds the output read from a plugin run when neither
+// the definition nor the plugin system sets a limit
+const DefaultMaxOutputBytes = 5 * 1024 * 1024 // 5MB
 const maxStderrCapture = 4096                 // 4KB
 
 // ExecPlugin executes a shell script and parses its JSON output.
 type ExecPlugin struct {
+	collector.Logging
 	config         PluginConfig
 	mu             sync.Mutex
 	lastExecution  time.Time
+	lastCount      int      // Samples returned by the last run
+	lastValue      *float64 // Value of the last run when it returned a single sample
 	lastStderr     string
 	maxOutputBytes int64
+	userAgent      string       // User-Agent of http source requests
+	httpClient     *http.Client // Client of the http source; nil uses http.DefaultClient
+
+	// execMu serializes executions so concurrent triggers share one cached result
+	execMu   sync.Mutex
+	cached   []collector.Metric
+	cachedAt time.Time
+
+	// rateMu guards the previous samples used for rate: true
+	rateMu   sync.Mutex
+	previous map[string]rateSample
+
+	// histMu guards the histograms accumulated for observe: true
+	histMu     sync.Mutex
+	histograms map[string]*histogram
+	histOrder  []string // Series keys in first-seen order, for stable output
+
+	// aggMu guards the window accumulated for aggregate_window_seconds
+	aggMu      sync.Mutex
+	aggregates map[string]*aggregate
+	aggOrder   []string  // Series keys in first-seen order, for stable output
+	aggStart   time.Time // When the current window started
+}
+
+// rateSample is the last observed value of a series for rate computation
+type rateSample struct {
+	value float64
+	at    time.Time
 }
 
 // NewExecPlugin creates a new shell script plugin executor.
 func NewExecPlugin(config PluginConfig) *ExecPlugin {
+	maxOutput := int64(DefaultMaxOutputBytes)
+	if config.MaxOutputBytes > 0 {
+		maxOutput = config.MaxOutputBytes
+	}
 	return &ExecPlugin{
 		config:         config,
-		maxOutputBytes: defaultMaxOutputBytes,
+		maxOutputBytes: maxOutput,
 	}
 }
 
+// SetDefaultTimeout sets the timeout used when the plugin config has none
+func (e *ExecPlugin) SetDefaultTimeout(d time.Duration) {
+	if e.config.Timeout <= 0 && d >= time.Second {
+		e.config.Timeout = int(d / time.Second)
+	}
+}
+
+// SetDefaultMaxOutputBytes sets the output limit used when the plugin config
+// has none. Values <= 0 keep DefaultMaxOutputBytes.
+func (e *ExecPlugin) SetDefaultMaxOutputBytes(n int64) {
+	if e.config.MaxOutputBytes <= 0 && n > 0 {
+		e.maxOutputBytes = n
+	}
+}
+
+// Interval returns how often the plugin runs; 0 means every collection cycle
+func (e *ExecPlugin) Interval() time.Duration {
+	return time.Duration(e.config.Interval) * time.Second
+}
+
+// SetMinInterval raises a configured interval below d to d, rounded up to
+// whole seconds, and reports whether it did. Plugins without an interval run
+// once per collection cycle and are left alone.
+func (e *ExecPlugin) SetMinInterval(d time.Duration) bool {
+	if e.config.Interval <= 0 || e.Interval() >= d {
+		return false
+	}
+	e.config.Interval = int((d + time.Second - 1) / time.Second)
+	return true
+}
+
+// SetUserAgent sets the User-Agent header sent by the http source. Headers in
+// the definition win over it.
+func (e *ExecPlugin) SetUserAgent(ua string) {
+	e.userAgent = ua
+}
+
+// SetProxy routes http source requests through proxy
+func (e *ExecPlugin) SetProxy(proxy collector.ProxyConfig) {
+	e.httpClient = &http.Client{Transport: collector.ProxyTransport(proxy)}
+}
+
 // Name returns the collector name with plugin_ prefix.
 func (e *ExecPlugin) Name() string {
 	return fmt.Sprintf("plugin_%s", e.config.Name)
@@ -56,18 +136,81 @@ func (e *ExecPlugin) Collect(ctx context.Context) ([]collector.Metric, error) {
 	}
 	e.mu.Unlock()
 
-	metrics, err := e.execute(ctx)
+	metrics, err := e.executeCached(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	e.mu.Lock()
 	e.lastExecution = time.Now()
+	e.lastCount = len(metrics)
+	e.lastValue = nil
+	if len(metrics) == 1 {
+		v := metrics[0].Value
+		e.lastValue = &v
+	}
 	e.mu.Unlock()
 
 	return metrics, nil
 }
 
+// fillStatus adds the plugin's definition and last run to st. The next run is
+// due an interval after the last successful one; plugins without an interval,
+// or that never succeeded, run in the next collection cycle.
+func (e *ExecPlugin) fillStatus(st *PluginStatus) {
+	st.Source = detectSource(e.config)
+	st.Metric = bareMetricName(e.config)
+	st.Interval = e.Interval()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	st.LastRun = e.lastExecution
+	st.LastMetricCount = e.lastCount
+	st.LastValue = e.lastValue
+	if st.Interval > 0 && !e.lastExecution.IsZero() {
+		st.NextRun = e.lastExecution.Add(st.Interval)
+	}
+}
+
+// executeCached returns the cached result while it is younger than the
+// configured cache TTL, otherwise runs the plugin and caches a successful result.
+func (e *ExecPlugin) executeCached(ctx context.Context) ([]collector.Metric, error) {
+	ttl := e.config.GetCacheTTL()
+	if ttl == 0 {
+		return e.execute(ctx)
+	}
+
+	e.execMu.Lock()
+	defer e.execMu.Unlock()
+
+	if !e.cachedAt.IsZero() && time.Since(e.cachedAt) < ttl {
+		e.Logger().Debug().Str("plugin", e.config.Name).Time("cached_at", e.cachedAt).Msg("Returning cached plugin result")
+		return copyMetrics(e.cached), nil
+	}
+
+	metrics, err := e.execute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	e.cached = copyMetrics(metrics)
+	e.cachedAt = time.Now()
+	return metrics, nil
+}
+
+// copyMetrics deep-copies metrics so cached label maps are never shared.
+func copyMetrics(metrics []collector.Metric) []collector.Metric {
+	out := make([]collector.Metric, len(metrics))
+	for i, m := range metrics {
+		labels := make(map[string]string, len(m.Labels))
+		for k, v := range m.Labels {
+			labels[k] = v
+		}
+		m.Labels = labels
+		out[i] = m
+	}
+	return out
+}
+
 // LastStderr returns the last captured stderr output for diagnostics.
 func (e *ExecPlugin) LastStderr() string {
 	e.mu.Lock()
@@ -76,25 +219,35 @@ func (e *ExecPlugin) LastStderr() string {
 }
 
 func (e *ExecPlugin) execute(ctx context.Context) ([]collector.Metric, error) {
+	switch detectSource(e.config) {
+	case SourceTCP:
+		return e.executeTCP(ctx)
+	case SourceHTTP:
+		return e.executeHTTP(ctx)
+	case SourceFile:
+		return e.executeFile()
+	}
+
 	timeout := e.config.GetTimeout(DefaultTimeout)
 	execCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	cmd := exec.CommandContext(execCtx, e.config.Path, e.config.Args...)
 
-	// Set working directory (default /tmp)
+	// Set working directory (default /tmp, or the temp directory on Windows)
 	cmd.Dir = e.config.WorkingDir
 	if cmd.Dir == "" {
 		cmd.Dir = "/tmp"
+		if runtime.GOOS == "windows" {
+			cmd.Dir = os.TempDir()
+		}
 	}
 
 	// Safe environment — no os.Environ() inheritance
 	cmd.Env = BuildSafeEnv(e.config.Env)
 
-	// Orphan prevention on Linux
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		Pdeathsig: syscall.SIGTERM,
-	}
+	preventOrphan(cmd)
+	killProcessGroup(cmd)
 
 	// Capture stdout with size limit
 	var stdout bytes.Buffer
@@ -121,7 +274,7 @@ func (e *ExecPlugin) execute(ctx context.Context) ([]collector.Metric, error) {
 		return nil, fmt.Errorf("plugin %s failed: %w (stderr: %s)", e.config.Name, err, truncate(stderr.String(), 200))
 	}
 
-	log.Debug().
+	e.Logger().Debug().
 		Str("plugin", e.config.Name).
 		Dur("duration", duration).
 		Int("output_bytes", stdout.Len()).
@@ -136,17 +289,29 @@ func (e *ExecPlugin) execute(ctx context.Context) ([]collector.Metric, error) {
 		return nil, fmt.Errorf("plugin %s output exceeded %d bytes limit", e.config.Name, e.maxOutputBytes)
 	}
 
-	// Parse JSON output
+	return e.parseOutput(stdout.Bytes())
+}
+
+// parseOutput decodes the JSON metric array emitted by a plugin source and
+// converts it to prefixed collector metrics.
+func (e *ExecPlugin) parseOutput(data []byte) ([]collector.Metric, error) {
 	var pluginMetrics []PluginMetric
-	if err := json.Unmarshal(stdout.Bytes(), &pluginMetrics); err != nil {
+	if err := json.Unmarshal(data, &pluginMetrics); err != nil {
 		return nil, fmt.Errorf("failed to parse plugin %s output: %w", e.config.Name, err)
 	}
 
+	return e.convertMetrics(pluginMetrics), nil
+}
+
+// convertMetrics validates plugin metrics and converts them to collector metrics.
+func (e *ExecPlugin) convertMetrics(pluginMetrics []PluginMetric) []collector.Metric {
+
 	// Validate and sanitize
-	validated := ValidateMetricOutput(pluginMetrics, e.config.Name)
+	validated := validateMetricOutput(pluginMetrics, e.config.Name, e.Logger())
 
 	// Convert to collector.Metric with prefixing
 	prefix := fmt.Sprintf("plugin_%s_", e.config.Name)
+	scale, offset := e.config.GetScale(), e.config.Offset
 	metrics := make([]collector.Metric, 0, len(validated))
 
 	for _, pm := range validated {
@@ -164,15 +329,66 @@ func (e *ExecPlugin) execute(ctx context.Context) ([]collector.Metric, error) {
 		}
 		labels["plugin"] = e.config.Name
 
+		var timestamp time.Time
+		if pm.Timestamp > 0 {
+			sec, frac := math.Modf(pm.Timestamp)
+			timestamp = time.Unix(int64(sec), int64(frac*1e9))
+		}
+
 		metrics = append(metrics, collector.Metric{
-			Name:   prefix + pm.Name,
-			Labels: labels,
-			Value:  pm.Value,
-			Type:   metricType,
+			Name:      prefix + pm.Name,
+			Labels:    labels,
+			Value:     pm.Value*scale + offset,
+			Type:      metricType,
+			Help:      pm.Help,
+			Timestamp: timestamp,
 		})
 	}
 
-	return metrics, nil
+	if e.config.Rate {
+		metrics = e.applyRate(metrics, time.Now())
+	}
+	if e.config.Observe {
+		metrics = e.observe(metrics, time.Now())
+	}
+	if e.config.GetAggregateWindow() > 0 {
+		metrics = e.aggregateWindow(metrics, time.Now())
+	}
+	return metrics
+}
+
+// applyRate replaces each cumulative value with its per-second rate since the
+// previous run, shipped as a gauge. A series' first sample only primes the
+// state and is not emitted. A decrease is a counter reset, so the previous
+// value is taken as 0. Series missing from a run are forgotten.
+func (e *ExecPlugin) applyRate(metrics []collector.Metric, now time.Time) []collector.Metric {
+	e.rateMu.Lock()
+	defer e.rateMu.Unlock()
+
+	current := make(map[string]rateSample, len(metrics))
+	defer func() { e.previous = current }()
+
+	out := metrics[:0]
+	for _, m := range metrics {
+		at := m.TimestampOr(now)
+		key := collector.SeriesKey(m)
+		prev, seen := e.previous[key]
+		current[key] = rateSample{value: m.Value, at: at}
+
+		dt := at.Sub(prev.at).Seconds()
+		if !seen || dt <= 0 {
+			continue
+		}
+
+		delta := m.Value - prev.value
+		if delta < 0 {
+			delta = m.Value
+		}
+		m.Value = delta / dt
+		m.Type = "gauge"
+		out = append(out, m)
+	}
+	return out
 }
 
 // limitedWriter wraps a writer with a byte limit.
@@ -188,14 +404,18 @@ func (lw *limitedWriter) Write(p []byte) (int, error) {
 		lw.truncated = true
 		return len(p), nil // Discard but don't error — let the process finish
 	}
-	remaining := lw.limit - lw.written
-	if int64(len(p)) > remaining {
+	n := len(p)
+	if remaining := lw.limit - lw.written; int64(n) > remaining {
 		p = p[:remaining]
 		lw.truncated = true
 	}
-	n, err := lw.w.Write(p)
-	lw.written += int64(n)
-	return n, err
+	written, err := lw.w.Write(p)
+	lw.written += int64(written)
+	if err != nil {
+		return written, err
+	}
+	// Report the whole write as done so the copy keeps draining the pipe
+	return n, nil
 }
 
 func truncate(s string, maxLen int) string {
@@ -5,7 +5,9 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/0x524A/metricsd/internal/collector"
 )
@@ -152,6 +154,34 @@ func TestExecPlugin_Name(t *testing.T) {
 // Verify ExecPlugin satisfies collector.Collector interface
 var _ collector.Collector = (*ExecPlugin)(nil)
 
+func TestExecPlugin_SetMinInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval int
+		min      time.Duration
+		want     time.Duration
+		clamped  bool
+	}{
+		{"below floor", 1, 10 * time.Second, 10 * time.Second, true},
+		{"rounds up to whole seconds", 1, 2500 * time.Millisecond, 3 * time.Second, true},
+		{"at floor", 10, 10 * time.Second, 10 * time.Second, false},
+		{"above floor", 60, 10 * time.Second, 60 * time.Second, false},
+		{"every cycle left alone", 0, 10 * time.Second, 0, false},
+		{"no floor", 1, 0, time.Second, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ep := NewExecPlugin(PluginConfig{Name: "p", Interval: tc.interval})
+			if got := ep.SetMinInterval(tc.min); got != tc.clamped {
+				t.Errorf("SetMinInterval = %v, want %v", got, tc.clamped)
+			}
+			if got := ep.Interval(); got != tc.want {
+				t.Errorf("Interval = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
 // TestExecPlugin_LastStderr verifies that stderr output is captured and
 // returned by LastStderr even when the plugin succeeds.
 func TestExecPlugin_LastStderr(t *testing.T) {
@@ -242,3 +272,162 @@ func TestTruncate(t *testing.T) {
 		})
 	}
 }
+
+func TestExecPlugin_CacheSeconds(t *testing.T) {
+	tmpDir := t.TempDir()
+	counter := filepath.Join(tmpDir, "runs")
+	script := "#!/bin/bash\necho x >> " + counter + "\necho '[{\"name\":\"m\",\"value\":1}]'\n"
+
+	t.Run("cached result reused within TTL", func(t *testing.T) {
+		path := writeTestPlugin(t, tmpDir, "cached", script)
+		ep := NewExecPlugin(PluginConfig{Name: "cached", Path: path, Timeout: 5, CacheSeconds: 60})
+
+		for i := 0; i < 3; i++ {
+			metrics, err := ep.Collect(context.Background())
+			if err != nil {
+				t.Fatalf("Collect failed: %v", err)
+			}
+			if len(metrics) != 1 {
+				t.Fatalf("expected 1 metric, got %d", len(metrics))
+			}
+			metrics[0].Labels["mutated"] = "yes"
+		}
+
+		data, _ := os.ReadFile(counter)
+		if runs := len(data) / 2; runs != 1 {
+			t.Errorf("expected 1 execution, got %d", runs)
+		}
+
+		metrics, _ := ep.Collect(context.Background())
+		if _, ok := metrics[0].Labels["mutated"]; ok {
+			t.Error("cached labels should not be shared with callers")
+		}
+	})
+
+	t.Run("no caching when unset", func(t *testing.T) {
+		os.Remove(counter)
+		path := writeTestPlugin(t, tmpDir, "uncached", script)
+		ep := NewExecPlugin(PluginConfig{Name: "uncached", Path: path, Timeout: 5})
+		ep.Collect(context.Background())
+		ep.Collect(context.Background())
+
+		data, _ := os.ReadFile(counter)
+		if runs := len(data) / 2; runs != 2 {
+			t.Errorf("expected 2 executions, got %d", runs)
+		}
+	})
+}
+
+func TestExecPlugin_Timestamp(t *testing.T) {
+	ep := NewExecPlugin(PluginConfig{Name: "ts"})
+	metrics, err := ep.parseOutput([]byte(`[{"name":"delayed","value":1,"timestamp":1700000000.5},{"name":"now","value":2}]`))
+	if err != nil {
+		t.Fatalf("parseOutput failed: %v", err)
+	}
+	if len(metrics) != 2 {
+		t.Fatalf("expected 2 metrics, got %d", len(metrics))
+	}
+	if got := metrics[0].Timestamp.UnixMilli(); got != 1700000000500 {
+		t.Errorf("expected timestamp 1700000000500ms, got %d", got)
+	}
+	if !metrics[1].Timestamp.IsZero() {
+		t.Errorf("expected zero timestamp when omitted, got %v", metrics[1].Timestamp)
+	}
+}
+
+func TestExecPlugin_ScaleOffset(t *testing.T) {
+	kib, ratio := 1024.0, 0.01
+	tests := []struct {
+		name   string
+		scale  *float64
+		offset float64
+		want   float64
+	}{
+		{"unset keeps raw value", nil, 0, 50},
+		{"KiB to bytes", &kib, 0, 51200},
+		{"percent to ratio", &ratio, 0, 0.5},
+		{"scale and offset", &ratio, -1, -0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ep := NewExecPlugin(PluginConfig{Name: "unit", Scale: tt.scale, Offset: tt.offset})
+			metrics, err := ep.parseOutput([]byte(`[{"name":"v","value":50}]`))
+			if err != nil {
+				t.Fatalf("parseOutput failed: %v", err)
+			}
+			if got := metrics[0].Value; got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExecPlugin_Rate(t *testing.T) {
+	ep := NewExecPlugin(PluginConfig{Name: "r", Rate: true})
+	start := time.Unix(1700000000, 0)
+	sample := func(v float64) []collector.Metric {
+		return []collector.Metric{{Name: "bytes", Value: v, Type: "counter", Labels: map[string]string{"dev": "eth0"}}}
+	}
+
+	if got := ep.applyRate(sample(100), start); len(got) != 0 {
+		t.Fatalf("first sample should only prime state, got %v", got)
+	}
+
+	got := ep.applyRate(sample(300), start.Add(10*time.Second))
+	if len(got) != 1 || got[0].Value != 20 || got[0].Type != "gauge" {
+		t.Fatalf("expected 20/s gauge, got %+v", got)
+	}
+
+	// Counter reset: previous value treated as 0
+	got = ep.applyRate(sample(50), start.Add(20*time.Second))
+	if len(got) != 1 || got[0].Value != 5 {
+		t.Fatalf("expected 5/s after reset, got %+v", got)
+	}
+
+	// Same timestamp gives no rate
+	if got := ep.applyRate(sample(60), start.Add(20*time.Second)); len(got) != 0 {
+		t.Errorf("expected no output for zero elapsed time, got %+v", got)
+	}
+}
+
+func TestExecPlugin_MaxOutputBytes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "value")
+	if err := os.WriteFile(path, []byte("12345678\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		definition int64
+		system     int64
+		wantErr    bool
+	}{
+		{"default limit", 0, 0, false},
+		{"definition limit", 4, 0, true},
+		{"system default", 0, 4, true},
+		{"definition wins over system default", 64, 4, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ep := NewExecPlugin(PluginConfig{Name: "file", File: &FileSource{Path: path}, MaxOutputBytes: tc.definition})
+			ep.SetDefaultMaxOutputBytes(tc.system)
+			_, err := ep.Collect(context.Background())
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Collect error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "exceeded") {
+				t.Errorf("expected an output limit error, got %v", err)
+			}
+		})
+	}
+
+	t.Run("command output", func(t *testing.T) {
+		script := writeTestPlugin(t, dir, "chatty", "#!/bin/sh\nhead -c 100000 /dev/zero\n")
+		ep := NewExecPlugin(PluginConfig{Name: "chatty", Path: script, Timeout: 5, MaxOutputBytes: 1000})
+		_, err := ep.Collect(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "exceeded 1000 bytes") {
+			t.Errorf("expected an output limit error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,201 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+// tailChunkSize is how much of the file is read per step when scanning
+// backwards for tail_lines
+const tailChunkSize = 64 * 1024
+
+// executeFile reads the configured file, or just its head or tail, and parses
+// the content like a TCP reply. With a glob every matching file is read and
+// its metrics are labelled from the file's path.
+func (e *ExecPlugin) executeFile() ([]collector.Metric, error) {
+	src := e.config.File
+	if src.Glob != "" {
+		return e.executeFileGlob()
+	}
+	pluginMetrics, err := e.readFileSource(src.Path)
+	if err != nil {
+		return nil, err
+	}
+	return e.convertMetrics(pluginMetrics), nil
+}
+
+// executeFileGlob reads every file matching the glob. A file whose path does
+// not match path_labels, or that can't be read, is skipped with a warning;
+// the run fails only when every matched file failed. No matches is not an error.
+func (e *ExecPlugin) executeFileGlob() ([]collector.Metric, error) {
+	src := e.config.File
+	paths, err := filepath.Glob(src.Glob)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: invalid glob %q: %w", e.config.Name, src.Glob, err)
+	}
+	if len(paths) == 0 {
+		e.Logger().Debug().Str("plugin", e.config.Name).Str("glob", src.Glob).Msg("Plugin file glob matched no files")
+		return []collector.Metric{}, nil
+	}
+
+	var pathRegex *regexp.Regexp
+	if src.PathLabels != "" {
+		pathRegex = regexp.MustCompile(src.PathLabels) // Checked by validatePluginDefinition
+	}
+
+	var all []PluginMetric
+	var lastErr error
+	for _, path := range paths {
+		labels, ok := fileLabels(path, pathRegex)
+		if !ok {
+			e.Logger().Warn().Str("plugin", e.config.Name).Str("path", path).Msg("Skipping file not matched by path_labels")
+			continue
+		}
+		pluginMetrics, err := e.readFileSource(path)
+		if err != nil {
+			e.Logger().Warn().Err(err).Str("plugin", e.config.Name).Str("path", path).Msg("Skipping unreadable file")
+			lastErr = err
+			continue
+		}
+		lastErr = nil
+		for _, pm := range pluginMetrics {
+			merged := make(map[string]string, len(pm.Labels)+len(labels))
+			for k, v := range pm.Labels {
+				merged[k] = v
+			}
+			for k, v := range labels {
+				merged[k] = v
+			}
+			pm.Labels = merged
+			all = append(all, pm)
+		}
+	}
+	if len(all) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return e.convertMetrics(all), nil
+}
+
+// fileLabels derives labels from a matched path: the named groups of
+// pathRegex, or a single "path" label when there is no regex
+func fileLabels(path string, pathRegex *regexp.Regexp) (map[string]string, bool) {
+	if pathRegex == nil {
+		return map[string]string{"path": path}, true
+	}
+	match := pathRegex.FindStringSubmatch(path)
+	if match == nil {
+		return nil, false
+	}
+	labels := make(map[string]string)
+	for i, name := range pathRegex.SubexpNames() {
+		if name != "" {
+			labels[name] = match[i]
+		}
+	}
+	return labels, true
+}
+
+// readFileSource reads one file, or just its head or tail, and decodes it as
+// a JSON metric array or a bare number.
+func (e *ExecPlugin) readFileSource(path string) ([]PluginMetric, error) {
+	src := e.config.File
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s failed to open %s: %w", e.config.Name, path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	startTime := time.Now()
+	var data []byte
+	switch {
+	case src.TailLines > 0:
+		data, err = readTailLines(f, src.TailLines, e.maxOutputBytes)
+	case src.MaxBytes > 0:
+		data, err = io.ReadAll(io.LimitReader(f, min(src.MaxBytes, e.maxOutputBytes+1)))
+	default:
+		data, err = io.ReadAll(io.LimitReader(f, e.maxOutputBytes+1))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s failed to read %s: %w", e.config.Name, path, err)
+	}
+	if int64(len(data)) > e.maxOutputBytes {
+		return nil, fmt.Errorf("plugin %s output exceeded %d bytes limit", e.config.Name, e.maxOutputBytes)
+	}
+
+	e.Logger().Debug().
+		Str("plugin", e.config.Name).
+		Str("path", path).
+		Dur("duration", time.Since(startTime)).
+		Int("output_bytes", len(data)).
+		Msg("Plugin file source read")
+
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	// A bare number is reported as a single gauge
+	if value, err := strconv.ParseFloat(string(data), 64); err == nil {
+		name := src.MetricName
+		if name == "" {
+			name = defaultSourceMetricName
+		}
+		return []PluginMetric{{Name: name, Value: value}}, nil
+	}
+
+	var pluginMetrics []PluginMetric
+	if err := json.Unmarshal(data, &pluginMetrics); err != nil {
+		return nil, fmt.Errorf("failed to parse plugin %s output: %w", e.config.Name, err)
+	}
+	return pluginMetrics, nil
+}
+
+// readTailLines returns the last n lines of f, ignoring a trailing newline,
+// reading backwards from the end so the cost depends on the tail, not the
+// file size. Once more than limit bytes are buffered without finding n lines,
+// what was read is returned and the caller reports the overflow.
+func readTailLines(f *os.File, n int, limit int64) ([]byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf []byte
+	for pos := info.Size(); pos > 0; {
+		size := min(int64(tailChunkSize), pos)
+		pos -= size
+		chunk := make([]byte, size)
+		if _, err := f.ReadAt(chunk, pos); err != nil && err != io.EOF {
+			return nil, err
+		}
+		buf = append(chunk, buf...)
+
+		content := bytes.TrimRight(buf, "\r\n")
+		if pos == 0 || bytes.Count(content, []byte{'\n'}) >= n {
+			break
+		}
+		if int64(len(buf)) > limit {
+			return buf, nil
+		}
+	}
+
+	content := bytes.TrimRight(buf, "\r\n")
+	end := len(content)
+	for i := 0; i < n; i++ {
+		nl := bytes.LastIndexByte(content[:end], '\n')
+		if nl < 0 {
+			return content, nil
+		}
+		end = nl
+	}
+	return content[end+1:], nil
+}
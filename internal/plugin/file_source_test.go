@@ -0,0 +1,161 @@
+// internal/plugin/file_source_test.go
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestExecPlugin_FileSource(t *testing.T) {
+	dir := t.TempDir()
+
+	// A log larger than the tail chunk so the backwards scan crosses reads
+	var log strings.Builder
+	for i := 1; i <= 20000; i++ {
+		log.WriteString(strconv.Itoa(i) + "\n")
+	}
+	logPath := filepath.Join(dir, "counter.log")
+	os.WriteFile(logPath, []byte(log.String()), 0644)
+	jsonPath := filepath.Join(dir, "metrics.json")
+	os.WriteFile(jsonPath, []byte(`[{"name":"a","value":1}]`+"\ntrailing garbage"), 0644)
+
+	collect := func(t *testing.T, src *FileSource, maxOutput int64) ([]float64, error) {
+		t.Helper()
+		ep := NewExecPlugin(PluginConfig{Name: "file", File: src})
+		if maxOutput > 0 {
+			ep.maxOutputBytes = maxOutput
+		}
+		metrics, err := ep.Collect(context.Background())
+		values := make([]float64, 0, len(metrics))
+		for _, m := range metrics {
+			values = append(values, m.Value)
+		}
+		return values, err
+	}
+
+	t.Run("last line", func(t *testing.T) {
+		values, err := collect(t, &FileSource{Path: logPath, TailLines: 1}, 0)
+		if err != nil {
+			t.Fatalf("Collect failed: %v", err)
+		}
+		if len(values) != 1 || values[0] != 20000 {
+			t.Errorf("expected last value 20000, got %v", values)
+		}
+	})
+
+	t.Run("tail bounded by output limit", func(t *testing.T) {
+		if _, err := collect(t, &FileSource{Path: logPath, TailLines: 20000}, 1024); err == nil || !strings.Contains(err.Error(), "exceeded") {
+			t.Errorf("expected output limit error, got %v", err)
+		}
+	})
+
+	t.Run("first bytes", func(t *testing.T) {
+		values, err := collect(t, &FileSource{Path: jsonPath, MaxBytes: 24}, 0)
+		if err != nil {
+			t.Fatalf("Collect failed: %v", err)
+		}
+		if len(values) != 1 || values[0] != 1 {
+			t.Errorf("expected the JSON head to parse, got %v", values)
+		}
+	})
+
+	t.Run("whole file over limit", func(t *testing.T) {
+		if _, err := collect(t, &FileSource{Path: logPath}, 1024); err == nil {
+			t.Error("expected output limit error for a full read")
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := collect(t, &FileSource{Path: filepath.Join(dir, "missing")}, 0); err == nil {
+			t.Error("expected an error for a missing file")
+		}
+	})
+}
+
+func TestReadTailLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		n       int
+		want    string
+	}{
+		{"trailing newline", "1\n2\n3\n", 1, "3"},
+		{"no trailing newline", "1\n2\n3", 2, "2\n3"},
+		{"more lines than file", "1\n2\n", 5, "1\n2"},
+		{"single line", "42", 1, "42"},
+		{"empty", "", 1, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "f")
+			os.WriteFile(path, []byte(tc.content), 0644)
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			got, err := readTailLines(f, tc.n, 1<<20)
+			if err != nil {
+				t.Fatalf("readTailLines failed: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExecPlugin_FileSourceGlob(t *testing.T) {
+	dir := t.TempDir()
+	for zone, temp := range map[string]string{"0": "41000\n", "1": "52500\n"} {
+		zoneDir := filepath.Join(dir, "thermal_zone"+zone)
+		os.MkdirAll(zoneDir, 0755)
+		os.WriteFile(filepath.Join(zoneDir, "temp"), []byte(temp), 0644)
+	}
+	scale := 0.001
+
+	t.Run("labels from named groups", func(t *testing.T) {
+		ep := NewExecPlugin(PluginConfig{Name: "thermal", Scale: &scale, File: &FileSource{
+			Glob:       filepath.Join(dir, "thermal_zone*", "temp"),
+			PathLabels: `thermal_zone(?P<zone>[0-9]+)`,
+			MetricName: "celsius",
+		}})
+		metrics, err := ep.Collect(context.Background())
+		if err != nil {
+			t.Fatalf("Collect failed: %v", err)
+		}
+		got := map[string]float64{}
+		for _, m := range metrics {
+			if m.Name != "plugin_thermal_celsius" {
+				t.Errorf("unexpected metric name %s", m.Name)
+			}
+			got[m.Labels["zone"]] = m.Value
+		}
+		if len(got) != 2 || got["0"] != 41 || got["1"] != 52.5 {
+			t.Errorf("unexpected per-zone values: %v", got)
+		}
+	})
+
+	t.Run("default path label", func(t *testing.T) {
+		ep := NewExecPlugin(PluginConfig{Name: "thermal", File: &FileSource{Glob: filepath.Join(dir, "thermal_zone0", "temp")}})
+		metrics, err := ep.Collect(context.Background())
+		if err != nil {
+			t.Fatalf("Collect failed: %v", err)
+		}
+		if len(metrics) != 1 || metrics[0].Labels["path"] != filepath.Join(dir, "thermal_zone0", "temp") {
+			t.Errorf("expected a path label, got %+v", metrics)
+		}
+	})
+
+	t.Run("no matches", func(t *testing.T) {
+		ep := NewExecPlugin(PluginConfig{Name: "thermal", File: &FileSource{Glob: filepath.Join(dir, "cooling*", "temp")}})
+		metrics, err := ep.Collect(context.Background())
+		if err != nil || len(metrics) != 0 {
+			t.Errorf("expected no metrics and no error, got %v, %v", metrics, err)
+		}
+	})
+}
@@ -0,0 +1,159 @@
+// internal/plugin/histogram.go
+package plugin
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+// DefaultBuckets are the histogram upper bounds used by observe when buckets is unset.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// observeStaleAfter is how long a histogram is kept without new samples. For
+// plugins with a long interval it is observeStaleRuns intervals instead.
+const (
+	observeStaleAfter = time.Hour
+	observeStaleRuns  = 10
+)
+
+// histogram accumulates observed samples of one series since startup.
+type histogram struct {
+	name   string
+	labels map[string]string
+	help   string
+	counts []uint64 // Per bucket, not cumulative; the last entry is +Inf
+	sum    float64
+	count  uint64
+	seen   time.Time // When the series last had a sample
+}
+
+// observe records each metric value as a sample of its series' histogram and
+// returns the _bucket, _sum and _count series of every histogram still kept.
+// Histograms without samples for observeStaleAfter are dropped so series that
+// come and go, e.g. labelled by request ID, don't accumulate forever.
+func (e *ExecPlugin) observe(metrics []collector.Metric, now time.Time) []collector.Metric {
+	buckets := e.config.GetBuckets()
+
+	e.histMu.Lock()
+	defer e.histMu.Unlock()
+
+	if e.histograms == nil {
+		e.histograms = make(map[string]*histogram)
+	}
+	e.evictHistograms(now)
+	for _, m := range metrics {
+		key := collector.SeriesKey(m)
+		h, ok := e.histograms[key]
+		if !ok {
+			h = &histogram{name: m.Name, labels: m.Labels, help: m.Help, counts: make([]uint64, len(buckets)+1)}
+			e.histograms[key] = h
+			e.histOrder = append(e.histOrder, key)
+		}
+		// First bound >= value, i.e. the bucket with le >= value; +Inf when past the end
+		h.counts[sort.SearchFloat64s(buckets, m.Value)]++
+		h.sum += m.Value
+		h.count++
+		h.seen = now
+	}
+
+	out := make([]collector.Metric, 0, len(e.histOrder)*(len(buckets)+3))
+	for _, key := range e.histOrder {
+		h := e.histograms[key]
+		var cumulative uint64
+		for i, n := range h.counts {
+			cumulative += n
+			le := "+Inf"
+			if i < len(buckets) {
+				le = strconv.FormatFloat(buckets[i], 'g', -1, 64)
+			}
+			labels := h.copyLabels()
+			labels["le"] = le
+			out = append(out, collector.Metric{Name: h.name + "_bucket", Labels: labels, Value: float64(cumulative), Type: "counter", Help: h.help})
+		}
+		out = append(out,
+			collector.Metric{Name: h.name + "_sum", Labels: h.copyLabels(), Value: h.sum, Type: "counter", Help: h.help},
+			collector.Metric{Name: h.name + "_count", Labels: h.copyLabels(), Value: float64(h.count), Type: "counter", Help: h.help},
+		)
+	}
+	return out
+}
+
+// evictHistograms drops the histograms without samples for the stale period
+func (e *ExecPlugin) evictHistograms(now time.Time) {
+	staleAfter := max(observeStaleAfter, observeStaleRuns*e.Interval())
+	kept := e.histOrder[:0]
+	for _, key := range e.histOrder {
+		if now.Sub(e.histograms[key].seen) > staleAfter {
+			delete(e.histograms, key)
+			continue
+		}
+		kept = append(kept, key)
+	}
+	e.histOrder = kept
+}
+
+// copyLabels returns a fresh label map so shipped metrics never share state
+func (h *histogram) copyLabels() map[string]string {
+	labels := make(map[string]string, len(h.labels)+1)
+	for k, v := range h.labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// savedHistogram is a histogram as persisted by SaveState
+type savedHistogram struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels"`
+	Help   string            `json:"help,omitempty"`
+	Counts []uint64          `json:"counts"`
+	Sum    float64           `json:"sum"`
+	Count  uint64            `json:"count"`
+	Seen   time.Time         `json:"seen"`
+}
+
+// SaveState returns the observe histograms in first-seen order, so their
+// _bucket, _sum and _count counters continue after a restart
+func (e *ExecPlugin) SaveState() (json.RawMessage, error) {
+	e.histMu.Lock()
+	defer e.histMu.Unlock()
+	saved := make([]savedHistogram, 0, len(e.histOrder))
+	for _, key := range e.histOrder {
+		h := e.histograms[key]
+		saved = append(saved, savedHistogram{Name: h.name, Labels: h.labels, Help: h.help, Counts: h.counts, Sum: h.sum, Count: h.count, Seen: h.seen})
+	}
+	return json.Marshal(saved)
+}
+
+// RestoreState loads histograms saved by SaveState. A histogram saved with a
+// different number of buckets than configured now is dropped and starts over;
+// one saved without a last sample time counts as seen at restore.
+func (e *ExecPlugin) RestoreState(data json.RawMessage) error {
+	var saved []savedHistogram
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+	buckets := e.config.GetBuckets()
+	now := time.Now()
+
+	e.histMu.Lock()
+	defer e.histMu.Unlock()
+	e.histograms = make(map[string]*histogram, len(saved))
+	e.histOrder = nil
+	for _, s := range saved {
+		if len(s.Counts) != len(buckets)+1 {
+			continue
+		}
+		if s.Seen.IsZero() {
+			s.Seen = now
+		}
+		key := collector.SeriesKey(collector.Metric{Name: s.Name, Labels: s.Labels})
+		e.histograms[key] = &histogram{name: s.Name, labels: s.Labels, help: s.Help, counts: s.Counts, sum: s.Sum, count: s.Count, seen: s.Seen}
+		e.histOrder = append(e.histOrder, key)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+func TestObserve(t *testing.T) {
+	ep := NewExecPlugin(PluginConfig{Name: "lat", Observe: true, Buckets: []float64{0.1, 1}})
+
+	values := func(vs ...float64) []PluginMetric {
+		out := make([]PluginMetric, len(vs))
+		for i, v := range vs {
+			out[i] = PluginMetric{Name: "request_seconds", Value: v, Labels: map[string]string{"path": "/"}}
+		}
+		return out
+	}
+
+	got := ep.convertMetrics(values(0.05, 0.1, 0.5, 3))
+	want := map[string]float64{"0.1": 2, "1": 3, "+Inf": 4}
+	var sum, count float64
+	for _, m := range got {
+		switch m.Name {
+		case "plugin_lat_request_seconds_bucket":
+			if m.Type != "counter" || m.Labels["path"] != "/" {
+				t.Errorf("unexpected bucket: %+v", m)
+			}
+			if m.Value != want[m.Labels["le"]] {
+				t.Errorf("bucket le=%s = %v, want %v", m.Labels["le"], m.Value, want[m.Labels["le"]])
+			}
+			delete(want, m.Labels["le"])
+		case "plugin_lat_request_seconds_sum":
+			sum = m.Value
+		case "plugin_lat_request_seconds_count":
+			count = m.Value
+		default:
+			t.Errorf("unexpected metric %s", m.Name)
+		}
+	}
+	if len(want) != 0 {
+		t.Errorf("missing buckets %v", want)
+	}
+	if sum != 3.65 || count != 4 {
+		t.Errorf("sum/count = %v/%v, want 3.65/4", sum, count)
+	}
+
+	// Histograms are cumulative across runs and keep being shipped without new samples
+	got = ep.convertMetrics(values(0.01))
+	if len(got) != 5 {
+		t.Fatalf("expected 3 buckets + sum + count, got %d", len(got))
+	}
+	if got[0].Labels["le"] != "0.1" || got[0].Value != 3 {
+		t.Errorf("expected le=0.1 bucket of 3, got %+v", got[0])
+	}
+	if got := ep.convertMetrics(nil); len(got) != 5 || got[4].Value != 5 {
+		t.Errorf("expected count 5 with no new samples, got %+v", got)
+	}
+}
+
+func TestObserve_SaveRestoreState(t *testing.T) {
+	cfg := PluginConfig{Name: "lat", Observe: true, Buckets: []float64{0.1, 1}}
+	ep := NewExecPlugin(cfg)
+	ep.convertMetrics([]PluginMetric{{Name: "request_seconds", Value: 0.5, Labels: map[string]string{"path": "/"}}})
+	data, err := ep.SaveState()
+	if err != nil {
+		t.Fatalf("SaveState: %v", err)
+	}
+
+	restored := NewExecPlugin(cfg)
+	if err := restored.RestoreState(data); err != nil {
+		t.Fatalf("RestoreState: %v", err)
+	}
+	got := restored.convertMetrics([]PluginMetric{{Name: "request_seconds", Value: 2, Labels: map[string]string{"path": "/"}}})
+	if len(got) != 5 || got[3].Value != 2.5 || got[4].Value != 2 {
+		t.Errorf("expected sum 2.5 and count 2 continuing the saved histogram, got %+v", got)
+	}
+
+	// Changed buckets start over
+	rebucketed := NewExecPlugin(PluginConfig{Name: "lat", Observe: true, Buckets: []float64{1}})
+	if err := rebucketed.RestoreState(data); err != nil {
+		t.Fatalf("RestoreState: %v", err)
+	}
+	if got := rebucketed.convertMetrics(nil); len(got) != 0 {
+		t.Errorf("expected the histogram with other buckets to be dropped, got %+v", got)
+	}
+}
+
+func TestObserve_EvictsStaleHistograms(t *testing.T) {
+	sample := func(path string) []collector.Metric {
+		return []collector.Metric{{Name: "request_seconds", Value: 0.5, Labels: map[string]string{"path": path}}}
+	}
+	paths := func(metrics []collector.Metric) map[string]bool {
+		seen := make(map[string]bool)
+		for _, m := range metrics {
+			seen[m.Labels["path"]] = true
+		}
+		return seen
+	}
+
+	t.Run("after an hour", func(t *testing.T) {
+		ep := NewExecPlugin(PluginConfig{Name: "lat", Observe: true, Buckets: []float64{1}})
+		start := time.Unix(1_700_000_000, 0)
+		ep.observe(sample("/a"), start)
+		ep.observe(sample("/b"), start.Add(30*time.Minute))
+
+		if got := paths(ep.observe(nil, start.Add(time.Hour))); !got["/a"] || !got["/b"] {
+			t.Errorf("expected both histograms within the hour, got %v", got)
+		}
+		if got := paths(ep.observe(nil, start.Add(time.Hour+time.Minute))); got["/a"] || !got["/b"] {
+			t.Errorf("expected only /b after /a went stale, got %v", got)
+		}
+		if len(ep.histograms) != 1 || len(ep.histOrder) != 1 {
+			t.Errorf("expected the stale histogram to be forgotten, got %d/%d", len(ep.histograms), len(ep.histOrder))
+		}
+	})
+
+	t.Run("long interval", func(t *testing.T) {
+		ep := NewExecPlugin(PluginConfig{Name: "lat", Observe: true, Buckets: []float64{1}, Interval: 3600})
+		start := time.Unix(1_700_000_000, 0)
+		ep.observe(sample("/a"), start)
+		if got := paths(ep.observe(nil, start.Add(5*time.Hour))); !got["/a"] {
+			t.Errorf("expected the histogram kept for %d runs, got %v", observeStaleRuns, got)
+		}
+		if got := paths(ep.observe(nil, start.Add(11*time.Hour))); got["/a"] {
+			t.Errorf("expected the histogram dropped after %d runs, got %v", observeStaleRuns, got)
+		}
+	})
+}
+
+func TestGetBuckets(t *testing.T) {
+	if got := (PluginConfig{}).GetBuckets(); len(got) != len(DefaultBuckets) {
+		t.Errorf("expected default buckets, got %v", got)
+	}
+	if got := (PluginConfig{Buckets: []float64{1, 2}}).GetBuckets(); len(got) != 2 {
+		t.Errorf("expected configured buckets, got %v", got)
+	}
+}
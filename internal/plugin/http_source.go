@@ -0,0 +1,92 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+// defaultHTTPClient is used until SetProxy sets a client; unlike
+// http.DefaultClient it also serves unix:// URLs
+var defaultHTTPClient = &http.Client{Transport: collector.ProxyTransport(collector.ProxyConfig{})}
+
+// executeHTTP fetches the configured URL with its auth headers and parses the
+// body, bounded by the plugin timeout and output limit.
+func (e *ExecPlugin) executeHTTP(ctx context.Context) ([]collector.Metric, error) {
+	src := e.config.HTTP
+	timeout := e.config.GetTimeout(DefaultTimeout)
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, src.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s failed to create request: %w", e.config.Name, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if e.userAgent != "" {
+		req.Header.Set("User-Agent", e.userAgent)
+	}
+	for k, v := range src.Headers {
+		req.Header.Set(k, os.ExpandEnv(v))
+	}
+	if src.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+os.ExpandEnv(src.BearerToken))
+	} else if src.Username != "" {
+		req.SetBasicAuth(os.ExpandEnv(src.Username), os.ExpandEnv(src.Password))
+	}
+
+	client := e.httpClient
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	startTime := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		if reqCtx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("plugin %s timed out after %v", e.config.Name, timeout)
+		}
+		return nil, fmt.Errorf("plugin %s request failed: %w", e.config.Name, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, e.maxOutputBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s failed to read response: %w", e.config.Name, err)
+	}
+	if !collector.AcceptsStatus(src.AcceptStatus, resp.StatusCode) {
+		return nil, fmt.Errorf("plugin %s: unexpected status code %d: %s", e.config.Name, resp.StatusCode, truncate(string(body), 200))
+	}
+	if int64(len(body)) > e.maxOutputBytes {
+		return nil, fmt.Errorf("plugin %s output exceeded %d bytes limit", e.config.Name, e.maxOutputBytes)
+	}
+
+	e.Logger().Debug().
+		Str("plugin", e.config.Name).
+		Str("url", src.URL).
+		Dur("duration", time.Since(startTime)).
+		Int("output_bytes", len(body)).
+		Msg("Plugin HTTP source read")
+
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return []collector.Metric{}, nil
+	}
+
+	// A bare number is reported as a single gauge
+	if value, err := strconv.ParseFloat(string(body), 64); err == nil {
+		name := src.MetricName
+		if name == "" {
+			name = defaultSourceMetricName
+		}
+		return e.convertMetrics([]PluginMetric{{Name: name, Value: value}}), nil
+	}
+
+	return e.parseOutput(body)
+}
@@ -0,0 +1,137 @@
+// internal/plugin/http_source_test.go
+package plugin
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExecPlugin_HTTPSource(t *testing.T) {
+	var gotAuth, gotHeader, gotUA string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth, gotHeader, gotUA = r.Header.Get("Authorization"), r.Header.Get("X-Tenant"), r.Header.Get("User-Agent")
+		switch r.URL.Path {
+		case "/number":
+			w.Write([]byte("17\n"))
+		case "/fail":
+			http.Error(w, "forbidden", http.StatusForbidden)
+		case "/partial":
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte("5"))
+		default:
+			w.Write([]byte(`[{"name":"jobs","value":3,"labels":{"queue":"mail"}}]`))
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("METRICSD_TEST_TOKEN", "s3cret")
+
+	t.Run("user agent", func(t *testing.T) {
+		ep := NewExecPlugin(PluginConfig{Name: "api", Timeout: 2, HTTP: &HTTPSource{URL: srv.URL + "/number"}})
+		ep.SetUserAgent("metricsd/1.2.3")
+		if _, err := ep.Collect(context.Background()); err != nil {
+			t.Fatalf("Collect failed: %v", err)
+		}
+		if gotUA != "metricsd/1.2.3" {
+			t.Errorf("User-Agent = %q, want metricsd/1.2.3", gotUA)
+		}
+	})
+
+	t.Run("unauthenticated JSON body", func(t *testing.T) {
+		ep := NewExecPlugin(PluginConfig{Name: "api", Timeout: 2, HTTP: &HTTPSource{URL: srv.URL + "/metrics"}})
+		metrics, err := ep.Collect(context.Background())
+		if err != nil {
+			t.Fatalf("Collect failed: %v", err)
+		}
+		if len(metrics) != 1 || metrics[0].Name != "plugin_api_jobs" || metrics[0].Labels["queue"] != "mail" {
+			t.Errorf("unexpected metrics: %+v", metrics)
+		}
+		if gotAuth != "" {
+			t.Errorf("expected no Authorization header, got %q", gotAuth)
+		}
+	})
+
+	t.Run("bearer token and headers expand env", func(t *testing.T) {
+		ep := NewExecPlugin(PluginConfig{Name: "api", Timeout: 2, HTTP: &HTTPSource{
+			URL:         srv.URL + "/number",
+			BearerToken: "${METRICSD_TEST_TOKEN}",
+			Headers:     map[string]string{"X-Tenant": "team-$METRICSD_TEST_TOKEN"},
+			MetricName:  "depth",
+		}})
+		metrics, err := ep.Collect(context.Background())
+		if err != nil {
+			t.Fatalf("Collect failed: %v", err)
+		}
+		if len(metrics) != 1 || metrics[0].Name != "plugin_api_depth" || metrics[0].Value != 17 {
+			t.Errorf("unexpected metrics: %+v", metrics)
+		}
+		if gotAuth != "Bearer s3cret" || gotHeader != "team-s3cret" {
+			t.Errorf("unexpected auth headers: %q %q", gotAuth, gotHeader)
+		}
+	})
+
+	t.Run("basic auth", func(t *testing.T) {
+		ep := NewExecPlugin(PluginConfig{Name: "api", Timeout: 2, HTTP: &HTTPSource{URL: srv.URL, Username: "svc", Password: "${METRICSD_TEST_TOKEN}"}})
+		if _, err := ep.Collect(context.Background()); err != nil {
+			t.Fatalf("Collect failed: %v", err)
+		}
+		if !strings.HasPrefix(gotAuth, "Basic ") {
+			t.Errorf("expected basic auth, got %q", gotAuth)
+		}
+	})
+
+	t.Run("accept_status", func(t *testing.T) {
+		ep := NewExecPlugin(PluginConfig{Name: "api", Timeout: 2, HTTP: &HTTPSource{URL: srv.URL + "/partial"}})
+		if _, err := ep.Collect(context.Background()); err == nil || !strings.Contains(err.Error(), "206") {
+			t.Errorf("expected 206 to be rejected by default, got %v", err)
+		}
+		ep = NewExecPlugin(PluginConfig{Name: "api", Timeout: 2, HTTP: &HTTPSource{URL: srv.URL + "/partial", AcceptStatus: []int{200, 206}}})
+		if metrics, err := ep.Collect(context.Background()); err != nil || len(metrics) != 1 || metrics[0].Value != 5 {
+			t.Errorf("expected 206 body to be parsed, got %v, %v", metrics, err)
+		}
+	})
+
+	t.Run("rejected status is an error", func(t *testing.T) {
+		ep := NewExecPlugin(PluginConfig{Name: "api", Timeout: 2, HTTP: &HTTPSource{URL: srv.URL + "/fail"}})
+		if _, err := ep.Collect(context.Background()); err == nil || !strings.Contains(err.Error(), "403") {
+			t.Errorf("expected status error, got %v", err)
+		}
+	})
+}
+
+func TestExecPlugin_HTTPSourceUnixSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "metricsd") // Short enough for the socket path limit
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	socket := filepath.Join(dir, "app.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("UNIX sockets unavailable: %v", err)
+	}
+	var gotPath string
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.Write([]byte("7"))
+	})}
+	go func() { _ = srv.Serve(ln) }()
+	defer func() { _ = srv.Close() }()
+
+	ep := NewExecPlugin(PluginConfig{Name: "sidecar", Timeout: 2, HTTP: &HTTPSource{URL: "unix://" + socket + ":/queue/depth"}})
+	metrics, err := ep.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	if len(metrics) != 1 || metrics[0].Value != 7 {
+		t.Errorf("unexpected metrics: %+v", metrics)
+	}
+	if gotPath != "/queue/depth" {
+		t.Errorf("request path = %q, want /queue/depth", gotPath)
+	}
+}
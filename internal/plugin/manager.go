@@ -2,10 +2,14 @@ package plugin
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
 	"sync"
 	"time"
 
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
 
 	"github.com/0x524A/metricsd/internal/collector"
 )
@@ -18,14 +22,58 @@ type pluginEntry struct {
 // Manager coordinates all plugin collectors with parallel execution,
 // circuit breaker, and health tracking. Implements collector.Collector.
 type Manager struct {
-	mu      sync.RWMutex
-	plugins []pluginEntry
-	health  map[string]*PluginHealth
+	collector.Logging
+	mu             sync.RWMutex
+	plugins        []pluginEntry
+	health         map[string]*PluginHealth
+	maxConcurrency int
+	loggerSet      bool
+	loadErrors     int // Plugins skipped at startup because they failed to load
+	loadErrorsSet  bool
 }
 
 func NewManager() *Manager {
 	return &Manager{
-		health: make(map[string]*PluginHealth),
+		health:         make(map[string]*PluginHealth),
+		maxConcurrency: runtime.GOMAXPROCS(0),
+	}
+}
+
+// SetMaxConcurrency bounds how many plugins run at once. Values <= 0 reset
+// the limit to GOMAXPROCS.
+func (m *Manager) SetMaxConcurrency(n int) {
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	m.mu.Lock()
+	m.maxConcurrency = n
+	m.mu.Unlock()
+}
+
+// SetLoadErrors records how many plugins were skipped because they failed to
+// load; Collect reports it as metricsd_plugin_load_errors.
+func (m *Manager) SetLoadErrors(n int) {
+	m.mu.Lock()
+	m.loadErrors = n
+	m.loadErrorsSet = true
+	m.mu.Unlock()
+}
+
+// SetLogger sets the manager's logger and passes it on to plugins that accept one
+func (m *Manager) SetLogger(logger zerolog.Logger) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Logging.SetLogger(logger)
+	m.loggerSet = true
+	for _, entry := range m.plugins {
+		m.injectLogger(entry.collector)
+	}
+}
+
+// injectLogger hands the manager's logger to c if one was set and c accepts it
+func (m *Manager) injectLogger(c collector.Collector) {
+	if s, ok := c.(collector.LoggerSetter); ok && m.loggerSet {
+		s.SetLogger(*m.Logger())
 	}
 }
 
@@ -37,6 +85,7 @@ func (m *Manager) AddExecPlugin(ep *ExecPlugin) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	name := ep.config.Name
+	m.injectLogger(ep)
 	m.plugins = append(m.plugins, pluginEntry{name: name, collector: ep})
 	m.health[name] = &PluginHealth{Name: name, Status: "ok"}
 }
@@ -44,6 +93,7 @@ func (m *Manager) AddExecPlugin(ep *ExecPlugin) {
 func (m *Manager) AddGoPlugin(name string, c collector.Collector) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.injectLogger(c)
 	m.plugins = append(m.plugins, pluginEntry{name: name, collector: c})
 	m.health[name] = &PluginHealth{Name: name, Status: "ok"}
 }
@@ -52,12 +102,15 @@ func (m *Manager) Collect(ctx context.Context) ([]collector.Metric, error) {
 	m.mu.RLock()
 	entries := make([]pluginEntry, len(m.plugins))
 	copy(entries, m.plugins)
+	maxConcurrency := m.maxConcurrency
+	loadErrors, loadErrorsSet := m.loadErrors, m.loadErrorsSet
 	m.mu.RUnlock()
 
 	type result struct {
 		name    string
 		metrics []collector.Metric
 		err     error
+		skipped bool // Never got a worker slot before ctx ended
 	}
 
 	// Snapshot circuit breaker state under a single lock acquisition
@@ -74,19 +127,29 @@ func (m *Manager) Collect(ctx context.Context) ([]collector.Metric, error) {
 	m.mu.RUnlock()
 
 	results := make(chan result, len(entries))
+	sem := make(chan struct{}, maxConcurrency)
 	var wg sync.WaitGroup
 	now := time.Now()
 
 	for _, entry := range entries {
 		if cs, ok := circuits[entry.name]; ok && !cs.openUntil.IsZero() && now.Before(cs.openUntil) {
-			log.Debug().Str("plugin", entry.name).Time("circuit_open_until", cs.openUntil).Msg("Skipping plugin — circuit open")
+			m.Logger().Debug().Str("plugin", entry.name).Time("circuit_open_until", cs.openUntil).Msg("Skipping plugin — circuit open")
 			continue
 		}
 
 		wg.Add(1)
 		go func(e pluginEntry) {
 			defer wg.Done()
-			metrics, err := e.collector.Collect(ctx)
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				results <- result{name: e.name, err: ctx.Err(), skipped: true}
+				return
+			}
+			defer func() { <-sem }()
+
+			metrics, err := collectSafely(ctx, e.collector)
 			results <- result{name: e.name, metrics: metrics, err: err}
 		}(entry)
 	}
@@ -98,6 +161,13 @@ func (m *Manager) Collect(ctx context.Context) ([]collector.Metric, error) {
 
 	var allMetrics []collector.Metric
 	for r := range results {
+		// A plugin that never ran says nothing about its health, so it must
+		// not count toward the circuit breaker
+		if r.skipped {
+			m.Logger().Warn().Str("plugin", r.name).Err(r.err).Msg("Skipped plugin, no worker slot before the collection deadline")
+			continue
+		}
+
 		m.mu.Lock()
 		h := m.health[r.name]
 		h.LastCollect = time.Now()
@@ -105,7 +175,7 @@ func (m *Manager) Collect(ctx context.Context) ([]collector.Metric, error) {
 		if r.err != nil {
 			h.ConsecutiveFails++
 			h.LastError = r.err.Error()
-			log.Warn().Str("plugin", r.name).Int("consecutive_fails", h.ConsecutiveFails).Err(r.err).Msg("Plugin collection failed")
+			m.Logger().Warn().Str("plugin", r.name).Int("consecutive_fails", h.ConsecutiveFails).Err(r.err).Msg("Plugin collection failed")
 
 			if h.ConsecutiveFails >= MaxConsecutiveFailures {
 				backoff := time.Duration(1<<uint(h.ConsecutiveFails-MaxConsecutiveFailures)) * time.Minute
@@ -114,7 +184,7 @@ func (m *Manager) Collect(ctx context.Context) ([]collector.Metric, error) {
 				}
 				h.CircuitOpenUntil = time.Now().Add(backoff)
 				h.Status = "circuit_open"
-				log.Warn().Str("plugin", r.name).Dur("backoff", backoff).Msg("Circuit breaker opened")
+				m.Logger().Warn().Str("plugin", r.name).Dur("backoff", backoff).Msg("Circuit breaker opened")
 			} else {
 				h.Status = "failing"
 			}
@@ -130,9 +200,30 @@ func (m *Manager) Collect(ctx context.Context) ([]collector.Metric, error) {
 		m.mu.Unlock()
 	}
 
+	if loadErrorsSet {
+		allMetrics = append(allMetrics, collector.Metric{
+			Name:   "metricsd_plugin_load_errors",
+			Value:  float64(loadErrors),
+			Type:   "gauge",
+			Labels: map[string]string{},
+			Help:   "Plugins skipped at startup because they failed to load",
+		})
+	}
 	return allMetrics, nil
 }
 
+// collectSafely runs a plugin collector, converting a panic into an error so
+// one misbehaving plugin cannot take down the others.
+func collectSafely(ctx context.Context, c collector.Collector) (metrics []collector.Metric, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			metrics = nil
+			err = fmt.Errorf("plugin panicked: %v", r)
+		}
+	}()
+	return c.Collect(ctx)
+}
+
 func (m *Manager) GetHealth() map[string]PluginHealth {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -143,8 +234,80 @@ func (m *Manager) GetHealth() map[string]PluginHealth {
 	return snapshot
 }
 
+// Statuses returns the status of every loaded plugin in load order. A plugin
+// whose circuit is open is next run when the circuit closes.
+func (m *Manager) Statuses() []PluginStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	now := time.Now()
+	statuses := make([]PluginStatus, 0, len(m.plugins))
+	for _, entry := range m.plugins {
+		h := m.health[entry.name]
+		st := PluginStatus{
+			Name:            entry.name,
+			Source:          SourceGo,
+			Status:          h.Status,
+			LastRun:         h.LastSuccess,
+			LastMetricCount: h.LastMetricCount,
+			LastError:       h.LastError,
+		}
+		if ep, ok := entry.collector.(*ExecPlugin); ok {
+			ep.fillStatus(&st)
+		}
+		if h.CircuitOpenUntil.After(now) && h.CircuitOpenUntil.After(st.NextRun) {
+			st.NextRun = h.CircuitOpenUntil
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses
+}
+
 func (m *Manager) PluginCount() int {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	return len(m.plugins)
 }
+
+// SaveState returns the state of every plugin implementing
+// collector.StateKeeper, keyed by plugin name
+func (m *Manager) SaveState() (json.RawMessage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	states := make(map[string]json.RawMessage)
+	for _, entry := range m.plugins {
+		k, ok := entry.collector.(collector.StateKeeper)
+		if !ok {
+			continue
+		}
+		data, err := k.SaveState()
+		if err != nil {
+			return nil, fmt.Errorf("plugin %s: %w", entry.name, err)
+		}
+		states[entry.name] = data
+	}
+	return json.Marshal(states)
+}
+
+// RestoreState hands each plugin its state saved by SaveState. Plugins no
+// longer loaded are ignored; restore errors are joined.
+func (m *Manager) RestoreState(data json.RawMessage) error {
+	var states map[string]json.RawMessage
+	if err := json.Unmarshal(data, &states); err != nil {
+		return err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var errs []error
+	for _, entry := range m.plugins {
+		k, ok := entry.collector.(collector.StateKeeper)
+		if !ok {
+			continue
+		}
+		if state, ok := states[entry.name]; ok {
+			if err := k.RestoreState(state); err != nil {
+				errs = append(errs, fmt.Errorf("plugin %s: %w", entry.name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -3,7 +3,11 @@ package plugin
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/0x524A/metricsd/internal/collector"
 )
@@ -158,3 +162,191 @@ func TestManager_PluginCount(t *testing.T) {
 		t.Errorf("expected PluginCount 3, got %d", m.PluginCount())
 	}
 }
+
+func TestManager_Statuses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("42"))
+	}))
+	defer srv.Close()
+
+	m := NewManager()
+	m.AddExecPlugin(NewExecPlugin(PluginConfig{Name: "queue", Interval: 60, HTTP: &HTTPSource{URL: srv.URL, MetricName: "queue_depth"}}))
+	m.AddGoPlugin("broken", &mockCollector{name: "broken", err: fmt.Errorf("boom")})
+
+	before := m.Statuses()
+	if len(before) != 2 {
+		t.Fatalf("expected 2 statuses, got %d", len(before))
+	}
+	if st := before[0]; !st.LastRun.IsZero() || !st.NextRun.IsZero() || st.LastValue != nil {
+		t.Errorf("expected no run yet, got %+v", st)
+	}
+
+	start := time.Now()
+	m.Collect(context.Background())
+	statuses := m.Statuses()
+
+	queue := statuses[0]
+	if queue.Name != "queue" || queue.Source != SourceHTTP || queue.Metric != "queue_depth" || queue.Interval != time.Minute {
+		t.Errorf("unexpected definition fields: %+v", queue)
+	}
+	if queue.Status != "ok" || queue.LastMetricCount != 1 || queue.LastValue == nil || *queue.LastValue != 42 {
+		t.Errorf("unexpected last run: %+v", queue)
+	}
+	if queue.LastRun.Before(start) || !queue.NextRun.Equal(queue.LastRun.Add(time.Minute)) {
+		t.Errorf("expected next run an interval after %v, got %v", queue.LastRun, queue.NextRun)
+	}
+
+	broken := statuses[1]
+	if broken.Source != SourceGo || broken.Status != "failing" || broken.LastError != "boom" || !broken.NextRun.IsZero() {
+		t.Errorf("unexpected status for failing Go plugin: %+v", broken)
+	}
+}
+
+func TestManager_SetLoadErrors(t *testing.T) {
+	m := NewManager()
+	m.AddGoPlugin("p1", &mockCollector{name: "p1", metrics: []collector.Metric{{Name: "m1", Value: 1, Type: "gauge"}}})
+	m.SetLoadErrors(2)
+
+	metrics, err := m.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	var found bool
+	for _, metric := range metrics {
+		if metric.Name == "metricsd_plugin_load_errors" {
+			found = true
+			if metric.Value != 2 || metric.Type != "gauge" {
+				t.Errorf("unexpected load errors metric: %+v", metric)
+			}
+		}
+	}
+	if !found || len(metrics) != 2 {
+		t.Errorf("expected plugin metric plus metricsd_plugin_load_errors, got %+v", metrics)
+	}
+}
+
+type panicCollector struct{}
+
+func (p *panicCollector) Name() string { return "panicky" }
+func (p *panicCollector) Collect(ctx context.Context) ([]collector.Metric, error) {
+	panic("boom")
+}
+
+// trackingCollector records the peak number of concurrent Collect calls.
+type trackingCollector struct {
+	mu      *sync.Mutex
+	active  *int
+	peak    *int
+	sleep   time.Duration
+	metrics []collector.Metric
+}
+
+func (c *trackingCollector) Name() string { return "tracking" }
+func (c *trackingCollector) Collect(ctx context.Context) ([]collector.Metric, error) {
+	c.mu.Lock()
+	*c.active++
+	if *c.active > *c.peak {
+		*c.peak = *c.active
+	}
+	c.mu.Unlock()
+	time.Sleep(c.sleep)
+	c.mu.Lock()
+	*c.active--
+	c.mu.Unlock()
+	return c.metrics, nil
+}
+
+func TestManager_Concurrency(t *testing.T) {
+	t.Run("panicking plugin does not affect others", func(t *testing.T) {
+		m := NewManager()
+		m.AddGoPlugin("panicky", &panicCollector{})
+		m.AddGoPlugin("good", &mockCollector{
+			name:    "good",
+			metrics: []collector.Metric{{Name: "m1", Value: 1, Type: "gauge"}},
+		})
+
+		metrics, err := m.Collect(context.Background())
+		if err != nil {
+			t.Fatalf("Collect failed: %v", err)
+		}
+		if len(metrics) != 1 {
+			t.Errorf("expected 1 metric, got %d", len(metrics))
+		}
+		if h := m.GetHealth()["panicky"]; h.Status != "failing" {
+			t.Errorf("expected panicky plugin to be failing, got %s", h.Status)
+		}
+	})
+
+	t.Run("max concurrency bounds parallel plugins", func(t *testing.T) {
+		m := NewManager()
+		m.SetMaxConcurrency(2)
+
+		var mu sync.Mutex
+		var active, peak int
+		for i := 0; i < 6; i++ {
+			name := fmt.Sprintf("p%d", i)
+			m.AddGoPlugin(name, &trackingCollector{
+				mu: &mu, active: &active, peak: &peak,
+				sleep:   50 * time.Millisecond,
+				metrics: []collector.Metric{{Name: name, Value: 1, Type: "gauge"}},
+			})
+		}
+
+		metrics, _ := m.Collect(context.Background())
+		if len(metrics) != 6 {
+			t.Errorf("expected 6 metrics, got %d", len(metrics))
+		}
+		if peak > 2 {
+			t.Errorf("expected at most 2 concurrent plugins, got %d", peak)
+		}
+		if peak < 2 {
+			t.Errorf("expected plugins to run concurrently, peak was %d", peak)
+		}
+	})
+}
+
+// blockingCollector runs until ctx ends and counts its runs
+type blockingCollector struct {
+	mu   sync.Mutex
+	runs int
+}
+
+func (b *blockingCollector) Name() string { return "blocking" }
+func (b *blockingCollector) Collect(ctx context.Context) ([]collector.Metric, error) {
+	b.mu.Lock()
+	b.runs++
+	b.mu.Unlock()
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestManager_SkippedPluginsKeepHealth(t *testing.T) {
+	m := NewManager()
+	m.SetMaxConcurrency(1)
+	plugins := map[string]*blockingCollector{"a": {}, "b": {}}
+	for name, c := range plugins {
+		m.AddGoPlugin(name, c)
+	}
+
+	for i := 0; i < MaxConsecutiveFailures; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		m.Collect(ctx)
+		cancel()
+	}
+
+	// Only cycles a plugin actually ran in count toward its circuit breaker
+	health := m.GetHealth()
+	total := 0
+	for name, c := range plugins {
+		c.mu.Lock()
+		runs := c.runs
+		c.mu.Unlock()
+		total += runs
+		if h := health[name]; h.ConsecutiveFails != runs {
+			t.Errorf("plugin %s: ran %d times but has %d consecutive fails", name, runs, h.ConsecutiveFails)
+		}
+	}
+	if total >= 2*MaxConsecutiveFailures {
+		t.Errorf("expected some runs to be skipped for want of a slot, got %d runs", total)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package plugin
+
+import "os/exec"
+
+// killProcessGroup is a no-op: without process groups cancellation kills
+// only the plugin process itself
+func killProcessGroup(cmd *exec.Cmd) {}
@@ -5,13 +5,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
 
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
-)
 
-var metricNameRegex = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	"github.com/0x524A/metricsd/internal/collector"
+)
 
 // ValidatePluginPath resolves symlinks and verifies the path stays within pluginsDir.
 // Returns the resolved absolute path, or an error if the path escapes or is invalid.
@@ -39,20 +39,111 @@ func ValidatePluginPath(pluginPath, pluginsDir string) (string, error) {
 		return "", fmt.Errorf("plugin %s resolves to %s which is outside plugins dir %s", pluginPath, resolvedPath, resolvedDir)
 	}
 
-	// Warn if world-writable
-	info, err := os.Stat(resolvedPath)
-	if err != nil {
+	if _, err := os.Stat(resolvedPath); err != nil {
 		return "", fmt.Errorf("failed to stat plugin: %w", err)
 	}
-	if info.Mode()&0002 != 0 {
-		log.Warn().
-			Str("plugin", resolvedPath).
-			Msg("Plugin is world-writable — this is a security risk")
-	}
 
 	return resolvedPath, nil
 }
 
+// Command policies for plugin executables
+const (
+	CommandPolicyAny       = "any"       // Any executable inside the plugins dir (default)
+	CommandPolicyAllowlist = "allowlist" // Only executables listed in allowed_commands
+)
+
+// CommandAllowlist holds canonical paths of the executables plugins may run.
+// A nil allowlist permits every executable.
+type CommandAllowlist struct {
+	paths map[string]bool
+}
+
+// NewCommandAllowlist canonicalizes commands for comparison with resolved
+// plugin paths: relative entries are taken relative to pluginsDir, then
+// cleaned and symlink-resolved, so "/bin/../bin/rm" and links to it all
+// match "/bin/rm" and nothing else. Entries that don't exist are kept cleaned.
+func NewCommandAllowlist(commands []string, pluginsDir string) *CommandAllowlist {
+	a := &CommandAllowlist{paths: make(map[string]bool, len(commands))}
+	for _, cmd := range commands {
+		if !filepath.IsAbs(cmd) {
+			cmd = filepath.Join(pluginsDir, cmd)
+		}
+		if abs, err := filepath.Abs(cmd); err == nil {
+			cmd = abs
+		}
+		if resolved, err := filepath.EvalSymlinks(cmd); err == nil {
+			cmd = resolved
+		}
+		a.paths[filepath.Clean(cmd)] = true
+	}
+	return a
+}
+
+// Allows reports whether the already resolved resolvedPath may be executed
+func (a *CommandAllowlist) Allows(resolvedPath string) bool {
+	return a == nil || a.paths[filepath.Clean(resolvedPath)]
+}
+
+// shellCommands are interpreters whose -c flag runs an inline script
+var shellCommands = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "ash": true, "busybox": true,
+}
+
+// shellOptionsWithValue are the shell options whose value is the next argument
+var shellOptionsWithValue = map[string]bool{"--rcfile": true, "--init-file": true}
+
+// invokesInlineShell reports whether path is a shell run with -c (alone or in
+// a flag group such as -ec), i.e. the command it executes lives in args. Like
+// the shell, it only reads options up to the first operand (the script) or
+// "--"; a -c after that is an argument of the script. path counts as a shell
+// when its name or that of the file a symlink points to is a known shell, so
+// a shell copied under another name goes unnoticed: this is a guard against
+// mistakes, not a sandbox.
+func invokesInlineShell(path string, args []string) bool {
+	name := filepath.Base(path)
+	shell := shellCommands[name]
+	if resolved, err := filepath.EvalSymlinks(path); err == nil && shellCommands[filepath.Base(resolved)] {
+		shell = true
+	}
+	if !shell {
+		return false
+	}
+	if name == "busybox" {
+		// Invoked by its own name, busybox runs the applet named by its first argument
+		if len(args) == 0 || args[0] == "busybox" || !shellCommands[args[0]] {
+			return false
+		}
+		args = args[1:]
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--" || arg == "-":
+			return false
+		case strings.HasPrefix(arg, "--"):
+			if shellOptionsWithValue[arg] {
+				i++
+			}
+			continue
+		case len(arg) < 2 || (arg[0] != '-' && arg[0] != '+'):
+			return false
+		}
+		flags := arg[1:]
+		if strings.Trim(flags, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			continue
+		}
+		if arg[0] == '-' && strings.ContainsRune(flags, 'c') {
+			return true
+		}
+		// -o pipefail, -O extglob: the option name is the next argument
+		if strings.ContainsAny(flags, "oO") {
+			i++
+		}
+	}
+	return false
+}
+
 // BuildSafeEnv constructs a minimal environment for plugin execution.
 // Does NOT inherit os.Environ(). Only includes safe defaults + explicit extras.
 func BuildSafeEnv(extraEnv []string) []string {
@@ -66,18 +157,23 @@ func BuildSafeEnv(extraEnv []string) []string {
 }
 
 // ValidateMetricOutput filters and sanitizes plugin output metrics.
-// Rejects: empty names, invalid names, labels starting with __.
+// Rejects: empty names, invalid names, invalid label names, labels starting with __.
 // Truncates: label values over 1024 chars.
 func ValidateMetricOutput(metrics []PluginMetric, pluginName string) []PluginMetric {
+	return validateMetricOutput(metrics, pluginName, &log.Logger)
+}
+
+// validateMetricOutput is ValidateMetricOutput logging rejections to logger
+func validateMetricOutput(metrics []PluginMetric, pluginName string, logger *zerolog.Logger) []PluginMetric {
 	valid := make([]PluginMetric, 0, len(metrics))
 
 	for _, pm := range metrics {
 		if pm.Name == "" {
-			log.Warn().Str("plugin", pluginName).Msg("Skipping metric with empty name")
+			logger.Warn().Str("plugin", pluginName).Msg("Skipping metric with empty name")
 			continue
 		}
-		if !metricNameRegex.MatchString(pm.Name) {
-			log.Warn().Str("plugin", pluginName).Str("name", pm.Name).Msg("Skipping metric with invalid name")
+		if !collector.MetricNameRegex.MatchString(pm.Name) {
+			logger.Warn().Str("plugin", pluginName).Str("name", pm.Name).Msg("Skipping metric with invalid name")
 			continue
 		}
 
@@ -86,7 +182,12 @@ func ValidateMetricOutput(metrics []PluginMetric, pluginName string) []PluginMet
 		sanitizedLabels := make(map[string]string, len(pm.Labels))
 		for k, v := range pm.Labels {
 			if strings.HasPrefix(k, "__") {
-				log.Warn().Str("plugin", pluginName).Str("label", k).Msg("Rejecting metric with reserved label prefix __")
+				logger.Warn().Str("plugin", pluginName).Str("label", k).Msg("Rejecting metric with reserved label prefix __")
+				hasReserved = true
+				break
+			}
+			if !collector.LabelNameRegex.MatchString(k) {
+				logger.Warn().Str("plugin", pluginName).Str("metric", pm.Name).Str("label", k).Msg("Rejecting metric with invalid label name")
 				hasReserved = true
 				break
 			}
@@ -48,14 +48,14 @@ func TestValidatePluginPath(t *testing.T) {
 		}
 	})
 
-	t.Run("world-writable warns but succeeds", func(t *testing.T) {
+	t.Run("world-writable succeeds", func(t *testing.T) {
 		wwPlugin := filepath.Join(tmpDir, "ww_plugin")
 		if err := os.WriteFile(wwPlugin, []byte("#!/bin/bash\necho '[]'"), 0757); err != nil {
 			t.Fatalf("Failed to write plugin: %v", err)
 		}
 		resolved, err := ValidatePluginPath(wwPlugin, tmpDir)
 		if err != nil {
-			t.Errorf("expected success with warning, got error: %v", err)
+			t.Errorf("expected success, got error: %v", err)
 		}
 		if resolved == "" {
 			t.Error("expected non-empty resolved path")
@@ -121,6 +121,16 @@ func TestValidateMetricOutput(t *testing.T) {
 		}
 	})
 
+	t.Run("invalid label name rejected", func(t *testing.T) {
+		metrics := []PluginMetric{
+			{Name: "cpu_usage", Value: 1, Labels: map[string]string{"data-center": "east"}},
+		}
+		result := ValidateMetricOutput(metrics, "test_plugin")
+		if len(result) != 0 {
+			t.Errorf("expected 0 metrics (invalid label name), got %d", len(result))
+		}
+	})
+
 	t.Run("label value over 1024 chars truncated", func(t *testing.T) {
 		longVal := make([]byte, 2000)
 		for i := range longVal {
@@ -186,3 +196,81 @@ func TestValidateMetricOutput_NilLabels(t *testing.T) {
 		t.Errorf("expected 1 metric, got %d", len(result))
 	}
 }
+
+func TestCommandAllowlist(t *testing.T) {
+	dir := t.TempDir()
+	allowed := writeTestPlugin(t, dir, "allowed", "#!/bin/bash\necho '[]'")
+	other := writeTestPlugin(t, dir, "other", "#!/bin/bash\necho '[]'")
+	if err := os.Symlink(allowed, filepath.Join(dir, "link")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	resolvedAllowed, _ := filepath.EvalSymlinks(allowed)
+	resolvedOther, _ := filepath.EvalSymlinks(other)
+
+	t.Run("nil allows everything", func(t *testing.T) {
+		var a *CommandAllowlist
+		if !a.Allows(resolvedOther) {
+			t.Error("nil allowlist should allow any path")
+		}
+	})
+
+	tests := []struct {
+		name     string
+		commands []string
+		want     bool
+	}{
+		{"absolute", []string{allowed}, true},
+		{"relative to plugins dir", []string{"allowed"}, true},
+		{"dot-dot", []string{filepath.Join(dir, "..", filepath.Base(dir), "allowed")}, true},
+		{"symlink", []string{"link"}, true},
+		{"not listed", []string{"other_missing"}, false},
+		{"empty", nil, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewCommandAllowlist(tc.commands, dir)
+			if got := a.Allows(resolvedAllowed); got != tc.want {
+				t.Errorf("Allows(%s) = %v, want %v", resolvedAllowed, got, tc.want)
+			}
+			if a.Allows(resolvedOther) {
+				t.Errorf("Allows(%s) should be false", resolvedOther)
+			}
+		})
+	}
+}
+
+func TestInvokesInlineShell(t *testing.T) {
+	dir := t.TempDir()
+	link := filepath.Join(dir, "run")
+	if err := os.Symlink("/bin/sh", link); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		args []string
+		want bool
+	}{
+		{"-c", "/bin/sh", []string{"-c", "true"}, true},
+		{"flag group", "/bin/bash", []string{"-ec", "true"}, true},
+		{"after other flags", "/bin/bash", []string{"-e", "-u", "-c", "true"}, true},
+		{"after long option", "/bin/bash", []string{"--norc", "-c", "true"}, true},
+		{"after option value", "/bin/bash", []string{"-o", "pipefail", "-c", "true"}, true},
+		{"after rcfile", "/bin/bash", []string{"--rcfile", "rc", "-c", "true"}, true},
+		{"script argument", "/bin/bash", []string{"collect.sh", "-c", "conf.ini"}, false},
+		{"after --", "/bin/sh", []string{"--", "-c", "true"}, false},
+		{"plus option", "/bin/sh", []string{"+c"}, false},
+		{"not a shell", "/plugins/collect", []string{"-c", "conf.ini"}, false},
+		{"symlink to a shell", link, []string{"-c", "true"}, true},
+		{"busybox applet", "/bin/busybox", []string{"sh", "-c", "true"}, true},
+		{"busybox other applet", "/bin/busybox", []string{"wc", "-c", "file"}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := invokesInlineShell(tc.path, tc.args); got != tc.want {
+				t.Errorf("invokesInlineShell(%q, %q) = %v, want %v", tc.path, tc.args, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+//go:build linux
+
+package plugin
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// preventOrphan has the kernel send SIGTERM to the plugin if metricsd dies first
+func preventOrphan(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Pdeathsig: syscall.SIGTERM,
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package plugin
+
+import "os/exec"
+
+// preventOrphan is a no-op: parent-death signals only exist on Linux
+func preventOrphan(cmd *exec.Cmd) {}
@@ -0,0 +1,249 @@
+// internal/plugin/tcp_source.go
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+// defaultSourceMetricName names bare numeric replies of tcp, http and file sources
+const defaultSourceMetricName = "value"
+
+// detectSource reports which source a plugin definition reads from.
+func detectSource(config PluginConfig) string {
+	switch {
+	case config.TCP != nil:
+		return SourceTCP
+	case config.HTTP != nil:
+		return SourceHTTP
+	case config.File != nil:
+		return SourceFile
+	}
+	return SourceExec
+}
+
+// bareMetricName returns the name given to bare numeric values of tcp, http
+// and file sources; empty for exec plugins, whose output names its metrics
+func bareMetricName(config PluginConfig) string {
+	var name string
+	switch {
+	case config.TCP != nil:
+		name = config.TCP.MetricName
+	case config.HTTP != nil:
+		name = config.HTTP.MetricName
+	case config.File != nil:
+		name = config.File.MetricName
+	default:
+		return ""
+	}
+	if name == "" {
+		name = defaultSourceMetricName
+	}
+	return name
+}
+
+// validatePluginDefinition checks that a plugin definition configures exactly
+// one source, that the chosen source has its required fields, and that the
+// value transform options are consistent.
+func validatePluginDefinition(config PluginConfig) error {
+	sources := 0
+	for _, set := range []bool{config.TCP != nil, config.HTTP != nil, config.File != nil} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return fmt.Errorf("plugin %s: only one of the tcp, http and file sources may be set", config.Name)
+	}
+	source := detectSource(config)
+	if source != SourceExec {
+		if config.Path != "" {
+			return fmt.Errorf("plugin %s: %s source cannot be combined with an executable", config.Name, source)
+		}
+		if len(config.Args) > 0 || len(config.Env) > 0 || config.WorkingDir != "" {
+			return fmt.Errorf("plugin %s: args, env and working_dir are not valid for the %s source", config.Name, source)
+		}
+	}
+	switch source {
+	case SourceFile:
+		if (config.File.Path == "") == (config.File.Glob == "") {
+			return fmt.Errorf("plugin %s: file source requires exactly one of path and glob", config.Name)
+		}
+		if _, err := filepath.Match(config.File.Glob, ""); err != nil {
+			return fmt.Errorf("plugin %s: invalid file glob %q: %w", config.Name, config.File.Glob, err)
+		}
+		if config.File.PathLabels != "" {
+			re, err := regexp.Compile(config.File.PathLabels)
+			if err != nil {
+				return fmt.Errorf("plugin %s: invalid file path_labels: %w", config.Name, err)
+			}
+			for _, name := range re.SubexpNames()[1:] {
+				if name != "" && (!collector.LabelNameRegex.MatchString(name) || strings.HasPrefix(name, "__")) {
+					return fmt.Errorf("plugin %s: path_labels group %q is not a valid label name", config.Name, name)
+				}
+			}
+		}
+		if config.File.TailLines < 0 || config.File.MaxBytes < 0 {
+			return fmt.Errorf("plugin %s: file tail_lines and max_bytes cannot be negative", config.Name)
+		}
+		if config.File.TailLines > 0 && config.File.MaxBytes > 0 {
+			return fmt.Errorf("plugin %s: file tail_lines and max_bytes are mutually exclusive", config.Name)
+		}
+	case SourceHTTP:
+		u, err := url.Parse(config.HTTP.URL)
+		if err == nil && u.Scheme == collector.UnixSocketScheme {
+			if _, _, err := collector.SplitUnixSocketURL(u); err != nil {
+				return fmt.Errorf("plugin %s: http source: %w", config.Name, err)
+			}
+		} else if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("plugin %s: http source requires an absolute http(s) or unix url, got %q", config.Name, config.HTTP.URL)
+		}
+		if config.HTTP.BearerToken != "" && config.HTTP.Username != "" {
+			return fmt.Errorf("plugin %s: http source bearer_token and username are mutually exclusive", config.Name)
+		}
+		for _, code := range config.HTTP.AcceptStatus {
+			if code < 100 || code > 599 {
+				return fmt.Errorf("plugin %s: invalid http accept_status code %d", config.Name, code)
+			}
+		}
+	case SourceTCP:
+		if config.TCP.Address == "" {
+			return fmt.Errorf("plugin %s: tcp source requires an address", config.Name)
+		}
+		if _, _, err := net.SplitHostPort(config.TCP.Address); err != nil {
+			return fmt.Errorf("plugin %s: invalid tcp address %q: %w", config.Name, config.TCP.Address, err)
+		}
+	default:
+		if config.Path == "" {
+			return fmt.Errorf("plugin %s: no executable path", config.Name)
+		}
+		if !config.AllowShell && invokesInlineShell(config.Path, config.Args) {
+			return fmt.Errorf("plugin %s: %s -c runs an inline script from args, which bypasses the plugins_dir and allowed_commands checks on what gets executed; move the script into its own plugin file or set allow_shell: true", config.Name, filepath.Base(config.Path))
+		}
+	}
+	if config.Rate && config.Observe {
+		return fmt.Errorf("plugin %s: rate and observe cannot be combined", config.Name)
+	}
+	if config.MaxOutputBytes < 0 {
+		return fmt.Errorf("plugin %s: max_output_bytes cannot be negative", config.Name)
+	}
+	if config.Interval < 0 {
+		return fmt.Errorf("plugin %s: interval_seconds cannot be negative", config.Name)
+	}
+	if config.AggregateWindowSeconds < 0 {
+		return fmt.Errorf("plugin %s: aggregate_window_seconds cannot be negative", config.Name)
+	}
+	switch config.AggregateFunc {
+	case "", AggregateAvg, AggregateMin, AggregateMax, AggregateLast:
+	default:
+		return fmt.Errorf("plugin %s: invalid aggregate_func %q (must be avg, min, max or last)", config.Name, config.AggregateFunc)
+	}
+	if config.AggregateWindowSeconds > 0 && config.Observe {
+		return fmt.Errorf("plugin %s: observe and aggregate_window_seconds cannot be combined", config.Name)
+	}
+	for i := 1; i < len(config.Buckets); i++ {
+		if config.Buckets[i] <= config.Buckets[i-1] {
+			return fmt.Errorf("plugin %s: buckets must be strictly increasing", config.Name)
+		}
+	}
+	return nil
+}
+
+// executeTCP dials the configured address, optionally sends a request payload
+// and reads the reply until the delimiter, bounded by the plugin timeout.
+func (e *ExecPlugin) executeTCP(ctx context.Context) ([]collector.Metric, error) {
+	src := e.config.TCP
+	timeout := e.config.GetTimeout(DefaultTimeout)
+	deadline := time.Now().Add(timeout)
+
+	dialer := &net.Dialer{Deadline: deadline}
+	startTime := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", src.Address)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s failed to connect to %s: %w", e.config.Name, src.Address, err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, fmt.Errorf("plugin %s failed to set deadline: %w", e.config.Name, err)
+	}
+
+	if src.Send != "" {
+		if _, err := io.WriteString(conn, src.Send); err != nil {
+			return nil, fmt.Errorf("plugin %s failed to send request: %w", e.config.Name, err)
+		}
+	}
+
+	reply, err := readUntilDelimiter(io.LimitReader(conn, e.maxOutputBytes+1), src.Delimiter)
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, fmt.Errorf("plugin %s timed out after %v", e.config.Name, timeout)
+		}
+		return nil, fmt.Errorf("plugin %s failed to read reply: %w", e.config.Name, err)
+	}
+	if int64(len(reply)) > e.maxOutputBytes {
+		return nil, fmt.Errorf("plugin %s output exceeded %d bytes limit", e.config.Name, e.maxOutputBytes)
+	}
+
+	e.Logger().Debug().
+		Str("plugin", e.config.Name).
+		Str("address", src.Address).
+		Dur("duration", time.Since(startTime)).
+		Int("output_bytes", len(reply)).
+		Msg("Plugin TCP source read")
+
+	reply = bytes.TrimSpace(reply)
+	if len(reply) == 0 {
+		return []collector.Metric{}, nil
+	}
+
+	// A bare number is reported as a single gauge
+	if value, err := strconv.ParseFloat(string(reply), 64); err == nil {
+		name := src.MetricName
+		if name == "" {
+			name = defaultSourceMetricName
+		}
+		return e.convertMetrics([]PluginMetric{{Name: name, Value: value}}), nil
+	}
+
+	return e.parseOutput(reply)
+}
+
+// readUntilDelimiter reads from r until delim is seen or EOF. The delimiter is
+// not included in the returned bytes.
+func readUntilDelimiter(r io.Reader, delim string) ([]byte, error) {
+	if delim == "" {
+		delim = "\n"
+	}
+	d := []byte(delim)
+
+	var buf bytes.Buffer
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			return buf.Bytes(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteByte(b)
+		if bytes.HasSuffix(buf.Bytes(), d) {
+			return buf.Bytes()[:buf.Len()-len(d)], nil
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// internal/plugin/tcp_source_test.go
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// startTCPServer accepts connections and answers each with reply after
+// reading one line (when expectSend is true).
+func startTCPServer(t *testing.T, expectSend bool, reply string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(c net.Conn) {
+				defer c.Close()
+				if expectSend {
+					bufio.NewReader(c).ReadString('\n')
+				}
+				c.Write([]byte(reply))
+			}(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestExecPlugin_TCPSource(t *testing.T) {
+	t.Run("bare number reply", func(t *testing.T) {
+		addr := startTCPServer(t, true, "42.5\n")
+		ep := NewExecPlugin(PluginConfig{Name: "sock", Timeout: 2, TCP: &TCPSource{Address: addr, Send: "temp\n", MetricName: "temp"}})
+		metrics, err := ep.Collect(context.Background())
+		if err != nil {
+			t.Fatalf("Collect failed: %v", err)
+		}
+		if len(metrics) != 1 {
+			t.Fatalf("expected 1 metric, got %d", len(metrics))
+		}
+		if metrics[0].Name != "plugin_sock_temp" || metrics[0].Value != 42.5 {
+			t.Errorf("unexpected metric: %+v", metrics[0])
+		}
+	})
+
+	t.Run("JSON reply with custom delimiter", func(t *testing.T) {
+		addr := startTCPServer(t, false, `[{"name":"a","value":1},{"name":"b","value":2}]END trailing`)
+		ep := NewExecPlugin(PluginConfig{Name: "sock", Timeout: 2, TCP: &TCPSource{Address: addr, Delimiter: "END"}})
+		metrics, err := ep.Collect(context.Background())
+		if err != nil {
+			t.Fatalf("Collect failed: %v", err)
+		}
+		if len(metrics) != 2 {
+			t.Errorf("expected 2 metrics, got %d", len(metrics))
+		}
+	})
+
+	t.Run("timeout when server never replies", func(t *testing.T) {
+		ln, _ := net.Listen("tcp", "127.0.0.1:0")
+		defer ln.Close()
+		go func() {
+			conn, err := ln.Accept()
+			if err == nil {
+				time.Sleep(3 * time.Second)
+				conn.Close()
+			}
+		}()
+		ep := NewExecPlugin(PluginConfig{Name: "slow", Timeout: 1, TCP: &TCPSource{Address: ln.Addr().String()}})
+		start := time.Now()
+		if _, err := ep.Collect(context.Background()); err == nil {
+			t.Error("expected timeout error")
+		}
+		if time.Since(start) > 2*time.Second {
+			t.Error("timeout not respected")
+		}
+	})
+
+	t.Run("connection refused returns error", func(t *testing.T) {
+		ep := NewExecPlugin(PluginConfig{Name: "down", Timeout: 1, TCP: &TCPSource{Address: "127.0.0.1:1"}})
+		if _, err := ep.Collect(context.Background()); err == nil {
+			t.Error("expected connection error")
+		}
+	})
+}
+
+func TestValidatePluginDefinition(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  PluginConfig
+		wantErr bool
+	}{
+		{"exec ok", PluginConfig{Name: "p", Path: "/bin/true"}, false},
+		{"exec without path", PluginConfig{Name: "p"}, true},
+		{"tcp ok", PluginConfig{Name: "p", TCP: &TCPSource{Address: "localhost:9000"}}, false},
+		{"tcp missing address", PluginConfig{Name: "p", TCP: &TCPSource{}}, true},
+		{"tcp bad address", PluginConfig{Name: "p", TCP: &TCPSource{Address: "localhost"}}, true},
+		{"tcp with executable", PluginConfig{Name: "p", Path: "/bin/true", TCP: &TCPSource{Address: "localhost:9000"}}, true},
+		{"tcp with args", PluginConfig{Name: "p", Args: []string{"-v"}, TCP: &TCPSource{Address: "localhost:9000"}}, true},
+		{"http ok", PluginConfig{Name: "p", HTTP: &HTTPSource{URL: "https://api.internal/metrics", BearerToken: "${TOKEN}"}}, false},
+		{"http relative url", PluginConfig{Name: "p", HTTP: &HTTPSource{URL: "/metrics"}}, true},
+		{"http unix socket", PluginConfig{Name: "p", HTTP: &HTTPSource{URL: "unix:///run/app.sock:/stats"}}, false},
+		{"http relative unix socket", PluginConfig{Name: "p", HTTP: &HTTPSource{URL: "unix://app.sock"}}, true},
+		{"http bearer and basic", PluginConfig{Name: "p", HTTP: &HTTPSource{URL: "http://x/", BearerToken: "t", Username: "u"}}, true},
+		{"http with tcp", PluginConfig{Name: "p", HTTP: &HTTPSource{URL: "http://x/"}, TCP: &TCPSource{Address: "localhost:9000"}}, true},
+		{"file ok", PluginConfig{Name: "p", File: &FileSource{Path: "/var/log/app.log", TailLines: 1}}, false},
+		{"file missing path", PluginConfig{Name: "p", File: &FileSource{TailLines: 1}}, true},
+		{"file glob", PluginConfig{Name: "p", File: &FileSource{Glob: "/sys/class/thermal/thermal_zone*/temp", PathLabels: `zone(?P<zone>\d+)`}}, false},
+		{"file path and glob", PluginConfig{Name: "p", File: &FileSource{Path: "/f", Glob: "/g*"}}, true},
+		{"file bad glob", PluginConfig{Name: "p", File: &FileSource{Glob: "/sys/[a"}}, true},
+		{"file bad label group", PluginConfig{Name: "p", File: &FileSource{Glob: "/g*", PathLabels: `(?P<__zone>\d+)`}}, true},
+		{"file tail and max bytes", PluginConfig{Name: "p", File: &FileSource{Path: "/f", TailLines: 1, MaxBytes: 10}}, true},
+		{"file with executable", PluginConfig{Name: "p", Path: "/bin/true", File: &FileSource{Path: "/f"}}, true},
+		{"observe with buckets", PluginConfig{Name: "p", Path: "/bin/true", Observe: true, Buckets: []float64{0.1, 1}}, false},
+		{"observe with rate", PluginConfig{Name: "p", Path: "/bin/true", Observe: true, Rate: true}, true},
+		{"unsorted buckets", PluginConfig{Name: "p", Path: "/bin/true", Observe: true, Buckets: []float64{1, 0.1}}, true},
+		{"aggregate window", PluginConfig{Name: "p", Path: "/bin/true", AggregateWindowSeconds: 60, AggregateFunc: AggregateMax}, false},
+		{"unknown aggregate func", PluginConfig{Name: "p", Path: "/bin/true", AggregateWindowSeconds: 60, AggregateFunc: "median"}, true},
+		{"negative max output", PluginConfig{Name: "p", Path: "/bin/true", MaxOutputBytes: -1}, true},
+		{"negative interval", PluginConfig{Name: "p", Path: "/bin/true", Interval: -1}, true},
+		{"negative aggregate window", PluginConfig{Name: "p", Path: "/bin/true", AggregateWindowSeconds: -1}, true},
+		{"aggregate with observe", PluginConfig{Name: "p", Path: "/bin/true", AggregateWindowSeconds: 60, Observe: true}, true},
+		{"inline shell", PluginConfig{Name: "p", Path: "/plugins/sh", Args: []string{"-c", "rm -rf /"}}, true},
+		{"inline shell flag group", PluginConfig{Name: "p", Path: "/plugins/bash", Args: []string{"-ec", "true"}}, true},
+		{"inline shell allowed", PluginConfig{Name: "p", Path: "/plugins/bash", Args: []string{"-c", "true"}, AllowShell: true}, false},
+		{"shell running a script", PluginConfig{Name: "p", Path: "/plugins/bash", Args: []string{"-e", "collect.sh"}}, false},
+		{"non-shell with -c", PluginConfig{Name: "p", Path: "/plugins/collect", Args: []string{"-c", "conf.ini"}}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validatePluginDefinition(tc.config)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("validatePluginDefinition() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestDiscoverPlugins_TCPDefinition(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "sensor.json"), []byte(`{"tcp":{"address":"127.0.0.1:9100","send":"read\n"}}`), 0644)
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"tcp":{}}`), 0644)
+	writeTestPlugin(t, dir, "script", "#!/bin/bash\necho '[]'")
+	os.WriteFile(filepath.Join(dir, "script.json"), []byte(`{"timeout":5}`), 0644)
+
+	plugins, err := DiscoverPlugins(dir, 30*time.Second, false, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("DiscoverPlugins failed: %v", err)
+	}
+	if len(plugins) != 2 {
+		t.Fatalf("expected 2 plugins (script + sensor), got %d", len(plugins))
+	}
+	var sensor *ExecPlugin
+	for _, p := range plugins {
+		if p.config.Name == "sensor" {
+			sensor = p
+		}
+	}
+	if sensor == nil || detectSource(sensor.config) != SourceTCP {
+		t.Error("expected sensor plugin with tcp source")
+	}
+}
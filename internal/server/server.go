@@ -2,12 +2,19 @@ package server
 
 import (
 	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/http/pprof"
+	"os"
+	"strings"
 	"time"
 
-	"github.com/rs/zerolog/log"
+	"github.com/0x524A/metricsd/internal/collector"
 )
 
 // CollectorHealth is the health info for a single collector.
@@ -31,13 +38,118 @@ type HealthProvider interface {
 	GetHealthData() map[string]CollectorHealth
 }
 
+// ReadinessChecker reports whether the daemon is ready to serve, and why not.
+type ReadinessChecker interface {
+	Ready() (ready bool, reason string)
+}
+
+// DebugCollectorStatus is a collector's entry in the /debug/status response.
+type DebugCollectorStatus struct {
+	MetricCount int    `json:"metric_count"`
+	LastError   string `json:"last_error,omitempty"`
+	LastErrorAt string `json:"last_error_at,omitempty"`
+	Disabled    bool   `json:"disabled,omitempty"` // Skipped after repeated failures
+	Paused      bool   `json:"paused,omitempty"`   // Disabled through /admin/collectors
+}
+
+// DebugStatus is the /debug/status response describing the last cycle.
+type DebugStatus struct {
+	LastCycle        string                          `json:"last_cycle,omitempty"`
+	LastCycleSeconds float64                         `json:"last_cycle_duration_seconds"`
+	LastCycleError   string                          `json:"last_cycle_error,omitempty"`
+	Collectors       map[string]DebugCollectorStatus `json:"collectors"`
+}
+
+// StatusProvider supplies the last cycle's status to /debug/status.
+type StatusProvider interface {
+	GetDebugStatus() DebugStatus
+}
+
+// DebugPlugin is a plugin's entry in the /debug/plugins response. NextRun is
+// empty when the plugin runs in the next collection cycle.
+type DebugPlugin struct {
+	Name            string   `json:"name"`
+	Source          string   `json:"source"`
+	Metric          string   `json:"metric,omitempty"`
+	IntervalSeconds float64  `json:"interval_seconds"`
+	Status          string   `json:"status"`
+	LastRun         string   `json:"last_run,omitempty"`
+	NextRun         string   `json:"next_run,omitempty"`
+	LastMetricCount int      `json:"last_metric_count"`
+	LastValue       *float64 `json:"last_value,omitempty"`
+	LastError       string   `json:"last_error,omitempty"`
+}
+
+// DebugPlugins is the /debug/plugins response.
+type DebugPlugins struct {
+	Plugins []DebugPlugin `json:"plugins"`
+}
+
+// PluginStatusProvider supplies the loaded plugins to /debug/plugins.
+type PluginStatusProvider interface {
+	GetDebugPlugins() DebugPlugins
+}
+
+// CollectorToggler pauses and resumes collectors for the admin endpoints.
+// SetCollectorEnabled returns an error wrapping ErrUnknownCollector for names
+// that aren't registered.
+type CollectorToggler interface {
+	SetCollectorEnabled(name string, enabled bool) error
+}
+
+// ErrUnknownCollector is matched with errors.Is to answer 404 for unknown names
+var ErrUnknownCollector = errors.New("unknown collector")
+
+// CollectTrigger runs an immediate collect-and-ship cycle for POST /admin/collect.
+// CollectNow returns an error wrapping ErrCollectInProgress when a cycle is
+// already running.
+type CollectTrigger interface {
+	CollectNow(ctx context.Context) (CollectResult, error)
+}
+
+// ErrCollectInProgress is matched with errors.Is to answer 409 while a cycle runs
+var ErrCollectInProgress = errors.New("collection cycle already in progress")
+
+// CollectResult is the response body of POST /admin/collect. Shipped is 0 when
+// shipping failed, in which case Error says why.
+type CollectResult struct {
+	Collected int    `json:"collected"`
+	Shipped   int    `json:"shipped"`
+	Error     string `json:"error,omitempty"`
+}
+
+// CollectorToggleStatus is the response body of the admin collector endpoints.
+type CollectorToggleStatus struct {
+	Collector string `json:"collector"`
+	Enabled   bool   `json:"enabled"`
+}
+
+// ProbeStatus is the response body of the liveness and readiness probes.
+type ProbeStatus struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
 // Server provides HTTP endpoints for health checks.
 type Server struct {
+	collector.Logging
 	host           string
 	port           int
 	server         *http.Server
 	startTime      time.Time
 	healthProvider HealthProvider
+	readiness      ReadinessChecker
+	statusProvider StatusProvider
+	pluginStatus   PluginStatusProvider
+	pprofEnabled   bool
+	toggler        CollectorToggler
+	collectTrigger CollectTrigger
+
+	// TLS is enabled when certFile is set; caFile additionally requires client certificates
+	certFile, keyFile, caFile string
+
+	// Credentials required on every path except the probes; empty disables auth
+	username, password, bearerToken string
 }
 
 // NewServer creates a new HTTP server.
@@ -51,21 +163,88 @@ func NewServer(host string, port int, healthProvider HealthProvider) *Server {
 	}
 }
 
+// SetReadinessChecker sets the source of the /readyz result.
+// Without one, /readyz always reports ready.
+func (s *Server) SetReadinessChecker(r ReadinessChecker) {
+	s.readiness = r
+}
+
+// SetStatusProvider enables /debug/status, served from p.
+func (s *Server) SetStatusProvider(p StatusProvider) {
+	s.statusProvider = p
+}
+
+// SetPluginStatusProvider enables /debug/plugins, served from p.
+func (s *Server) SetPluginStatusProvider(p PluginStatusProvider) {
+	s.pluginStatus = p
+}
+
+// SetPprofEnabled mounts the net/http/pprof handlers under /debug/pprof/ when enabled.
+func (s *Server) SetPprofEnabled(enabled bool) {
+	s.pprofEnabled = enabled
+}
+
+// SetCollectorToggler mounts POST /admin/collectors/{name}/enable and /disable.
+// They are only served when auth is configured.
+func (s *Server) SetCollectorToggler(t CollectorToggler) {
+	s.toggler = t
+}
+
+// SetCollectTrigger mounts POST /admin/collect. Like the collector toggles, it
+// is only served when auth is configured.
+func (s *Server) SetCollectTrigger(t CollectTrigger) {
+	s.collectTrigger = t
+}
+
+// SetTLS makes Start serve HTTPS with the given certificate and key. When
+// caFile is set, clients must present a certificate signed by that CA.
+func (s *Server) SetTLS(certFile, keyFile, caFile string) {
+	s.certFile = certFile
+	s.keyFile = keyFile
+	s.caFile = caFile
+}
+
+// SetAuth requires basic auth credentials or a bearer token on every path
+// except the /health, /healthz and /readyz probes. Empty values disable that method.
+func (s *Server) SetAuth(username, password, bearerToken string) {
+	s.username = username
+	s.password = password
+	s.bearerToken = bearerToken
+}
+
 // Start starts the HTTP server.
 func (s *Server) Start(ctx context.Context) error {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/health", s.handleHealth)
-
 	s.server = &http.Server{
 		Addr:    fmt.Sprintf("%s:%d", s.host, s.port),
-		Handler: mux,
+		Handler: s.routes(),
+	}
+
+	if s.caFile != "" {
+		caCert, err := os.ReadFile(s.caFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return fmt.Errorf("no certificates found in client CA file %s", s.caFile)
+		}
+		s.server.TLSConfig = &tls.Config{
+			ClientCAs:  pool,
+			ClientAuth: tls.RequireAndVerifyClientCert,
+		}
 	}
 
-	log.Info().Str("host", s.host).Int("port", s.port).Msg("Starting HTTP server")
+	s.Logger().Info().Str("host", s.host).Int("port", s.port).Bool("tls", s.certFile != "").Msg("Starting HTTP server")
 
 	errChan := make(chan error, 1)
 	go func() {
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if s.certFile != "" {
+			err = s.server.ListenAndServeTLS(s.certFile, s.keyFile)
+		} else {
+			err = s.server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			errChan <- err
 		}
 	}()
@@ -78,10 +257,83 @@ func (s *Server) Start(ctx context.Context) error {
 	}
 }
 
+// routes builds the server's handler. Optional endpoints are only mounted when configured.
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/healthz", s.handleLiveness)
+	mux.HandleFunc("/readyz", s.handleReadiness)
+	if s.statusProvider != nil {
+		mux.HandleFunc("/debug/status", s.handleDebugStatus)
+	}
+	if s.pluginStatus != nil {
+		mux.HandleFunc("/debug/plugins", s.handleDebugPlugins)
+	}
+	if s.pprofEnabled {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	authEnabled := s.username != "" || s.bearerToken != ""
+	if s.toggler != nil && authEnabled {
+		mux.HandleFunc("/admin/collectors/{name}/{action}", s.handleCollectorToggle)
+	}
+	if s.collectTrigger != nil && authEnabled {
+		mux.HandleFunc("/admin/collect", s.handleCollect)
+	}
+	return s.requireAuth(mux)
+}
+
+// requireAuth rejects requests without valid credentials, except for the
+// health, liveness and readiness probes which health checks call unauthenticated.
+func (s *Server) requireAuth(next http.Handler) http.Handler {
+	if s.username == "" && s.bearerToken == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isHealthPath(r.URL.Path) || s.authorized(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if s.username != "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="metricsd"`)
+		} else {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metricsd"`)
+		}
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	})
+}
+
+// isHealthPath reports whether path is one of the unauthenticated health probes
+func isHealthPath(path string) bool {
+	return path == "/health" || path == "/healthz" || path == "/readyz"
+}
+
+// authorized reports whether r carries the configured basic credentials or bearer token
+func (s *Server) authorized(r *http.Request) bool {
+	if s.username != "" {
+		if user, pass, ok := r.BasicAuth(); ok {
+			userOK := subtle.ConstantTimeCompare([]byte(user), []byte(s.username)) == 1
+			passOK := subtle.ConstantTimeCompare([]byte(pass), []byte(s.password)) == 1
+			if userOK && passOK {
+				return true
+			}
+		}
+	}
+	if s.bearerToken != "" {
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			return subtle.ConstantTimeCompare([]byte(token), []byte(s.bearerToken)) == 1
+		}
+	}
+	return false
+}
+
 // Shutdown gracefully shuts down the server.
 func (s *Server) Shutdown(ctx context.Context) error {
 	if s.server != nil {
-		log.Info().Msg("Shutting down HTTP server")
+		s.Logger().Info().Msg("Shutting down HTTP server")
 		return s.server.Shutdown(ctx)
 	}
 	return nil
@@ -106,6 +358,108 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(status); err != nil {
-		log.Error().Err(err).Msg("Failed to encode health status")
+		s.Logger().Error().Err(err).Msg("Failed to encode health status")
+	}
+}
+
+// handleLiveness reports that the process is up and serving HTTP.
+func (s *Server) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, ProbeStatus{Status: "alive"})
+}
+
+// handleReadiness returns 503 until the orchestrator reports ready.
+func (s *Server) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.readiness != nil {
+		if ready, reason := s.readiness.Ready(); !ready {
+			s.writeJSON(w, http.StatusServiceUnavailable, ProbeStatus{Status: "not_ready", Reason: reason})
+			return
+		}
+	}
+	s.writeJSON(w, http.StatusOK, ProbeStatus{Status: "ready"})
+}
+
+// handleDebugStatus reports the last collection cycle for troubleshooting.
+func (s *Server) handleDebugStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, s.statusProvider.GetDebugStatus())
+}
+
+// handleDebugPlugins lists the loaded plugins and their schedule.
+func (s *Server) handleDebugPlugins(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, s.pluginStatus.GetDebugPlugins())
+}
+
+// handleCollectorToggle pauses or resumes the named collector.
+func (s *Server) handleCollectorToggle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var enabled bool
+	switch r.PathValue("action") {
+	case "enable":
+		enabled = true
+	case "disable":
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	name := r.PathValue("name")
+	if err := s.toggler.SetCollectorEnabled(name, enabled); err != nil {
+		if errors.Is(err, ErrUnknownCollector) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.Logger().Info().Str("collector", name).Bool("enabled", enabled).Str("remote", r.RemoteAddr).Msg("Collector toggled via admin endpoint")
+	s.writeJSON(w, http.StatusOK, CollectorToggleStatus{Collector: name, Enabled: enabled})
+}
+
+// handleCollect runs a collect-and-ship cycle and reports its metric counts
+func (s *Server) handleCollect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.Logger().Info().Str("remote", r.RemoteAddr).Msg("Collection triggered via admin endpoint")
+	result, err := s.collectTrigger.CollectNow(r.Context())
+	if err != nil {
+		if errors.Is(err, ErrCollectInProgress) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		result.Error = err.Error()
+		s.writeJSON(w, http.StatusBadGateway, result)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.Logger().Error().Err(err).Msg("Failed to encode response")
 	}
 }
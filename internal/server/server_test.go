@@ -3,11 +3,21 @@ package server
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"math/big"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -177,6 +187,266 @@ func TestServer_StartAndShutdown(t *testing.T) {
 	}
 }
 
+// writeTestCert writes a self-signed localhost certificate and key to dir.
+func writeTestCert(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
+
+func TestServer_TLS(t *testing.T) {
+	certFile, keyFile := writeTestCert(t, t.TempDir())
+	certPEM, _ := os.ReadFile(certFile)
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(certPEM)
+
+	start := func(t *testing.T, caFile string) string {
+		t.Helper()
+		port := freePort(t)
+		srv := NewServer("127.0.0.1", port, nil)
+		srv.SetTLS(certFile, keyFile, caFile)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- srv.Start(ctx) }()
+		t.Cleanup(func() {
+			cancel()
+			<-done
+		})
+
+		addr := fmt.Sprintf("127.0.0.1:%d", port)
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if conn, err := net.Dial("tcp", addr); err == nil {
+				conn.Close()
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return "https://" + addr + "/healthz"
+	}
+
+	t.Run("server certificate", func(t *testing.T) {
+		url := start(t, "")
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+		resp, err := client.Get(url)
+		if err != nil {
+			t.Fatalf("HTTPS request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("expected 200, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("client certificate required", func(t *testing.T) {
+		// The self-signed server certificate doubles as the client CA and client cert
+		url := start(t, certFile)
+
+		noCert := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+		if resp, err := noCert.Get(url); err == nil {
+			resp.Body.Close()
+			t.Fatal("expected request without a client certificate to fail")
+		}
+
+		clientCert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		withCert := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, Certificates: []tls.Certificate{clientCert}}}}
+		resp, err := withCert.Get(url)
+		if err != nil {
+			t.Fatalf("mTLS request failed: %v", err)
+		}
+		resp.Body.Close()
+	})
+}
+
+func TestServer_Auth(t *testing.T) {
+	srv := NewServer("127.0.0.1", 0, nil)
+	srv.SetAuth("admin", "s3cret", "tok")
+	srv.SetPprofEnabled(true)
+	handler := srv.routes()
+
+	tests := []struct {
+		name  string
+		path  string
+		setup func(r *http.Request)
+		want  int
+	}{
+		{"liveness is open", "/healthz", nil, http.StatusOK},
+		{"readiness is open", "/readyz", nil, http.StatusOK},
+		{"health is open", "/health", nil, http.StatusOK},
+		{"debug needs credentials", "/debug/pprof/", nil, http.StatusUnauthorized},
+		{"wrong password", "/debug/pprof/", func(r *http.Request) { r.SetBasicAuth("admin", "nope") }, http.StatusUnauthorized},
+		{"basic auth", "/debug/pprof/", func(r *http.Request) { r.SetBasicAuth("admin", "s3cret") }, http.StatusOK},
+		{"bearer token", "/debug/pprof/", func(r *http.Request) { r.Header.Set("Authorization", "Bearer tok") }, http.StatusOK},
+		{"wrong token", "/debug/pprof/", func(r *http.Request) { r.Header.Set("Authorization", "Bearer other") }, http.StatusUnauthorized},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.setup != nil {
+				tc.setup(req)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Errorf("GET %s = %d, want %d", tc.path, rec.Code, tc.want)
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 response without WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+type mockToggler struct {
+	enabled map[string]bool
+}
+
+func (m *mockToggler) SetCollectorEnabled(name string, enabled bool) error {
+	if _, ok := m.enabled[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownCollector, name)
+	}
+	m.enabled[name] = enabled
+	return nil
+}
+
+func TestCollectorToggleEndpoint(t *testing.T) {
+	toggler := &mockToggler{enabled: map[string]bool{"plugins": true}}
+	srv := NewServer("127.0.0.1", 0, nil)
+	srv.SetAuth("", "", "tok")
+	srv.SetCollectorToggler(toggler)
+	handler := srv.routes()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		auth   bool
+		want   int
+	}{
+		{"requires auth", http.MethodPost, "/admin/collectors/plugins/disable", false, http.StatusUnauthorized},
+		{"disable", http.MethodPost, "/admin/collectors/plugins/disable", true, http.StatusOK},
+		{"GET not allowed", http.MethodGet, "/admin/collectors/plugins/enable", true, http.StatusMethodNotAllowed},
+		{"unknown action", http.MethodPost, "/admin/collectors/plugins/restart", true, http.StatusNotFound},
+		{"unknown collector", http.MethodPost, "/admin/collectors/gpu/disable", true, http.StatusNotFound},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.auth {
+				req.Header.Set("Authorization", "Bearer tok")
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Errorf("%s %s = %d, want %d", tc.method, tc.path, rec.Code, tc.want)
+			}
+		})
+	}
+	if toggler.enabled["plugins"] {
+		t.Error("expected plugins to be disabled")
+	}
+
+	t.Run("not mounted without auth", func(t *testing.T) {
+		open := NewServer("127.0.0.1", 0, nil)
+		open.SetCollectorToggler(toggler)
+		rec := httptest.NewRecorder()
+		open.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/collectors/plugins/enable", nil))
+		if rec.Code != http.StatusNotFound || toggler.enabled["plugins"] {
+			t.Errorf("expected 404 without auth, got %d", rec.Code)
+		}
+	})
+}
+
+type mockCollectTrigger struct {
+	result CollectResult
+	err    error
+	calls  int
+}
+
+func (m *mockCollectTrigger) CollectNow(ctx context.Context) (CollectResult, error) {
+	m.calls++
+	return m.result, m.err
+}
+
+func TestCollectEndpoint(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		auth    bool
+		trigger *mockCollectTrigger
+		want    int
+		body    CollectResult
+	}{
+		{"requires auth", http.MethodPost, false, &mockCollectTrigger{}, http.StatusUnauthorized, CollectResult{}},
+		{"GET not allowed", http.MethodGet, true, &mockCollectTrigger{}, http.StatusMethodNotAllowed, CollectResult{}},
+		{"success", http.MethodPost, true, &mockCollectTrigger{result: CollectResult{Collected: 10, Shipped: 14}}, http.StatusOK, CollectResult{Collected: 10, Shipped: 14}},
+		{"in progress", http.MethodPost, true, &mockCollectTrigger{err: fmt.Errorf("%w: busy", ErrCollectInProgress)}, http.StatusConflict, CollectResult{}},
+		{"ship failed", http.MethodPost, true, &mockCollectTrigger{result: CollectResult{Collected: 10}, err: fmt.Errorf("ship failed")}, http.StatusBadGateway, CollectResult{Collected: 10, Error: "ship failed"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := NewServer("127.0.0.1", 0, nil)
+			srv.SetAuth("", "", "tok")
+			srv.SetCollectTrigger(tc.trigger)
+
+			req := httptest.NewRequest(tc.method, "/admin/collect", nil)
+			if tc.auth {
+				req.Header.Set("Authorization", "Bearer tok")
+			}
+			rec := httptest.NewRecorder()
+			srv.routes().ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Fatalf("%s /admin/collect = %d, want %d", tc.method, rec.Code, tc.want)
+			}
+			if rec.Header().Get("Content-Type") != "application/json" {
+				return
+			}
+			var got CollectResult
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if got != tc.body {
+				t.Errorf("response = %+v, want %+v", got, tc.body)
+			}
+		})
+	}
+
+	t.Run("not mounted without auth", func(t *testing.T) {
+		trigger := &mockCollectTrigger{}
+		open := NewServer("127.0.0.1", 0, nil)
+		open.SetCollectTrigger(trigger)
+		rec := httptest.NewRecorder()
+		open.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/collect", nil))
+		if rec.Code != http.StatusNotFound || trigger.calls != 0 {
+			t.Errorf("expected 404 without auth, got %d", rec.Code)
+		}
+	})
+}
+
 func TestNewServer_NilProvider(t *testing.T) {
 	// NewServer with nil provider must not panic.
 	srv := NewServer("localhost", 0, nil)
@@ -222,3 +492,173 @@ func TestServer_ShutdownWithoutStart(t *testing.T) {
 		t.Errorf("expected nil error, got %v", err)
 	}
 }
+
+type mockReadiness struct {
+	ready  bool
+	reason string
+}
+
+func (m *mockReadiness) Ready() (bool, string) { return m.ready, m.reason }
+
+func TestProbeEndpoints(t *testing.T) {
+	tests := []struct {
+		name       string
+		readiness  ReadinessChecker
+		path       string
+		wantCode   int
+		wantStatus string
+	}{
+		{"liveness", &mockReadiness{ready: false}, "/healthz", http.StatusOK, "alive"},
+		{"ready without checker", nil, "/readyz", http.StatusOK, "ready"},
+		{"ready", &mockReadiness{ready: true}, "/readyz", http.StatusOK, "ready"},
+		{"not ready", &mockReadiness{ready: false, reason: "no successful cycle"}, "/readyz", http.StatusServiceUnavailable, "not_ready"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := NewServer("localhost", 0, nil)
+			if tt.readiness != nil {
+				srv.SetReadinessChecker(tt.readiness)
+			}
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+
+			if tt.path == "/healthz" {
+				srv.handleLiveness(w, req)
+			} else {
+				srv.handleReadiness(w, req)
+			}
+
+			if w.Code != tt.wantCode {
+				t.Errorf("expected %d, got %d", tt.wantCode, w.Code)
+			}
+			var status ProbeStatus
+			if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if status.Status != tt.wantStatus {
+				t.Errorf("expected status %q, got %q", tt.wantStatus, status.Status)
+			}
+			if tt.wantCode != http.StatusOK && status.Reason == "" {
+				t.Error("expected a reason when not ready")
+			}
+		})
+	}
+}
+
+type mockStatusProvider struct {
+	status DebugStatus
+}
+
+func (m *mockStatusProvider) GetDebugStatus() DebugStatus { return m.status }
+
+func TestDebugStatusEndpoint(t *testing.T) {
+	srv := NewServer("localhost", 0, nil)
+	srv.SetStatusProvider(&mockStatusProvider{status: DebugStatus{
+		LastCycle:        "2026-04-10T19:00:00Z",
+		LastCycleSeconds: 1.5,
+		Collectors: map[string]DebugCollectorStatus{
+			"system": {MetricCount: 42},
+			"http":   {LastError: "connection refused", LastErrorAt: "2026-04-10T19:00:00Z"},
+		},
+	}})
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/status", nil)
+	w := httptest.NewRecorder()
+	srv.handleDebugStatus(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var status DebugStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if status.LastCycleSeconds != 1.5 {
+		t.Errorf("expected duration 1.5, got %v", status.LastCycleSeconds)
+	}
+	if status.Collectors["system"].MetricCount != 42 {
+		t.Errorf("expected 42 system metrics, got %d", status.Collectors["system"].MetricCount)
+	}
+	if status.Collectors["http"].LastError != "connection refused" {
+		t.Errorf("expected http last error, got %q", status.Collectors["http"].LastError)
+	}
+}
+
+type mockPluginStatusProvider struct {
+	plugins DebugPlugins
+}
+
+func (m *mockPluginStatusProvider) GetDebugPlugins() DebugPlugins { return m.plugins }
+
+func TestDebugPluginsEndpoint(t *testing.T) {
+	t.Run("not mounted without provider", func(t *testing.T) {
+		srv := NewServer("localhost", 0, nil)
+		w := httptest.NewRecorder()
+		srv.routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/plugins", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("lists plugins", func(t *testing.T) {
+		value := 42.0
+		srv := NewServer("localhost", 0, nil)
+		srv.SetPluginStatusProvider(&mockPluginStatusProvider{plugins: DebugPlugins{Plugins: []DebugPlugin{
+			{Name: "queue", Source: "http", Metric: "queue_depth", IntervalSeconds: 60, Status: "ok", NextRun: "2026-04-10T19:01:00Z", LastMetricCount: 1, LastValue: &value},
+			{Name: "broken", Source: "go", Status: "failing", LastError: "boom"},
+		}}})
+
+		w := httptest.NewRecorder()
+		srv.routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/plugins", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+		var got struct {
+			Plugins []map[string]any `json:"plugins"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if len(got.Plugins) != 2 {
+			t.Fatalf("expected 2 plugins, got %d", len(got.Plugins))
+		}
+		if got.Plugins[0]["next_run"] != "2026-04-10T19:01:00Z" || got.Plugins[0]["last_value"] != 42.0 {
+			t.Errorf("unexpected queue entry: %v", got.Plugins[0])
+		}
+		if _, ok := got.Plugins[1]["next_run"]; ok || got.Plugins[1]["last_error"] != "boom" {
+			t.Errorf("unexpected broken entry: %v", got.Plugins[1])
+		}
+	})
+
+	t.Run("rejects POST", func(t *testing.T) {
+		srv := NewServer("localhost", 0, nil)
+		srv.SetPluginStatusProvider(&mockPluginStatusProvider{})
+		w := httptest.NewRecorder()
+		srv.handleDebugPlugins(w, httptest.NewRequest(http.MethodPost, "/debug/plugins", nil))
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected 405, got %d", w.Code)
+		}
+	})
+}
+
+func TestPprofEndpoints(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("enabled=%v", enabled), func(t *testing.T) {
+			srv := NewServer("localhost", 0, nil)
+			srv.SetPprofEnabled(enabled)
+
+			req := httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+			w := httptest.NewRecorder()
+			srv.routes().ServeHTTP(w, req)
+
+			want := http.StatusNotFound
+			if enabled {
+				want = http.StatusOK
+			}
+			if w.Code != want {
+				t.Errorf("expected %d, got %d", want, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package shipper
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// certReloader serves the client certificate for mutual TLS and reloads it from
+// disk when the cert or key file changes, so rotated certificates are picked up
+// on the next handshake without a restart.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	logger   func() *zerolog.Logger // The owning shipper's logger
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	certStat fileStamp
+	keyStat  fileStamp
+}
+
+// fileStamp identifies a version of a file by modification time and size
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// newCertReloader loads the key pair once and fails if it is unusable. Reloads
+// are logged to logger, which is called at reload time so a logger injected
+// into the shipper after construction is used.
+func newCertReloader(certFile, keyFile string, logger func() *zerolog.Logger) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile, logger: logger}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetClientCertificate implements tls.Config.GetClientCertificate. If reloading a
+// changed file fails (e.g. the sidecar is midway through writing it), the previous
+// certificate keeps being served.
+func (r *certReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.changed() {
+		if err := r.reload(); err != nil {
+			r.logger().Warn().Err(err).Str("cert_file", r.certFile).Msg("Failed to reload TLS client certificate, keeping previous one")
+		} else {
+			r.logger().Info().Str("cert_file", r.certFile).Msg("Reloaded TLS client certificate")
+		}
+	}
+	return r.cert, nil
+}
+
+// changed reports whether either file differs from the last loaded version
+func (r *certReloader) changed() bool {
+	certStat, err := statFile(r.certFile)
+	if err != nil {
+		return false
+	}
+	keyStat, err := statFile(r.keyFile)
+	if err != nil {
+		return false
+	}
+	return certStat != r.certStat || keyStat != r.keyStat
+}
+
+func (r *certReloader) reload() error {
+	certStat, err := statFile(r.certFile)
+	if err != nil {
+		return err
+	}
+	keyStat, err := statFile(r.keyFile)
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	r.cert = &cert
+	r.certStat = certStat
+	r.keyStat = keyStat
+	return nil
+}
+
+func statFile(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
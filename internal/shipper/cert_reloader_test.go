@@ -0,0 +1,89 @@
+package shipper
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// TestCertReloader verifies that the client certificate is served from the
+// initial load, reloaded after the files change, and kept when a reload fails.
+func TestCertReloader(t *testing.T) {
+	certFile, keyFile, cleanup := generateTestCert(t)
+	defer cleanup()
+
+	var logs bytes.Buffer
+	logger := zerolog.New(&logs)
+	r, err := newCertReloader(certFile, keyFile, func() *zerolog.Logger { return &logger })
+	if err != nil {
+		t.Fatalf("newCertReloader: %v", err)
+	}
+
+	first, err := r.GetClientCertificate(nil)
+	if err != nil || first == nil {
+		t.Fatalf("GetClientCertificate: cert=%v err=%v", first, err)
+	}
+
+	// Unchanged files return the same certificate.
+	again, _ := r.GetClientCertificate(nil)
+	if again != first {
+		t.Error("expected cached certificate when files are unchanged")
+	}
+
+	// Rotate: copy a freshly generated pair over the original paths.
+	newCert, newKey, cleanup2 := generateTestCert(t)
+	defer cleanup2()
+	copyFile(t, newCert, certFile)
+	copyFile(t, newKey, keyFile)
+	future := time.Now().Add(time.Minute)
+	_ = os.Chtimes(certFile, future, future)
+	_ = os.Chtimes(keyFile, future, future)
+
+	rotated, err := r.GetClientCertificate(nil)
+	if err != nil {
+		t.Fatalf("GetClientCertificate after rotation: %v", err)
+	}
+	if bytes.Equal(rotated.Certificate[0], first.Certificate[0]) {
+		t.Error("expected rotated certificate to be loaded")
+	}
+	if !strings.Contains(logs.String(), "Reloaded TLS client certificate") {
+		t.Errorf("expected the reload to be logged to the given logger, got %q", logs.String())
+	}
+
+	// A broken write keeps the last good certificate.
+	if err := os.WriteFile(certFile, []byte("garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later := future.Add(time.Minute)
+	_ = os.Chtimes(certFile, later, later)
+
+	kept, err := r.GetClientCertificate(nil)
+	if err != nil {
+		t.Fatalf("GetClientCertificate after bad write: %v", err)
+	}
+	if kept != rotated {
+		t.Error("expected previous certificate to be kept after failed reload")
+	}
+}
+
+// TestNewCertReloader_Missing verifies that a missing key pair fails up front.
+func TestNewCertReloader_Missing(t *testing.T) {
+	if _, err := newCertReloader("/nonexistent/cert.pem", "/nonexistent/key.pem", nil); err == nil {
+		t.Error("expected error for missing certificate files")
+	}
+}
+
+func copyFile(t *testing.T, src, dst string) {
+	t.Helper()
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
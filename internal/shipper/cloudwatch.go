@@ -0,0 +1,156 @@
+package shipper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+// CloudWatch PutMetricData limits
+const (
+	cloudWatchMaxDatumsPerRequest = 1000
+	cloudWatchMaxDimensions       = 10
+)
+
+// cloudWatchAPI is the subset of the CloudWatch client used by the shipper
+type cloudWatchAPI interface {
+	PutMetricData(ctx context.Context, params *cloudwatch.PutMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.PutMetricDataOutput, error)
+}
+
+// CloudWatchShipper ships metrics to Amazon CloudWatch using PutMetricData
+type CloudWatchShipper struct {
+	collector.Logging
+	client    cloudWatchAPI
+	namespace string
+	timeout   time.Duration
+}
+
+// NewCloudWatchShipper creates a new CloudWatch shipper. Credentials come from
+// the default AWS chain (environment, shared config, instance/task role).
+// region may be empty to use AWS_REGION; endpoint overrides the service URL,
+// e.g. for LocalStack.
+func NewCloudWatchShipper(ctx context.Context, region, namespace, endpoint string, timeout time.Duration) (*CloudWatchShipper, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	if region != "" {
+		opts = append(opts, awsconfig.WithRegion(region))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	client := cloudwatch.NewFromConfig(awsCfg, func(o *cloudwatch.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
+
+	return &CloudWatchShipper{
+		client:    client,
+		namespace: namespace,
+		timeout:   timeout,
+	}, nil
+}
+
+// Ship sends metrics as CloudWatch datums, split to respect the per-request
+// limit. Every batch is attempted; failures are joined into the returned error.
+func (s *CloudWatchShipper) Ship(ctx context.Context, metrics []collector.Metric) error {
+	datums := convertToCloudWatch(metrics, time.Now())
+	if len(datums) == 0 {
+		return nil
+	}
+
+	batches := (len(datums) + cloudWatchMaxDatumsPerRequest - 1) / cloudWatchMaxDatumsPerRequest
+	var errs []error
+	for i := 0; i < batches; i++ {
+		end := min((i+1)*cloudWatchMaxDatumsPerRequest, len(datums))
+		if err := s.put(ctx, datums[i*cloudWatchMaxDatumsPerRequest:end]); err != nil {
+			errs = append(errs, fmt.Errorf("batch %d/%d: %w", i+1, batches, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	s.Logger().Info().
+		Int("metric_count", len(datums)).
+		Str("namespace", s.namespace).
+		Msg("Successfully shipped metrics to CloudWatch")
+
+	return nil
+}
+
+// put performs one PutMetricData call under the configured deadline
+func (s *CloudWatchShipper) put(ctx context.Context, datums []types.MetricDatum) error {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	_, err := s.client.PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
+		Namespace:  aws.String(s.namespace),
+		MetricData: datums,
+	})
+	if err != nil {
+		return fmt.Errorf("PutMetricData failed: %w", err)
+	}
+	return nil
+}
+
+// convertToCloudWatch maps metrics to datums. Labels become dimensions, sorted
+// by name and capped at cloudWatchMaxDimensions; empty values are dropped since
+// CloudWatch rejects them. NaN and infinite values are skipped for the same reason.
+func convertToCloudWatch(metrics []collector.Metric, now time.Time) []types.MetricDatum {
+	datums := make([]types.MetricDatum, 0, len(metrics))
+	for _, m := range metrics {
+		if math.IsNaN(m.Value) || math.IsInf(m.Value, 0) {
+			continue
+		}
+
+		keys := make([]string, 0, len(m.Labels))
+		for k, v := range m.Labels {
+			if v != "" {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		if len(keys) > cloudWatchMaxDimensions {
+			keys = keys[:cloudWatchMaxDimensions]
+		}
+
+		dims := make([]types.Dimension, 0, len(keys))
+		for _, k := range keys {
+			dims = append(dims, types.Dimension{Name: aws.String(k), Value: aws.String(m.Labels[k])})
+		}
+
+		ts := m.Timestamp
+		if ts.IsZero() {
+			ts = now
+		}
+
+		datums = append(datums, types.MetricDatum{
+			MetricName: aws.String(m.Name),
+			Dimensions: dims,
+			Value:      aws.Float64(m.Value),
+			Timestamp:  aws.Time(ts),
+			Unit:       types.StandardUnitNone,
+		})
+	}
+	return datums
+}
+
+// Close is a no-op; the SDK client holds no resources that need releasing
+func (s *CloudWatchShipper) Close() error {
+	return nil
+}
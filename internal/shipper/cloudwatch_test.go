@@ -0,0 +1,111 @@
+package shipper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+// fakeCloudWatch records PutMetricData calls and fails the ones listed in failCalls.
+type fakeCloudWatch struct {
+	mu        sync.Mutex
+	inputs    []*cloudwatch.PutMetricDataInput
+	failCalls map[int]bool
+}
+
+func (f *fakeCloudWatch) PutMetricData(_ context.Context, in *cloudwatch.PutMetricDataInput, _ ...func(*cloudwatch.Options)) (*cloudwatch.PutMetricDataOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inputs = append(f.inputs, in)
+	if f.failCalls[len(f.inputs)] {
+		return nil, errors.New("throttled")
+	}
+	return &cloudwatch.PutMetricDataOutput{}, nil
+}
+
+func TestConvertToCloudWatch(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	labels := map[string]string{"empty": ""}
+	for i := 0; i < 12; i++ {
+		labels[fmt.Sprintf("l%02d", i)] = "v"
+	}
+
+	datums := convertToCloudWatch([]collector.Metric{
+		{Name: "cpu", Value: 1.5, Type: "gauge", Labels: map[string]string{"host": "a"}},
+		{Name: "wide", Value: 1, Type: "gauge", Labels: labels},
+		{Name: "nan", Value: math.NaN(), Type: "gauge"},
+		{Name: "stamped", Value: 2, Type: "counter", Timestamp: time.Unix(1600000000, 0)},
+	}, now)
+
+	if len(datums) != 3 {
+		t.Fatalf("expected NaN to be skipped, got %d datums", len(datums))
+	}
+
+	cpu := datums[0]
+	if aws.ToString(cpu.MetricName) != "cpu" || aws.ToFloat64(cpu.Value) != 1.5 || !aws.ToTime(cpu.Timestamp).Equal(now) {
+		t.Errorf("unexpected cpu datum: %+v", cpu)
+	}
+	if len(cpu.Dimensions) != 1 || aws.ToString(cpu.Dimensions[0].Name) != "host" || aws.ToString(cpu.Dimensions[0].Value) != "a" {
+		t.Errorf("unexpected cpu dimensions: %+v", cpu.Dimensions)
+	}
+
+	wide := datums[1]
+	if len(wide.Dimensions) != cloudWatchMaxDimensions {
+		t.Fatalf("expected %d dimensions, got %d", cloudWatchMaxDimensions, len(wide.Dimensions))
+	}
+	if got := aws.ToString(wide.Dimensions[0].Name); got != "l00" {
+		t.Errorf("expected dimensions sorted by name without empty values, first is %q", got)
+	}
+
+	if got := aws.ToTime(datums[2].Timestamp); !got.Equal(time.Unix(1600000000, 0)) {
+		t.Errorf("expected metric timestamp to be kept, got %v", got)
+	}
+}
+
+func TestCloudWatchShipper_Batches(t *testing.T) {
+	fake := &fakeCloudWatch{failCalls: map[int]bool{2: true}}
+	s := &CloudWatchShipper{client: fake, namespace: "metricsd"}
+
+	metrics := make([]collector.Metric, cloudWatchMaxDatumsPerRequest*2+5)
+	for i := range metrics {
+		metrics[i] = collector.Metric{Name: "m", Value: float64(i), Type: "gauge"}
+	}
+
+	err := s.Ship(context.Background(), metrics)
+	if err == nil {
+		t.Fatal("expected error from the failed batch")
+	}
+
+	if len(fake.inputs) != 3 {
+		t.Fatalf("expected 3 PutMetricData calls, got %d", len(fake.inputs))
+	}
+	wantSizes := []int{cloudWatchMaxDatumsPerRequest, cloudWatchMaxDatumsPerRequest, 5}
+	for i, in := range fake.inputs {
+		if aws.ToString(in.Namespace) != "metricsd" {
+			t.Errorf("call %d: expected namespace metricsd, got %q", i, aws.ToString(in.Namespace))
+		}
+		if len(in.MetricData) != wantSizes[i] {
+			t.Errorf("call %d: expected %d datums, got %d", i, wantSizes[i], len(in.MetricData))
+		}
+	}
+}
+
+func TestCloudWatchShipper_Empty(t *testing.T) {
+	fake := &fakeCloudWatch{}
+	s := &CloudWatchShipper{client: fake, namespace: "metricsd"}
+	if err := s.Ship(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fake.inputs) != 0 {
+		t.Errorf("expected no calls for empty input, got %d", len(fake.inputs))
+	}
+}
@@ -0,0 +1,313 @@
+package shipper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+// DefaultDatadogSite is the Datadog site used when none is configured
+const DefaultDatadogSite = "datadoghq.com"
+
+// datadogMaxPayloadBytes keeps each request under the v2 series intake limit
+// (512000 bytes compressed; requests are sent uncompressed to stay well within it)
+const datadogMaxPayloadBytes = 500 * 1000
+
+// Datadog v2 metric intake types
+const (
+	datadogTypeCount = 1
+	datadogTypeGauge = 3
+)
+
+// DatadogShipper ships metrics to the Datadog v2 series API
+type DatadogShipper struct {
+	collector.Logging
+	url      string
+	apiKey   string
+	hostname string
+	client   *http.Client
+	ua       string
+	maxBody  int // Request size limit, datadogMaxPayloadBytes
+
+	mu       sync.Mutex
+	previous map[string]datadogCounter // Last cumulative value per counter series, for deltas
+}
+
+// datadogCounterStaleAfter is how long a counter missing from Ship calls keeps
+// its baseline
+const datadogCounterStaleAfter = time.Hour
+
+// datadogCounter is the baseline of one counter series
+type datadogCounter struct {
+	value float64
+	seen  time.Time // When the series was last shipped
+}
+
+type datadogPayload struct {
+	Series []datadogSeries `json:"series"`
+}
+
+type datadogSeries struct {
+	Metric    string            `json:"metric"`
+	Type      int               `json:"type"`
+	Points    []datadogPoint    `json:"points"`
+	Tags      []string          `json:"tags,omitempty"`
+	Resources []datadogResource `json:"resources,omitempty"`
+
+	counterKey string // Series key of a count, for committing its baseline
+}
+
+// datadogChunk is one request body and the counts it carries
+type datadogChunk struct {
+	body     []byte
+	counters []string
+}
+
+type datadogPoint struct {
+	Timestamp int64   `json:"timestamp"`
+	Value     float64 `json:"value"`
+}
+
+type datadogResource struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// NewDatadogShipper creates a new Datadog shipper for the given site, e.g.
+// "datadoghq.eu". An empty apiKey falls back to the DD_API_KEY environment variable.
+func NewDatadogShipper(apiKey, site string, timeout time.Duration) (*DatadogShipper, error) {
+	if apiKey == "" {
+		apiKey = os.Getenv("DD_API_KEY")
+	}
+	if apiKey == "" {
+		return nil, fmt.Errorf("datadog shipper requires an API key (api_key or DD_API_KEY)")
+	}
+	if site == "" {
+		site = DefaultDatadogSite
+	}
+
+	hostname, _ := os.Hostname()
+
+	return &DatadogShipper{
+		url:      "https://api." + site + "/api/v2/series",
+		apiKey:   apiKey,
+		hostname: hostname,
+		client:   &http.Client{Timeout: timeout, Transport: newTransport(nil)},
+		maxBody:  datadogMaxPayloadBytes,
+		previous: make(map[string]datadogCounter),
+	}, nil
+}
+
+// Ship converts metrics to Datadog series and posts them in as many requests
+// as the payload limit requires. Every request is attempted; failures are
+// joined. The counter baselines of the requests that succeeded advance even
+// when others fail, so a retry does not count their increases twice.
+func (s *DatadogShipper) Ship(ctx context.Context, metrics []collector.Metric) error {
+	now := time.Now()
+	series, counters := s.convert(metrics, now)
+	if len(series) == 0 {
+		s.commitCounters(counters, nil, now)
+		return nil
+	}
+
+	payloads, err := chunkDatadogSeries(series, s.maxBody)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	var failed []string
+	for i, chunk := range payloads {
+		if err := s.post(ctx, chunk.body); err != nil {
+			errs = append(errs, fmt.Errorf("request %d/%d: %w", i+1, len(payloads), err))
+			failed = append(failed, chunk.counters...)
+		}
+	}
+	s.commitCounters(counters, failed, now)
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	s.Logger().Info().
+		Int("metric_count", len(series)).
+		Int("request_count", len(payloads)).
+		Str("endpoint", s.url).
+		Msg("Successfully shipped metrics to Datadog")
+
+	return nil
+}
+
+// convert maps metrics to Datadog series. Gauges are sent as gauges. Counters
+// are cumulative here but Datadog counts are per interval, so each counter
+// ships its increase since the last successful Ship; the first sample only
+// primes the state and a decrease is treated as a reset. NaN and Inf are
+// dropped. The returned counter values become the baseline via commitCounters,
+// so a retried Ship resends the same increases.
+func (s *DatadogShipper) convert(metrics []collector.Metric, now time.Time) ([]datadogSeries, map[string]float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := make(map[string]float64, len(metrics))
+	series := make([]datadogSeries, 0, len(metrics))
+	for _, m := range metrics {
+		if math.IsNaN(m.Value) || math.IsInf(m.Value, 0) {
+			continue
+		}
+
+		ddType, value, key := datadogTypeGauge, m.Value, ""
+		if m.Type == "counter" {
+			key = collector.SeriesKey(m)
+			current[key] = m.Value
+			prev, seen := s.previous[key]
+			if !seen {
+				continue
+			}
+			ddType, value = datadogTypeCount, m.Value-prev.value
+			if value < 0 {
+				value = m.Value
+			}
+		}
+
+		series = append(series, datadogSeries{
+			Metric:    m.Name,
+			Type:      ddType,
+			Points:    []datadogPoint{{Timestamp: m.TimestampOr(now).Unix(), Value: value}},
+			Tags:      datadogTags(m.Labels),
+			Resources: []datadogResource{{Name: s.hostname, Type: "host"}},
+
+			counterKey: key,
+		})
+	}
+	return series, current
+}
+
+// commitCounters records counter values as the baseline for the next deltas,
+// except for the failed series, which keep their previous baseline. Series
+// absent from counters keep theirs too, so a counter skipping a cycle (a
+// plugin on a longer interval, an endpoint that is down) still gets its
+// increase when it returns, until it has been absent for
+// datadogCounterStaleAfter.
+func (s *DatadogShipper) commitCounters(counters map[string]float64, failed []string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range failed {
+		delete(counters, key)
+	}
+	for key, value := range counters {
+		s.previous[key] = datadogCounter{value: value, seen: now}
+	}
+	for key, prev := range s.previous {
+		if now.Sub(prev.seen) > datadogCounterStaleAfter {
+			delete(s.previous, key)
+		}
+	}
+}
+
+// datadogTags renders labels as sorted key:value tags
+func datadogTags(labels map[string]string) []string {
+	tags := make([]string, 0, len(labels))
+	for k, v := range labels {
+		tags = append(tags, k+":"+v)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// chunkDatadogSeries encodes series into JSON payloads of at most maxBytes each.
+// A single series larger than maxBytes is sent on its own.
+func chunkDatadogSeries(series []datadogSeries, maxBytes int) ([]datadogChunk, error) {
+	const prefix, suffix = `{"series":[`, `]}`
+
+	var payloads []datadogChunk
+	var counters []string
+	var buf bytes.Buffer
+	for _, ser := range series {
+		data, err := json.Marshal(ser)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal series %s: %w", ser.Metric, err)
+		}
+		if buf.Len() > 0 && buf.Len()+1+len(data)+len(suffix) > maxBytes {
+			buf.WriteString(suffix)
+			payloads = append(payloads, datadogChunk{body: bytes.Clone(buf.Bytes()), counters: counters})
+			buf.Reset()
+			counters = nil
+		}
+		if buf.Len() == 0 {
+			buf.WriteString(prefix)
+		} else {
+			buf.WriteByte(',')
+		}
+		buf.Write(data)
+		if ser.counterKey != "" {
+			counters = append(counters, ser.counterKey)
+		}
+	}
+	if buf.Len() > 0 {
+		buf.WriteString(suffix)
+		payloads = append(payloads, datadogChunk{body: buf.Bytes(), counters: counters})
+	}
+	return payloads, nil
+}
+
+// post sends one payload to the series intake
+func (s *DatadogShipper) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("DD-API-KEY", s.apiKey)
+	if s.ua != "" {
+		req.Header.Set("User-Agent", s.ua)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// SetRateLimiter makes every intake payload wait on limiter
+func (s *DatadogShipper) SetRateLimiter(limiter *RateLimiter) {
+	limitClient(s.client, limiter)
+}
+
+// SetUserAgent sets the User-Agent header of intake requests
+func (s *DatadogShipper) SetUserAgent(ua string) {
+	s.ua = ua
+}
+
+// SetProxy sets the proxy used for intake requests
+func (s *DatadogShipper) SetProxy(proxy collector.ProxyConfig) {
+	setClientProxy(s.client, proxy)
+}
+
+// SetConnPool tunes connection reuse against the intake
+func (s *DatadogShipper) SetConnPool(pool ConnPool) {
+	setClientConnPool(s.client, pool)
+}
+
+// Close cleans up resources
+func (s *DatadogShipper) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
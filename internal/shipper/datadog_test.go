@@ -0,0 +1,250 @@
+package shipper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+// datadogServer records decoded payloads and answers with status.
+type datadogServer struct {
+	mu       sync.Mutex
+	payloads []datadogPayload
+	apiKeys  []string
+	status   int
+	failOn   map[int]bool // 1-based request numbers answered with 500
+}
+
+func (d *datadogServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var p datadogPayload
+	_ = json.Unmarshal(body, &p)
+
+	d.mu.Lock()
+	d.payloads = append(d.payloads, p)
+	d.apiKeys = append(d.apiKeys, r.Header.Get("DD-API-KEY"))
+	status := d.status
+	if d.failOn[len(d.payloads)] {
+		status = http.StatusInternalServerError
+	}
+	d.mu.Unlock()
+
+	if status == 0 {
+		status = http.StatusAccepted
+	}
+	w.WriteHeader(status)
+}
+
+func newTestDatadogShipper(t *testing.T, dd *datadogServer) *DatadogShipper {
+	t.Helper()
+	srv := httptest.NewServer(dd)
+	t.Cleanup(srv.Close)
+
+	s, err := NewDatadogShipper("key-123", "", time.Second)
+	if err != nil {
+		t.Fatalf("NewDatadogShipper: %v", err)
+	}
+	s.url = srv.URL
+	s.hostname = "host-a"
+	return s
+}
+
+func TestNewDatadogShipper(t *testing.T) {
+	t.Setenv("DD_API_KEY", "")
+	if _, err := NewDatadogShipper("", "", time.Second); err == nil {
+		t.Error("expected error without an API key")
+	}
+
+	t.Setenv("DD_API_KEY", "from-env")
+	s, err := NewDatadogShipper("", "datadoghq.eu", time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.apiKey != "from-env" {
+		t.Errorf("expected API key from DD_API_KEY, got %q", s.apiKey)
+	}
+	if s.url != "https://api.datadoghq.eu/api/v2/series" {
+		t.Errorf("unexpected intake URL %q", s.url)
+	}
+}
+
+func TestDatadogShipper_Ship(t *testing.T) {
+	dd := &datadogServer{}
+	s := newTestDatadogShipper(t, dd)
+
+	batch := func(requests float64) []collector.Metric {
+		return []collector.Metric{
+			{Name: "cpu", Value: 42, Type: "gauge", Labels: map[string]string{"host": "a", "core": "0"}},
+			{Name: "requests_total", Value: requests, Type: "counter"},
+		}
+	}
+
+	// First cycle sends the gauge and only primes the counter
+	if err := s.Ship(context.Background(), batch(100)); err != nil {
+		t.Fatalf("Ship: %v", err)
+	}
+	first := dd.payloads[0].Series
+	if len(first) != 1 {
+		t.Fatalf("expected only the gauge on the first cycle, got %+v", first)
+	}
+	g := first[0]
+	if g.Metric != "cpu" || g.Type != datadogTypeGauge || g.Points[0].Value != 42 {
+		t.Errorf("unexpected gauge series: %+v", g)
+	}
+	if strings.Join(g.Tags, ",") != "core:0,host:a" {
+		t.Errorf("expected sorted key:value tags, got %v", g.Tags)
+	}
+	if len(g.Resources) != 1 || g.Resources[0].Name != "host-a" || g.Resources[0].Type != "host" {
+		t.Errorf("unexpected resources: %+v", g.Resources)
+	}
+	if dd.apiKeys[0] != "key-123" {
+		t.Errorf("expected DD-API-KEY header, got %q", dd.apiKeys[0])
+	}
+
+	// A failed ship must not move the counter baseline, so the retry resends the increase
+	dd.status = http.StatusInternalServerError
+	if err := s.Ship(context.Background(), batch(130)); err == nil {
+		t.Fatal("expected error on 500")
+	}
+	dd.status = 0
+	if err := s.Ship(context.Background(), batch(130)); err != nil {
+		t.Fatalf("Ship: %v", err)
+	}
+	last := dd.payloads[len(dd.payloads)-1].Series
+	if len(last) != 2 || last[1].Type != datadogTypeCount || last[1].Points[0].Value != 30 {
+		t.Errorf("expected count of 30 after retry, got %+v", last)
+	}
+
+	// Counter reset: the new value is the increase
+	if err := s.Ship(context.Background(), batch(5)); err != nil {
+		t.Fatalf("Ship: %v", err)
+	}
+	last = dd.payloads[len(dd.payloads)-1].Series
+	if last[1].Points[0].Value != 5 {
+		t.Errorf("expected count of 5 after reset, got %v", last[1].Points[0].Value)
+	}
+}
+
+// TestDatadogShipper_PartialFailure verifies that the counters of requests that
+// succeeded are not counted again when a failed Ship is retried.
+func TestDatadogShipper_PartialFailure(t *testing.T) {
+	dd := &datadogServer{}
+	s := newTestDatadogShipper(t, dd)
+	s.maxBody = 1000
+
+	batch := func(value float64) []collector.Metric {
+		metrics := make([]collector.Metric, 20)
+		for i := range metrics {
+			metrics[i] = collector.Metric{Name: fmt.Sprintf("requests_%02d_total", i), Value: value, Type: "counter"}
+		}
+		return metrics
+	}
+	if err := s.Ship(context.Background(), batch(100)); err != nil {
+		t.Fatalf("priming Ship: %v", err)
+	}
+
+	dd.failOn = map[int]bool{len(dd.payloads) + 2: true}
+	if err := s.Ship(context.Background(), batch(110)); err == nil {
+		t.Fatal("expected the second request to fail")
+	}
+	if err := s.Ship(context.Background(), batch(110)); err != nil {
+		t.Fatalf("retried Ship: %v", err)
+	}
+	if len(dd.payloads) < 5 {
+		t.Fatalf("expected several requests per Ship, got %d in total", len(dd.payloads))
+	}
+
+	counted := make(map[string]float64)
+	for i, p := range dd.payloads {
+		if dd.failOn[i+1] {
+			continue
+		}
+		for _, ser := range p.Series {
+			counted[ser.Metric] += ser.Points[0].Value
+		}
+	}
+	if len(counted) != 20 {
+		t.Fatalf("expected counts for 20 series, got %d", len(counted))
+	}
+	for name, total := range counted {
+		if total != 10 {
+			t.Errorf("%s: counted %v in total, want 10", name, total)
+		}
+	}
+}
+
+// TestDatadogShipper_AbsentCounter verifies that a counter missing from one
+// Ship, e.g. from a plugin on a longer interval, keeps its baseline.
+func TestDatadogShipper_AbsentCounter(t *testing.T) {
+	dd := &datadogServer{}
+	s := newTestDatadogShipper(t, dd)
+
+	gauge := collector.Metric{Name: "cpu", Value: 1, Type: "gauge"}
+	counter := func(v float64) collector.Metric {
+		return collector.Metric{Name: "plugin_runs_total", Value: v, Type: "counter"}
+	}
+	for i, batch := range [][]collector.Metric{
+		{gauge, counter(10)},
+		{gauge},
+		{gauge, counter(15)},
+	} {
+		if err := s.Ship(context.Background(), batch); err != nil {
+			t.Fatalf("Ship %d: %v", i+1, err)
+		}
+	}
+
+	last := dd.payloads[len(dd.payloads)-1].Series
+	if len(last) != 2 || last[1].Type != datadogTypeCount || last[1].Points[0].Value != 5 {
+		t.Fatalf("expected a count of 5 when the counter returned, got %+v", last)
+	}
+
+	// A baseline unseen for longer than the stale period is forgotten
+	start := time.Now()
+	s.commitCounters(map[string]float64{"a": 1}, nil, start)
+	s.commitCounters(map[string]float64{"b": 1}, nil, start.Add(datadogCounterStaleAfter+time.Second))
+	if _, ok := s.previous["a"]; ok {
+		t.Error("expected the stale baseline to be evicted")
+	}
+	if _, ok := s.previous["b"]; !ok {
+		t.Error("expected the fresh baseline to be kept")
+	}
+}
+
+func TestChunkDatadogSeries(t *testing.T) {
+	series := make([]datadogSeries, 50)
+	for i := range series {
+		series[i] = datadogSeries{Metric: fmt.Sprintf("metric_%02d", i), Type: datadogTypeGauge, Points: []datadogPoint{{Timestamp: 1, Value: 1}}}
+	}
+
+	payloads, err := chunkDatadogSeries(series, 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(payloads) < 2 {
+		t.Fatalf("expected several payloads, got %d", len(payloads))
+	}
+
+	total := 0
+	for i, p := range payloads {
+		if len(p.body) > 1000 {
+			t.Errorf("payload %d is %d bytes, over the limit", i, len(p.body))
+		}
+		var decoded datadogPayload
+		if err := json.Unmarshal(p.body, &decoded); err != nil {
+			t.Fatalf("payload %d is not valid JSON: %v", i, err)
+		}
+		total += len(decoded.Series)
+	}
+	if total != len(series) {
+		t.Errorf("expected %d series across payloads, got %d", len(series), total)
+	}
+}
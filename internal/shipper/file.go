@@ -11,13 +11,12 @@ import (
 	"sync"
 	"time"
 
-	"github.com/rs/zerolog/log"
-
 	"github.com/0x524A/metricsd/internal/collector"
 )
 
 // FileShipper writes metrics to a local file in JSON format for Splunk Universal Forwarder
 type FileShipper struct {
+	collector.Logging
 	filePath     string
 	maxSizeBytes int64
 	maxFiles     int
@@ -102,7 +101,7 @@ func (s *FileShipper) Ship(ctx context.Context, metrics []collector.Metric) erro
 
 	// Check if rotation is needed
 	if err := s.checkRotation(); err != nil {
-		log.Warn().Err(err).Msg("Failed to check/perform file rotation")
+		s.Logger().Warn().Err(err).Msg("Failed to check/perform file rotation")
 	}
 
 	var err error
@@ -119,10 +118,10 @@ func (s *FileShipper) Ship(ctx context.Context, metrics []collector.Metric) erro
 
 	// Sync to ensure data is written to disk
 	if err := s.file.Sync(); err != nil {
-		log.Warn().Err(err).Msg("Failed to sync file")
+		s.Logger().Warn().Err(err).Msg("Failed to sync file")
 	}
 
-	log.Info().
+	s.Logger().Info().
 		Int("metric_count", len(metrics)).
 		Int("payload_size_bytes", bytesWritten).
 		Str("file", s.filePath).
@@ -135,12 +134,12 @@ func (s *FileShipper) Ship(ctx context.Context, metrics []collector.Metric) erro
 // shipSingleMetric writes each metric as a separate JSON line
 func (s *FileShipper) shipSingleMetric(metrics []collector.Metric) (int, error) {
 	hostname, _ := os.Hostname()
-	timestamp := time.Now().Unix()
+	now := time.Now()
 	totalBytes := 0
 
 	for _, metric := range metrics {
 		event := FileMetricEvent{
-			Timestamp:  timestamp,
+			Timestamp:  metric.TimestampOr(now).Unix(),
 			MetricName: metric.Name,
 			Value:      metric.Value,
 			MetricType: metric.Type,
@@ -151,7 +150,7 @@ func (s *FileShipper) shipSingleMetric(metrics []collector.Metric) (int, error)
 
 		data, err := json.Marshal(event)
 		if err != nil {
-			log.Warn().Err(err).Str("metric", metric.Name).Msg("Failed to marshal metric")
+			s.Logger().Warn().Err(err).Str("metric", metric.Name).Msg("Failed to marshal metric")
 			continue
 		}
 
@@ -237,7 +236,7 @@ func (s *FileShipper) checkRotation() error {
 		return nil
 	}
 
-	log.Info().
+	s.Logger().Info().
 		Int64("size_bytes", info.Size()).
 		Int64("max_bytes", s.maxSizeBytes).
 		Msg("Rotating log file")
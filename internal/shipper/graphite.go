@@ -0,0 +1,150 @@
+package shipper
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+// graphiteTimeout bounds dialing and each write to Carbon
+const graphiteTimeout = 10 * time.Second
+
+// graphiteTagReplacer strips characters that are not allowed in Graphite tags
+var graphiteTagReplacer = strings.NewReplacer(";", "_", "~", "_", " ", "_", "=", "_", "\n", "_")
+
+// GraphiteShipper ships metrics to Graphite/Carbon using the plaintext protocol
+// with tags. A single TCP connection is kept open and reused between cycles.
+type GraphiteShipper struct {
+	collector.Logging
+	addr   string
+	prefix string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewGraphiteShipper creates a new Graphite plaintext shipper. addr is the Carbon
+// host:port (usually port 2003); prefix, if set, is prepended to every metric path.
+func NewGraphiteShipper(addr, prefix string) (*GraphiteShipper, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, fmt.Errorf("invalid graphite address %q: %w", addr, err)
+	}
+	return &GraphiteShipper{
+		addr:   addr,
+		prefix: strings.TrimSuffix(prefix, "."),
+	}, nil
+}
+
+// Ship writes metrics as plaintext lines. If the write fails on a reused
+// connection (e.g. Carbon restarted), it reconnects and retries once.
+func (s *GraphiteShipper) Ship(ctx context.Context, metrics []collector.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	data := s.format(metrics)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.write(ctx, data); err != nil {
+		s.Logger().Warn().Err(err).Str("addr", s.addr).Msg("Graphite write failed, reconnecting")
+		s.closeConn()
+		if err := s.write(ctx, data); err != nil {
+			s.closeConn()
+			return fmt.Errorf("failed to write to graphite: %w", err)
+		}
+	}
+
+	s.Logger().Info().
+		Int("metric_count", len(metrics)).
+		Str("addr", s.addr).
+		Msg("Successfully shipped metrics to Graphite")
+
+	return nil
+}
+
+// write sends data on the current connection, dialing first if needed
+func (s *GraphiteShipper) write(ctx context.Context, data []byte) error {
+	if s.conn == nil {
+		dialer := net.Dialer{Timeout: graphiteTimeout}
+		conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+		if err != nil {
+			return fmt.Errorf("failed to connect: %w", err)
+		}
+		s.conn = conn
+	}
+
+	if err := s.conn.SetWriteDeadline(time.Now().Add(graphiteTimeout)); err != nil {
+		return err
+	}
+	_, err := s.conn.Write(data)
+	return err
+}
+
+func (s *GraphiteShipper) closeConn() {
+	if s.conn != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+}
+
+// format renders metrics as "prefix.name;tag=value value timestamp" lines.
+// Non-finite values are skipped since Carbon can't store them.
+func (s *GraphiteShipper) format(metrics []collector.Metric) []byte {
+	now := time.Now()
+	var buf bytes.Buffer
+
+	for _, m := range metrics {
+		if math.IsNaN(m.Value) || math.IsInf(m.Value, 0) {
+			continue
+		}
+
+		if s.prefix != "" {
+			buf.WriteString(s.prefix)
+			buf.WriteByte('.')
+		}
+		buf.WriteString(graphiteTagReplacer.Replace(m.Name))
+
+		keys := make([]string, 0, len(m.Labels))
+		for k := range m.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			v := m.Labels[k]
+			if v == "" {
+				continue // Graphite rejects empty tag values
+			}
+			buf.WriteByte(';')
+			buf.WriteString(graphiteTagReplacer.Replace(k))
+			buf.WriteByte('=')
+			buf.WriteString(graphiteTagReplacer.Replace(v))
+		}
+
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatFloat(m.Value, 'f', -1, 64))
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatInt(m.TimestampOr(now).Unix(), 10))
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes()
+}
+
+// Close closes the Carbon connection
+func (s *GraphiteShipper) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeConn()
+	return nil
+}
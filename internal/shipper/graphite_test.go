@@ -0,0 +1,127 @@
+package shipper
+
+import (
+	"bufio"
+	"context"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+// startGraphiteServer accepts connections and sends every received line on the returned channel.
+func startGraphiteServer(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = lis.Close() })
+
+	lines := make(chan string, 100)
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go func(c net.Conn) {
+				defer c.Close()
+				scanner := bufio.NewScanner(c)
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+			}(conn)
+		}
+	}()
+	return lis.Addr().String(), lines
+}
+
+func readLines(t *testing.T, lines <-chan string, n int) []string {
+	t.Helper()
+	var got []string
+	for len(got) < n {
+		select {
+		case l := <-lines:
+			got = append(got, l)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out after %d of %d lines: %v", len(got), n, got)
+		}
+	}
+	return got
+}
+
+func TestNewGraphiteShipper_InvalidAddr(t *testing.T) {
+	if _, err := NewGraphiteShipper("no-port", ""); err == nil {
+		t.Error("expected error for address without port")
+	}
+}
+
+func TestGraphiteShipper_Format(t *testing.T) {
+	s, err := NewGraphiteShipper("localhost:2003", "acme.")
+	if err != nil {
+		t.Fatalf("NewGraphiteShipper: %v", err)
+	}
+
+	ts := time.Unix(1700000000, 0)
+	metrics := []collector.Metric{
+		{Name: "system_cpu_usage_percent", Value: 12.5, Labels: map[string]string{"core": "0", "host": "web 1"}, Timestamp: ts},
+		{Name: "up", Value: 1, Labels: map[string]string{"empty": ""}, Timestamp: ts},
+		{Name: "broken", Value: math.NaN(), Timestamp: ts},
+	}
+
+	got := strings.Split(strings.TrimSuffix(string(s.format(metrics)), "\n"), "\n")
+	want := []string{
+		"acme.system_cpu_usage_percent;core=0;host=web_1 12.5 1700000000",
+		"acme.up 1 1700000000",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("format() lines = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestGraphiteShipper_ShipAndReconnect(t *testing.T) {
+	addr, lines := startGraphiteServer(t)
+	s, err := NewGraphiteShipper(addr, "")
+	if err != nil {
+		t.Fatalf("NewGraphiteShipper: %v", err)
+	}
+	defer s.Close()
+
+	metrics := []collector.Metric{{Name: "up", Value: 1, Type: "gauge"}}
+	if err := s.Ship(context.Background(), metrics); err != nil {
+		t.Fatalf("Ship returned error: %v", err)
+	}
+	if got := readLines(t, lines, 1)[0]; !strings.HasPrefix(got, "up 1 ") {
+		t.Errorf("unexpected line %q", got)
+	}
+
+	// Break the pooled connection; the next Ship must reconnect transparently.
+	_ = s.conn.Close()
+	if err := s.Ship(context.Background(), metrics); err != nil {
+		t.Fatalf("Ship after broken connection returned error: %v", err)
+	}
+	readLines(t, lines, 1)
+}
+
+func TestGraphiteShipper_Unreachable(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	_ = lis.Close()
+
+	s, _ := NewGraphiteShipper(addr, "")
+	if err := s.Ship(context.Background(), []collector.Metric{{Name: "up", Value: 1}}); err == nil {
+		t.Error("expected error when Carbon is unreachable")
+	}
+}
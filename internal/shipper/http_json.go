@@ -2,6 +2,7 @@ package shipper
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
@@ -11,27 +12,52 @@ import (
 	"math"
 	"net/http"
 	"os"
+	"sync/atomic"
+	"text/template"
 	"time"
 
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
 
 	"github.com/0x524A/metricsd/internal/collector"
 )
 
 // HTTPJSONShipper ships metrics as JSON via HTTP POST (Single Responsibility Principle)
 type HTTPJSONShipper struct {
+	collector.Logging
 	endpoint string
 	client   *http.Client
+	headers  map[string]string
+	ua       string
+	schema   string
+	tmpl     *template.Template // Replaces the schema when set
+	timeout  time.Duration      // Bounds encoding, compression and the request together
+	gzip     atomic.Bool        // Cleared when the server answers 415 to a gzipped body
+}
+
+// HTTP JSON payload layouts
+const (
+	JSONSchemaNested = "nested" // {"timestamp": ..., "metrics": [{name, value, type, labels}]} (default)
+	JSONSchemaFlat   = "flat"   // [{metric, tags, value, ts}]
+)
+
+// jsonTemplateFuncs are available to templates set with SetTemplate
+var jsonTemplateFuncs = template.FuncMap{
+	// json encodes a value, e.g. {{json .Name}} for a quoted, escaped string
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
 }
 
 // NewHTTPJSONShipper creates a new HTTP JSON shipper
 func NewHTTPJSONShipper(endpoint string, tlsEnabled bool, certFile, keyFile, caFile string, insecureSkipVerify bool, timeout time.Duration) (*HTTPJSONShipper, error) {
+	var s *HTTPJSONShipper // Set at the end; the cert reloader logs through it
 	var tlsConfig *tls.Config
 
 	if tlsEnabled {
-		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		certs, err := newCertReloader(certFile, keyFile, func() *zerolog.Logger { return s.Logger() })
 		if err != nil {
-			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+			return nil, err
 		}
 
 		caCert, err := os.ReadFile(caFile)
@@ -45,23 +71,90 @@ func NewHTTPJSONShipper(endpoint string, tlsEnabled bool, certFile, keyFile, caF
 		}
 
 		tlsConfig = &tls.Config{
-			Certificates:       []tls.Certificate{cert},
-			RootCAs:            caCertPool,
-			InsecureSkipVerify: insecureSkipVerify,
+			GetClientCertificate: certs.GetClientCertificate,
+			RootCAs:              caCertPool,
+			InsecureSkipVerify:   insecureSkipVerify,
 		}
 	}
 
 	client := &http.Client{
-		Timeout: timeout,
-		Transport: &http.Transport{
-			TLSClientConfig: tlsConfig,
-		},
+		Timeout:   timeout,
+		Transport: newTransport(tlsConfig),
 	}
 
-	return &HTTPJSONShipper{
+	s = &HTTPJSONShipper{
 		endpoint: endpoint,
 		client:   client,
-	}, nil
+		schema:   JSONSchemaNested,
+		timeout:  timeout,
+	}
+	return s, nil
+}
+
+// SetCompression selects the request body compression, "gzip" or "none" (the
+// default). If the server rejects a gzipped body with 415 Unsupported Media
+// Type, the request is retried uncompressed and gzip stays off from then on.
+func (s *HTTPJSONShipper) SetCompression(compression string) error {
+	switch compression {
+	case "", CompressionNone:
+		s.gzip.Store(false)
+	case CompressionGzip:
+		s.gzip.Store(true)
+	default:
+		return fmt.Errorf("unsupported compression %q (must be 'gzip' or 'none')", compression)
+	}
+	return nil
+}
+
+// SetSchema selects the payload layout. Empty means nested.
+func (s *HTTPJSONShipper) SetSchema(schema string) error {
+	switch schema {
+	case "", JSONSchemaNested:
+		s.schema = JSONSchemaNested
+	case JSONSchemaFlat:
+		s.schema = JSONSchemaFlat
+	default:
+		return fmt.Errorf("unsupported json schema %q (must be %q or %q)", schema, JSONSchemaNested, JSONSchemaFlat)
+	}
+	return nil
+}
+
+// SetTemplate renders each payload with a text/template instead of a fixed
+// schema. The template is executed with a MetricPayload whose metrics all
+// carry a timestamp, and may use {{json .}} to encode values. Empty text
+// restores the schema.
+func (s *HTTPJSONShipper) SetTemplate(text string) error {
+	if text == "" {
+		s.tmpl = nil
+		return nil
+	}
+	tmpl, err := template.New("json_template").Funcs(jsonTemplateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid json template: %w", err)
+	}
+	s.tmpl = tmpl
+	return nil
+}
+
+// SetHeaders sets extra headers sent with every request, e.g. an API key.
+// They are applied after Content-Type, so they may override it.
+func (s *HTTPJSONShipper) SetHeaders(headers map[string]string) {
+	s.headers = headers
+}
+
+// SetUserAgent sets the User-Agent header; headers from SetHeaders win over it
+func (s *HTTPJSONShipper) SetUserAgent(ua string) {
+	s.ua = ua
+}
+
+// SetProxy sets the proxy used for requests, replacing the environment's
+func (s *HTTPJSONShipper) SetProxy(proxy collector.ProxyConfig) {
+	setClientProxy(s.client, proxy)
+}
+
+// SetConnPool tunes connection reuse
+func (s *HTTPJSONShipper) SetConnPool(pool ConnPool) {
+	setClientConnPool(s.client, pool)
 }
 
 // MetricPayload represents the JSON structure for shipping metrics
@@ -72,10 +165,19 @@ type MetricPayload struct {
 
 // MetricData represents a single metric in JSON format
 type MetricData struct {
-	Name   string            `json:"name"`
+	Name      string            `json:"name"`
+	Value     float64           `json:"value"`
+	Type      string            `json:"type"`
+	Labels    map[string]string `json:"labels"`
+	Timestamp int64             `json:"timestamp,omitempty"` // Set only when the metric carries its own timestamp
+}
+
+// FlatMetric is one element of the flat schema's top-level array
+type FlatMetric struct {
+	Metric string            `json:"metric"`
+	Tags   map[string]string `json:"tags"`
 	Value  float64           `json:"value"`
-	Type   string            `json:"type"`
-	Labels map[string]string `json:"labels"`
+	TS     int64             `json:"ts"` // Unix seconds
 }
 
 // Ship sends metrics to the HTTP JSON endpoint
@@ -84,63 +186,155 @@ func (s *HTTPJSONShipper) Ship(ctx context.Context, metrics []collector.Metric)
 		return nil
 	}
 
-	// Convert to JSON payload
-	payload := s.convertToPayload(metrics)
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
 
-	// Marshal to JSON
-	data, err := json.Marshal(payload)
+	data, err := s.encode(s.convertToPayload(metrics))
 	if err != nil {
 		return fmt.Errorf("failed to marshal metrics: %w", err)
 	}
 
-	// Create HTTP request
+	gzipped := s.gzip.Load()
+	status, respBody, sent, err := s.post(ctx, data, gzipped)
+	if err != nil {
+		return err
+	}
+	if gzipped && status == http.StatusUnsupportedMediaType {
+		s.gzip.Store(false)
+		s.Logger().Warn().
+			Str("endpoint", s.endpoint).
+			Msg("Endpoint rejected gzip with 415, sending uncompressed from now on")
+		if status, respBody, sent, err = s.post(ctx, data, false); err != nil {
+			return err
+		}
+	}
+
+	if status < 200 || status >= 300 {
+		return fmt.Errorf("unexpected status code %d: %s", status, respBody)
+	}
+
+	s.Logger().Info().
+		Int("metric_count", len(metrics)).
+		Int("payload_size_bytes", len(data)).
+		Int("sent_bytes", sent).
+		Str("endpoint", s.endpoint).
+		Msg("Successfully shipped metrics via HTTP JSON")
+
+	return nil
+}
+
+// post sends one body, gzipping it first when asked, and returns the status,
+// the response body for errors and the number of body bytes sent
+func (s *HTTPJSONShipper) post(ctx context.Context, data []byte, gzipped bool) (int, string, int, error) {
+	if gzipped {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return 0, "", 0, fmt.Errorf("failed to compress metrics: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return 0, "", 0, fmt.Errorf("failed to compress metrics: %w", err)
+		}
+		data = buf.Bytes()
+		// Compression counts against the timeout; don't start a request past the deadline
+		if err := ctx.Err(); err != nil {
+			return 0, "", 0, fmt.Errorf("compression exceeded the deadline: %w", err)
+		}
+	}
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(data))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return 0, "", 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	if s.ua != "" {
+		req.Header.Set("User-Agent", s.ua)
+	}
+	if gzipped {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
 
-	// Send request
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return 0, "", 0, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
+	var body []byte
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+		body, _ = io.ReadAll(resp.Body)
 	}
+	return resp.StatusCode, string(body), len(data), nil
+}
 
-	log.Info().
-		Int("metric_count", len(metrics)).
-		Int("payload_size_bytes", len(data)).
-		Str("endpoint", s.endpoint).
-		Msg("Successfully shipped metrics via HTTP JSON")
+func (s *HTTPJSONShipper) convertToPayload(metrics []collector.Metric) MetricPayload {
+	return newMetricPayload(metrics, s.Logger())
+}
 
-	return nil
+// encode renders the payload with the template or in the configured schema
+func (s *HTTPJSONShipper) encode(payload MetricPayload) ([]byte, error) {
+	switch {
+	case s.tmpl != nil:
+		for i := range payload.Metrics {
+			if payload.Metrics[i].Timestamp == 0 {
+				payload.Metrics[i].Timestamp = payload.Timestamp
+			}
+		}
+		var buf bytes.Buffer
+		if err := s.tmpl.Execute(&buf, payload); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case s.schema == JSONSchemaFlat:
+		flat := make([]FlatMetric, len(payload.Metrics))
+		for i, m := range payload.Metrics {
+			ts := m.Timestamp
+			if ts == 0 {
+				ts = payload.Timestamp
+			}
+			tags := m.Labels
+			if tags == nil {
+				tags = map[string]string{}
+			}
+			flat[i] = FlatMetric{Metric: m.Name, Tags: tags, Value: m.Value, TS: ts}
+		}
+		return json.Marshal(flat)
+	default:
+		return json.Marshal(payload)
+	}
 }
 
-func (s *HTTPJSONShipper) convertToPayload(metrics []collector.Metric) MetricPayload {
+// newMetricPayload builds the JSON payload shared by the HTTP JSON and Kafka shippers
+func newMetricPayload(metrics []collector.Metric, logger *zerolog.Logger) MetricPayload {
 	metricData := make([]MetricData, 0, len(metrics))
 
 	for _, metric := range metrics {
 		// Skip metrics with NaN or Inf values as they cannot be marshaled to JSON
 		if math.IsNaN(metric.Value) || math.IsInf(metric.Value, 0) {
-			log.Warn().
+			logger.Warn().
 				Str("metric_name", metric.Name).
 				Float64("value", metric.Value).
 				Msg("Skipping metric with invalid value (NaN or Inf)")
 			continue
 		}
 
-		metricData = append(metricData, MetricData{
+		data := MetricData{
 			Name:   metric.Name,
 			Value:  metric.Value,
 			Type:   metric.Type,
 			Labels: metric.Labels,
-		})
+		}
+		if !metric.Timestamp.IsZero() {
+			data.Timestamp = metric.Timestamp.Unix()
+		}
+		metricData = append(metricData, data)
 	}
 
 	return MetricPayload{
@@ -149,6 +343,11 @@ func (s *HTTPJSONShipper) convertToPayload(metrics []collector.Metric) MetricPay
 	}
 }
 
+// SetRateLimiter limits outbound requests to the limiter's rate
+func (s *HTTPJSONShipper) SetRateLimiter(limiter *RateLimiter) {
+	limitClient(s.client, limiter)
+}
+
 // Close cleans up resources
 func (s *HTTPJSONShipper) Close() error {
 	s.client.CloseIdleConnections()
@@ -1,12 +1,14 @@
 package shipper
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"io"
 	"math"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -217,3 +219,225 @@ func TestNewHTTPJSONShipper_BadCert(t *testing.T) {
 		t.Error("expected error for bad cert")
 	}
 }
+
+// TestHTTPJSONShipper_Headers verifies that configured headers are sent with
+// every request.
+func TestHTTPJSONShipper_Headers(t *testing.T) {
+	var captured http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	s := newTestHTTPJSONShipper(t, srv.URL)
+	s.SetHeaders(map[string]string{"X-API-Key": "secret", "X-Tenant": "team-a"})
+
+	metrics := []collector.Metric{{Name: "up", Value: 1, Type: "gauge"}}
+	if err := s.Ship(context.Background(), metrics); err != nil {
+		t.Fatalf("Ship returned error: %v", err)
+	}
+
+	if got := captured.Get("X-API-Key"); got != "secret" {
+		t.Errorf("X-API-Key: want %q, got %q", "secret", got)
+	}
+	if got := captured.Get("X-Tenant"); got != "team-a" {
+		t.Errorf("X-Tenant: want %q, got %q", "team-a", got)
+	}
+	if got := captured.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type: want application/json, got %q", got)
+	}
+}
+
+func TestHTTPJSONShipper_UserAgent(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("User-Agent"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	s := newTestHTTPJSONShipper(t, srv.URL)
+	s.SetUserAgent("metricsd/1.2.3")
+	metrics := []collector.Metric{{Name: "up", Value: 1, Type: "gauge"}}
+	if err := s.Ship(context.Background(), metrics); err != nil {
+		t.Fatalf("Ship returned error: %v", err)
+	}
+	s.SetHeaders(map[string]string{"User-Agent": "custom"})
+	if err := s.Ship(context.Background(), metrics); err != nil {
+		t.Fatalf("Ship returned error: %v", err)
+	}
+
+	if len(got) != 2 || got[0] != "metricsd/1.2.3" || got[1] != "custom" {
+		t.Errorf("User-Agent: want [metricsd/1.2.3 custom], got %v", got)
+	}
+}
+
+func TestHTTPJSONShipper_SetProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	s := newTestHTTPJSONShipper(t, "http://ingest.example.com/metrics")
+	s.SetProxy(collector.ProxyConfig{HTTPProxy: proxy.URL})
+	if err := s.Ship(context.Background(), []collector.Metric{{Name: "up", Value: 1, Type: "gauge"}}); err != nil {
+		t.Fatalf("Ship returned error: %v", err)
+	}
+	if proxied != "http://ingest.example.com/metrics" {
+		t.Errorf("expected the request to go through the proxy, proxied %q", proxied)
+	}
+}
+
+// TestHTTPJSONShipper_DeterministicLabels verifies that label keys are
+// serialized in sorted order so payloads can be diffed.
+func TestHTTPJSONShipper_DeterministicLabels(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	s := newTestHTTPJSONShipper(t, srv.URL)
+	m := collector.Metric{Name: "up", Value: 1, Type: "gauge", Labels: map[string]string{"zone": "a", "app": "api", "job": "x"}, Timestamp: time.Unix(1700000000, 0)}
+	for i := 0; i < 2; i++ {
+		if err := s.Ship(context.Background(), []collector.Metric{m}); err != nil {
+			t.Fatalf("Ship: %v", err)
+		}
+	}
+
+	if !strings.Contains(bodies[0], `"labels":{"app":"api","job":"x","zone":"a"}`) {
+		t.Errorf("expected sorted label keys, got %s", bodies[0])
+	}
+}
+
+func TestHTTPJSONShipper_Schemas(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	metrics := []collector.Metric{
+		{Name: "cpu", Value: 45.2, Type: "gauge", Labels: map[string]string{"core": "0"}, Timestamp: time.Unix(1700000000, 0)},
+		{Name: `quote"d`, Value: 1, Type: "counter"},
+	}
+
+	t.Run("flat", func(t *testing.T) {
+		s := newTestHTTPJSONShipper(t, srv.URL)
+		if err := s.SetSchema(JSONSchemaFlat); err != nil {
+			t.Fatalf("SetSchema: %v", err)
+		}
+		if err := s.Ship(context.Background(), metrics); err != nil {
+			t.Fatalf("Ship: %v", err)
+		}
+		var got []FlatMetric
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatalf("flat payload is not an array: %v\n%s", err, body)
+		}
+		if len(got) != 2 || got[0].Metric != "cpu" || got[0].Tags["core"] != "0" || got[0].TS != 1700000000 {
+			t.Errorf("unexpected flat payload: %+v", got)
+		}
+		if got[1].TS == 0 || got[1].Tags == nil {
+			t.Errorf("expected payload time and empty tags for the second metric, got %+v", got[1])
+		}
+	})
+
+	t.Run("template", func(t *testing.T) {
+		s := newTestHTTPJSONShipper(t, srv.URL)
+		tmpl := `{"points":[{{range $i, $m := .Metrics}}{{if $i}},{{end}}{"n":{{json $m.Name}},"v":{{$m.Value}},"t":{{$m.Timestamp}},"dims":{{json $m.Labels}}}{{end}}]}`
+		if err := s.SetTemplate(tmpl); err != nil {
+			t.Fatalf("SetTemplate: %v", err)
+		}
+		if err := s.Ship(context.Background(), metrics); err != nil {
+			t.Fatalf("Ship: %v", err)
+		}
+		var got struct {
+			Points []struct {
+				N    string            `json:"n"`
+				V    float64           `json:"v"`
+				T    int64             `json:"t"`
+				Dims map[string]string `json:"dims"`
+			} `json:"points"`
+		}
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatalf("template output is not valid JSON: %v\n%s", err, body)
+		}
+		if len(got.Points) != 2 || got.Points[1].N != `quote"d` || got.Points[0].T != 1700000000 || got.Points[1].T == 0 {
+			t.Errorf("unexpected template payload: %+v", got)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		s := newTestHTTPJSONShipper(t, srv.URL)
+		if err := s.SetSchema("xml"); err == nil {
+			t.Error("expected error for unknown schema")
+		}
+		if err := s.SetTemplate("{{.Metrics"); err == nil {
+			t.Error("expected error for unparsable template")
+		}
+	})
+}
+
+func TestHTTPJSONShipper_Gzip(t *testing.T) {
+	t.Run("compressed body", func(t *testing.T) {
+		var encoding string
+		var payload MetricPayload
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoding = r.Header.Get("Content-Encoding")
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Errorf("body is not gzip: %v", err)
+				return
+			}
+			_ = json.NewDecoder(zr).Decode(&payload)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+
+		s := newTestHTTPJSONShipper(t, srv.URL)
+		if err := s.SetCompression(CompressionGzip); err != nil {
+			t.Fatalf("SetCompression: %v", err)
+		}
+		if err := s.Ship(context.Background(), []collector.Metric{{Name: "cpu", Value: 1, Type: "gauge"}}); err != nil {
+			t.Fatalf("Ship: %v", err)
+		}
+		if encoding != "gzip" || len(payload.Metrics) != 1 {
+			t.Errorf("expected a gzipped payload with one metric, got encoding %q and %+v", encoding, payload)
+		}
+	})
+
+	t.Run("415 falls back to uncompressed", func(t *testing.T) {
+		var encodings []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encodings = append(encodings, r.Header.Get("Content-Encoding"))
+			if r.Header.Get("Content-Encoding") == "gzip" {
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+
+		s := newTestHTTPJSONShipper(t, srv.URL)
+		_ = s.SetCompression(CompressionGzip)
+		metrics := []collector.Metric{{Name: "cpu", Value: 1, Type: "gauge"}}
+		for i := 0; i < 2; i++ {
+			if err := s.Ship(context.Background(), metrics); err != nil {
+				t.Fatalf("Ship %d: %v", i, err)
+			}
+		}
+		if strings.Join(encodings, ",") != "gzip,," {
+			t.Errorf("expected one gzip attempt then plain bodies, got %q", encodings)
+		}
+	})
+
+	if err := newTestHTTPJSONShipper(t, "http://localhost").SetCompression(CompressionSnappy); err == nil {
+		t.Error("expected snappy to be rejected")
+	}
+}
@@ -0,0 +1,265 @@
+package shipper
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+// Kafka message formats
+const (
+	KafkaFormatJSON     = "json"     // MetricPayload, as sent by the HTTP JSON shipper
+	KafkaFormatProtobuf = "protobuf" // OTLP ExportMetricsServiceRequest
+)
+
+// kafkaMetricsPerMessage bounds the size of a single Kafka message
+const kafkaMetricsPerMessage = 1000
+
+// kafkaWriter is the subset of kafka.Writer used by the shipper
+type kafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// KafkaSASL holds SASL credentials. Mechanism is "plain", "scram-sha-256" or
+// "scram-sha-512"; an empty mechanism disables SASL.
+type KafkaSASL struct {
+	Mechanism string
+	Username  string
+	Password  string
+}
+
+// KafkaShipper produces metric batches to a Kafka topic, keyed by hostname
+type KafkaShipper struct {
+	collector.Logging
+	writer kafkaWriter
+	topic  string
+	format string
+	key    []byte
+	async  bool
+
+	mu                sync.Mutex
+	deliveryFailures  int          // Async deliveries failed since the last Flush
+	lastDeliveryError error        // Most recent of them
+	inflight          atomic.Int64 // Async messages queued but not yet completed
+}
+
+// kafkaFlushPollInterval is how often Flush checks for outstanding async messages
+const kafkaFlushPollInterval = 10 * time.Millisecond
+
+// NewKafkaShipper creates a new Kafka shipper. With async set, Ship returns as
+// soon as messages are queued and delivery failures are reported by the next
+// Ship call. The TLS settings and SASL credentials apply to every broker.
+func NewKafkaShipper(brokers []string, topic, format string, async bool, saslCfg KafkaSASL, tlsEnabled bool, certFile, keyFile, caFile string, insecureSkipVerify bool, timeout time.Duration) (*KafkaShipper, error) {
+	var s *KafkaShipper // Set below; the cert reloader logs through it
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("kafka shipper requires at least one broker")
+	}
+	if format == "" {
+		format = KafkaFormatJSON
+	}
+	if format != KafkaFormatJSON && format != KafkaFormatProtobuf {
+		return nil, fmt.Errorf("invalid kafka format %q (must be %q or %q)", format, KafkaFormatJSON, KafkaFormatProtobuf)
+	}
+
+	mechanism, err := kafkaSASLMechanism(saslCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &kafka.Transport{
+		DialTimeout: timeout,
+		SASL:        mechanism,
+	}
+
+	if tlsEnabled {
+		tlsConfig := &tls.Config{
+			InsecureSkipVerify: insecureSkipVerify,
+		}
+
+		if certFile != "" || keyFile != "" {
+			certs, err := newCertReloader(certFile, keyFile, func() *zerolog.Logger { return s.Logger() })
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.GetClientCertificate = certs.GetClientCertificate
+		}
+
+		caCert, err := os.ReadFile(caFile)
+		if err != nil && caFile != "" {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		if len(caCert) > 0 {
+			caCertPool := x509.NewCertPool()
+			caCertPool.AppendCertsFromPEM(caCert)
+			tlsConfig.RootCAs = caCertPool
+		}
+
+		transport.TLS = tlsConfig
+	}
+
+	hostname, _ := os.Hostname()
+
+	s = &KafkaShipper{
+		topic:  topic,
+		format: format,
+		key:    []byte(hostname),
+		async:  async,
+	}
+	s.writer = &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchTimeout: 10 * time.Millisecond,
+		WriteTimeout: timeout,
+		Async:        async,
+		Completion:   s.onCompletion,
+		Transport:    transport,
+	}
+
+	return s, nil
+}
+
+// kafkaSASLMechanism maps the configured SASL mechanism to a kafka-go implementation
+func kafkaSASLMechanism(cfg KafkaSASL) (sasl.Mechanism, error) {
+	switch strings.ToLower(cfg.Mechanism) {
+	case "":
+		return nil, nil
+	case "plain":
+		return plain.Mechanism{Username: cfg.Username, Password: cfg.Password}, nil
+	case "scram-sha-256":
+		return scram.Mechanism(scram.SHA256, cfg.Username, cfg.Password)
+	case "scram-sha-512":
+		return scram.Mechanism(scram.SHA512, cfg.Username, cfg.Password)
+	default:
+		return nil, fmt.Errorf("unsupported SASL mechanism %q (must be plain, scram-sha-256 or scram-sha-512)", cfg.Mechanism)
+	}
+}
+
+// onCompletion logs and counts async delivery failures; synchronous failures
+// are returned by WriteMessages instead.
+func (s *KafkaShipper) onCompletion(messages []kafka.Message, err error) {
+	if s.async {
+		s.inflight.Add(-int64(len(messages)))
+	}
+	if err == nil {
+		return
+	}
+	s.Logger().Warn().Err(err).Int("message_count", len(messages)).Str("topic", s.topic).Msg("Kafka delivery failed")
+
+	s.mu.Lock()
+	s.deliveryFailures++
+	s.lastDeliveryError = err
+	s.mu.Unlock()
+}
+
+// Ship produces metrics as one or more messages. In async mode it returns once
+// they are queued; delivery failures are logged and not returned, since they
+// belong to earlier batches and retrying this one would produce it twice.
+func (s *KafkaShipper) Ship(ctx context.Context, metrics []collector.Metric) error {
+	if len(metrics) > 0 {
+		msgs, err := s.messages(metrics)
+		if err != nil {
+			return err
+		}
+		if s.async {
+			s.inflight.Add(int64(len(msgs)))
+		}
+		if err := s.writer.WriteMessages(ctx, msgs...); err != nil {
+			if s.async {
+				// Nothing was queued, so no completion will follow
+				s.inflight.Add(-int64(len(msgs)))
+			}
+			return fmt.Errorf("failed to produce to kafka topic %s: %w", s.topic, err)
+		}
+
+		s.Logger().Info().
+			Int("metric_count", len(metrics)).
+			Int("message_count", len(msgs)).
+			Str("topic", s.topic).
+			Msg("Successfully shipped metrics to Kafka")
+	}
+
+	return nil
+}
+
+// messages encodes metrics in the configured format, kafkaMetricsPerMessage per message
+func (s *KafkaShipper) messages(metrics []collector.Metric) ([]kafka.Message, error) {
+	contentType := "application/json"
+	if s.format == KafkaFormatProtobuf {
+		contentType = "application/x-protobuf"
+	}
+
+	var msgs []kafka.Message
+	for start := 0; start < len(metrics); start += kafkaMetricsPerMessage {
+		chunk := metrics[start:min(start+kafkaMetricsPerMessage, len(metrics))]
+
+		var value []byte
+		var err error
+		if s.format == KafkaFormatProtobuf {
+			value, err = proto.Marshal(&colmetricspb.ExportMetricsServiceRequest{ResourceMetrics: convertToOTLP(chunk, nil)})
+		} else {
+			value, err = json.Marshal(newMetricPayload(chunk, s.Logger()))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode metrics: %w", err)
+		}
+
+		msgs = append(msgs, kafka.Message{
+			Key:     s.key,
+			Value:   value,
+			Headers: []kafka.Header{{Key: "content-type", Value: []byte(contentType)}},
+		})
+	}
+	return msgs, nil
+}
+
+// Flush waits for queued async messages to be delivered and reports the async
+// delivery failures since the previous Flush. It is a no-op in sync mode.
+func (s *KafkaShipper) Flush(ctx context.Context) error {
+	ticker := time.NewTicker(kafkaFlushPollInterval)
+	defer ticker.Stop()
+	for s.inflight.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("kafka flush abandoned with %d messages in flight: %w", s.inflight.Load(), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+	return s.takeDeliveryErrors()
+}
+
+// takeDeliveryErrors returns and clears the async delivery failures recorded so far
+func (s *KafkaShipper) takeDeliveryErrors() error {
+	s.mu.Lock()
+	failures, last := s.deliveryFailures, s.lastDeliveryError
+	s.deliveryFailures, s.lastDeliveryError = 0, nil
+	s.mu.Unlock()
+	if failures > 0 {
+		return fmt.Errorf("%d async kafka deliveries failed, the last with: %w", failures, last)
+	}
+	return nil
+}
+
+// Close flushes pending async messages and closes broker connections
+func (s *KafkaShipper) Close() error {
+	return s.writer.Close()
+}
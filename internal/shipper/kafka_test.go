@@ -0,0 +1,156 @@
+package shipper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+// fakeKafkaWriter records produced messages and returns err from WriteMessages.
+type fakeKafkaWriter struct {
+	msgs []kafka.Message
+	err  error
+}
+
+func (f *fakeKafkaWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	f.msgs = append(f.msgs, msgs...)
+	return f.err
+}
+
+func (f *fakeKafkaWriter) Close() error { return nil }
+
+func newTestKafkaShipper(t *testing.T, format string) (*KafkaShipper, *fakeKafkaWriter) {
+	t.Helper()
+	s, err := NewKafkaShipper([]string{"localhost:9092"}, "metrics", format, false, KafkaSASL{}, false, "", "", "", false, time.Second)
+	if err != nil {
+		t.Fatalf("NewKafkaShipper: %v", err)
+	}
+	fake := &fakeKafkaWriter{}
+	s.writer = fake
+	s.key = []byte("host-a")
+	return s, fake
+}
+
+func TestNewKafkaShipper_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		brokers []string
+		format  string
+		sasl    KafkaSASL
+	}{
+		{"no brokers", nil, "", KafkaSASL{}},
+		{"bad format", []string{"b:9092"}, "avro", KafkaSASL{}},
+		{"bad sasl", []string{"b:9092"}, "", KafkaSASL{Mechanism: "gssapi"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewKafkaShipper(tt.brokers, "metrics", tt.format, false, tt.sasl, false, "", "", "", false, time.Second); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	for _, mech := range []string{"plain", "SCRAM-SHA-256", "scram-sha-512"} {
+		if _, err := NewKafkaShipper([]string{"b:9092"}, "metrics", "", false, KafkaSASL{Mechanism: mech, Username: "u", Password: "p"}, false, "", "", "", false, time.Second); err != nil {
+			t.Errorf("mechanism %s: unexpected error: %v", mech, err)
+		}
+	}
+}
+
+func TestKafkaShipper_JSON(t *testing.T) {
+	s, fake := newTestKafkaShipper(t, KafkaFormatJSON)
+
+	metrics := make([]collector.Metric, kafkaMetricsPerMessage+1)
+	for i := range metrics {
+		metrics[i] = collector.Metric{Name: "cpu", Value: float64(i), Type: "gauge", Labels: map[string]string{}}
+	}
+	if err := s.Ship(context.Background(), metrics); err != nil {
+		t.Fatalf("Ship: %v", err)
+	}
+
+	if len(fake.msgs) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(fake.msgs))
+	}
+	if string(fake.msgs[0].Key) != "host-a" {
+		t.Errorf("expected message keyed by hostname, got %q", fake.msgs[0].Key)
+	}
+	var payload MetricPayload
+	if err := json.Unmarshal(fake.msgs[0].Value, &payload); err != nil {
+		t.Fatalf("message is not a JSON payload: %v", err)
+	}
+	if len(payload.Metrics) != kafkaMetricsPerMessage {
+		t.Errorf("expected %d metrics in the first message, got %d", kafkaMetricsPerMessage, len(payload.Metrics))
+	}
+}
+
+func TestKafkaShipper_Protobuf(t *testing.T) {
+	s, fake := newTestKafkaShipper(t, KafkaFormatProtobuf)
+
+	if err := s.Ship(context.Background(), []collector.Metric{{Name: "cpu", Value: 1, Type: "gauge"}}); err != nil {
+		t.Fatalf("Ship: %v", err)
+	}
+	if len(fake.msgs) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(fake.msgs))
+	}
+	var req colmetricspb.ExportMetricsServiceRequest
+	if err := proto.Unmarshal(fake.msgs[0].Value, &req); err != nil {
+		t.Fatalf("message is not an OTLP request: %v", err)
+	}
+	if got := req.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Name; got != "cpu" {
+		t.Errorf("expected metric cpu, got %q", got)
+	}
+}
+
+func TestKafkaShipper_Errors(t *testing.T) {
+	s, fake := newTestKafkaShipper(t, KafkaFormatJSON)
+	metrics := []collector.Metric{{Name: "cpu", Value: 1, Type: "gauge"}}
+
+	fake.err = errors.New("broker down")
+	if err := s.Ship(context.Background(), metrics); err == nil {
+		t.Error("expected synchronous produce error")
+	}
+
+	// Async delivery failures belong to earlier batches, so Ship must not
+	// report them and have the orchestrator retry the current one
+	fake.err = nil
+	s.onCompletion([]kafka.Message{{}}, errors.New("not leader"))
+	if err := s.Ship(context.Background(), metrics); err != nil {
+		t.Errorf("Ship returned an earlier async delivery error: %v", err)
+	}
+	if err := s.Flush(context.Background()); err == nil {
+		t.Error("expected Flush to report the async delivery error")
+	}
+}
+
+func TestKafkaShipper_Flush(t *testing.T) {
+	s, _ := newTestKafkaShipper(t, KafkaFormatJSON)
+	s.async = true
+	metrics := []collector.Metric{{Name: "cpu", Value: 1, Type: "gauge"}}
+
+	if err := s.Ship(context.Background(), metrics); err != nil {
+		t.Fatalf("Ship: %v", err)
+	}
+
+	// The fake never completes the message, so Flush gives up at the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error with a message in flight, got %v", err)
+	}
+
+	s.onCompletion([]kafka.Message{{}}, errors.New("not leader"))
+	if err := s.Flush(context.Background()); err == nil {
+		t.Error("expected the delivery failure from Flush")
+	}
+	if err := s.Flush(context.Background()); err != nil {
+		t.Errorf("expected nothing left to flush, got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package shipper
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+// WithMatch wraps s so that it only receives metrics matching at least one of
+// selectors. No selectors returns s unchanged.
+func WithMatch(s Shipper, selectors []collector.Selector) Shipper {
+	if len(selectors) == 0 {
+		return s
+	}
+	return &matchShipper{next: s, selectors: selectors}
+}
+
+// matchShipper drops metrics that match none of its selectors before Ship
+type matchShipper struct {
+	next      Shipper
+	selectors []collector.Selector
+}
+
+// Ship forwards the matching metrics. When none match the wrapped shipper is
+// not called, so stateful shippers keep their state.
+func (m *matchShipper) Ship(ctx context.Context, metrics []collector.Metric) error {
+	matched := make([]collector.Metric, 0, len(metrics))
+	for _, metric := range metrics {
+		if collector.MatchAny(m.selectors, metric) {
+			matched = append(matched, metric)
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+	return m.next.Ship(ctx, matched)
+}
+
+func (m *matchShipper) Close() error {
+	return m.next.Close()
+}
+
+// Flush forwards to the wrapped shipper when it buffers data
+func (m *matchShipper) Flush(ctx context.Context) error {
+	if f, ok := m.next.(Flusher); ok {
+		return f.Flush(ctx)
+	}
+	return nil
+}
+
+// SetLogger forwards to the wrapped shipper when it accepts a logger
+func (m *matchShipper) SetLogger(logger zerolog.Logger) {
+	if s, ok := m.next.(collector.LoggerSetter); ok {
+		s.SetLogger(logger)
+	}
+}
@@ -0,0 +1,69 @@
+package shipper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+type recordingShipper struct {
+	countingShipper
+	metrics []collector.Metric
+}
+
+func (r *recordingShipper) Ship(_ context.Context, metrics []collector.Metric) error {
+	r.ships++
+	r.metrics = metrics
+	return nil
+}
+
+func TestWithMatch(t *testing.T) {
+	sel, err := collector.ParseSelector(`{__name__=~"system_.*"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("no selectors", func(t *testing.T) {
+		inner := &recordingShipper{}
+		if s := WithMatch(inner, nil); s != Shipper(inner) {
+			t.Error("expected the shipper to be returned unwrapped")
+		}
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		inner := &recordingShipper{}
+		s := WithMatch(inner, []collector.Selector{sel})
+		err := s.Ship(context.Background(), []collector.Metric{
+			{Name: "system_load1", Value: 1},
+			{Name: "app_requests_total", Value: 2},
+			{Name: "system_uptime_seconds", Value: 3},
+		})
+		if err != nil {
+			t.Fatalf("Ship: %v", err)
+		}
+		if len(inner.metrics) != 2 || inner.metrics[0].Name != "system_load1" || inner.metrics[1].Name != "system_uptime_seconds" {
+			t.Errorf("shipped %+v, want only the system_ metrics", inner.metrics)
+		}
+	})
+
+	t.Run("nothing matches", func(t *testing.T) {
+		inner := &recordingShipper{}
+		s := WithMatch(inner, []collector.Selector{sel})
+		if err := s.Ship(context.Background(), []collector.Metric{{Name: "app_requests_total"}}); err != nil {
+			t.Fatalf("Ship: %v", err)
+		}
+		if inner.ships != 0 {
+			t.Errorf("expected the inner shipper not to be called, got %d calls", inner.ships)
+		}
+
+		f, ok := s.(Flusher)
+		if !ok {
+			t.Fatal("wrapped shipper should still flush")
+		}
+		_ = f.Flush(context.Background())
+		if inner.flushes != 1 {
+			t.Error("Flush was not forwarded")
+		}
+	})
+}
@@ -0,0 +1,214 @@
+package shipper
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+// otlpScopeName identifies metricsd as the instrumentation scope in OTLP payloads
+const otlpScopeName = "metricsd"
+
+// Aggregation temporalities of exported OTLP sums
+const (
+	TemporalityCumulative = "cumulative"
+	TemporalityDelta      = "delta"
+)
+
+// otlpStartTime is the start time of cumulative sums: metricsd's start, the
+// earliest point it can vouch for
+var otlpStartTime = time.Now()
+
+// convertToOTLP groups metrics by name and type into OTLP metrics, so a name
+// shared by counters and gauges yields one sum and one gauge. Counters become
+// monotonic sums; everything else becomes a gauge. Sums are cumulative, starting
+// at otlpStartTime, unless starts is set: it then holds the interval start of
+// each metric (from otlpDeltas.convert) and sums are sent with delta
+// temporality. Shared by the OTLP shipper variants.
+func convertToOTLP(metrics []collector.Metric, starts []time.Time) []*metricspb.ResourceMetrics {
+	now := time.Now()
+	byKey := make(map[string]*metricspb.Metric)
+	var order []string
+
+	temporality := metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+	if starts != nil {
+		temporality = metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+	}
+
+	for i, m := range metrics {
+		at := m.TimestampOr(now)
+		point := &metricspb.NumberDataPoint{
+			Attributes:   otlpAttributes(m.Labels),
+			TimeUnixNano: uint64(at.UnixNano()),
+			Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: m.Value},
+		}
+		switch {
+		case starts != nil && !starts[i].IsZero():
+			point.StartTimeUnixNano = uint64(starts[i].UnixNano())
+		case starts == nil && m.Type == "counter":
+			point.StartTimeUnixNano = uint64(min(otlpStartTime.UnixNano(), at.UnixNano()))
+		}
+
+		key := m.Name + "\x00gauge"
+		if m.Type == "counter" {
+			key = m.Name + "\x00sum"
+		}
+		om, ok := byKey[key]
+		if !ok {
+			om = &metricspb.Metric{Name: m.Name, Description: m.Help}
+			if m.Type == "counter" {
+				om.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+					AggregationTemporality: temporality,
+					IsMonotonic:            true,
+				}}
+			} else {
+				om.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{}}
+			}
+			byKey[key] = om
+			order = append(order, key)
+		}
+
+		switch data := om.Data.(type) {
+		case *metricspb.Metric_Sum:
+			data.Sum.DataPoints = append(data.Sum.DataPoints, point)
+		case *metricspb.Metric_Gauge:
+			data.Gauge.DataPoints = append(data.Gauge.DataPoints, point)
+		}
+	}
+
+	otlpMetrics := make([]*metricspb.Metric, 0, len(order))
+	for _, key := range order {
+		otlpMetrics = append(otlpMetrics, byKey[key])
+	}
+
+	return []*metricspb.ResourceMetrics{{
+		Resource: &resourcepb.Resource{
+			Attributes: []*commonpb.KeyValue{stringAttribute("service.name", otlpScopeName)},
+		},
+		ScopeMetrics: []*metricspb.ScopeMetrics{{
+			Scope:   &commonpb.InstrumentationScope{Name: otlpScopeName},
+			Metrics: otlpMetrics,
+		}},
+	}}
+}
+
+// otlpDeltaStaleAfter is how long the baseline of a counter missing from
+// exports is kept; a series returning later is primed again
+const otlpDeltaStaleAfter = time.Hour
+
+// otlpDeltas tracks the last exported value of each counter series so sums
+// can be sent with delta temporality. The state is held in memory only.
+type otlpDeltas struct {
+	mu       sync.Mutex
+	previous map[string]otlpCounterSample
+}
+
+type otlpCounterSample struct {
+	value float64
+	at    time.Time // Sample time
+	seen  time.Time // Export time, for eviction
+}
+
+// parseTemporality returns delta state for TemporalityDelta and nil for
+// cumulative (the default)
+func parseTemporality(temporality string) (*otlpDeltas, error) {
+	switch temporality {
+	case "", TemporalityCumulative:
+		return nil, nil
+	case TemporalityDelta:
+		return &otlpDeltas{previous: make(map[string]otlpCounterSample)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported temporality %q (must be 'cumulative' or 'delta')", temporality)
+	}
+}
+
+// convert returns a copy of metrics with each counter replaced by its increase
+// since the last committed export, along with the start of each metric's
+// interval (the previous sample's time; zero for gauges). A series' first
+// sample only primes the state and is left out, and a decrease is taken as a
+// counter reset, so the whole value is the increase. The returned samples
+// become the baseline via commit, so a retried export resends the same deltas.
+func (d *otlpDeltas) convert(metrics []collector.Metric, now time.Time) ([]collector.Metric, []time.Time, map[string]otlpCounterSample) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	current := make(map[string]otlpCounterSample, len(d.previous))
+	out := make([]collector.Metric, 0, len(metrics))
+	starts := make([]time.Time, 0, len(metrics))
+	for _, m := range metrics {
+		if m.Type != "counter" {
+			out = append(out, m)
+			starts = append(starts, time.Time{})
+			continue
+		}
+
+		key := collector.SeriesKey(m)
+		at := m.TimestampOr(now)
+		current[key] = otlpCounterSample{value: m.Value, at: at, seen: now}
+		prev, seen := d.previous[key]
+		if !seen || !at.After(prev.at) {
+			continue
+		}
+
+		delta := m.Value - prev.value
+		if delta < 0 {
+			delta = m.Value
+		}
+		m.Value = delta
+		m.Timestamp = at
+		out = append(out, m)
+		starts = append(starts, prev.at)
+	}
+	return out, starts, current
+}
+
+// commit records counter samples as the baseline for the next deltas. Series
+// absent from counters keep their baseline, so a counter skipping a cycle
+// (a plugin on a longer interval, an endpoint that is down) still gets its
+// delta when it returns, until it has been absent for otlpDeltaStaleAfter.
+func (d *otlpDeltas) commit(counters map[string]otlpCounterSample) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var newest time.Time
+	for key, sample := range counters {
+		d.previous[key] = sample
+		if sample.seen.After(newest) {
+			newest = sample.seen
+		}
+	}
+	for key, sample := range d.previous {
+		if newest.Sub(sample.seen) > otlpDeltaStaleAfter {
+			delete(d.previous, key)
+		}
+	}
+}
+
+// otlpAttributes converts labels to OTLP attributes in a stable (sorted) order
+func otlpAttributes(labels map[string]string) []*commonpb.KeyValue {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]*commonpb.KeyValue, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, stringAttribute(k, labels[k]))
+	}
+	return attrs
+}
+
+func stringAttribute(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
@@ -0,0 +1,175 @@
+package shipper
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+// otlpGRPCMaxAttempts bounds retries of an export that failed with Unavailable
+const otlpGRPCMaxAttempts = 3
+
+// OTLPGRPCShipper ships metrics to an OpenTelemetry collector over OTLP/gRPC
+type OTLPGRPCShipper struct {
+	collector.Logging
+	endpoint string
+	conn     *grpc.ClientConn
+	client   colmetricspb.MetricsServiceClient
+	timeout  time.Duration
+	backoff  time.Duration // Initial delay between Unavailable retries, doubled each attempt
+	deltas   *otlpDeltas   // Counter baselines for delta temporality; nil for cumulative
+}
+
+// NewOTLPGRPCShipper creates a new OTLP/gRPC shipper. The endpoint is a gRPC
+// target such as "otel-collector:4317". The TLS settings become the transport
+// credentials and timeout is the deadline for each Export RPC.
+func NewOTLPGRPCShipper(endpoint string, tlsEnabled bool, certFile, keyFile, caFile string, insecureSkipVerify bool, timeout time.Duration) (*OTLPGRPCShipper, error) {
+	var s *OTLPGRPCShipper // Set at the end; the cert reloader logs through it
+	creds := insecure.NewCredentials()
+
+	if tlsEnabled {
+		tlsConfig := &tls.Config{
+			InsecureSkipVerify: insecureSkipVerify,
+		}
+
+		if certFile != "" || keyFile != "" {
+			certs, err := newCertReloader(certFile, keyFile, func() *zerolog.Logger { return s.Logger() })
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.GetClientCertificate = certs.GetClientCertificate
+		}
+
+		caCert, err := os.ReadFile(caFile)
+		if err != nil && caFile != "" {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		if len(caCert) > 0 {
+			caCertPool := x509.NewCertPool()
+			caCertPool.AppendCertsFromPEM(caCert)
+			tlsConfig.RootCAs = caCertPool
+		}
+
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	// grpc.NewClient connects lazily and reconnects with backoff on its own
+	conn, err := grpc.NewClient(endpoint, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client: %w", err)
+	}
+
+	s = &OTLPGRPCShipper{
+		endpoint: endpoint,
+		conn:     conn,
+		client:   colmetricspb.NewMetricsServiceClient(conn),
+		timeout:  timeout,
+		backoff:  500 * time.Millisecond,
+	}
+	return s, nil
+}
+
+// SetTemporality selects the aggregation temporality of exported sums:
+// "cumulative" (the default) or "delta". Delta keeps the last value of every
+// counter series in memory, so after a restart each series' first export only
+// re-primes the baseline.
+func (s *OTLPGRPCShipper) SetTemporality(temporality string) error {
+	deltas, err := parseTemporality(temporality)
+	if err != nil {
+		return err
+	}
+	s.deltas = deltas
+	return nil
+}
+
+// Ship exports metrics via the OTLP MetricsService. Exports failing with
+// Unavailable (collector restarting, connection dropped) are retried a few
+// times after prompting the connection to reconnect immediately.
+func (s *OTLPGRPCShipper) Ship(ctx context.Context, metrics []collector.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	var starts []time.Time
+	var counters map[string]otlpCounterSample
+	if s.deltas != nil {
+		metrics, starts, counters = s.deltas.convert(metrics, time.Now())
+		if len(metrics) == 0 {
+			s.deltas.commit(counters)
+			return nil
+		}
+	}
+
+	req := &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: convertToOTLP(metrics, starts),
+	}
+
+	backoff := s.backoff
+	var err error
+	for attempt := 1; attempt <= otlpGRPCMaxAttempts; attempt++ {
+		err = s.export(ctx, req)
+		if err == nil {
+			if s.deltas != nil {
+				s.deltas.commit(counters)
+			}
+			s.Logger().Info().
+				Int("metric_count", len(metrics)).
+				Str("endpoint", s.endpoint).
+				Msg("Successfully shipped metrics via OTLP/gRPC")
+			return nil
+		}
+		if status.Code(err) != codes.Unavailable || attempt == otlpGRPCMaxAttempts {
+			break
+		}
+
+		s.Logger().Warn().Err(err).Int("attempt", attempt).Msg("OTLP collector unavailable, reconnecting")
+		s.conn.ResetConnectBackoff()
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to export metrics: %w", err)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	return fmt.Errorf("failed to export metrics: %w", err)
+}
+
+// export performs one Export RPC under the configured deadline
+func (s *OTLPGRPCShipper) export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) error {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	resp, err := s.client.Export(ctx, req)
+	if err != nil {
+		return err
+	}
+	if ps := resp.GetPartialSuccess(); ps != nil && ps.GetRejectedDataPoints() > 0 {
+		s.Logger().Warn().
+			Int64("rejected_data_points", ps.GetRejectedDataPoints()).
+			Str("reason", ps.GetErrorMessage()).
+			Msg("OTLP collector rejected some data points")
+	}
+	return nil
+}
+
+// Close closes the gRPC connection
+func (s *OTLPGRPCShipper) Close() error {
+	return s.conn.Close()
+}
@@ -0,0 +1,342 @@
+package shipper
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+// fakeMetricsService records exports and fails the first failUntil calls with Unavailable.
+type fakeMetricsService struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+
+	mu        sync.Mutex
+	calls     int
+	failUntil int
+	failCode  codes.Code
+	received  []*colmetricspb.ExportMetricsServiceRequest
+}
+
+func (f *fakeMetricsService) Export(_ context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.calls <= f.failUntil {
+		return nil, status.Error(f.failCode, "not ready")
+	}
+	f.received = append(f.received, req)
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+func startFakeOTLPServer(t *testing.T, svc *fakeMetricsService) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := grpc.NewServer()
+	colmetricspb.RegisterMetricsServiceServer(srv, svc)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func newTestOTLPGRPCShipper(t *testing.T, endpoint string) *OTLPGRPCShipper {
+	t.Helper()
+	s, err := NewOTLPGRPCShipper(endpoint, false, "", "", "", false, 5*time.Second)
+	if err != nil {
+		t.Fatalf("NewOTLPGRPCShipper: %v", err)
+	}
+	s.backoff = 10 * time.Millisecond
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+// TestOTLPGRPCShipper_Ship verifies that counters are exported as monotonic sums,
+// gauges as gauges, and labels as attributes.
+func TestOTLPGRPCShipper_Ship(t *testing.T) {
+	svc := &fakeMetricsService{}
+	s := newTestOTLPGRPCShipper(t, startFakeOTLPServer(t, svc))
+
+	metrics := []collector.Metric{
+		{Name: "cpu_usage", Value: 42.5, Type: "gauge", Labels: map[string]string{"core": "0"}},
+		{Name: "cpu_usage", Value: 10, Type: "gauge", Labels: map[string]string{"core": "1"}},
+		{Name: "requests_total", Value: 7, Type: "counter", Help: "Requests served", Labels: map[string]string{}},
+	}
+	if err := s.Ship(context.Background(), metrics); err != nil {
+		t.Fatalf("Ship returned error: %v", err)
+	}
+
+	if len(svc.received) != 1 {
+		t.Fatalf("expected 1 export, got %d", len(svc.received))
+	}
+	got := svc.received[0].GetResourceMetrics()[0].GetScopeMetrics()[0].GetMetrics()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 metrics (grouped by name), got %d", len(got))
+	}
+
+	gauge := got[0]
+	if gauge.GetName() != "cpu_usage" || gauge.GetGauge() == nil {
+		t.Fatalf("expected cpu_usage gauge, got %v", gauge)
+	}
+	points := gauge.GetGauge().GetDataPoints()
+	if len(points) != 2 || points[0].GetAsDouble() != 42.5 {
+		t.Errorf("unexpected gauge data points: %v", points)
+	}
+	if attrs := points[0].GetAttributes(); len(attrs) != 1 || attrs[0].GetKey() != "core" || attrs[0].GetValue().GetStringValue() != "0" {
+		t.Errorf("unexpected attributes: %v", attrs)
+	}
+
+	sum := got[1].GetSum()
+	if sum == nil || !sum.GetIsMonotonic() {
+		t.Fatalf("expected requests_total as monotonic sum, got %v", got[1])
+	}
+	if got[1].GetDescription() != "Requests served" {
+		t.Errorf("description: want %q, got %q", "Requests served", got[1].GetDescription())
+	}
+	if sum.GetDataPoints()[0].GetAsDouble() != 7 {
+		t.Errorf("sum value: want 7, got %v", sum.GetDataPoints()[0].GetAsDouble())
+	}
+}
+
+// TestOTLPGRPCShipper_DeltaTemporality verifies that with delta temporality
+// counters export their increase since the previous successful export, with
+// the previous sample's time as start time, while gauges are unchanged.
+func TestOTLPGRPCShipper_DeltaTemporality(t *testing.T) {
+	svc := &fakeMetricsService{}
+	s := newTestOTLPGRPCShipper(t, startFakeOTLPServer(t, svc))
+	if err := s.SetTemporality(TemporalityDelta); err != nil {
+		t.Fatalf("SetTemporality: %v", err)
+	}
+
+	t0 := time.Unix(1700000000, 0)
+	cycle := func(counter float64, at time.Time) []collector.Metric {
+		return []collector.Metric{
+			{Name: "requests_total", Value: counter, Type: "counter", Labels: map[string]string{}, Timestamp: at},
+			{Name: "temperature", Value: 21, Type: "gauge", Labels: map[string]string{}, Timestamp: at},
+		}
+	}
+	for i, v := range []float64{100, 130, 5} {
+		if err := s.Ship(context.Background(), cycle(v, t0.Add(time.Duration(i)*10*time.Second))); err != nil {
+			t.Fatalf("Ship %d: %v", i, err)
+		}
+	}
+	if len(svc.received) != 3 {
+		t.Fatalf("expected 3 exports, got %d", len(svc.received))
+	}
+
+	// The first export only primes the counter
+	first := svc.received[0].GetResourceMetrics()[0].GetScopeMetrics()[0].GetMetrics()
+	if len(first) != 1 || first[0].GetGauge() == nil {
+		t.Fatalf("first export should contain only the gauge, got %v", first)
+	}
+
+	for i, want := range []float64{30, 5} { // 130-100, then a reset to 5
+		got := svc.received[i+1].GetResourceMetrics()[0].GetScopeMetrics()[0].GetMetrics()
+		sum := got[0].GetSum()
+		if sum == nil || sum.GetAggregationTemporality() != metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA {
+			t.Fatalf("export %d: expected a delta sum, got %v", i+1, got[0])
+		}
+		point := sum.GetDataPoints()[0]
+		if point.GetAsDouble() != want {
+			t.Errorf("export %d: delta want %v, got %v", i+1, want, point.GetAsDouble())
+		}
+		start, end := t0.Add(time.Duration(i)*10*time.Second), t0.Add(time.Duration(i+1)*10*time.Second)
+		if point.GetStartTimeUnixNano() != uint64(start.UnixNano()) || point.GetTimeUnixNano() != uint64(end.UnixNano()) {
+			t.Errorf("export %d: interval %d-%d, want %d-%d", i+1, point.GetStartTimeUnixNano(), point.GetTimeUnixNano(), start.UnixNano(), end.UnixNano())
+		}
+		if got[1].GetGauge().GetDataPoints()[0].GetAsDouble() != 21 {
+			t.Errorf("export %d: gauge changed: %v", i+1, got[1])
+		}
+	}
+}
+
+// TestOTLPGRPCShipper_DeltaRetry verifies that a failed export does not advance
+// the counter baselines, so it is resent with the same delta.
+func TestOTLPGRPCShipper_DeltaRetry(t *testing.T) {
+	svc := &fakeMetricsService{}
+	s := newTestOTLPGRPCShipper(t, startFakeOTLPServer(t, svc))
+	if err := s.SetTemporality(TemporalityDelta); err != nil {
+		t.Fatalf("SetTemporality: %v", err)
+	}
+
+	t0 := time.Unix(1700000000, 0)
+	counter := func(v float64, at time.Time) []collector.Metric {
+		return []collector.Metric{{Name: "requests_total", Value: v, Type: "counter", Labels: map[string]string{}, Timestamp: at}}
+	}
+	if err := s.Ship(context.Background(), counter(10, t0)); err != nil {
+		t.Fatalf("priming Ship: %v", err)
+	}
+
+	svc.mu.Lock()
+	svc.failUntil, svc.failCode = svc.calls+1, codes.InvalidArgument
+	svc.mu.Unlock()
+	batch := counter(25, t0.Add(time.Minute))
+	if err := s.Ship(context.Background(), batch); err == nil {
+		t.Fatal("expected the export to fail")
+	}
+	if err := s.Ship(context.Background(), batch); err != nil {
+		t.Fatalf("retried Ship: %v", err)
+	}
+	if batch[0].Value != 25 {
+		t.Errorf("Ship modified the caller's metrics: %+v", batch[0])
+	}
+
+	got := svc.received[len(svc.received)-1].GetResourceMetrics()[0].GetScopeMetrics()[0].GetMetrics()
+	if v := got[0].GetSum().GetDataPoints()[0].GetAsDouble(); v != 15 {
+		t.Errorf("retried delta: want 15, got %v", v)
+	}
+}
+
+// TestOTLPGRPCShipper_DeltaAbsentSeries verifies that a counter missing from
+// one export keeps its baseline and exports its delta when it returns.
+func TestOTLPGRPCShipper_DeltaAbsentSeries(t *testing.T) {
+	svc := &fakeMetricsService{}
+	s := newTestOTLPGRPCShipper(t, startFakeOTLPServer(t, svc))
+	if err := s.SetTemporality(TemporalityDelta); err != nil {
+		t.Fatalf("SetTemporality: %v", err)
+	}
+
+	t0 := time.Unix(1700000000, 0)
+	cycles := [][]collector.Metric{
+		{
+			{Name: "jobs_total", Value: 10, Type: "counter", Labels: map[string]string{}, Timestamp: t0},
+			{Name: "requests_total", Value: 100, Type: "counter", Labels: map[string]string{}, Timestamp: t0},
+		},
+		{{Name: "requests_total", Value: 110, Type: "counter", Labels: map[string]string{}, Timestamp: t0.Add(time.Minute)}},
+		{{Name: "jobs_total", Value: 14, Type: "counter", Labels: map[string]string{}, Timestamp: t0.Add(2 * time.Minute)}},
+	}
+	for i, metrics := range cycles {
+		if err := s.Ship(context.Background(), metrics); err != nil {
+			t.Fatalf("Ship %d: %v", i, err)
+		}
+	}
+
+	got := svc.received[len(svc.received)-1].GetResourceMetrics()[0].GetScopeMetrics()[0].GetMetrics()
+	if len(got) != 1 || got[0].GetName() != "jobs_total" {
+		t.Fatalf("expected the returning jobs_total, got %v", got)
+	}
+	point := got[0].GetSum().GetDataPoints()[0]
+	if point.GetAsDouble() != 4 {
+		t.Errorf("delta want 4, got %v", point.GetAsDouble())
+	}
+	if point.GetStartTimeUnixNano() != uint64(t0.UnixNano()) {
+		t.Errorf("start time: want %d, got %d", t0.UnixNano(), point.GetStartTimeUnixNano())
+	}
+}
+
+func TestOTLPDeltas_EvictsStaleSeries(t *testing.T) {
+	d, _ := parseTemporality(TemporalityDelta)
+	now := time.Now()
+	d.commit(map[string]otlpCounterSample{
+		"old":  {value: 1, at: now, seen: now},
+		"kept": {value: 1, at: now, seen: now},
+	})
+	d.commit(map[string]otlpCounterSample{
+		"kept": {value: 2, seen: now.Add(otlpDeltaStaleAfter / 2)},
+		"new":  {value: 2, seen: now.Add(otlpDeltaStaleAfter / 2)},
+	})
+	if len(d.previous) != 3 {
+		t.Fatalf("expected absent series to be kept, got %v", d.previous)
+	}
+
+	d.commit(map[string]otlpCounterSample{"new": {value: 3, seen: now.Add(otlpDeltaStaleAfter + time.Minute)}})
+	if _, ok := d.previous["old"]; ok {
+		t.Error("expected the series absent for longer than otlpDeltaStaleAfter to be evicted")
+	}
+	if _, ok := d.previous["kept"]; !ok {
+		t.Error("expected the recently seen series to be kept")
+	}
+}
+
+func TestOTLPGRPCShipper_SetTemporality(t *testing.T) {
+	s := newTestOTLPGRPCShipper(t, "127.0.0.1:1")
+	if err := s.SetTemporality("rate"); err == nil {
+		t.Error("expected an error for an unknown temporality")
+	}
+	if err := s.SetTemporality(TemporalityCumulative); err != nil || s.deltas != nil {
+		t.Errorf("cumulative should disable delta state, got err=%v deltas=%v", err, s.deltas)
+	}
+}
+
+// TestOTLPGRPCShipper_Retry verifies that Unavailable errors are retried and
+// other errors are returned immediately.
+func TestOTLPGRPCShipper_Retry(t *testing.T) {
+	tests := []struct {
+		name      string
+		failUntil int
+		failCode  codes.Code
+		wantErr   bool
+		wantCalls int
+	}{
+		{"recovers after unavailable", 2, codes.Unavailable, false, 3},
+		{"gives up after max attempts", 5, codes.Unavailable, true, otlpGRPCMaxAttempts},
+		{"no retry on invalid argument", 1, codes.InvalidArgument, true, 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := &fakeMetricsService{failUntil: tc.failUntil, failCode: tc.failCode}
+			s := newTestOTLPGRPCShipper(t, startFakeOTLPServer(t, svc))
+
+			err := s.Ship(context.Background(), []collector.Metric{{Name: "up", Value: 1, Type: "gauge"}})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Ship error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if svc.calls != tc.wantCalls {
+				t.Errorf("expected %d Export calls, got %d", tc.wantCalls, svc.calls)
+			}
+		})
+	}
+}
+
+// TestOTLPGRPCShipper_ShipEmpty verifies that an empty batch makes no RPC.
+func TestOTLPGRPCShipper_ShipEmpty(t *testing.T) {
+	svc := &fakeMetricsService{}
+	s := newTestOTLPGRPCShipper(t, startFakeOTLPServer(t, svc))
+
+	if err := s.Ship(context.Background(), nil); err != nil {
+		t.Errorf("expected nil error for empty metrics, got: %v", err)
+	}
+	if svc.calls != 0 {
+		t.Errorf("expected no Export calls, got %d", svc.calls)
+	}
+}
+
+// TestConvertToOTLP_MixedTypes verifies that counters and gauges sharing a name
+// are kept apart and that cumulative sums carry a start time.
+func TestConvertToOTLP_MixedTypes(t *testing.T) {
+	metrics := []collector.Metric{
+		{Name: "requests", Value: 1, Type: "gauge", Labels: map[string]string{"k": "a"}},
+		{Name: "requests", Value: 2, Type: "counter", Labels: map[string]string{"k": "b"}},
+		{Name: "requests", Value: 3, Type: "gauge", Labels: map[string]string{"k": "c"}},
+	}
+
+	om := convertToOTLP(metrics, nil)[0].ScopeMetrics[0].Metrics
+	if len(om) != 2 {
+		t.Fatalf("expected a gauge and a sum, got %d metrics", len(om))
+	}
+	gauge, sum := om[0].GetGauge(), om[1].GetSum()
+	if gauge == nil || sum == nil {
+		t.Fatalf("expected gauge then sum, got %v", om)
+	}
+	if len(gauge.DataPoints) != 2 || len(sum.DataPoints) != 1 {
+		t.Fatalf("expected 2 gauge points and 1 sum point, got %d and %d", len(gauge.DataPoints), len(sum.DataPoints))
+	}
+	if sum.AggregationTemporality != metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE {
+		t.Errorf("expected cumulative temporality, got %v", sum.AggregationTemporality)
+	}
+	p := sum.DataPoints[0]
+	if p.StartTimeUnixNano == 0 || p.StartTimeUnixNano > p.TimeUnixNano {
+		t.Errorf("expected a start time not after %d, got %d", p.TimeUnixNano, p.StartTimeUnixNano)
+	}
+}
@@ -5,35 +5,55 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"sort"
 	"time"
 
 	"github.com/golang/snappy"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/prompb"
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
 
 	"github.com/0x524A/metricsd/internal/collector"
 )
 
+// Request body compression modes; snappy is for remote write, gzip for http_json
+const (
+	CompressionSnappy = "snappy"
+	CompressionNone   = "none"
+	CompressionGzip   = "gzip"
+)
+
+// defaultMaxSamplesPerSend matches the Prometheus queue_config default
+const defaultMaxSamplesPerSend = 2000
+
+// remoteWriteVersion is the protocol version sent in X-Prometheus-Remote-Write-Version
+const remoteWriteVersion = "0.1.0"
+
 // PrometheusRemoteWriteShipper ships metrics using Prometheus remote write protocol (Single Responsibility Principle)
 type PrometheusRemoteWriteShipper struct {
-	endpoint string
-	client   *http.Client
+	collector.Logging
+	endpoint          string
+	client            *http.Client
+	compression       string
+	maxSamplesPerSend int
+	ua                string
 }
 
 // NewPrometheusRemoteWriteShipper creates a new Prometheus remote write shipper
 func NewPrometheusRemoteWriteShipper(endpoint string, tlsEnabled bool, certFile, keyFile, caFile string, insecureSkipVerify bool, timeout time.Duration) (*PrometheusRemoteWriteShipper, error) {
+	var s *PrometheusRemoteWriteShipper // Set at the end; the cert reloader logs through it
 	var tlsConfig *tls.Config
 
 	if tlsEnabled {
-		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		certs, err := newCertReloader(certFile, keyFile, func() *zerolog.Logger { return s.Logger() })
 		if err != nil {
-			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+			return nil, err
 		}
 
 		caCert, err := os.ReadFile(caFile)
@@ -47,37 +67,104 @@ func NewPrometheusRemoteWriteShipper(endpoint string, tlsEnabled bool, certFile,
 		}
 
 		tlsConfig = &tls.Config{
-			Certificates:       []tls.Certificate{cert},
-			RootCAs:            caCertPool,
-			InsecureSkipVerify: insecureSkipVerify,
+			GetClientCertificate: certs.GetClientCertificate,
+			RootCAs:              caCertPool,
+			InsecureSkipVerify:   insecureSkipVerify,
 		}
 	}
 
 	client := &http.Client{
-		Timeout: timeout,
-		Transport: &http.Transport{
-			TLSClientConfig: tlsConfig,
-		},
+		Timeout:   timeout,
+		Transport: newTransport(tlsConfig),
 	}
 
-	return &PrometheusRemoteWriteShipper{
-		endpoint: endpoint,
-		client:   client,
-	}, nil
+	s = &PrometheusRemoteWriteShipper{
+		endpoint:          endpoint,
+		client:            client,
+		compression:       CompressionSnappy,
+		maxSamplesPerSend: defaultMaxSamplesPerSend,
+	}
+	return s, nil
+}
+
+// SetCompression selects the request body compression. Empty means snappy,
+// which is what the remote write spec requires; "none" is meant for debugging
+// against proxies that log request bodies.
+func (s *PrometheusRemoteWriteShipper) SetCompression(compression string) error {
+	switch compression {
+	case "", CompressionSnappy:
+		s.compression = CompressionSnappy
+	case CompressionNone:
+		s.compression = CompressionNone
+	default:
+		return fmt.Errorf("unsupported compression %q (must be 'snappy' or 'none')", compression)
+	}
+	return nil
 }
 
-// Ship sends metrics to the Prometheus remote write endpoint
+// SetUserAgent sets the User-Agent header of remote write requests
+func (s *PrometheusRemoteWriteShipper) SetUserAgent(ua string) {
+	s.ua = ua
+}
+
+// SetProxy sets the proxy used for remote write requests
+func (s *PrometheusRemoteWriteShipper) SetProxy(proxy collector.ProxyConfig) {
+	setClientProxy(s.client, proxy)
+}
+
+// SetConnPool tunes connection reuse against the remote write endpoint
+func (s *PrometheusRemoteWriteShipper) SetConnPool(pool ConnPool) {
+	setClientConnPool(s.client, pool)
+}
+
+// SetMaxSamplesPerSend limits how many samples go into a single request.
+// Larger batches are split into several requests. Values <= 0 restore the default.
+func (s *PrometheusRemoteWriteShipper) SetMaxSamplesPerSend(n int) {
+	if n <= 0 {
+		n = defaultMaxSamplesPerSend
+	}
+	s.maxSamplesPerSend = n
+}
+
+// Ship sends metrics to the Prometheus remote write endpoint, splitting them into
+// chunks of at most maxSamplesPerSend samples. Every chunk is attempted; failures
+// are joined into the returned error.
 func (s *PrometheusRemoteWriteShipper) Ship(ctx context.Context, metrics []collector.Metric) error {
 	if len(metrics) == 0 {
 		return nil
 	}
 
+	chunkCount := (len(metrics) + s.maxSamplesPerSend - 1) / s.maxSamplesPerSend
+	var errs []error
+	for i := 0; i < chunkCount; i++ {
+		start := i * s.maxSamplesPerSend
+		end := min(start+s.maxSamplesPerSend, len(metrics))
+		if err := s.send(ctx, metrics[start:end]); err != nil {
+			errs = append(errs, fmt.Errorf("chunk %d/%d: %w", i+1, chunkCount, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	s.Logger().Info().
+		Int("metric_count", len(metrics)).
+		Int("requests", chunkCount).
+		Str("endpoint", s.endpoint).
+		Msg("Successfully shipped metrics via Prometheus remote write")
+
+	return nil
+}
+
+// send encodes one chunk of metrics as a WriteRequest and posts it
+func (s *PrometheusRemoteWriteShipper) send(ctx context.Context, metrics []collector.Metric) error {
 	// Convert to Prometheus TimeSeries
 	timeseries := s.convertToTimeSeries(metrics)
 
 	// Create WriteRequest
 	writeRequest := &prompb.WriteRequest{
 		Timeseries: timeseries,
+		Metadata:   s.convertToMetadata(metrics),
 	}
 
 	// Marshal to protobuf
@@ -86,18 +173,24 @@ func (s *PrometheusRemoteWriteShipper) Ship(ctx context.Context, metrics []colle
 		return fmt.Errorf("failed to marshal write request: %w", err)
 	}
 
-	// Compress with Snappy
-	compressed := snappy.Encode(nil, data)
+	if s.compression == CompressionSnappy {
+		data = snappy.Encode(nil, data)
+	}
 
 	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(compressed))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(data))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Content-Encoding", "snappy")
+	if s.compression == CompressionSnappy {
+		req.Header.Set("Content-Encoding", "snappy")
+	}
 	req.Header.Set("Content-Type", "application/x-protobuf")
-	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", remoteWriteVersion)
+	if s.ua != "" {
+		req.Header.Set("User-Agent", s.ua)
+	}
 
 	// Send request
 	resp, err := s.client.Do(req)
@@ -111,16 +204,11 @@ func (s *PrometheusRemoteWriteShipper) Ship(ctx context.Context, metrics []colle
 		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
 	}
 
-	log.Info().
-		Int("metric_count", len(metrics)).
-		Str("endpoint", s.endpoint).
-		Msg("Successfully shipped metrics via Prometheus remote write")
-
 	return nil
 }
 
 func (s *PrometheusRemoteWriteShipper) convertToTimeSeries(metrics []collector.Metric) []prompb.TimeSeries {
-	now := time.Now().UnixMilli()
+	now := time.Now()
 	timeseries := make([]prompb.TimeSeries, 0, len(metrics))
 
 	for _, metric := range metrics {
@@ -134,21 +222,115 @@ func (s *PrometheusRemoteWriteShipper) convertToTimeSeries(metrics []collector.M
 				Value: v,
 			})
 		}
+		// Remote write requires labels sorted by name, __name__ included
+		sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+
+		if metric.Histogram != nil {
+			timeseries = append(timeseries, prompb.TimeSeries{
+				Labels:     labels,
+				Histograms: []prompb.Histogram{toPromHistogram(metric.Histogram, metric.TimestampOr(now).UnixMilli())},
+			})
+			continue
+		}
 
+		timestamp := metric.TimestampOr(now)
 		timeseries = append(timeseries, prompb.TimeSeries{
 			Labels: labels,
 			Samples: []prompb.Sample{
 				{
 					Value:     metric.Value,
-					Timestamp: now,
+					Timestamp: timestamp.UnixMilli(),
 				},
 			},
+			Exemplars: toPromExemplars(metric.Exemplars, timestamp),
 		})
 	}
 
 	return timeseries
 }
 
+// toPromExemplars converts exemplars, stamping those without a timestamp with
+// their sample's; nil for none
+func toPromExemplars(exemplars []collector.Exemplar, sampleTime time.Time) []prompb.Exemplar {
+	if len(exemplars) == 0 {
+		return nil
+	}
+	out := make([]prompb.Exemplar, 0, len(exemplars))
+	for _, ex := range exemplars {
+		labels := make([]prompb.Label, 0, len(ex.Labels))
+		for _, k := range collector.SortedLabelNames(ex.Labels) {
+			labels = append(labels, prompb.Label{Name: k, Value: ex.Labels[k]})
+		}
+		out = append(out, prompb.Exemplar{
+			Labels:    labels,
+			Value:     ex.Value,
+			Timestamp: ex.TimestampOr(sampleTime).UnixMilli(),
+		})
+	}
+	return out
+}
+
+// toPromHistogram encodes a native histogram with integer counts
+func toPromHistogram(h *collector.NativeHistogram, timestamp int64) prompb.Histogram {
+	positiveSpans, positiveDeltas := collector.SparseBuckets(h.PositiveBuckets)
+	negativeSpans, negativeDeltas := collector.SparseBuckets(h.NegativeBuckets)
+	return prompb.Histogram{
+		Count:          &prompb.Histogram_CountInt{CountInt: h.Count},
+		Sum:            h.Sum,
+		Schema:         h.Schema,
+		ZeroThreshold:  h.ZeroThreshold,
+		ZeroCount:      &prompb.Histogram_ZeroCountInt{ZeroCountInt: h.ZeroCount},
+		PositiveSpans:  toPromSpans(positiveSpans),
+		PositiveDeltas: positiveDeltas,
+		NegativeSpans:  toPromSpans(negativeSpans),
+		NegativeDeltas: negativeDeltas,
+		Timestamp:      timestamp,
+	}
+}
+
+func toPromSpans(spans []collector.BucketSpan) []prompb.BucketSpan {
+	out := make([]prompb.BucketSpan, len(spans))
+	for i, s := range spans {
+		out[i] = prompb.BucketSpan{Offset: s.Offset, Length: s.Length}
+	}
+	return out
+}
+
+// convertToMetadata builds one metadata entry per metric family that carries help text
+func (s *PrometheusRemoteWriteShipper) convertToMetadata(metrics []collector.Metric) []prompb.MetricMetadata {
+	var metadata []prompb.MetricMetadata
+	seen := make(map[string]struct{})
+
+	for _, metric := range metrics {
+		if metric.Help == "" {
+			continue
+		}
+		if _, ok := seen[metric.Name]; ok {
+			continue
+		}
+		seen[metric.Name] = struct{}{}
+
+		metricType := prompb.MetricMetadata_GAUGE
+		if metric.Histogram != nil {
+			metricType = prompb.MetricMetadata_HISTOGRAM
+		} else if metric.Type == "counter" {
+			metricType = prompb.MetricMetadata_COUNTER
+		}
+		metadata = append(metadata, prompb.MetricMetadata{
+			Type:             metricType,
+			MetricFamilyName: metric.Name,
+			Help:             metric.Help,
+		})
+	}
+
+	return metadata
+}
+
+// SetRateLimiter makes every request, including each chunk of a split batch, wait on limiter
+func (s *PrometheusRemoteWriteShipper) SetRateLimiter(limiter *RateLimiter) {
+	limitClient(s.client, limiter)
+}
+
 // Close cleans up resources
 func (s *PrometheusRemoteWriteShipper) Close() error {
 	s.client.CloseIdleConnections()
@@ -3,8 +3,13 @@ package shipper
 import (
 	"context"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -145,6 +150,76 @@ func TestPrometheusShipper_ShipSuccess(t *testing.T) {
 	}
 }
 
+// TestPrometheusShipper_NativeHistogram verifies that histogram metrics are sent
+// in the Histograms field with valid sparse buckets and histogram metadata.
+func TestPrometheusShipper_NativeHistogram(t *testing.T) {
+	var capturedBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s := newTestPrometheusShipper(t, srv.URL)
+
+	h := collector.NewNativeHistogram(collector.DefaultNativeHistogramSchema)
+	for _, v := range []float64{0.01, 0.02, 0.5, 3, 3, 120} {
+		h.Observe(v)
+	}
+	m := h.Metric("request_duration_seconds", map[string]string{"path": "/"})
+	m.Help = "Request latency"
+
+	if err := s.Ship(context.Background(), []collector.Metric{m}); err != nil {
+		t.Fatalf("Ship returned error: %v", err)
+	}
+
+	wr := decodeWriteRequest(t, capturedBody)
+	if len(wr.Timeseries) != 1 {
+		t.Fatalf("expected 1 timeseries, got %d", len(wr.Timeseries))
+	}
+	ts := wr.Timeseries[0]
+	if len(ts.Samples) != 0 || len(ts.Histograms) != 1 {
+		t.Fatalf("expected a single histogram and no samples, got %d samples and %d histograms", len(ts.Samples), len(ts.Histograms))
+	}
+
+	got := ts.Histograms[0].ToIntHistogram()
+	if err := got.Validate(); err != nil {
+		t.Fatalf("encoded histogram is invalid: %v", err)
+	}
+	if got.Count != 6 || got.Schema != collector.DefaultNativeHistogramSchema {
+		t.Errorf("unexpected histogram: %v", got)
+	}
+	if len(wr.Metadata) != 1 || wr.Metadata[0].Type != prompb.MetricMetadata_HISTOGRAM {
+		t.Errorf("expected histogram metadata, got %v", wr.Metadata)
+	}
+}
+
+// TestPrometheusShipper_SortedLabels verifies that labels are sent sorted by
+// name, as the remote write spec requires, whatever the map iteration order.
+func TestPrometheusShipper_SortedLabels(t *testing.T) {
+	var capturedBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s := newTestPrometheusShipper(t, srv.URL)
+	m := collector.Metric{Name: "up", Value: 1, Type: "gauge", Labels: map[string]string{"zone": "a", "Region": "eu", "app": "api", "job": "x", "instance": "h1"}}
+	if err := s.Ship(context.Background(), []collector.Metric{m}); err != nil {
+		t.Fatalf("Ship returned error: %v", err)
+	}
+
+	var names []string
+	for _, l := range decodeWriteRequest(t, capturedBody).Timeseries[0].Labels {
+		names = append(names, l.Name)
+	}
+	want := []string{"Region", "__name__", "app", "instance", "job", "zone"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("label order: got %v, want %v", names, want)
+	}
+}
+
 // TestPrometheusShipper_ShipEmpty verifies that an empty metrics slice returns
 // nil without making any HTTP request.
 func TestPrometheusShipper_ShipEmpty(t *testing.T) {
@@ -165,6 +240,64 @@ func TestPrometheusShipper_ShipEmpty(t *testing.T) {
 	}
 }
 
+func TestPrometheusShipper_UserAgent(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("User-Agent")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	s := newTestPrometheusShipper(t, srv.URL)
+	s.SetUserAgent("metricsd/1.2.3")
+	if err := s.Ship(context.Background(), []collector.Metric{{Name: "up", Value: 1, Type: "gauge"}}); err != nil {
+		t.Fatalf("Ship returned error: %v", err)
+	}
+	if got != "metricsd/1.2.3" {
+		t.Errorf("User-Agent: want metricsd/1.2.3, got %q", got)
+	}
+}
+
+func TestPrometheusShipper_ConnPool(t *testing.T) {
+	var mu sync.Mutex
+	conns := 0
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			conns++
+			mu.Unlock()
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	s := newTestPrometheusShipper(t, srv.URL)
+	transport := s.client.Transport.(*http.Transport)
+	if transport.MaxIdleConnsPerHost != DefaultMaxIdleConns || transport.IdleConnTimeout != DefaultIdleConnTimeout {
+		t.Errorf("unexpected default pool: %d idle per host, %v timeout", transport.MaxIdleConnsPerHost, transport.IdleConnTimeout)
+	}
+
+	s.SetConnPool(ConnPool{MaxIdleConns: 10, MaxConnsPerHost: 4, IdleConnTimeout: time.Minute})
+	if transport.MaxIdleConns != 10 || transport.MaxIdleConnsPerHost != 10 || transport.MaxConnsPerHost != 4 || transport.IdleConnTimeout != time.Minute {
+		t.Errorf("SetConnPool not applied: %+v", transport)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := s.Ship(context.Background(), []collector.Metric{{Name: "up", Value: 1, Type: "gauge"}}); err != nil {
+			t.Fatalf("Ship returned error: %v", err)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if conns != 1 {
+		t.Errorf("expected sequential ships to reuse one connection, opened %d", conns)
+	}
+}
+
 // TestPrometheusShipper_ServerError verifies that a non-2xx response causes
 // Ship to return a non-nil error.
 func TestPrometheusShipper_ServerError(t *testing.T) {
@@ -185,6 +318,145 @@ func TestPrometheusShipper_ServerError(t *testing.T) {
 	}
 }
 
+// TestPrometheusShipper_SnappyRoundTrip verifies that the shipped body decodes
+// through snappy into exactly the WriteRequest built from the input metrics.
+func TestPrometheusShipper_SnappyRoundTrip(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s := newTestPrometheusShipper(t, srv.URL)
+
+	ts := time.UnixMilli(1700000000123)
+	metrics := []collector.Metric{
+		{Name: "queue_depth", Value: 7, Type: "gauge", Help: "Items waiting", Labels: map[string]string{"queue": "a"}, Timestamp: ts},
+	}
+	if err := s.Ship(context.Background(), metrics); err != nil {
+		t.Fatalf("Ship returned error: %v", err)
+	}
+
+	want := prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{{
+			Labels:  []prompb.Label{{Name: "__name__", Value: "queue_depth"}, {Name: "queue", Value: "a"}},
+			Samples: []prompb.Sample{{Value: 7, Timestamp: ts.UnixMilli()}},
+		}},
+		Metadata: []prompb.MetricMetadata{{Type: prompb.MetricMetadata_GAUGE, MetricFamilyName: "queue_depth", Help: "Items waiting"}},
+	}
+	got := decodeWriteRequest(t, body)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round-tripped WriteRequest mismatch:\ngot  %+v\nwant %+v", got, want)
+	}
+}
+
+// TestPrometheusShipper_CompressionNone verifies that "none" sends raw protobuf
+// without a Content-Encoding header, and that unknown modes are rejected.
+func TestPrometheusShipper_CompressionNone(t *testing.T) {
+	var (
+		encoding string
+		version  string
+		body     []byte
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+		version = r.Header.Get("X-Prometheus-Remote-Write-Version")
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s := newTestPrometheusShipper(t, srv.URL)
+	if err := s.SetCompression("gzip"); err == nil {
+		t.Error("expected error for unsupported compression")
+	}
+	if err := s.SetCompression(CompressionNone); err != nil {
+		t.Fatalf("SetCompression: %v", err)
+	}
+
+	metrics := []collector.Metric{{Name: "up", Value: 1, Type: "gauge"}}
+	if err := s.Ship(context.Background(), metrics); err != nil {
+		t.Fatalf("Ship returned error: %v", err)
+	}
+
+	if encoding != "" {
+		t.Errorf("Content-Encoding: want empty, got %q", encoding)
+	}
+	if version != "0.1.0" {
+		t.Errorf("X-Prometheus-Remote-Write-Version: want %q, got %q", "0.1.0", version)
+	}
+	var wr prompb.WriteRequest
+	if err := wr.Unmarshal(body); err != nil {
+		t.Fatalf("body is not raw protobuf: %v", err)
+	}
+	if len(wr.Timeseries) != 1 || labelValue(wr.Timeseries[0], "__name__") != "up" {
+		t.Errorf("unexpected timeseries: %+v", wr.Timeseries)
+	}
+}
+
+// TestPrometheusShipper_Chunking verifies that large batches are split into
+// requests of at most max_samples_per_send samples, that each chunk is a valid
+// snappy-encoded WriteRequest, and that a failing chunk doesn't stop the rest.
+func TestPrometheusShipper_Chunking(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		sizes []int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		wr := decodeWriteRequest(t, body)
+		mu.Lock()
+		sizes = append(sizes, len(wr.Timeseries))
+		n := len(sizes)
+		mu.Unlock()
+		if r.URL.Query().Get("fail") == "2" && n == 2 {
+			http.Error(w, "too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	metrics := make([]collector.Metric, 5)
+	for i := range metrics {
+		metrics[i] = collector.Metric{Name: "m", Value: float64(i), Type: "gauge", Labels: map[string]string{"i": strconv.Itoa(i)}}
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr bool
+	}{
+		{"all succeed", "", false},
+		{"middle chunk fails", "?fail=2", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mu.Lock()
+			sizes = nil
+			mu.Unlock()
+
+			s := newTestPrometheusShipper(t, srv.URL+tc.query)
+			s.SetMaxSamplesPerSend(2)
+
+			err := s.Ship(context.Background(), metrics)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Ship error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "chunk 2/3") {
+				t.Errorf("error should identify the failed chunk: %v", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !reflect.DeepEqual(sizes, []int{2, 2, 1}) {
+				t.Errorf("chunk sizes: want [2 2 1], got %v", sizes)
+			}
+		})
+	}
+}
+
 // TestPrometheusShipper_ConvertToTimeSeries exercises convertToTimeSeries
 // directly, verifying __name__ labels, custom labels, sample values, and that
 // timestamps are non-zero.
@@ -251,6 +523,81 @@ func TestPrometheusShipper_ConvertToTimeSeries(t *testing.T) {
 	}
 }
 
+// TestPrometheusShipper_ConvertToMetadata verifies that help text produces one
+// metadata entry per metric family and metrics without help are omitted.
+func TestPrometheusShipper_ConvertToMetadata(t *testing.T) {
+	s := &PrometheusRemoteWriteShipper{}
+	metrics := []collector.Metric{
+		{Name: "requests_total", Type: "counter", Help: "Total requests.", Labels: map[string]string{"code": "200"}},
+		{Name: "requests_total", Type: "counter", Help: "Total requests.", Labels: map[string]string{"code": "500"}},
+		{Name: "temperature", Type: "gauge", Help: "Current temperature."},
+		{Name: "no_help", Type: "gauge"},
+	}
+
+	md := s.convertToMetadata(metrics)
+	if len(md) != 2 {
+		t.Fatalf("expected 2 metadata entries, got %d", len(md))
+	}
+	if md[0].MetricFamilyName != "requests_total" || md[0].Type != prompb.MetricMetadata_COUNTER || md[0].Help != "Total requests." {
+		t.Errorf("unexpected metadata[0]: %+v", md[0])
+	}
+	if md[1].MetricFamilyName != "temperature" || md[1].Type != prompb.MetricMetadata_GAUGE {
+		t.Errorf("unexpected metadata[1]: %+v", md[1])
+	}
+}
+
+// TestPrometheusShipper_ConvertToTimeSeries_Timestamp verifies that a metric's
+// own timestamp is used instead of the ship time when set.
+func TestPrometheusShipper_ConvertToTimeSeries_Timestamp(t *testing.T) {
+	s := &PrometheusRemoteWriteShipper{}
+	sampleTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	ts := s.convertToTimeSeries([]collector.Metric{
+		{Name: "delayed", Value: 1, Type: "gauge", Timestamp: sampleTime},
+		{Name: "current", Value: 2, Type: "gauge"},
+	})
+
+	if got := ts[0].Samples[0].Timestamp; got != sampleTime.UnixMilli() {
+		t.Errorf("delayed timestamp: want %d, got %d", sampleTime.UnixMilli(), got)
+	}
+	if got := ts[1].Samples[0].Timestamp; got <= sampleTime.UnixMilli() {
+		t.Errorf("current timestamp should default to now, got %d", got)
+	}
+}
+
+// TestPrometheusShipper_ConvertToTimeSeries_Exemplars verifies that exemplars
+// are forwarded with sorted labels and default to their sample's timestamp.
+func TestPrometheusShipper_ConvertToTimeSeries_Exemplars(t *testing.T) {
+	s := &PrometheusRemoteWriteShipper{}
+	sampleTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	exemplarTime := sampleTime.Add(-time.Second)
+
+	ts := s.convertToTimeSeries([]collector.Metric{
+		{
+			Name: "req_seconds_bucket", Value: 10, Type: "counter", Timestamp: sampleTime,
+			Exemplars: []collector.Exemplar{
+				{Labels: map[string]string{"trace_id": "abc", "span_id": "def"}, Value: 0.25, Timestamp: exemplarTime},
+				{Labels: map[string]string{"trace_id": "ghi"}, Value: 0.5},
+			},
+		},
+		{Name: "plain", Value: 1, Type: "gauge"},
+	})
+
+	ex := ts[0].Exemplars
+	if len(ex) != 2 {
+		t.Fatalf("expected 2 exemplars, got %d", len(ex))
+	}
+	if ex[0].Labels[0].Name != "span_id" || ex[0].Labels[1].Name != "trace_id" || ex[0].Value != 0.25 {
+		t.Errorf("unexpected exemplar: %+v", ex[0])
+	}
+	if ex[0].Timestamp != exemplarTime.UnixMilli() || ex[1].Timestamp != sampleTime.UnixMilli() {
+		t.Errorf("exemplar timestamps: got %d and %d", ex[0].Timestamp, ex[1].Timestamp)
+	}
+	if ts[1].Exemplars != nil {
+		t.Errorf("metric without exemplars got %+v", ts[1].Exemplars)
+	}
+}
+
 // TestPrometheusShipper_Close verifies that Close does not panic and returns nil.
 func TestPrometheusShipper_Close(t *testing.T) {
 	s := newTestPrometheusShipper(t, "http://127.0.0.1:9999")
@@ -0,0 +1,240 @@
+package shipper
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+// DefaultPushgatewayJob is the job of the grouping key when none is configured
+const DefaultPushgatewayJob = "metricsd"
+
+// pushgatewayDeleteTimeout bounds the DELETE sent by Close when the shipper
+// has no timeout of its own
+const pushgatewayDeleteTimeout = 10 * time.Second
+
+// PushgatewayShipper pushes metrics to a Prometheus Pushgateway in the text
+// exposition format. Every Ship replaces the metrics of one group, identified
+// by the job and the grouping labels.
+type PushgatewayShipper struct {
+	collector.Logging
+	groupURL         string
+	client           *http.Client
+	method           string
+	deleteOnShutdown bool
+	ua               string
+}
+
+// NewPushgatewayShipper creates a new Pushgateway shipper. endpoint is the
+// Pushgateway base URL, e.g. http://pushgateway:9091; an empty job defaults to
+// DefaultPushgatewayJob. grouping adds labels to the grouping key.
+func NewPushgatewayShipper(endpoint, job string, grouping map[string]string, timeout time.Duration) (*PushgatewayShipper, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid pushgateway endpoint %q", endpoint)
+	}
+	if job == "" {
+		job = DefaultPushgatewayJob
+	}
+	if _, ok := grouping["job"]; ok {
+		return nil, fmt.Errorf("pushgateway grouping must not contain job; set the job instead")
+	}
+
+	return &PushgatewayShipper{
+		groupURL: strings.TrimSuffix(endpoint, "/") + pushgatewayGroupPath(job, grouping),
+		client:   &http.Client{Timeout: timeout, Transport: newTransport(nil)},
+		method:   http.MethodPut,
+	}, nil
+}
+
+// pushgatewayGroupPath returns /metrics/job/<job>/<label>/<value>... with the
+// grouping labels sorted. Values that are empty or contain a slash use the
+// Pushgateway's @base64 encoding.
+func pushgatewayGroupPath(job string, grouping map[string]string) string {
+	var b strings.Builder
+	b.WriteString("/metrics")
+	writeSegment := func(name, value string) {
+		if value == "" || strings.Contains(value, "/") {
+			b.WriteString("/" + name + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value)))
+			if value == "" {
+				b.WriteString("=")
+			}
+			return
+		}
+		b.WriteString("/" + name + "/" + url.PathEscape(value))
+	}
+	writeSegment("job", job)
+	for _, name := range collector.SortedLabelNames(grouping) {
+		writeSegment(name, grouping[name])
+	}
+	return b.String()
+}
+
+// SetMethod selects how pushes update the group: PUT (the default) replaces
+// all of its metrics, POST only those with the same names as the pushed ones
+func (s *PushgatewayShipper) SetMethod(method string) error {
+	switch strings.ToUpper(method) {
+	case "", http.MethodPut:
+		s.method = http.MethodPut
+	case http.MethodPost:
+		s.method = http.MethodPost
+	default:
+		return fmt.Errorf("unsupported pushgateway method %q (must be 'put' or 'post')", method)
+	}
+	return nil
+}
+
+// SetDeleteOnShutdown makes Close delete the group from the Pushgateway, so a
+// stopped metricsd does not leave its last values behind
+func (s *PushgatewayShipper) SetDeleteOnShutdown(enabled bool) {
+	s.deleteOnShutdown = enabled
+}
+
+// SetUserAgent sets the User-Agent header of push requests
+func (s *PushgatewayShipper) SetUserAgent(ua string) {
+	s.ua = ua
+}
+
+// SetProxy sets the proxy used for push requests
+func (s *PushgatewayShipper) SetProxy(proxy collector.ProxyConfig) {
+	setClientProxy(s.client, proxy)
+}
+
+// SetConnPool tunes connection reuse against the Pushgateway
+func (s *PushgatewayShipper) SetConnPool(pool ConnPool) {
+	setClientConnPool(s.client, pool)
+}
+
+// SetRateLimiter makes every request, the shutdown DELETE included, wait on limiter
+func (s *PushgatewayShipper) SetRateLimiter(limiter *RateLimiter) {
+	limitClient(s.client, limiter)
+}
+
+// Ship pushes metrics to the group
+func (s *PushgatewayShipper) Ship(ctx context.Context, metrics []collector.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	body, count := encodePushgatewayText(metrics)
+	if err := s.send(ctx, s.method, body); err != nil {
+		return err
+	}
+
+	s.Logger().Info().
+		Int("metric_count", count).
+		Str("endpoint", s.groupURL).
+		Msg("Successfully pushed metrics to Pushgateway")
+
+	return nil
+}
+
+// send performs one request against the group URL
+func (s *PushgatewayShipper) send(ctx context.Context, method string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, s.groupURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	}
+	if s.ua != "" {
+		req.Header.Set("User-Agent", s.ua)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// encodePushgatewayText renders counters and gauges in the text exposition
+// format, grouped into families by name; it returns the body and the number
+// of samples written. The Pushgateway rejects pushed timestamps, so none are
+// sent, and native histograms need the protobuf format, so they are skipped.
+func encodePushgatewayText(metrics []collector.Metric) ([]byte, int) {
+	sorted := make([]collector.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		if m.Histogram == nil {
+			sorted = append(sorted, m)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	var buf bytes.Buffer
+	for i, m := range sorted {
+		if i == 0 || sorted[i-1].Name != m.Name {
+			// A family takes the help text and type of its first sample
+			if m.Help != "" {
+				fmt.Fprintf(&buf, "# HELP %s %s\n", m.Name, escapeHelp(m.Help))
+			}
+			metricType := "gauge"
+			if m.Type == "counter" {
+				metricType = "counter"
+			}
+			fmt.Fprintf(&buf, "# TYPE %s %s\n", m.Name, metricType)
+		}
+
+		buf.WriteString(m.Name)
+		if len(m.Labels) > 0 {
+			buf.WriteByte('{')
+			for j, name := range collector.SortedLabelNames(m.Labels) {
+				if j > 0 {
+					buf.WriteByte(',')
+				}
+				fmt.Fprintf(&buf, "%s=\"%s\"", name, escapeLabelValue(m.Labels[name]))
+			}
+			buf.WriteByte('}')
+		}
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatFloat(m.Value, 'g', -1, 64))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), len(sorted)
+}
+
+var (
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string       { return helpEscaper.Replace(s) }
+func escapeLabelValue(s string) string { return labelValueEscaper.Replace(s) }
+
+// Close deletes the group when delete_on_shutdown is set and releases idle
+// connections
+func (s *PushgatewayShipper) Close() error {
+	defer s.client.CloseIdleConnections()
+	if !s.deleteOnShutdown {
+		return nil
+	}
+
+	timeout := s.client.Timeout
+	if timeout <= 0 {
+		timeout = pushgatewayDeleteTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := s.send(ctx, http.MethodDelete, nil); err != nil {
+		return fmt.Errorf("failed to delete pushgateway group: %w", err)
+	}
+	s.Logger().Info().Str("endpoint", s.groupURL).Msg("Deleted metrics group from Pushgateway")
+	return nil
+}
@@ -0,0 +1,162 @@
+package shipper
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+// pushgatewayRequest is one request seen by the fake Pushgateway
+type pushgatewayRequest struct {
+	method      string
+	path        string
+	contentType string
+	body        string
+}
+
+// pushgatewayServer records requests and answers with status (default 200)
+type pushgatewayServer struct {
+	mu       sync.Mutex
+	requests []pushgatewayRequest
+	status   int
+}
+
+func (p *pushgatewayServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	p.mu.Lock()
+	p.requests = append(p.requests, pushgatewayRequest{r.Method, r.URL.EscapedPath(), r.Header.Get("Content-Type"), string(body)})
+	status := p.status
+	p.mu.Unlock()
+	if status != 0 {
+		w.WriteHeader(status)
+	}
+}
+
+func newTestPushgatewayShipper(t *testing.T, pg *pushgatewayServer, job string, grouping map[string]string) *PushgatewayShipper {
+	t.Helper()
+	srv := httptest.NewServer(pg)
+	t.Cleanup(srv.Close)
+
+	s, err := NewPushgatewayShipper(srv.URL, job, grouping, time.Second)
+	if err != nil {
+		t.Fatalf("NewPushgatewayShipper: %v", err)
+	}
+	return s
+}
+
+func TestNewPushgatewayShipper(t *testing.T) {
+	if _, err := NewPushgatewayShipper("not a url", "", nil, time.Second); err == nil {
+		t.Error("expected error for an invalid endpoint")
+	}
+	if _, err := NewPushgatewayShipper("http://pushgateway:9091", "", map[string]string{"job": "x"}, time.Second); err == nil {
+		t.Error("expected error for a job grouping label")
+	}
+}
+
+func TestPushgatewayGroupPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		job      string
+		grouping map[string]string
+		want     string
+	}{
+		{"job only", "backup", nil, "/metrics/job/backup"},
+		{"sorted grouping", "backup", map[string]string{"zone": "eu", "instance": "db-1"}, "/metrics/job/backup/instance/db-1/zone/eu"},
+		{"slash in value", "backup", map[string]string{"path": "/var/tmp"}, "/metrics/job/backup/path@base64/L3Zhci90bXA"},
+		{"empty value", "backup", map[string]string{"instance": ""}, "/metrics/job/backup/instance@base64/="},
+		{"escaped value", "nightly backup", nil, "/metrics/job/nightly%20backup"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := pushgatewayGroupPath(tc.job, tc.grouping); got != tc.want {
+				t.Errorf("pushgatewayGroupPath() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPushgatewayShipper_Ship(t *testing.T) {
+	pg := &pushgatewayServer{}
+	s := newTestPushgatewayShipper(t, pg, "", map[string]string{"instance": "web-1"})
+
+	metrics := []collector.Metric{
+		{Name: "jobs_processed_total", Type: "counter", Value: 42, Help: "Jobs processed", Labels: map[string]string{"queue": "mail"}},
+		{Name: "backup_last_success", Type: "gauge", Value: 1.7e9, Timestamp: time.Unix(1700000000, 0)},
+		{Name: "jobs_processed_total", Type: "counter", Value: 7, Labels: map[string]string{"queue": "say \"hi\""}},
+		{Name: "request_seconds", Histogram: &collector.NativeHistogram{Count: 1}},
+	}
+	if err := s.Ship(context.Background(), metrics); err != nil {
+		t.Fatalf("Ship() error: %v", err)
+	}
+
+	if len(pg.requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(pg.requests))
+	}
+	req := pg.requests[0]
+	if req.method != http.MethodPut || req.path != "/metrics/job/metricsd/instance/web-1" {
+		t.Errorf("got %s %s, want PUT /metrics/job/metricsd/instance/web-1", req.method, req.path)
+	}
+	if req.contentType != "text/plain; version=0.0.4" {
+		t.Errorf("unexpected Content-Type %q", req.contentType)
+	}
+	want := "# TYPE backup_last_success gauge\n" +
+		"backup_last_success 1.7e+09\n" +
+		"# HELP jobs_processed_total Jobs processed\n" +
+		"# TYPE jobs_processed_total counter\n" +
+		"jobs_processed_total{queue=\"mail\"} 42\n" +
+		"jobs_processed_total{queue=\"say \\\"hi\\\"\"} 7\n"
+	if req.body != want {
+		t.Errorf("body:\n%s\nwant:\n%s", req.body, want)
+	}
+}
+
+func TestPushgatewayShipper_Method(t *testing.T) {
+	pg := &pushgatewayServer{}
+	s := newTestPushgatewayShipper(t, pg, "batch", nil)
+	if err := s.SetMethod("patch"); err == nil {
+		t.Error("expected error for an unsupported method")
+	}
+	if err := s.SetMethod("post"); err != nil {
+		t.Fatalf("SetMethod(post): %v", err)
+	}
+	if err := s.Ship(context.Background(), []collector.Metric{{Name: "up", Value: 1}}); err != nil {
+		t.Fatalf("Ship() error: %v", err)
+	}
+	if pg.requests[0].method != http.MethodPost {
+		t.Errorf("expected POST, got %s", pg.requests[0].method)
+	}
+}
+
+func TestPushgatewayShipper_ErrorStatus(t *testing.T) {
+	pg := &pushgatewayServer{status: http.StatusBadRequest}
+	s := newTestPushgatewayShipper(t, pg, "batch", nil)
+	if err := s.Ship(context.Background(), []collector.Metric{{Name: "up", Value: 1}}); err == nil {
+		t.Error("expected error for a 400 response")
+	}
+}
+
+func TestPushgatewayShipper_DeleteOnShutdown(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		pg := &pushgatewayServer{}
+		s := newTestPushgatewayShipper(t, pg, "batch", nil)
+		s.SetDeleteOnShutdown(enabled)
+		if err := s.Close(); err != nil {
+			t.Fatalf("Close() error: %v", err)
+		}
+		if !enabled {
+			if len(pg.requests) != 0 {
+				t.Errorf("expected no request without delete_on_shutdown, got %v", pg.requests)
+			}
+			continue
+		}
+		if len(pg.requests) != 1 || pg.requests[0].method != http.MethodDelete || pg.requests[0].path != "/metrics/job/batch" {
+			t.Errorf("expected DELETE /metrics/job/batch, got %v", pg.requests)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package shipper
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+// RateLimiter is a token bucket allowing perSecond requests on average with
+// bursts of up to one second's worth of tokens.
+type RateLimiter struct {
+	mu        sync.Mutex
+	perSecond float64
+	burst     float64
+	tokens    float64
+	last      time.Time
+	now       func() time.Time // Replaced in tests
+}
+
+// NewRateLimiter returns a limiter for perSecond requests, or nil (no limit)
+// when perSecond <= 0.
+func NewRateLimiter(perSecond float64) *RateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	burst := math.Max(1, math.Floor(perSecond))
+	return &RateLimiter{
+		perSecond: perSecond,
+		burst:     burst,
+		tokens:    burst,
+		last:      time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Wait blocks until a request may be sent or ctx is done. A nil limiter never blocks.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	for {
+		l.mu.Lock()
+		now := l.now()
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.perSecond)
+		l.last = now
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - l.tokens) / l.perSecond * float64(time.Second))
+		l.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// RateLimitSetter is implemented by shippers that limit each outbound request
+// themselves, e.g. every chunk of a split batch
+type RateLimitSetter interface {
+	SetRateLimiter(limiter *RateLimiter)
+}
+
+// WithRateLimit applies limiter to s. Shippers implementing RateLimitSetter
+// limit every request they send; others are wrapped so each Ship call waits
+// for a token. A WithMatch wrapper gets the limiter applied to the shipper it
+// wraps, so wrapping order doesn't matter. A nil limiter returns s unchanged.
+func WithRateLimit(s Shipper, limiter *RateLimiter) Shipper {
+	if limiter == nil {
+		return s
+	}
+	if m, ok := s.(*matchShipper); ok {
+		m.next = WithRateLimit(m.next, limiter)
+		return m
+	}
+	if rl, ok := s.(RateLimitSetter); ok {
+		rl.SetRateLimiter(limiter)
+		return s
+	}
+	return &rateLimitedShipper{next: s, limiter: limiter}
+}
+
+// rateLimitedShipper waits for a token before every Ship call
+type rateLimitedShipper struct {
+	next    Shipper
+	limiter *RateLimiter
+}
+
+func (r *rateLimitedShipper) Ship(ctx context.Context, metrics []collector.Metric) error {
+	if err := r.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return r.next.Ship(ctx, metrics)
+}
+
+func (r *rateLimitedShipper) Close() error {
+	return r.next.Close()
+}
+
+// Flush forwards to the wrapped shipper when it buffers data
+func (r *rateLimitedShipper) Flush(ctx context.Context) error {
+	if f, ok := r.next.(Flusher); ok {
+		return f.Flush(ctx)
+	}
+	return nil
+}
+
+// SetLogger forwards to the wrapped shipper when it accepts a logger
+func (r *rateLimitedShipper) SetLogger(logger zerolog.Logger) {
+	if s, ok := r.next.(collector.LoggerSetter); ok {
+		s.SetLogger(logger)
+	}
+}
+
+// rateLimitedTransport waits for a token before every HTTP round trip
+type rateLimitedTransport struct {
+	next    http.RoundTripper
+	limiter *RateLimiter
+}
+
+// limitClient wraps client's transport so every request waits on limiter
+func limitClient(client *http.Client, limiter *RateLimiter) {
+	if limiter == nil {
+		return
+	}
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	client.Transport = &rateLimitedTransport{next: next, limiter: limiter}
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
+
+// CloseIdleConnections lets http.Client.CloseIdleConnections reach the wrapped transport
+func (t *rateLimitedTransport) CloseIdleConnections() {
+	if c, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,148 @@
+package shipper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/0x524A/metricsd/internal/collector"
+)
+
+// newFrozenRateLimiter returns a limiter whose clock only moves when *now does
+func newFrozenRateLimiter(perSecond float64, now *time.Time) *RateLimiter {
+	l := NewRateLimiter(perSecond)
+	l.now = func() time.Time { return *now }
+	l.last = *now
+	return l
+}
+
+func TestRateLimiter(t *testing.T) {
+	if NewRateLimiter(0) != nil {
+		t.Fatal("expected no limiter for a zero rate")
+	}
+	var unlimited *RateLimiter
+	if err := unlimited.Wait(context.Background()); err != nil {
+		t.Fatalf("nil limiter should never block: %v", err)
+	}
+
+	now := time.Now()
+	l := newFrozenRateLimiter(2, &now)
+	for i := 0; i < 2; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatalf("burst request %d: %v", i, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected to block once the burst is spent, got %v", err)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("expected a token after half a second at 2/s: %v", err)
+	}
+}
+
+func TestWithRateLimit_HTTPShipperLimitsEachChunk(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	rw := newTestPrometheusShipper(t, srv.URL)
+	rw.SetMaxSamplesPerSend(1)
+	now := time.Now()
+	if s := WithRateLimit(rw, newFrozenRateLimiter(2, &now)); s != Shipper(rw) {
+		t.Fatal("HTTP shippers should limit their own requests, not be wrapped")
+	}
+
+	metrics := []collector.Metric{{Name: "a", Type: "gauge"}, {Name: "b", Type: "gauge"}, {Name: "c", Type: "gauge"}}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := rw.Ship(ctx, metrics); err == nil {
+		t.Error("expected the third chunk to be held back by the limiter")
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("expected 2 requests within the burst, got %d", got)
+	}
+}
+
+// TestWithRateLimit_UnderMatch verifies that a match filter doesn't hide the
+// per-request limiting of the shipper it wraps
+func TestWithRateLimit_UnderMatch(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	sel, err := collector.ParseSelector(`{__name__=~"system_.*"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rw := newTestPrometheusShipper(t, srv.URL)
+	rw.SetMaxSamplesPerSend(1)
+	now := time.Now()
+	s := WithRateLimit(WithMatch(rw, []collector.Selector{sel}), newFrozenRateLimiter(2, &now))
+	if _, ok := s.(*matchShipper); !ok {
+		t.Fatalf("expected the match filter to stay outermost, got %T", s)
+	}
+
+	metrics := []collector.Metric{
+		{Name: "system_a", Type: "gauge"}, {Name: "app_b", Type: "gauge"},
+		{Name: "system_c", Type: "gauge"}, {Name: "system_d", Type: "gauge"},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Ship(ctx, metrics); err == nil {
+		t.Error("expected the third matching chunk to be held back by the limiter")
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("expected 2 requests within the burst, got %d", got)
+	}
+}
+
+// countingShipper counts Ship and Flush calls
+type countingShipper struct {
+	ships, flushes int
+}
+
+func (c *countingShipper) Ship(context.Context, []collector.Metric) error { c.ships++; return nil }
+func (c *countingShipper) Close() error                                   { return nil }
+func (c *countingShipper) Flush(context.Context) error                    { c.flushes++; return nil }
+
+func TestWithRateLimit_WrapsOtherShippers(t *testing.T) {
+	inner := &countingShipper{}
+	now := time.Now()
+	s := WithRateLimit(inner, newFrozenRateLimiter(1, &now))
+
+	if err := s.Ship(context.Background(), nil); err != nil {
+		t.Fatalf("Ship: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Ship(ctx, nil); err == nil {
+		t.Error("expected the second Ship to wait for a token")
+	}
+	if inner.ships != 1 {
+		t.Errorf("expected 1 Ship to reach the inner shipper, got %d", inner.ships)
+	}
+
+	f, ok := s.(Flusher)
+	if !ok {
+		t.Fatal("wrapped shipper should still flush")
+	}
+	_ = f.Flush(context.Background())
+	if inner.flushes != 1 {
+		t.Error("Flush was not forwarded")
+	}
+}
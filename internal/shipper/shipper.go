@@ -2,6 +2,9 @@ package shipper
 
 import (
 	"context"
+	"crypto/tls"
+	"net/http"
+	"time"
 
 	"github.com/0x524A/metricsd/internal/collector"
 )
@@ -11,3 +14,85 @@ type Shipper interface {
 	Ship(ctx context.Context, metrics []collector.Metric) error
 	Close() error
 }
+
+// UserAgentSetter is implemented by shippers that send HTTP requests. An
+// empty user agent keeps Go's default.
+type UserAgentSetter interface {
+	SetUserAgent(ua string)
+}
+
+// ProxySetter is implemented by shippers that send HTTP requests
+type ProxySetter interface {
+	SetProxy(proxy collector.ProxyConfig)
+}
+
+// ConnPoolSetter is implemented by shippers that send HTTP requests
+type ConnPoolSetter interface {
+	SetConnPool(pool ConnPool)
+}
+
+// ConnPool tunes connection reuse of an HTTP shipper. Zero fields keep the
+// defaults below.
+type ConnPool struct {
+	MaxIdleConns    int           // Idle keep-alive connections kept open, also per host
+	MaxConnsPerHost int           // Connections per host, in use or idle (0: unlimited)
+	IdleConnTimeout time.Duration // How long an idle connection is kept
+}
+
+// Connection pool defaults. net/http keeps only 2 idle connections per host,
+// which under frequent or chunked shipping means a new connection (and a
+// TIME_WAIT socket) for most requests.
+const (
+	DefaultMaxIdleConns    = 100
+	DefaultIdleConnTimeout = 90 * time.Second
+)
+
+// newTransport returns the transport of an HTTP shipper: proxy from the
+// environment, the given TLS settings and the default connection pool
+func newTransport(tlsConfig *tls.Config) *http.Transport {
+	t := &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: tlsConfig,
+	}
+	applyConnPool(t, ConnPool{})
+	return t
+}
+
+// applyConnPool sets pool's limits on t, filling in defaults
+func applyConnPool(t *http.Transport, pool ConnPool) {
+	if pool.MaxIdleConns <= 0 {
+		pool.MaxIdleConns = DefaultMaxIdleConns
+	}
+	if pool.IdleConnTimeout <= 0 {
+		pool.IdleConnTimeout = DefaultIdleConnTimeout
+	}
+	t.MaxIdleConns = pool.MaxIdleConns
+	t.MaxIdleConnsPerHost = pool.MaxIdleConns
+	t.MaxConnsPerHost = pool.MaxConnsPerHost
+	t.IdleConnTimeout = pool.IdleConnTimeout
+}
+
+// setClientConnPool applies pool to client's transport. Like setClientProxy
+// it must run before the transport is wrapped.
+func setClientConnPool(client *http.Client, pool ConnPool) {
+	if t, ok := client.Transport.(*http.Transport); ok {
+		applyConnPool(t, pool)
+	}
+}
+
+// setClientProxy routes client's requests through proxy. It must run before
+// the client's transport is wrapped, e.g. by SetRateLimiter.
+func setClientProxy(client *http.Client, proxy collector.ProxyConfig) {
+	switch t := client.Transport.(type) {
+	case nil:
+		client.Transport = collector.ProxyTransport(proxy)
+	case *http.Transport:
+		t.Proxy = proxy.ProxyFunc()
+	}
+}
+
+// Flusher is implemented by shippers that buffer data between Ship calls.
+// Flush blocks until buffered data is delivered or ctx is done.
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
@@ -13,27 +13,30 @@ import (
 	"os"
 	"time"
 
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
 
 	"github.com/0x524A/metricsd/internal/collector"
 )
 
 // SplunkHECShipper ships metrics to Splunk HTTP Event Collector
 type SplunkHECShipper struct {
+	collector.Logging
 	endpoint     string
 	token        string
 	client       *http.Client
 	debugLogFile string // Optional file path to log payloads for debugging
+	ua           string
 }
 
 // NewSplunkHECShipper creates a new Splunk HEC shipper
 func NewSplunkHECShipper(endpoint, token string, tlsEnabled bool, certFile, keyFile, caFile string, insecureSkipVerify bool, timeout time.Duration, debugLogFile string) (*SplunkHECShipper, error) {
+	var s *SplunkHECShipper // Set at the end; the cert reloader logs through it
 	var tlsConfig *tls.Config
 
 	if tlsEnabled {
-		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		certs, err := newCertReloader(certFile, keyFile, func() *zerolog.Logger { return s.Logger() })
 		if err != nil {
-			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+			return nil, err
 		}
 
 		caCert, err := os.ReadFile(caFile)
@@ -47,9 +50,9 @@ func NewSplunkHECShipper(endpoint, token string, tlsEnabled bool, certFile, keyF
 		}
 
 		tlsConfig = &tls.Config{
-			Certificates:       []tls.Certificate{cert},
-			RootCAs:            caCertPool,
-			InsecureSkipVerify: insecureSkipVerify,
+			GetClientCertificate: certs.GetClientCertificate,
+			RootCAs:              caCertPool,
+			InsecureSkipVerify:   insecureSkipVerify,
 		}
 	} else {
 		// For HTTPS without client cert (common for Splunk HEC)
@@ -59,10 +62,8 @@ func NewSplunkHECShipper(endpoint, token string, tlsEnabled bool, certFile, keyF
 	}
 
 	client := &http.Client{
-		Timeout: timeout,
-		Transport: &http.Transport{
-			TLSClientConfig: tlsConfig,
-		},
+		Timeout:   timeout,
+		Transport: newTransport(tlsConfig),
 	}
 
 	// Ensure endpoint has the correct path for HEC
@@ -74,12 +75,13 @@ func NewSplunkHECShipper(endpoint, token string, tlsEnabled bool, certFile, keyF
 	}
 	endpoint += "services/collector/event"
 
-	return &SplunkHECShipper{
+	s = &SplunkHECShipper{
 		endpoint:     endpoint,
 		token:        token,
 		client:       client,
 		debugLogFile: debugLogFile,
-	}, nil
+	}
+	return s, nil
 }
 
 // SplunkHECEvent represents a single event for Splunk HEC
@@ -107,10 +109,11 @@ func (s *SplunkHECShipper) Ship(ctx context.Context, metrics []collector.Metric)
 	// For metrics, we'll create one event per metric
 	var buffer bytes.Buffer
 	skippedCount := 0
+	now := time.Now()
 	for _, metric := range metrics {
 		// Skip metrics with NaN or Inf values as they cannot be marshaled to JSON
 		if math.IsNaN(metric.Value) || math.IsInf(metric.Value, 0) {
-			log.Warn().
+			s.Logger().Warn().
 				Str("metric_name", metric.Name).
 				Float64("value", metric.Value).
 				Msg("Skipping metric with invalid value (NaN or Inf)")
@@ -119,7 +122,7 @@ func (s *SplunkHECShipper) Ship(ctx context.Context, metrics []collector.Metric)
 		}
 
 		event := SplunkHECEvent{
-			Time:       float64(time.Now().Unix()),
+			Time:       float64(metric.TimestampOr(now).Unix()),
 			Host:       hostname,
 			Source:     "metricsd",
 			SourceType: "metrics",
@@ -163,6 +166,9 @@ func (s *SplunkHECShipper) Ship(ctx context.Context, metrics []collector.Metric)
 	// Set headers required by Splunk HEC
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", fmt.Sprintf("Splunk %s", s.token))
+	if s.ua != "" {
+		req.Header.Set("User-Agent", s.ua)
+	}
 
 	// Send request
 	resp, err := s.client.Do(req)
@@ -177,7 +183,7 @@ func (s *SplunkHECShipper) Ship(ctx context.Context, metrics []collector.Metric)
 	}
 
 	successCount := len(metrics) - skippedCount
-	logEvent := log.Info().
+	logEvent := s.Logger().Info().
 		Int("metric_count", successCount).
 		Int("payload_size_bytes", payloadSize).
 		Str("endpoint", s.endpoint)
@@ -191,6 +197,26 @@ func (s *SplunkHECShipper) Ship(ctx context.Context, metrics []collector.Metric)
 	return nil
 }
 
+// SetRateLimiter makes each HEC post wait on limiter
+func (s *SplunkHECShipper) SetRateLimiter(limiter *RateLimiter) {
+	limitClient(s.client, limiter)
+}
+
+// SetUserAgent sets the User-Agent header of HEC posts
+func (s *SplunkHECShipper) SetUserAgent(ua string) {
+	s.ua = ua
+}
+
+// SetProxy sets the proxy used for HEC posts
+func (s *SplunkHECShipper) SetProxy(proxy collector.ProxyConfig) {
+	setClientProxy(s.client, proxy)
+}
+
+// SetConnPool tunes connection reuse against the HEC endpoint
+func (s *SplunkHECShipper) SetConnPool(pool ConnPool) {
+	setClientConnPool(s.client, pool)
+}
+
 // Close cleans up resources
 func (s *SplunkHECShipper) Close() error {
 	s.client.CloseIdleConnections()
@@ -201,7 +227,7 @@ func (s *SplunkHECShipper) Close() error {
 func (s *SplunkHECShipper) logPayloadToFile(payload string) {
 	f, err := os.OpenFile(s.debugLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
-		log.Error().Err(err).Str("file", s.debugLogFile).Msg("Failed to open debug log file")
+		s.Logger().Error().Err(err).Str("file", s.debugLogFile).Msg("Failed to open debug log file")
 		return
 	}
 	defer func() { _ = f.Close() }()
@@ -209,6 +235,6 @@ func (s *SplunkHECShipper) logPayloadToFile(payload string) {
 	timestamp := time.Now().Format(time.RFC3339)
 	header := fmt.Sprintf("\n=== Splunk HEC Payload at %s ===\n", timestamp)
 	if _, err := f.WriteString(header + payload + "\n"); err != nil {
-		log.Error().Err(err).Msg("Failed to write to debug log file")
+		s.Logger().Error().Err(err).Msg("Failed to write to debug log file")
 	}
 }